@@ -0,0 +1,108 @@
+// Command breachindex builds the breached password index used to screen new passwords.
+//
+// The input is a SHA-1 dump in the HIBP format, one HASH:COUNT per line, e.g. the
+// pwned-passwords-sha1-ordered-by-hash file. The output is a bloom filter for the
+// password.breached.index_file config:
+//
+//	go run ./cmd/breachindex -in pwned-passwords-sha1-ordered-by-hash.txt -out breached.bloom
+//
+// A dump already sorted by hash can also be used as index file as is, it is searched on disk
+// instead of being read in memory.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"user-service-sample/utils/breach"
+)
+
+func main() {
+	in := flag.String("in", "", "SHA-1 dump, one HASH:COUNT per line")
+	out := flag.String("out", "breached.bloom", "bloom filter file to write")
+	falsePositiveRate := flag.Float64("fp-rate", 0.001, "false positive rate of the bloom filter")
+	minCount := flag.Int("min-count", 1, "skip hashes seen less than this many times in breaches")
+	flag.Parse()
+
+	if *in == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// the filter is sized on the number of hashes, the dump is read twice
+	n, err := eachHash(*in, *minCount, func([20]byte) {})
+	if err != nil {
+		log.Fatalf("failed reading %s, err: %v", *in, err)
+	}
+
+	filter, err := breach.NewBloomFilter(n, *falsePositiveRate)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := eachHash(*in, *minCount, filter.Add); err != nil {
+		log.Fatalf("failed reading %s, err: %v", *in, err)
+	}
+
+	if err := writeFilter(*out, filter); err != nil {
+		log.Fatalf("failed writing %s, err: %v", *out, err)
+	}
+	log.Printf("wrote %d hashes to %s", n, *out)
+}
+
+// eachHash calls fn with every hash of the dump seen at least minCount times
+func eachHash(path string, minCount int, fn func([20]byte)) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var n uint64
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		hash, err := breach.ParseHashLine(line)
+		if err != nil {
+			return n, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if _, count, ok := strings.Cut(line, ":"); ok && minCount > 1 {
+			if c, err := strconv.Atoi(count); err == nil && c < minCount {
+				continue
+			}
+		}
+
+		fn(hash)
+		n++
+	}
+
+	return n, scanner.Err()
+}
+
+func writeFilter(path string, filter io.WriterTo) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	if _, err := filter.WriteTo(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
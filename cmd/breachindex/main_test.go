@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"user-service-sample/utils/breach"
+
+	"github.com/c2fo/testify/assert"
+)
+
+// dumpLine is the line of the password in a HIBP dump
+func dumpLine(password string, count int) string {
+	hash := sha1.Sum([]byte(password))
+	return fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(hash[:])), count)
+}
+
+func writeFile(t *testing.T, lines ...string) string {
+	path := filepath.Join(t.TempDir(), "dump.txt")
+	assert.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600))
+	return path
+}
+
+func TestEachHash(t *testing.T) {
+	dump := writeFile(t,
+		dumpLine("123456", 24230577),
+		"",
+		dumpLine("password", 9545824),
+		dumpLine("rahasia", 3),
+		dumpLine("once", 1),
+	)
+
+	testCases := []struct {
+		title    string
+		path     string
+		minCount int
+
+		expected    []string
+		expectedErr string
+	}{
+		{
+			title:    "every hash",
+			path:     dump,
+			minCount: 1,
+			expected: []string{"123456", "password", "rahasia", "once"},
+		},
+		{
+			title:    "hashes seen at least min count times",
+			path:     dump,
+			minCount: 3,
+			expected: []string{"123456", "password", "rahasia"},
+		},
+		{
+			title:       "invalid line",
+			path:        writeFile(t, dumpLine("123456", 1), "not a hash:2"),
+			minCount:    1,
+			expected:    []string{"123456"},
+			expectedErr: "line 2: " + breach.ErrInvalidHashLine.Error(),
+		},
+		{
+			title:       "missing dump",
+			path:        filepath.Join(t.TempDir(), "missing.txt"),
+			minCount:    1,
+			expected:    []string{},
+			expectedErr: "no such file or directory",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			hashes := [][20]byte{}
+			n, err := eachHash(tc.path, tc.minCount, func(hash [20]byte) {
+				hashes = append(hashes, hash)
+			})
+			if tc.expectedErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			expected := [][20]byte{}
+			for _, password := range tc.expected {
+				expected = append(expected, sha1.Sum([]byte(password)))
+			}
+			assert.Equal(t, uint64(len(expected)), n)
+			assert.Equal(t, expected, hashes)
+		})
+	}
+}
+
+// TestBreachIndex builds the filter as main does, the server has to open it as a bloom filter
+func TestBreachIndex(t *testing.T) {
+	breached := []string{"123456", "password", "rahasia"}
+	lines := []string{}
+	for _, password := range breached {
+		lines = append(lines, dumpLine(password, 2))
+	}
+	dump := writeFile(t, append(lines, dumpLine("once", 1))...)
+
+	n, err := eachHash(dump, 2, func([20]byte) {})
+	assert.NoError(t, err)
+	filter, err := breach.NewBloomFilter(n, 0.001)
+	assert.NoError(t, err)
+	_, err = eachHash(dump, 2, filter.Add)
+	assert.NoError(t, err)
+
+	out := filepath.Join(t.TempDir(), "breached.bloom")
+	assert.NoError(t, writeFilter(out, filter))
+
+	index, err := breach.Open(out)
+	assert.NoError(t, err)
+	assert.IsType(t, &breach.BloomFilter{}, index)
+	for _, password := range breached {
+		found, err := index.Contains(password)
+		assert.NoError(t, err)
+		assert.True(t, found, password)
+	}
+	found, err := index.Contains("correct horse battery staple")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	// the dump itself is sorted by hash, it can be used as index file as is
+	sort.Strings(lines)
+	dumpIndex, err := breach.Open(writeFile(t, lines...))
+	assert.NoError(t, err)
+	for _, password := range breached {
+		found, err := dumpIndex.Contains(password)
+		assert.NoError(t, err)
+		assert.True(t, found, password)
+	}
+}
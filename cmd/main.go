@@ -1,53 +1,141 @@
 package main
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 	"user-service-sample/config"
 	"user-service-sample/generated"
 	"user-service-sample/handler"
 	"user-service-sample/repository"
+	"user-service-sample/utils/breach"
+	"user-service-sample/utils/pruning"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/revocation"
+	"user-service-sample/utils/sms"
 
 	"github.com/labstack/echo/v4"
 )
 
+const (
+	revocationPruneInterval   = time.Hour
+	expiredCodesPruneInterval = time.Hour
+)
+
 var (
 	e      *echo.Echo
 	cfg    *config.Config
 	err    error
-	server generated.ServerInterface
+	server *handler.Server
 )
 
 func init() {
 	e = echo.New()
 
-	cfgFile := "/opt/config.yml"
-	if _, err := os.Stat(cfgFile); err != nil {
-		cfgFile = "config.yml"
+	cfg, err = config.NewConfig(configFile())
+	if err != nil {
+		e.Logger.Fatal(err)
 	}
-	cfg, err = config.NewConfig(cfgFile)
+
+	// the client IP address rate limits requests and is recorded in sessions & audit logs,
+	// it can only be read from X-Forwarded-For when set by a trusted proxy
+	e.IPExtractor, err = request_helper.NewIPExtractor(cfg.Server.TrustedProxies)
 	if err != nil {
 		e.Logger.Fatal(err)
 	}
 
-	server = newServer(cfg)
+	server, err = newServer(cfg)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+
+	swagger, err := generated.GetSwagger()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+
+	// operations secured by 'bearerAuth' in api.yml are authenticated by the group middleware
+	api := e.Group("", server.AuthMiddleware(swagger))
+	generated.RegisterHandlersWithBaseURL(api, server, "")
+}
 
-	generated.RegisterHandlers(e, server)
+func configFile() string {
+	cfgFile := "/opt/config.yml"
+	if _, err := os.Stat(cfgFile); err != nil {
+		cfgFile = "config.yml"
+	}
+	return cfgFile
 }
 
-func newServer(cfg *config.Config) *handler.Server {
+func newServer(cfg *config.Config) (*handler.Server, error) {
 	// dbDsn := os.Getenv("DATABASE_URL")
-	var repo repository.RepositoryInterface = repository.NewRepository(repository.NewRepositoryOptions{
+	repo := repository.NewRepository(repository.NewRepositoryOptions{
 		Dsn: cfg.DB.ToJdbcUrl(),
 	})
+
+	// revoked tokens are shared across instances through the database,
+	// expired denylist entries are pruned in the background
+	revocationStore := revocation.NewPostgresStore(repo.Db)
+	revocation.StartPruning(context.Background(), revocationStore, revocationPruneInterval, func(err error) {
+		e.Logger.Errorf("failed pruning revoked tokens, err: %v", err)
+	})
+	// so are expired one-time codes, MFA challenges & passkey challenges, anyone may request the latter
+	pruning.Start(context.Background(), repo, expiredCodesPruneInterval, func(err error) {
+		e.Logger.Errorf("failed pruning expired codes & challenges, err: %v", err)
+	})
+
+	smsSender, err := sms.NewSender(cfg.SMS)
+	if err != nil {
+		return nil, err
+	}
+
+	var breachedPasswords breach.Index
+	if cfg.Password.Breached.IndexFile != "" {
+		breachedPasswords, err = breach.Open(cfg.Password.Breached.IndexFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	opts := handler.NewServerOptions{
-		Config:     cfg,
-		Repository: repo,
+		Config:            cfg,
+		Repository:        repo,
+		RevocationStore:   revocationStore,
+		SMSSender:         smsSender,
+		BreachedPasswords: breachedPasswords,
 	}
 	return handler.NewServer(opts)
 }
 
+// reloadKeysOnHangup reloads the signing keys from the config file on SIGHUP, so keys can be
+// rotated without a restart. Only the keys are reloaded, every other setting of the file,
+// token settings included, is ignored until the next restart.
+func reloadKeysOnHangup() {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	go func() {
+		for range hangup {
+			reloaded, err := config.NewConfig(configFile())
+			if err != nil {
+				e.Logger.Errorf("failed reloading signing keys, err: %v", err)
+				continue
+			}
+			if err := server.KeyManager.Reload(reloaded.Secret); err != nil {
+				e.Logger.Errorf("failed reloading signing keys, keeping the current keys, err: %v", err)
+				continue
+			}
+			e.Logger.Info("reloaded signing keys")
+		}
+	}()
+}
+
 func main() {
-	if err := e.Start(":1323"); err != nil {
+	reloadKeysOnHangup()
+
+	if err := e.Start(":8080"); err != nil {
 		e.Logger.Fatal(err)
 	}
 }
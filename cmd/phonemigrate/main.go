@@ -0,0 +1,186 @@
+// Command phonemigrate rewrites the phone numbers stored before phone numbers were normalized
+// to their E.164 format, the format they are looked up in, e.g. "+62 812-3456-7890" and
+// "+620812..." become "+6281234567890":
+//
+//	go run ./cmd/phonemigrate -config config.yml -dry-run
+//	go run ./cmd/phonemigrate -config config.yml
+//
+// The phone numbers are normalized with the phone config, the same as the service. A phone number
+// that can't be normalized, e.g. one that is not a mobile phone number of an allowed country, is left
+// as it is, the service still looks it up as it is sent. Users whose phone numbers normalize to the
+// same one collide: none of them is rewritten, they are listed for the operator to resolve and the
+// command exits with status 1. Every rewrite runs in one transaction.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	"os"
+	"sort"
+
+	"user-service-sample/config"
+	"user-service-sample/repository"
+	"user-service-sample/utils/phone"
+)
+
+// storedPhoneNumber is a phone number of a user as stored in the 'users' table
+type storedPhoneNumber struct {
+	UserId             string
+	PhoneNumber        string
+	PendingPhoneNumber sql.NullString
+	Verified           bool
+}
+
+// rewrite is the canonical format of a stored phone number
+type rewrite struct {
+	UserId string
+	Column string
+	From   string
+	To     string
+}
+
+func main() {
+	configFile := flag.String("config", "config.yml", "config file of the service, for the database and phone settings")
+	dryRun := flag.Bool("dry-run", false, "only print the rewrites and collisions")
+	flag.Parse()
+
+	cfg, err := config.NewConfig(*configFile)
+	if err != nil {
+		log.Fatalf("failed loading %s, err: %v", *configFile, err)
+	}
+	normalizer, err := phone.NewNormalizer(cfg.Phone.GetAllowedCountries(), cfg.Phone.GetDefaultCountry())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db := repository.NewRepository(repository.NewRepositoryOptions{
+		Dsn: cfg.DB.ToJdbcUrl(),
+	}).Db
+	defer db.Close()
+
+	ctx := context.Background()
+	stored, err := loadPhoneNumbers(ctx, db)
+	if err != nil {
+		log.Fatalf("failed loading phone numbers, err: %v", err)
+	}
+
+	rewrites, collisions := plan(normalizer, stored)
+	for _, r := range rewrites {
+		log.Printf("user %s: %s %q -> %q", r.UserId, r.Column, r.From, r.To)
+	}
+	for _, phoneNumber := range sortedKeys(collisions) {
+		for _, u := range collisions[phoneNumber] {
+			log.Printf("collision on %s: user %s with phone number %q, verified: %t", phoneNumber, u.UserId, u.PhoneNumber, u.Verified)
+		}
+	}
+
+	if !*dryRun {
+		if err := apply(ctx, db, rewrites); err != nil {
+			log.Fatalf("failed rewriting phone numbers, nothing was rewritten, err: %v", err)
+		}
+	}
+	log.Printf("%d phone numbers rewritten, %d collisions left as they are", len(rewrites), len(collisions))
+
+	if len(collisions) > 0 {
+		os.Exit(1)
+	}
+}
+
+func loadPhoneNumbers(ctx context.Context, db *sql.DB) ([]storedPhoneNumber, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, phone_number, pending_phone_number, phone_verified_at IS NOT NULL
+		FROM users
+		ORDER BY created_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stored []storedPhoneNumber
+	for rows.Next() {
+		var s storedPhoneNumber
+		if err := rows.Scan(&s.UserId, &s.PhoneNumber, &s.PendingPhoneNumber, &s.Verified); err != nil {
+			return nil, err
+		}
+		stored = append(stored, s)
+	}
+
+	return stored, rows.Err()
+}
+
+// plan returns the rewrites of the phone numbers not in their canonical format, and the users
+// whose phone numbers share a canonical format, by canonical format. Colliding users aren't rewritten.
+// Pending phone numbers aren't unique until confirmed, they are always rewritten.
+func plan(normalizer *phone.Normalizer, stored []storedPhoneNumber) ([]rewrite, map[string][]storedPhoneNumber) {
+	canonical := func(phoneNumber string) string {
+		if normalized, err := normalizer.Normalize(phoneNumber); err == nil {
+			return normalized
+		}
+		return phoneNumber
+	}
+
+	holders := make(map[string][]storedPhoneNumber)
+	for _, s := range stored {
+		to := canonical(s.PhoneNumber)
+		holders[to] = append(holders[to], s)
+	}
+
+	var rewrites []rewrite
+	collisions := make(map[string][]storedPhoneNumber)
+	for _, s := range stored {
+		if to := canonical(s.PhoneNumber); len(holders[to]) > 1 {
+			collisions[to] = holders[to]
+		} else if to != s.PhoneNumber {
+			rewrites = append(rewrites, rewrite{UserId: s.UserId, Column: "phone_number", From: s.PhoneNumber, To: to})
+		}
+
+		if s.PendingPhoneNumber.Valid {
+			if to := canonical(s.PendingPhoneNumber.String); to != s.PendingPhoneNumber.String {
+				rewrites = append(rewrites, rewrite{UserId: s.UserId, Column: "pending_phone_number", From: s.PendingPhoneNumber.String, To: to})
+			}
+		}
+	}
+
+	return rewrites, collisions
+}
+
+// apply runs the rewrites in one transaction. A canonical phone number is only the target of
+// one rewrite and is never stored by another user, the unique constraint holds at every step.
+func apply(ctx context.Context, db *sql.DB, rewrites []rewrite) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	for _, r := range rewrites {
+		query := `UPDATE users SET phone_number = $3 WHERE id = $1 AND phone_number = $2`
+		if r.Column == "pending_phone_number" {
+			query = `UPDATE users SET pending_phone_number = $3 WHERE id = $1 AND pending_phone_number = $2`
+		}
+		if _, err = tx.ExecContext(ctx, query, r.UserId, r.From, r.To); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys(m map[string][]storedPhoneNumber) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
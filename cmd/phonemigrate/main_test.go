@@ -0,0 +1,139 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+
+	"user-service-sample/utils/phone"
+
+	"github.com/c2fo/testify/assert"
+)
+
+func TestPlan(t *testing.T) {
+	normalizer, err := phone.NewNormalizer([]string{"ID", "MY", "SG"}, "ID")
+	assert.NoError(t, err)
+
+	var (
+		canonical = storedPhoneNumber{UserId: "user-1", PhoneNumber: "+6281234567890", Verified: true}
+		// the same phone number as canonical, stored before normalization
+		spaced    = storedPhoneNumber{UserId: "user-2", PhoneNumber: "+62 812-3456-7890"}
+		local     = storedPhoneNumber{UserId: "user-3", PhoneNumber: "081234567890", Verified: true}
+		malaysian = storedPhoneNumber{UserId: "user-4", PhoneNumber: "+60 12-345 6789", Verified: true}
+		// a landline can't be normalized, it is left as it is
+		landline = storedPhoneNumber{UserId: "user-5", PhoneNumber: "+62 21 5551 2345"}
+		pending  = storedPhoneNumber{
+			UserId:             "user-6",
+			PhoneNumber:        "+6591234567",
+			PendingPhoneNumber: sql.NullString{String: "+62 0812 3456 7890", Valid: true},
+			Verified:           true,
+		}
+	)
+
+	testCases := []struct {
+		title  string
+		stored []storedPhoneNumber
+
+		expectedRewrites   []rewrite
+		expectedCollisions map[string][]storedPhoneNumber
+	}{
+		{
+			title:              "already canonical",
+			stored:             []storedPhoneNumber{canonical},
+			expectedCollisions: map[string][]storedPhoneNumber{},
+		},
+		{
+			title:  "rewritten to the canonical format",
+			stored: []storedPhoneNumber{spaced, malaysian},
+			expectedRewrites: []rewrite{
+				{UserId: "user-2", Column: "phone_number", From: "+62 812-3456-7890", To: "+6281234567890"},
+				{UserId: "user-4", Column: "phone_number", From: "+60 12-345 6789", To: "+60123456789"},
+			},
+			expectedCollisions: map[string][]storedPhoneNumber{},
+		},
+		{
+			title:              "not normalized",
+			stored:             []storedPhoneNumber{landline},
+			expectedCollisions: map[string][]storedPhoneNumber{},
+		},
+		{
+			title:  "collision with a canonical phone number",
+			stored: []storedPhoneNumber{canonical, spaced, malaysian},
+			expectedRewrites: []rewrite{
+				{UserId: "user-4", Column: "phone_number", From: "+60 12-345 6789", To: "+60123456789"},
+			},
+			expectedCollisions: map[string][]storedPhoneNumber{
+				"+6281234567890": {canonical, spaced},
+			},
+		},
+		{
+			title:  "collision of phone numbers in other formats",
+			stored: []storedPhoneNumber{spaced, local, landline},
+			expectedCollisions: map[string][]storedPhoneNumber{
+				"+6281234567890": {spaced, local},
+			},
+		},
+		{
+			title:              "collision of three users",
+			stored:             []storedPhoneNumber{local, canonical, spaced},
+			expectedCollisions: map[string][]storedPhoneNumber{"+6281234567890": {local, canonical, spaced}},
+		},
+		{
+			title: "collisions on two phone numbers",
+			stored: []storedPhoneNumber{
+				spaced, malaysian, local,
+				{UserId: "user-7", PhoneNumber: "0060123456789"},
+			},
+			expectedCollisions: map[string][]storedPhoneNumber{
+				"+6281234567890": {spaced, local},
+				"+60123456789":   {malaysian, {UserId: "user-7", PhoneNumber: "0060123456789"}},
+			},
+		},
+		{
+			title:  "pending phone number always rewritten",
+			stored: []storedPhoneNumber{canonical, pending},
+			expectedRewrites: []rewrite{
+				{UserId: "user-6", Column: "pending_phone_number", From: "+62 0812 3456 7890", To: "+6281234567890"},
+			},
+			expectedCollisions: map[string][]storedPhoneNumber{},
+		},
+		{
+			title: "pending phone number of a colliding user",
+			stored: []storedPhoneNumber{
+				canonical,
+				{UserId: "user-8", PhoneNumber: "0812 3456 7890", PendingPhoneNumber: sql.NullString{String: "+65 9123 4567", Valid: true}},
+			},
+			expectedRewrites: []rewrite{
+				{UserId: "user-8", Column: "pending_phone_number", From: "+65 9123 4567", To: "+6591234567"},
+			},
+			expectedCollisions: map[string][]storedPhoneNumber{
+				"+6281234567890": {canonical, {UserId: "user-8", PhoneNumber: "0812 3456 7890", PendingPhoneNumber: sql.NullString{String: "+65 9123 4567", Valid: true}}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			rewrites, collisions := plan(normalizer, tc.stored)
+			assert.Equal(t, tc.expectedRewrites, rewrites)
+			assert.Equal(t, tc.expectedCollisions, collisions)
+
+			// a canonical phone number is the target of one rewrite at most, and no other user stores it
+			targets := map[string]bool{}
+			for _, r := range rewrites {
+				if r.Column != "phone_number" {
+					continue
+				}
+				assert.False(t, targets[r.To], r.To)
+				targets[r.To] = true
+				for _, s := range tc.stored {
+					assert.False(t, s.UserId != r.UserId && s.PhoneNumber == r.To, r.To)
+				}
+			}
+		})
+	}
+}
+
+func TestSortedKeys(t *testing.T) {
+	keys := sortedKeys(map[string][]storedPhoneNumber{"+65": nil, "+60": nil, "+62": nil})
+	assert.Equal(t, []string{"+60", "+62", "+65"}, keys)
+}
@@ -1,10 +1,28 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type Config struct {
-	DB     DBConfig     `yaml:"db"`
-	Secret SecretConfig `yaml:"secret"`
+	Server   ServerConfig   `yaml:"server"`
+	DB       DBConfig       `yaml:"db"`
+	Secret   SecretConfig   `yaml:"secret"`
+	OAuth    OAuthConfig    `yaml:"oauth"`
+	Password PasswordConfig `yaml:"password"`
+	OTP      OTPConfig      `yaml:"otp"`
+	SMS      SMSConfig      `yaml:"sms"`
+	Phone    PhoneConfig    `yaml:"phone"`
+	MFA      MFAConfig      `yaml:"mfa"`
+	WebAuthn WebAuthnConfig `yaml:"webauthn"`
+}
+
+type ServerConfig struct {
+	// TrustedProxies are the CIDR ranges of the reverse proxies in front of the service,
+	// the client IP address is read from their X-Forwarded-For header. When empty the
+	// service is reached directly and the address of the connection is the client's.
+	TrustedProxies []string `yaml:"trusted_proxies"`
 }
 
 type DBConfig struct {
@@ -15,9 +33,445 @@ type DBConfig struct {
 	Database string `yaml:"database"`
 }
 
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+	defaultOTPLength           = 6
+	defaultOTPTTL              = 10 * time.Minute
+	defaultOTPMaxAttempts      = 5
+	defaultOTPResendInterval   = time.Minute
+	defaultOTPLockoutThreshold = 10
+	defaultOTPLockoutDuration  = 15 * time.Minute
+
+	defaultStrengthRateLimitRequests = 30
+	defaultStrengthRateLimitPer      = time.Minute
+
+	defaultPhoneCountry = "ID"
+
+	defaultMFAChallengeTTL         = 5 * time.Minute
+	defaultMFAChallengeMaxAttempts = 5
+	defaultMFARecoveryCodes        = 10
+	defaultMFALockoutThreshold     = 10
+	defaultMFALockoutDuration      = 15 * time.Minute
+	defaultTOTPIssuer              = "User Service"
+	defaultWebAuthnRPID            = "localhost"
+	defaultWebAuthnRPName          = "User Service"
+	defaultWebAuthnChallengeTTL    = 5 * time.Minute
+
+	// every passkey log in options request stores a challenge until it's pruned
+	defaultPasskeyLoginRateLimitRequests = 30
+	defaultPasskeyLoginRateLimitPer      = time.Minute
+)
+
 type SecretConfig struct {
+	// RsaPrivatePem & RsaPublicPem are the single signing key used before keysets,
+	// they are only read when Keys is empty.
 	RsaPrivatePem string `yaml:"rsa_private_pem"`
 	RsaPublicPem  string `yaml:"rsa_public_pem"`
+
+	// Keys is the signing keyset, exactly one key must be active (no RetiredAt).
+	Keys []SigningKeyConfig `yaml:"keys"`
+
+	// Issuer & Audiences are issued as 'iss' and 'aud', and required on verification when set.
+	// Issuer is required, it is the public URL of the service the discovery document publishes its endpoints under.
+	Issuer    string   `yaml:"issuer"`
+	Audiences []string `yaml:"audiences"`
+	// AccessTokenTTL & RefreshTokenTTL fall back to 15 minutes and 30 days when not set.
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl"`
+	// Leeway is the clock skew tolerated when validating 'exp', 'nbf' and 'iat'.
+	Leeway time.Duration `yaml:"leeway"`
+	// ClientId & Scopes are issued as 'client_id' and 'scope' on tokens from log in, both are optional.
+	ClientId string   `yaml:"client_id"`
+	Scopes   []string `yaml:"scopes"`
+}
+
+type SigningKeyConfig struct {
+	Kid string `yaml:"kid"`
+	// Algorithm is one of RS256 (default), ES256 (P-256 key) or EdDSA (Ed25519 key).
+	Algorithm string `yaml:"algorithm"`
+	// PrivatePem is required for the active key, retired keys only need PublicPem.
+	PrivatePem string `yaml:"private_pem"`
+	PublicPem  string `yaml:"public_pem"`
+	// RetiredAt marks the key as retired, it keeps verifying tokens until they age out.
+	RetiredAt *time.Time `yaml:"retired_at"`
+}
+
+type OAuthConfig struct {
+	// Clients may authenticate to the OAuth endpoints, e.g. token introspection.
+	Clients []OAuthClientConfig `yaml:"clients"`
+}
+
+type OAuthClientConfig struct {
+	Id     string `yaml:"id"`
+	Secret string `yaml:"secret"`
+}
+
+type PasswordConfig struct {
+	Hashing  PasswordHashingConfig  `yaml:"hashing"`
+	Policy   PasswordPolicyConfig   `yaml:"policy"`
+	History  PasswordHistoryConfig  `yaml:"history"`
+	Breached BreachedPasswordConfig `yaml:"breached"`
+	Strength PasswordStrengthConfig `yaml:"strength"`
+}
+
+type PasswordHistoryConfig struct {
+	// Remember is the number of last passwords, the current one included, a new password
+	// may not be one of. 0 allows reusing any password.
+	Remember int `yaml:"remember"`
+}
+
+// HistorySize is the number of replaced hashes to keep, the current hash is the last remembered one.
+func (c PasswordHistoryConfig) HistorySize() int {
+	if c.Remember <= 1 {
+		return 0
+	}
+	return c.Remember - 1
+}
+
+// PasswordPolicyConfig is the policy new passwords are validated with. Unset lengths & classes
+// keep the defaults: 6 to 64 characters with an uppercase letter, a number and a special character.
+type PasswordPolicyConfig struct {
+	MinLength int `yaml:"min_length"`
+	// MaxLength also bounds the passwords sent to log in or to confirm an action, lowering it locks out
+	// the users of a longer password.
+	MaxLength int `yaml:"max_length"`
+	// RequiredClasses are among uppercase, lowercase, number and special, an empty list requires none.
+	RequiredClasses []string `yaml:"required_classes"`
+	// DeniedWords may not appear anywhere in a password, case insensitive.
+	DeniedWords []string `yaml:"denied_words"`
+	// AllowPersonalInfo allows passwords containing the phone number or name of the user.
+	AllowPersonalInfo bool `yaml:"allow_personal_info"`
+	// MinScore is the strength estimate, from 0 to 4, a password must reach. 0 accepts any password.
+	MinScore int `yaml:"min_score"`
+}
+
+// PasswordStrengthConfig is about the strength estimate endpoint.
+type PasswordStrengthConfig struct {
+	// RateLimit defaults to 30 per minute.
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+}
+
+// RateLimitConfig allows Requests per client IP address over Per, each endpoint has its own defaults.
+type RateLimitConfig struct {
+	Requests int           `yaml:"requests"`
+	Per      time.Duration `yaml:"per"`
+}
+
+// BreachedPasswordConfig screens new passwords against a breached password corpus,
+// nothing is screened when IndexFile is not set.
+type BreachedPasswordConfig struct {
+	// IndexFile is a bloom filter built by cmd/breachindex, or a SHA-1 dump sorted by hash.
+	IndexFile string `yaml:"index_file"`
+}
+
+// PasswordHashingConfig is the algorithm & cost new password hashes are created with,
+// existing hashes with another algorithm or a lower cost are upgraded on log in.
+type PasswordHashingConfig struct {
+	// Algorithm is argon2id (default) or bcrypt.
+	Algorithm string         `yaml:"algorithm"`
+	Argon2id  Argon2idConfig `yaml:"argon2id"`
+	Bcrypt    BcryptConfig   `yaml:"bcrypt"`
+	Pepper    PepperConfig   `yaml:"pepper"`
+}
+
+// PepperConfig is an HMAC key applied to passwords before hashing, kept outside the database.
+// Hashes record the pepper version, they are upgraded to the current version on log in,
+// so a rotated out key must stay configured until no hash uses it anymore.
+type PepperConfig struct {
+	// CurrentVersion peppers new hashes, no pepper is applied when not set.
+	CurrentVersion string            `yaml:"current_version"`
+	Keys           []PepperKeyConfig `yaml:"keys"`
+}
+
+type PepperKeyConfig struct {
+	// Version is made of letters, digits, '-' and '_'.
+	Version string `yaml:"version"`
+	// Secret of at least 16 bytes, or SecretFile to read it from, e.g. a mounted secret.
+	Secret     string `yaml:"secret"`
+	SecretFile string `yaml:"secret_file"`
+}
+
+// Argon2idConfig parameters default to the OWASP recommendation when not set:
+// 19 MiB of memory, 2 iterations, 1 thread, 16 bytes salt & 32 bytes key.
+type Argon2idConfig struct {
+	// Memory is in KiB
+	Memory      uint32 `yaml:"memory"`
+	Iterations  uint32 `yaml:"iterations"`
+	Parallelism uint8  `yaml:"parallelism"`
+	SaltLength  uint32 `yaml:"salt_length"`
+	KeyLength   uint32 `yaml:"key_length"`
+}
+
+type BcryptConfig struct {
+	// Cost defaults to 12 when not set.
+	Cost int `yaml:"cost"`
+}
+
+// OTPConfig is about one-time codes sent by SMS, every field has a default when not set.
+type OTPConfig struct {
+	// Length is the number of digits, defaults to 6.
+	Length int `yaml:"length"`
+	// TTL defaults to 10 minutes.
+	TTL time.Duration `yaml:"ttl"`
+	// MaxAttempts is the number of wrong guesses before a code is unusable, defaults to 5.
+	MaxAttempts int `yaml:"max_attempts"`
+	// ResendInterval is the minimum time between two codes sent to a user, defaults to 1 minute.
+	ResendInterval time.Duration `yaml:"resend_interval"`
+	// LockoutThreshold is the number of wrong codes in a row, across the codes to log in & reset the password,
+	// locking these codes of the user for LockoutDuration, defaults to 10 wrong codes and 15 minutes.
+	LockoutThreshold int           `yaml:"lockout_threshold"`
+	LockoutDuration  time.Duration `yaml:"lockout_duration"`
+}
+
+type SMSConfig struct {
+	// Sender is log (default) or file, FilePath is required by the file sender.
+	Sender   string `yaml:"sender"`
+	FilePath string `yaml:"file_path"`
+}
+
+type PhoneConfig struct {
+	// AllowedCountries are the ISO 3166-1 alpha-2 codes of the countries users may register
+	// a phone number of, e.g. ID, MY or SG. Defaults to ID.
+	AllowedCountries []string `yaml:"allowed_countries"`
+	// DefaultCountry is the country of phone numbers in a local format, e.g. 0812...,
+	// defaults to the first allowed country.
+	DefaultCountry string `yaml:"default_country"`
+}
+
+// MFAConfig is the second factor asked on log in once the user enabled one.
+type MFAConfig struct {
+	// ChallengeTTL is how long the MFA token returned by log in may be exchanged, defaults to 5 minutes.
+	ChallengeTTL time.Duration `yaml:"challenge_ttl"`
+	// ChallengeMaxAttempts is the number of wrong codes before the MFA token is unusable, defaults to 5.
+	ChallengeMaxAttempts int `yaml:"challenge_max_attempts"`
+	// RecoveryCodes is the number of single-use codes given when MFA is enabled, defaults to 10.
+	RecoveryCodes int `yaml:"recovery_codes"`
+	// LockoutThreshold is the number of wrong codes in a row, across MFA tokens, locking the second factor
+	// of the user for LockoutDuration, defaults to 10 wrong codes and 15 minutes.
+	LockoutThreshold int           `yaml:"lockout_threshold"`
+	LockoutDuration  time.Duration `yaml:"lockout_duration"`
+	TOTP             TOTPConfig    `yaml:"totp"`
+}
+
+type TOTPConfig struct {
+	// Issuer is shown by authenticator apps next to the phone number, defaults to "User Service".
+	Issuer string `yaml:"issuer"`
+	// Encryption encrypts the secrets at rest, TOTP can't be enabled without an encryption key.
+	Encryption EncryptionConfig `yaml:"encryption"`
+}
+
+// EncryptionConfig is an AES-256-GCM key kept outside the database. Ciphertexts record the key version,
+// a rotated out key must stay configured until no ciphertext uses it anymore.
+type EncryptionConfig struct {
+	// CurrentVersion encrypts new ciphertexts, nothing can be encrypted when not set.
+	CurrentVersion string                `yaml:"current_version"`
+	Keys           []EncryptionKeyConfig `yaml:"keys"`
+}
+
+type EncryptionKeyConfig struct {
+	// Version is made of letters, digits, '-' and '_'.
+	Version string `yaml:"version"`
+	// Secret of at least 32 bytes, or SecretFile to read it from, e.g. a mounted secret.
+	Secret     string `yaml:"secret"`
+	SecretFile string `yaml:"secret_file"`
+}
+
+// WebAuthnConfig is the relying party passkeys are registered to, see https://www.w3.org/TR/webauthn-2/#relying-party.
+type WebAuthnConfig struct {
+	// RPID is the domain passkeys are bound to, e.g. example.com, defaults to localhost.
+	// Changing it makes every registered passkey unusable.
+	RPID string `yaml:"rp_id"`
+	// RPName is shown by authenticators along with the passkey, defaults to "User Service".
+	RPName string `yaml:"rp_name"`
+	// Origins are the web origins allowed to run the ceremonies, defaults to https://<rp_id>.
+	Origins []string `yaml:"origins"`
+	// ChallengeTTL is how long the options of a ceremony may be answered, defaults to 5 minutes.
+	ChallengeTTL time.Duration `yaml:"challenge_ttl"`
+	// LoginOptionsRateLimit limits POST /v1/login/passkey/options per client IP address, anyone may call it.
+	// Defaults to 30 per minute.
+	LoginOptionsRateLimit RateLimitConfig `yaml:"login_options_rate_limit"`
+}
+
+func (m MFAConfig) GetChallengeTTL() time.Duration {
+	if m.ChallengeTTL <= 0 {
+		return defaultMFAChallengeTTL
+	}
+
+	return m.ChallengeTTL
+}
+
+func (m MFAConfig) GetChallengeMaxAttempts() int {
+	if m.ChallengeMaxAttempts <= 0 {
+		return defaultMFAChallengeMaxAttempts
+	}
+
+	return m.ChallengeMaxAttempts
+}
+
+func (m MFAConfig) GetRecoveryCodes() int {
+	if m.RecoveryCodes <= 0 {
+		return defaultMFARecoveryCodes
+	}
+
+	return m.RecoveryCodes
+}
+
+func (m MFAConfig) GetLockoutThreshold() int {
+	if m.LockoutThreshold <= 0 {
+		return defaultMFALockoutThreshold
+	}
+
+	return m.LockoutThreshold
+}
+
+func (m MFAConfig) GetLockoutDuration() time.Duration {
+	if m.LockoutDuration <= 0 {
+		return defaultMFALockoutDuration
+	}
+
+	return m.LockoutDuration
+}
+
+func (t TOTPConfig) GetIssuer() string {
+	if t.Issuer == "" {
+		return defaultTOTPIssuer
+	}
+
+	return t.Issuer
+}
+
+func (w WebAuthnConfig) GetRPID() string {
+	if w.RPID == "" {
+		return defaultWebAuthnRPID
+	}
+
+	return w.RPID
+}
+
+func (w WebAuthnConfig) GetRPName() string {
+	if w.RPName == "" {
+		return defaultWebAuthnRPName
+	}
+
+	return w.RPName
+}
+
+func (w WebAuthnConfig) GetOrigins() []string {
+	if len(w.Origins) == 0 {
+		return []string{"https://" + w.GetRPID()}
+	}
+
+	return w.Origins
+}
+
+func (w WebAuthnConfig) GetChallengeTTL() time.Duration {
+	if w.ChallengeTTL <= 0 {
+		return defaultWebAuthnChallengeTTL
+	}
+
+	return w.ChallengeTTL
+}
+
+func (w WebAuthnConfig) GetLoginOptionsRateLimit() RateLimitConfig {
+	return w.LoginOptionsRateLimit.withDefaults(defaultPasskeyLoginRateLimitRequests, defaultPasskeyLoginRateLimitPer)
+}
+
+func (p PhoneConfig) GetAllowedCountries() []string {
+	if len(p.AllowedCountries) == 0 {
+		return []string{defaultPhoneCountry}
+	}
+
+	return p.AllowedCountries
+}
+
+func (p PhoneConfig) GetDefaultCountry() string {
+	if p.DefaultCountry == "" {
+		return p.GetAllowedCountries()[0]
+	}
+
+	return p.DefaultCountry
+}
+
+func (o OTPConfig) GetLength() int {
+	if o.Length <= 0 {
+		return defaultOTPLength
+	}
+
+	return o.Length
+}
+
+func (o OTPConfig) GetTTL() time.Duration {
+	if o.TTL <= 0 {
+		return defaultOTPTTL
+	}
+
+	return o.TTL
+}
+
+func (o OTPConfig) GetMaxAttempts() int {
+	if o.MaxAttempts <= 0 {
+		return defaultOTPMaxAttempts
+	}
+
+	return o.MaxAttempts
+}
+
+func (o OTPConfig) GetResendInterval() time.Duration {
+	if o.ResendInterval <= 0 {
+		return defaultOTPResendInterval
+	}
+
+	return o.ResendInterval
+}
+
+func (o OTPConfig) GetLockoutThreshold() int {
+	if o.LockoutThreshold <= 0 {
+		return defaultOTPLockoutThreshold
+	}
+
+	return o.LockoutThreshold
+}
+
+func (o OTPConfig) GetLockoutDuration() time.Duration {
+	if o.LockoutDuration <= 0 {
+		return defaultOTPLockoutDuration
+	}
+
+	return o.LockoutDuration
+}
+
+func (p PasswordStrengthConfig) GetRateLimit() RateLimitConfig {
+	return p.RateLimit.withDefaults(defaultStrengthRateLimitRequests, defaultStrengthRateLimitPer)
+}
+
+// withDefaults sets the requests & period of the endpoint that are not set
+func (r RateLimitConfig) withDefaults(requests int, per time.Duration) RateLimitConfig {
+	if r.Requests <= 0 {
+		r.Requests = requests
+	}
+	if r.Per <= 0 {
+		r.Per = per
+	}
+
+	return r
+}
+
+func (s SecretConfig) GetAccessTokenTTL() time.Duration {
+	if s.AccessTokenTTL <= 0 {
+		return defaultAccessTokenTTL
+	}
+
+	return s.AccessTokenTTL
+}
+
+func (s SecretConfig) GetRefreshTokenTTL() time.Duration {
+	if s.RefreshTokenTTL <= 0 {
+		return defaultRefreshTokenTTL
+	}
+
+	return s.RefreshTokenTTL
 }
 
 func (db *DBConfig) ToJdbcUrl() string {
@@ -20,11 +20,24 @@ CREATE TABLE users (
     "created_at" timestamp NOT NULL DEFAULT timezone('utc', now()),
     "updated_at" timestamp NOT NULL DEFAULT timezone('utc', now()),
     "deleted_at" timestamp,
+    -- E.164, e.g. +6281234567890, phone numbers stored before they were normalized are rewritten by cmd/phonemigrate
     "phone_number" VARCHAR(20) NOT NULL UNIQUE,
     "full_name" VARCHAR(100) NOT NULL,
-    "password_hash" VARCHAR(100) NOT NULL,
-    "salt" VARCHAR(20) NOT NULL,
-    "login_count" INTEGER NOT NULL DEFAULT 0
+    -- PHC string, e.g. $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+    "password_hash" VARCHAR(255) NOT NULL,
+    -- only set for legacy SHA-256 hashes, cleared when the hash is upgraded on log in
+    "salt" VARCHAR(20),
+    "login_count" INTEGER NOT NULL DEFAULT 0,
+    -- NULL while the registration is pending, set once the phone number is verified with a one-time code
+    "phone_verified_at" timestamp,
+    -- new phone number waiting for the code sent to it, it replaces 'phone_number' once confirmed
+    "pending_phone_number" VARCHAR(20),
+    -- wrong MFA codes in a row across MFA challenges, reaching the threshold locks MFA until 'mfa_locked_until'
+    "mfa_failed_attempts" INTEGER NOT NULL DEFAULT 0,
+    "mfa_locked_until" timestamp,
+    -- wrong codes in a row across the codes to log in & reset the password, reaching the threshold locks them until 'otp_locked_until'
+    "otp_failed_attempts" INTEGER NOT NULL DEFAULT 0,
+    "otp_locked_until" timestamp
 );
 
 -- add trigger to 'users'
@@ -32,9 +45,192 @@ CREATE TRIGGER refresh_users_updated_at BEFORE UPDATE
 ON users FOR EACH ROW EXECUTE PROCEDURE 
 refresh_updated_at_column();
 
+-- 'user_sessions' table
+-- one session per log in, the tokens issued for it carry its id. A session is active
+-- until it is revoked or its refresh token expires, 'expires_at' follows the latest refresh.
+CREATE TABLE user_sessions (
+    "id" uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    "created_at" timestamp NOT NULL DEFAULT timezone('utc', now()),
+    "user_id" uuid NOT NULL REFERENCES users ("id") ON DELETE CASCADE,
+    "user_agent" VARCHAR(512) NOT NULL DEFAULT '',
+    "ip_address" VARCHAR(45) NOT NULL DEFAULT '',
+    "device_label" VARCHAR(100) NOT NULL DEFAULT '',
+    "last_used_at" timestamp NOT NULL,
+    "expires_at" timestamp NOT NULL,
+    "revoked_at" timestamp
+);
+
+CREATE INDEX user_sessions_user_id_idx ON user_sessions ("user_id");
+
+-- 'refresh_tokens' table
+-- only the SHA-256 hash of a refresh token is stored. Every refresh rotates the
+-- token inside the same family, reusing a rotated token revokes the whole family.
+CREATE TABLE refresh_tokens (
+    "id" uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    "created_at" timestamp NOT NULL DEFAULT timezone('utc', now()),
+    "user_id" uuid NOT NULL REFERENCES users ("id") ON DELETE CASCADE,
+    "family_id" uuid NOT NULL,
+    "session_id" uuid REFERENCES user_sessions ("id") ON DELETE CASCADE,
+    "token_hash" VARCHAR(64) NOT NULL UNIQUE,
+    "expires_at" timestamp NOT NULL,
+    "rotated_at" timestamp,
+    "revoked_at" timestamp
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens ("family_id");
+CREATE INDEX refresh_tokens_session_id_idx ON refresh_tokens ("session_id");
+
+-- 'revoked_tokens' table
+-- denylist of access token ids (jti) and session ids (sid), entries are pruned once the token would have expired anyway
+CREATE TABLE revoked_tokens (
+    "token_id" VARCHAR(64) PRIMARY KEY,
+    "created_at" timestamp NOT NULL DEFAULT timezone('utc', now()),
+    "expires_at" timestamp NOT NULL
+);
+
+CREATE INDEX revoked_tokens_expires_at_idx ON revoked_tokens ("expires_at");
+
+-- 'revoked_user_tokens' table
+-- "log out everywhere", every token of the user issued before 'issued_before' is revoked,
+-- it is rounded up to the next second as the iat claim has no fraction of a second
+CREATE TABLE revoked_user_tokens (
+    "user_id" uuid PRIMARY KEY REFERENCES users ("id") ON DELETE CASCADE,
+    "created_at" timestamp NOT NULL DEFAULT timezone('utc', now()),
+    "issued_before" timestamp NOT NULL,
+    "expires_at" timestamp NOT NULL
+);
+
+-- 'one_time_codes' table
+-- short-lived numeric codes sent by SMS, only a hash of the code is stored.
+-- a code is consumed once, and is unusable after too many wrong attempts or past expiry.
+CREATE TABLE one_time_codes (
+    "id" uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    "created_at" timestamp NOT NULL DEFAULT timezone('utc', now()),
+    "user_id" uuid NOT NULL REFERENCES users ("id") ON DELETE CASCADE,
+    "purpose" VARCHAR(30) NOT NULL,
+    "code_hash" VARCHAR(64) NOT NULL,
+    "attempts" INTEGER NOT NULL DEFAULT 0,
+    "expires_at" timestamp NOT NULL,
+    "consumed_at" timestamp
+);
+
+CREATE INDEX one_time_codes_user_id_purpose_idx ON one_time_codes ("user_id", "purpose");
+CREATE INDEX one_time_codes_expires_at_idx ON one_time_codes ("expires_at");
+
+-- 'password_history' table
+-- hashes replaced by a password change or reset, a new password may not match the recent ones.
+-- only the configured number of most recent entries is kept per user.
+CREATE TABLE password_history (
+    "id" uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    "created_at" timestamp NOT NULL DEFAULT timezone('utc', now()),
+    "user_id" uuid NOT NULL REFERENCES users ("id") ON DELETE CASCADE,
+    "password_hash" VARCHAR(255) NOT NULL,
+    "salt" VARCHAR(255)
+);
+
+CREATE INDEX password_history_user_id_created_at_idx ON password_history ("user_id", "created_at" DESC);
+
+-- 'user_totp' table
+-- TOTP authenticator of a user, the secret is encrypted with a key kept outside the database.
+-- 'confirmed_at' is NULL until a first code confirms the enrollment, only a confirmed authenticator is asked on log in.
+CREATE TABLE user_totp (
+    "user_id" uuid PRIMARY KEY REFERENCES users ("id") ON DELETE CASCADE,
+    "created_at" timestamp NOT NULL DEFAULT timezone('utc', now()),
+    -- <key version>$<base64 nonce & AES-256-GCM sealed secret>
+    "secret_ciphertext" VARCHAR(255) NOT NULL,
+    "confirmed_at" timestamp,
+    -- time step of the last accepted code, a code of the same or an earlier step is a replay
+    "last_used_step" BIGINT NOT NULL DEFAULT 0
+);
+
+-- 'mfa_challenges' table
+-- a log in of a user with MFA waiting for the second factor, only the SHA-256 hash of the MFA token is stored.
+-- a challenge is consumed once, and is unusable after too many wrong codes or past expiry.
+CREATE TABLE mfa_challenges (
+    "id" uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    "created_at" timestamp NOT NULL DEFAULT timezone('utc', now()),
+    "user_id" uuid NOT NULL REFERENCES users ("id") ON DELETE CASCADE,
+    "token_hash" VARCHAR(64) NOT NULL UNIQUE,
+    -- device label of the log in request, given to the session once the challenge is passed
+    "device_label" VARCHAR(100) NOT NULL DEFAULT '',
+    "attempts" INTEGER NOT NULL DEFAULT 0,
+    "expires_at" timestamp NOT NULL,
+    "consumed_at" timestamp
+);
+
+CREATE INDEX mfa_challenges_user_id_idx ON mfa_challenges ("user_id");
+CREATE INDEX mfa_challenges_expires_at_idx ON mfa_challenges ("expires_at");
+
+-- 'mfa_recovery_codes' table
+-- single-use codes passing an MFA challenge without the authenticator app, only the SHA-256 hash of a code is stored.
+-- a new set replaces the previous one.
+CREATE TABLE mfa_recovery_codes (
+    "id" uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    "created_at" timestamp NOT NULL DEFAULT timezone('utc', now()),
+    "user_id" uuid NOT NULL REFERENCES users ("id") ON DELETE CASCADE,
+    "code_hash" VARCHAR(64) NOT NULL,
+    "used_at" timestamp,
+    UNIQUE ("user_id", "code_hash")
+);
+
+-- 'audit_logs' table
+-- security relevant events of a user, e.g. a log in with a recovery code.
+CREATE TABLE audit_logs (
+    "id" uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    "created_at" timestamp NOT NULL DEFAULT timezone('utc', now()),
+    "user_id" uuid NOT NULL REFERENCES users ("id") ON DELETE CASCADE,
+    "event" VARCHAR(50) NOT NULL,
+    "ip_address" VARCHAR(45) NOT NULL DEFAULT '',
+    "user_agent" VARCHAR(512) NOT NULL DEFAULT '',
+    -- event specific details, e.g. the number of recovery codes left
+    "details" VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX audit_logs_user_id_idx ON audit_logs ("user_id", "created_at");
+
+-- 'passkeys' table
+-- WebAuthn credentials a user signs in with, the public key is a COSE_Key.
+-- the signature counter of a credential must grow with every assertion, unless the authenticator has none (always 0).
+CREATE TABLE passkeys (
+    "id" uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    "created_at" timestamp NOT NULL DEFAULT timezone('utc', now()),
+    "updated_at" timestamp NOT NULL DEFAULT timezone('utc', now()),
+    "user_id" uuid NOT NULL REFERENCES users ("id") ON DELETE CASCADE,
+    -- base64url credential id
+    "credential_id" VARCHAR(1400) NOT NULL UNIQUE,
+    "public_key" BYTEA NOT NULL,
+    "sign_count" BIGINT NOT NULL DEFAULT 0,
+    -- comma separated transports hinted by the authenticator, e.g. internal,hybrid
+    "transports" VARCHAR(255) NOT NULL DEFAULT '',
+    "name" VARCHAR(100) NOT NULL,
+    "backup_eligible" BOOLEAN NOT NULL DEFAULT FALSE,
+    "backed_up" BOOLEAN NOT NULL DEFAULT FALSE,
+    "last_used_at" timestamp
+);
+
+CREATE INDEX passkeys_user_id_idx ON passkeys ("user_id");
+
+-- 'webauthn_challenges' table
+-- challenges of the passkey ceremonies, only the SHA-256 hash of a challenge is stored.
+-- a challenge is consumed once, a registration challenge belongs to the user, a sign in challenge to nobody yet.
+CREATE TABLE webauthn_challenges (
+    "id" uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    "created_at" timestamp NOT NULL DEFAULT timezone('utc', now()),
+    "user_id" uuid REFERENCES users ("id") ON DELETE CASCADE,
+    -- registration or login
+    "ceremony" VARCHAR(20) NOT NULL,
+    "challenge_hash" VARCHAR(64) NOT NULL UNIQUE,
+    "expires_at" timestamp NOT NULL,
+    "consumed_at" timestamp,
+    -- set when the user confirmed the registration with the current password or MFA, required to register the passkey
+    "stepped_up_at" timestamp
+);
+
+CREATE INDEX webauthn_challenges_expires_at_idx ON webauthn_challenges ("expires_at");
+
 -- sample data, with password: pAssW0$ds
-INSERT INTO users ("phone_number", "full_name", "password_hash", "salt") 
+INSERT INTO users ("phone_number", "full_name", "password_hash", "salt", "phone_verified_at") 
 VALUES 
-  ('+62810000001', 'Sample User 1', '9996f6bb66439b2d8bae91fc8f0fd81158c9d4f91ba9a892d30e2581ec8ddb26', '486j+Is1QGia1g=='), 
-  ('+62810000002', 'Sample User 2', '8521f9afd04ebf8117221921734a348aa5d098571694ec4167e0c4be85e694fd', 'yX3sLROvZRptpQ=='),
-  ('+62810000003', 'Sample User 3', '1c7784682871a16adc1c767f174a5353d47b84453c0bd2ad7ee0a4222764f2b9', '595lrZruPRtyGg==');
+  ('+62810000001', 'Sample User 1', '9996f6bb66439b2d8bae91fc8f0fd81158c9d4f91ba9a892d30e2581ec8ddb26', '486j+Is1QGia1g==', timezone('utc', now())), 
+  ('+62810000002', 'Sample User 2', '8521f9afd04ebf8117221921734a348aa5d098571694ec4167e0c4be85e694fd', 'yX3sLROvZRptpQ==', timezone('utc', now())),
+  ('+62810000003', 'Sample User 3', '1c7784682871a16adc1c767f174a5353d47b84453c0bd2ad7ee0a4222764f2b9', '595lrZruPRtyGg==', timezone('utc', now()));
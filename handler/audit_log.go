@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"user-service-sample/repository"
+	"user-service-sample/utils/string_helper"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// auditEventRecoveryCodeUsed is a log in or a step-up with a recovery code instead of the authenticator app
+	auditEventRecoveryCodeUsed = "mfa_recovery_code_used"
+	// auditEventMFALocked is MFA of the user locked after too many wrong codes in a row
+	auditEventMFALocked = "mfa_locked"
+	// auditEventOTPLocked is the codes to log in & reset the password of the user locked after too many wrong codes in a row
+	auditEventOTPLocked = "otp_locked"
+	// auditEventRecoveryCodesRegenerated is a new set of recovery codes replacing the previous one
+	auditEventRecoveryCodesRegenerated = "mfa_recovery_codes_regenerated"
+	// auditEventPasskeyRegistered is a new passkey the user may log in with
+	auditEventPasskeyRegistered = "passkey_registered"
+	// auditEventPasskeyDeleted is a passkey that can't log in anymore
+	auditEventPasskeyDeleted = "passkey_deleted"
+	// auditEventPasskeysDeleted is every passkey of the user deleted along with every session
+	auditEventPasskeysDeleted = "passkeys_deleted"
+)
+
+// auditLog records the event of the user with the client of the request. Failing is only logged,
+// the action was already done.
+func (s *Server) auditLog(ctx echo.Context, tracestr, userId, event, details string) {
+	err := s.Repository.InsertAuditLog(ctx.Request().Context(), nil, repository.InsertAuditLogInput{
+		UserId:    userId,
+		Event:     event,
+		IpAddress: ctx.RealIP(),
+		UserAgent: string_helper.Truncate(ctx.Request().UserAgent(), sessionUserAgentMaxLen),
+		Details:   details,
+	})
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed InsertAuditLog %s of user %s, err: %v", tracestr, event, userId, err)
+	}
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"user-service-sample/utils/authentication"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// security scheme name in api.yml
+	bearerAuthScheme = "bearerAuth"
+)
+
+// AuthMiddleware authenticates every operation secured by the 'bearerAuth' scheme in the API spec,
+// handlers read the verified claims with authentication.ClaimsFromContext.
+func (s *Server) AuthMiddleware(swagger *openapi3.T) echo.MiddlewareFunc {
+	return authentication.Middleware(authentication.MiddlewareOptions{
+		Swagger:        swagger,
+		SecurityScheme: bearerAuthScheme,
+		Verify: func(ctx echo.Context) (*authentication.Claims, error) {
+			return s.KeyManager.VerifyToken(ctx, s.RevocationStore)
+		},
+	})
+}
@@ -0,0 +1,394 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-service-sample/config"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/revocation"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+type failingRevocationStore struct {
+	revocation.Store
+}
+
+func (failingRevocationStore) IsRevoked(ctx context.Context, token revocation.Token) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestAuthMiddleware(t *testing.T) {
+
+	privateKey, _ := jwt.ParseRSAPrivateKeyFromPEM([]byte(test_helper.TestRsaPrivatePem))
+	signTestToken := func(modify func(claims jwt.MapClaims)) string {
+		claims := jwt.MapClaims{
+			"id":  test_helper.TestUserId,
+			"sub": test_helper.TestUserId,
+			"iss": test_helper.TestIssuer,
+			"aud": []string{test_helper.TestAudience},
+			"iat": time.Now().Unix(),
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+		modify(claims)
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(privateKey)
+		return "Bearer " + token
+	}
+
+	expiredJWT := signTestToken(func(claims jwt.MapClaims) {
+		claims["exp"] = time.Now().Add(-time.Hour).Unix()
+	})
+
+	// HS256 token using the RSA public key as HMAC secret, must not be accepted
+	algConfusionJWT, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":  test_helper.TestUserId,
+		"sub": test_helper.TestUserId,
+		"iss": test_helper.TestIssuer,
+		"aud": []string{test_helper.TestAudience},
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(test_helper.TestRsaPublicPem))
+
+	// keyset with an ES256 active key, an EdDSA and the RS256 key retired a minute ago
+	retiredAt := time.Now().Add(-time.Minute)
+	mixedKeysCfg := config.SecretConfig{
+		Issuer:    test_helper.TestIssuer,
+		Audiences: []string{test_helper.TestAudience},
+		Keys: []config.SigningKeyConfig{{
+			Kid:        "ec-key",
+			Algorithm:  authentication.AlgorithmES256,
+			PrivatePem: test_helper.TestEcPrivatePem,
+		}, {
+			Kid:        "ed-key",
+			Algorithm:  authentication.AlgorithmEdDSA,
+			PrivatePem: test_helper.TestEdPrivatePem,
+			RetiredAt:  &retiredAt,
+		}, {
+			Kid:        "rsa-key",
+			PrivatePem: test_helper.TestRsaPrivatePem,
+			RetiredAt:  &retiredAt,
+		}},
+	}
+	useMixedKeys := func(t *testing.T, s *serverMock) {
+		s.config.Secret.Keys = mixedKeysCfg.Keys
+		s.reloadKeys(t)
+	}
+	testUser := repository.User{Id: test_helper.TestUserId, FullName: "Test User"}
+	mixedKeys, _ := authentication.NewKeyManager(mixedKeysCfg)
+	es256JWT, _ := mixedKeys.GenerateSignedToken(testUser, "")
+	idToken, _ := mixedKeys.GenerateIDToken(testUser, "", es256JWT)
+
+	edPrivateKey, _ := jwt.ParseEdPrivateKeyFromPEM([]byte(test_helper.TestEdPrivatePem))
+	signWithKey := func(method jwt.SigningMethod, kid string, key interface{}) string {
+		token := jwt.NewWithClaims(method, jwt.MapClaims{
+			"id":  test_helper.TestUserId,
+			"sub": test_helper.TestUserId,
+			"iss": test_helper.TestIssuer,
+			"aud": []string{test_helper.TestAudience},
+			"iat": time.Now().Unix(),
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		token.Header["kid"] = kid
+		signed, _ := token.SignedString(key)
+		return "Bearer " + signed
+	}
+
+	// tokens issued within the second of a revocation of the user and in the second after it,
+	// iat has no fraction of a second so the first one may have been issued before the revocation
+	revokedAt := time.Now().Truncate(time.Second).Add(-2*time.Second + 500*time.Millisecond)
+	sameSecondJWT := signTestToken(func(claims jwt.MapClaims) {
+		claims["iat"] = revokedAt.Unix()
+	})
+	nextSecondJWT := signTestToken(func(claims jwt.MapClaims) {
+		claims["iat"] = revokedAt.Unix() + 1
+	})
+
+	invalidChallenge := `Bearer realm="user-service", error="invalid_token", error_description="the access token is invalid"`
+
+	testCases := []struct {
+		title        string
+		method       string
+		path         string
+		jwt          string
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode  int
+		expectedErrMsg    string
+		expectedChallenge string
+		expectedUserId    string
+	}{
+		{
+			title:            "operation without security requirement is not authenticated",
+			method:           echo.POST,
+			path:             "/v1/login",
+			expectedHttpCode: http.StatusOK,
+		},
+		{
+			title:             "missing Authorization header",
+			method:            echo.GET,
+			path:              "/v1/user",
+			expectedHttpCode:  http.StatusUnauthorized,
+			expectedErrMsg:    response.UnauthorizedErrorMsg,
+			expectedChallenge: `Bearer realm="user-service"`,
+		},
+		{
+			title:             "not a bearer token",
+			method:            echo.PATCH,
+			path:              "/v1/user",
+			jwt:               "Basic dXNlcjpwYXNz",
+			expectedHttpCode:  http.StatusUnauthorized,
+			expectedErrMsg:    response.UnauthorizedErrorMsg,
+			expectedChallenge: `Bearer realm="user-service", error="invalid_token", error_description="the access token is invalid"`,
+		},
+		{
+			title:             "invalid token",
+			method:            echo.GET,
+			path:              "/v1/user",
+			jwt:               "Bearer invalid-token",
+			expectedHttpCode:  http.StatusUnauthorized,
+			expectedErrMsg:    response.UnauthorizedErrorMsg,
+			expectedChallenge: `Bearer realm="user-service", error="invalid_token", error_description="the access token is invalid"`,
+		},
+		{
+			title:             "expired token",
+			method:            echo.POST,
+			path:              "/v1/logout",
+			jwt:               expiredJWT,
+			expectedHttpCode:  http.StatusUnauthorized,
+			expectedErrMsg:    response.UnauthorizedErrorMsg,
+			expectedChallenge: `Bearer realm="user-service", error="invalid_token", error_description="the access token expired"`,
+		},
+		{
+			title:             "token signed with an unexpected algorithm",
+			method:            echo.GET,
+			path:              "/v1/user",
+			jwt:               "Bearer " + algConfusionJWT,
+			expectedHttpCode:  http.StatusUnauthorized,
+			expectedErrMsg:    response.UnauthorizedErrorMsg,
+			expectedChallenge: invalidChallenge,
+		},
+		{
+			title:  "token from another issuer",
+			method: echo.GET,
+			path:   "/v1/user",
+			jwt: signTestToken(func(claims jwt.MapClaims) {
+				claims["iss"] = "https://evil.example.com"
+			}),
+			expectedHttpCode:  http.StatusUnauthorized,
+			expectedErrMsg:    response.UnauthorizedErrorMsg,
+			expectedChallenge: invalidChallenge,
+		},
+		{
+			title:  "token for another audience",
+			method: echo.GET,
+			path:   "/v1/user",
+			jwt: signTestToken(func(claims jwt.MapClaims) {
+				claims["aud"] = []string{"another-service"}
+			}),
+			expectedHttpCode:  http.StatusUnauthorized,
+			expectedErrMsg:    response.UnauthorizedErrorMsg,
+			expectedChallenge: invalidChallenge,
+		},
+		{
+			title:  "token without expiry",
+			method: echo.GET,
+			path:   "/v1/user",
+			jwt: signTestToken(func(claims jwt.MapClaims) {
+				delete(claims, "exp")
+			}),
+			expectedHttpCode:  http.StatusUnauthorized,
+			expectedErrMsg:    response.UnauthorizedErrorMsg,
+			expectedChallenge: invalidChallenge,
+		},
+		{
+			title:  "token not valid yet",
+			method: echo.GET,
+			path:   "/v1/user",
+			jwt: signTestToken(func(claims jwt.MapClaims) {
+				claims["nbf"] = time.Now().Add(time.Minute).Unix()
+			}),
+			expectedHttpCode:  http.StatusUnauthorized,
+			expectedErrMsg:    response.UnauthorizedErrorMsg,
+			expectedChallenge: invalidChallenge,
+		},
+		{
+			title:  "token not valid yet within clock skew leeway",
+			method: echo.GET,
+			path:   "/v1/user",
+			jwt: signTestToken(func(claims jwt.MapClaims) {
+				claims["nbf"] = time.Now().Add(10 * time.Second).Unix()
+				claims["iat"] = time.Now().Add(10 * time.Second).Unix()
+			}),
+			expectations: func(t *testing.T, s *serverMock) {
+				s.config.Secret.Leeway = 30 * time.Second
+				s.restartKeyManager(t)
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedUserId:   test_helper.TestUserId,
+		},
+		{
+			title:  "revoked token",
+			method: echo.GET,
+			path:   "/v1/user",
+			jwt:    test_helper.TestUserJWT,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.revocationStore.RevokeUser(context.Background(), test_helper.TestUserId, time.Now(), time.Now().Add(time.Hour))
+			},
+			expectedHttpCode:  http.StatusUnauthorized,
+			expectedErrMsg:    response.UnauthorizedErrorMsg,
+			expectedChallenge: `Bearer realm="user-service", error="invalid_token", error_description="the access token has been revoked"`,
+		},
+		{
+			title:  "token issued in the same second as the revocation of the user",
+			method: echo.GET,
+			path:   "/v1/user",
+			jwt:    sameSecondJWT,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.revocationStore.RevokeUser(context.Background(), test_helper.TestUserId, revokedAt, time.Now().Add(time.Hour))
+			},
+			expectedHttpCode:  http.StatusUnauthorized,
+			expectedErrMsg:    response.UnauthorizedErrorMsg,
+			expectedChallenge: `Bearer realm="user-service", error="invalid_token", error_description="the access token has been revoked"`,
+		},
+		{
+			title:  "token issued in the second after the revocation of the user",
+			method: echo.GET,
+			path:   "/v1/user",
+			jwt:    nextSecondJWT,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.revocationStore.RevokeUser(context.Background(), test_helper.TestUserId, revokedAt, time.Now().Add(time.Hour))
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedUserId:   test_helper.TestUserId,
+		},
+		{
+			title:  "revocation store unavailable",
+			method: echo.GET,
+			path:   "/v1/user",
+			jwt:    test_helper.TestUserJWT,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.server.RevocationStore = failingRevocationStore{}
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:            "ES256 token signed by the active key",
+			method:           echo.GET,
+			path:             "/v1/user",
+			jwt:              "Bearer " + es256JWT,
+			expectations:     useMixedKeys,
+			expectedHttpCode: http.StatusOK,
+			expectedUserId:   test_helper.TestUserId,
+		},
+		{
+			title:            "EdDSA token signed by a retired key",
+			method:           echo.GET,
+			path:             "/v1/user",
+			jwt:              signWithKey(jwt.SigningMethodEdDSA, "ed-key", edPrivateKey),
+			expectations:     useMixedKeys,
+			expectedHttpCode: http.StatusOK,
+			expectedUserId:   test_helper.TestUserId,
+		},
+		{
+			title:            "RS256 token signed by a retired key",
+			method:           echo.GET,
+			path:             "/v1/user",
+			jwt:              signWithKey(jwt.SigningMethodRS256, "rsa-key", privateKey),
+			expectations:     useMixedKeys,
+			expectedHttpCode: http.StatusOK,
+			expectedUserId:   test_helper.TestUserId,
+		},
+		{
+			title:             "token algorithm not matching the algorithm of its kid",
+			method:            echo.GET,
+			path:              "/v1/user",
+			jwt:               signWithKey(jwt.SigningMethodRS256, "ec-key", privateKey),
+			expectations:      useMixedKeys,
+			expectedHttpCode:  http.StatusUnauthorized,
+			expectedErrMsg:    response.UnauthorizedErrorMsg,
+			expectedChallenge: invalidChallenge,
+		},
+		{
+			title:             "ID token used as access token",
+			method:            echo.GET,
+			path:              "/v1/user",
+			jwt:               "Bearer " + idToken,
+			expectations:      useMixedKeys,
+			expectedHttpCode:  http.StatusUnauthorized,
+			expectedErrMsg:    response.UnauthorizedErrorMsg,
+			expectedChallenge: invalidChallenge,
+		},
+		{
+			title:             "RS256 token without kid when the active key is ES256",
+			method:            echo.GET,
+			path:              "/v1/user",
+			jwt:               test_helper.TestUserJWT,
+			expectations:      useMixedKeys,
+			expectedHttpCode:  http.StatusUnauthorized,
+			expectedErrMsg:    response.UnauthorizedErrorMsg,
+			expectedChallenge: invalidChallenge,
+		},
+		{
+			title:            "valid token",
+			method:           echo.GET,
+			path:             "/v1/user",
+			jwt:              test_helper.TestUserJWT,
+			expectedHttpCode: http.StatusOK,
+			expectedUserId:   test_helper.TestUserId,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			e := echo.New()
+			req := httptest.NewRequest(tc.method, "/", nil)
+			if tc.jwt != "" {
+				req.Header.Set(authentication.AuthHeaderKey, tc.jwt)
+			}
+			rec := httptest.NewRecorder()
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath(tc.path)
+
+			if tc.expectations != nil {
+				tc.expectations(t, s)
+			}
+
+			var nextCalled bool
+			var claims *authentication.Claims
+			err := s.withAuth(t, func(ctx echo.Context) error {
+				nextCalled = true
+				claims, _ = authentication.ClaimsFromContext(ctx)
+				return ctx.NoContent(http.StatusOK)
+			})(ctx)
+
+			// Assertions
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusOK {
+				assert.True(t, nextCalled)
+				if tc.expectedUserId != "" {
+					assert.Equal(t, tc.expectedUserId, claims.Id)
+				}
+			} else {
+				assert.False(t, nextCalled)
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+				assert.Equal(t, tc.expectedChallenge, rec.Header().Get(echo.HeaderWWWAuthenticate))
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package handler
+
+// canonicalPhoneNumber is the E.164 format of a phone number that passed the '_phone' validation,
+// phone numbers are stored & looked up in this format. A phone number that can't be normalized, e.g.
+// one stored before phone numbers were restricted to mobile phone numbers, is returned as it is.
+func (s *Server) canonicalPhoneNumber(phoneNumber string) string {
+	canonical, err := s.PhoneNormalizer.Normalize(phoneNumber)
+	if err != nil {
+		return phoneNumber
+	}
+
+	return canonical
+}
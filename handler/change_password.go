@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/password"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Change the password of the user, every other session is logged out.
+// A token without session can't be told apart from the others, it is revoked as well
+// and the client is told to log in again with 205 Reset Content.
+// (PUT /v1/user/password)
+func (s *Server) ChangePassword(ctx echo.Context) error {
+	tracestr := "handler.ChangePassword"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	claims, err := authentication.ClaimsFromContext(ctx)
+	if err != nil {
+		ctx.Logger().Infof("%s, ClaimsFromContext failed, err: %+v", tracestr, err)
+		return response.AccessForbidden(ctx)
+	}
+
+	var req generated.ChangePasswordJSONRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+
+	user, err := s.Repository.GetUser(ctx.Request().Context(), repository.GetUserInput{
+		Id: claims.Id,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return response.UserNotFound(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed GetUser by Id, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	match, _, err := s.PasswordHasher.Verify(req.CurrentPassword, user.PasswordHash, user.Salt)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed Verify password of user %s, err: %v", tracestr, user.Id, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if !match {
+		return response.IncorrectPassword(ctx)
+	}
+	messages := s.PasswordPolicy.Validate("newPassword", req.NewPassword, password.PersonalInfo{
+		PhoneNumber: user.PhoneNumber,
+		FullName:    user.FullName,
+	})
+	if len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+
+	reused, err := s.isRecentPassword(ctx, user, req.NewPassword)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed isRecentPassword, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if reused {
+		return response.PasswordReused(ctx, s.Config.Password.History.Remember)
+	}
+
+	passwordHash, err := s.PasswordHasher.Hash(req.NewPassword)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed Hash password, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	// the session of this request stays logged in, every other session loses its tokens
+	revokedIds, err := s.Repository.ChangeUserPassword(ctx.Request().Context(), nil, repository.ChangeUserPasswordInput{
+		Credentials: repository.UpdateUserCredentialsInput{
+			Id:           user.Id,
+			PasswordHash: passwordHash,
+			HistorySize:  s.Config.Password.History.HistorySize(),
+		},
+		KeepSessionId: claims.SessionId,
+	})
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed ChangeUserPassword, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	for _, sessionId := range revokedIds {
+		if err := authentication.RevokeSession(ctx.Request().Context(), s.RevocationStore, s.Config.Secret, sessionId); err != nil {
+			ctx.Logger().Errorf("%s, failed RevokeSession, err: %v", tracestr, err)
+			return response.InternalErrorResponse(ctx)
+		}
+	}
+
+	// a token without session can't be told apart from the others, all of them are revoked
+	// including the one of this request
+	if claims.SessionId == "" {
+		if err := authentication.RevokeAllUserTokens(ctx.Request().Context(), s.RevocationStore, s.Config.Secret, user.Id); err != nil {
+			ctx.Logger().Errorf("%s, failed RevokeAllUserTokens, err: %v", tracestr, err)
+			return response.InternalErrorResponse(ctx)
+		}
+		return ctx.NoContent(http.StatusResetContent)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
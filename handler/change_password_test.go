@@ -0,0 +1,323 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+)
+
+func TestChangePassword(t *testing.T) {
+
+	var (
+		validReqBody = generated.ChangePasswordJSONRequestBody{
+			CurrentPassword: test_helper.TestUserPassword,
+			NewPassword:     "n3wPassW0$d",
+		}
+
+		validUser = repository.User{
+			Id:           test_helper.TestUserId,
+			PhoneNumber:  test_helper.TestUserPhone,
+			FullName:     test_helper.TestUserName,
+			PasswordHash: test_helper.TestUserArgon2idHash,
+		}
+
+		otherSessionId = "d4e5f6a7-1b2c-4d3e-9f4a-5b6c7d8e9f04"
+	)
+
+	expectGetUser := func(s *serverMock) {
+		s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+			Id: test_helper.TestUserId,
+		}).
+			Return(validUser, nil)
+	}
+	// the mock config remembers 3 passwords, the current one and 2 from the history
+	expectPasswordHistory := func(s *serverMock, history []repository.PasswordHistory, err error) {
+		s.repository.EXPECT().GetPasswordHistory(gomock.Any(), repository.GetPasswordHistoryInput{
+			UserId: test_helper.TestUserId,
+			Limit:  2,
+		}).
+			Return(history, err)
+	}
+	// the new password is stored as a fresh hash, sessions but keepSessionId are revoked with it
+	expectChangePassword := func(s *serverMock, keepSessionId string, revokedIds []string, err error) {
+		s.repository.EXPECT().ChangeUserPassword(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.ChangeUserPasswordInput{})).
+			DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.ChangeUserPasswordInput) ([]string, error) {
+				assert.Equal(t, validUser.Id, input.Credentials.Id)
+				assert.Equal(t, 2, input.Credentials.HistorySize)
+				assert.False(t, input.Credentials.Rehash)
+				assert.Equal(t, keepSessionId, input.KeepSessionId)
+				match, _, verifyErr := s.server.PasswordHasher.Verify(validReqBody.NewPassword, input.Credentials.PasswordHash, "")
+				assert.NoError(t, verifyErr)
+				assert.True(t, match)
+				return revokedIds, err
+			})
+	}
+
+	testCases := []struct {
+		title        string
+		jwt          string
+		sessionId    string
+		request      *generated.ChangePasswordJSONRequestBody
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode            int
+		expectedErrMsg              string
+		expectedTokenRevoked        bool
+		expectedOtherSessionRevoked bool
+	}{
+		{
+			title:            "request aborted",
+			sessionId:        logoutTestSessionId,
+			request:          &validReqBody,
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "auth token invalid",
+			jwt:              "Bearer invalid-token",
+			request:          &validReqBody,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.UnauthorizedErrorMsg,
+		},
+		{
+			title:            "empty request body",
+			sessionId:        logoutTestSessionId,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["currentPassword is a required field","newPassword is a required field"]}`,
+		},
+		{
+			title:     "new password not following password requirement",
+			sessionId: logoutTestSessionId,
+			request: &generated.ChangePasswordJSONRequestBody{
+				CurrentPassword: test_helper.TestUserPassword,
+				NewPassword:     "password",
+			},
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["newPassword must contain at least 1 uppercase letter","newPassword must contain at least 1 number","newPassword must contain at least 1 special (non alpha-numeric) character"]}`,
+		},
+		{
+			title:     "new password contains the name of the user",
+			sessionId: logoutTestSessionId,
+			request: &generated.ChangePasswordJSONRequestBody{
+				CurrentPassword: test_helper.TestUserPassword,
+				NewPassword:     "UnitTest$123",
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["newPassword must not contain your name"]}`,
+		},
+		{
+			title:     "new password found in a data breach",
+			sessionId: logoutTestSessionId,
+			request: &generated.ChangePasswordJSONRequestBody{
+				CurrentPassword: test_helper.TestUserPassword,
+				NewPassword:     test_helper.TestBreachedPassword,
+			},
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `newPassword has appeared in a data breach, please choose a different password`,
+		},
+		{
+			title:     "new password same as current password",
+			sessionId: logoutTestSessionId,
+			request: &generated.ChangePasswordJSONRequestBody{
+				CurrentPassword: test_helper.TestUserPassword,
+				NewPassword:     test_helper.TestUserPassword,
+			},
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `newPassword cannot be equal to CurrentPassword`,
+		},
+		{
+			title:     "error in Repository.GetUser",
+			sessionId: logoutTestSessionId,
+			request:   &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Return(repository.User{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:     "user not found",
+			sessionId: logoutTestSessionId,
+			request:   &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Return(repository.User{}, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusNotFound,
+			expectedErrMsg:   response.UserNotFoundErrorMsg,
+		},
+		{
+			title:     "incorrect current password",
+			sessionId: logoutTestSessionId,
+			request: &generated.ChangePasswordJSONRequestBody{
+				CurrentPassword: "wrongP4$sWrd",
+				NewPassword:     validReqBody.NewPassword,
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.IncorrectPasswordErrorMsg,
+		},
+		{
+			title:     "error in Repository.GetPasswordHistory",
+			sessionId: logoutTestSessionId,
+			request:   &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				expectPasswordHistory(s, nil, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:     "new password is one of the remembered passwords",
+			sessionId: logoutTestSessionId,
+			request:   &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				reusedHash, err := s.server.PasswordHasher.Hash(validReqBody.NewPassword)
+				assert.NoError(t, err)
+				expectPasswordHistory(s, []repository.PasswordHistory{
+					{Id: "f6a7b8c9-3d4e-4f5a-9b6c-7d8e9f0a1b06", PasswordHash: test_helper.TestUserBcryptHash},
+					{Id: "a7b8c9d0-4e5f-4a6b-8c7d-8e9f0a1b2c07", PasswordHash: reusedHash},
+				}, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   fmt.Sprintf(response.PasswordReusedErrorMsg, 3),
+		},
+		{
+			title:     "error in Repository.ChangeUserPassword",
+			sessionId: logoutTestSessionId,
+			request:   &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				expectPasswordHistory(s, []repository.PasswordHistory{}, nil)
+				expectChangePassword(s, logoutTestSessionId, nil, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:     "success keeps the current session logged in",
+			sessionId: logoutTestSessionId,
+			request:   &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				expectPasswordHistory(s, []repository.PasswordHistory{}, nil)
+				expectChangePassword(s, logoutTestSessionId, []string{otherSessionId}, nil)
+			},
+			expectedHttpCode:            http.StatusNoContent,
+			expectedTokenRevoked:        false,
+			expectedOtherSessionRevoked: true,
+		},
+		{
+			title:   "success with a token without session revokes every token and asks to log in again",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				expectPasswordHistory(s, []repository.PasswordHistory{}, nil)
+				expectChangePassword(s, "", []string{logoutTestSessionId, otherSessionId}, nil)
+			},
+			expectedHttpCode:            http.StatusResetContent,
+			expectedTokenRevoked:        true,
+			expectedOtherSessionRevoked: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			jwt := tc.jwt
+			if jwt == "" {
+				token, err := s.server.KeyManager.GenerateSignedToken(logoutTestUser, tc.sessionId)
+				assert.NoError(t, err)
+				jwt = "Bearer " + token
+			}
+			otherToken, err := s.server.KeyManager.GenerateSignedToken(logoutTestUser, otherSessionId)
+			assert.NoError(t, err)
+
+			var reqBody io.Reader
+			if tc.request != nil {
+				reqBodyJson, _ := json.Marshal(*tc.request)
+				reqBody = bytes.NewReader(reqBodyJson)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.PUT, "/", reqBody)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(authentication.AuthHeaderKey, jwt)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/user/password")
+
+			tc.expectations(t, s)
+
+			err = s.withAuth(t, s.server.ChangePassword)(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusNoContent || tc.expectedHttpCode == http.StatusResetContent {
+				assert.NoError(t, err)
+
+				_, err = s.server.KeyManager.ParseToken(context.Background(), s.revocationStore, strings.TrimPrefix(jwt, "Bearer "))
+				if tc.expectedTokenRevoked {
+					assert.Equal(t, authentication.ErrTokenRevoked, err)
+				} else {
+					assert.NoError(t, err)
+				}
+
+				_, err = s.server.KeyManager.ParseToken(context.Background(), s.revocationStore, otherToken)
+				if tc.expectedOtherSessionRevoked {
+					assert.Equal(t, authentication.ErrTokenRevoked, err)
+				} else {
+					assert.NoError(t, err)
+				}
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+
+	openapi_types "github.com/deepmap/oapi-codegen/pkg/types"
+	"github.com/labstack/echo/v4"
+)
+
+// Delete a passkey of the user, it can't log in anymore
+// (DELETE /v1/user/passkeys/{id})
+func (s *Server) DeletePasskey(ctx echo.Context, id openapi_types.UUID) error {
+	tracestr := "handler.DeletePasskey"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	claims, err := authentication.ClaimsFromContext(ctx)
+	if err != nil {
+		ctx.Logger().Infof("%s, ClaimsFromContext failed, err: %+v", tracestr, err)
+		return response.AccessForbidden(ctx)
+	}
+
+	err = s.Repository.DeletePasskey(ctx.Request().Context(), nil, repository.DeletePasskeyInput{
+		Id:     id.String(),
+		UserId: claims.Id,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return response.PasskeyNotFound(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed DeletePasskey, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	s.auditLog(ctx, tracestr, claims.Id, auditEventPasskeyDeleted, "passkey="+id.String())
+
+	return ctx.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func TestDeletePasskey(t *testing.T) {
+
+	passkeyId := uuid.MustParse("c9d0e1f2-9999-4cad-8e1f-2a3b4c5d6e09")
+
+	expectDeletePasskey := func(s *serverMock, err error) {
+		s.repository.EXPECT().DeletePasskey(gomock.Any(), nil, repository.DeletePasskeyInput{
+			Id:     passkeyId.String(),
+			UserId: test_helper.TestUserId,
+		}).
+			Return(err)
+	}
+
+	testCases := []struct {
+		title        string
+		jwt          string
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+	}{
+		{
+			title:            "request aborted",
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "auth token invalid",
+			jwt:              "Bearer invalid-token",
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.UnauthorizedErrorMsg,
+		},
+		{
+			title: "error in Repository.DeletePasskey",
+			expectations: func(t *testing.T, s *serverMock) {
+				expectDeletePasskey(s, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title: "passkey of another user or already deleted",
+			expectations: func(t *testing.T, s *serverMock) {
+				expectDeletePasskey(s, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusNotFound,
+			expectedErrMsg:   response.PasskeyNotFoundErrorMsg,
+		},
+		{
+			title: "passkey deleted",
+			expectations: func(t *testing.T, s *serverMock) {
+				expectDeletePasskey(s, nil)
+				s.repository.EXPECT().InsertAuditLog(gomock.Any(), nil, repository.InsertAuditLogInput{
+					UserId:    test_helper.TestUserId,
+					Event:     auditEventPasskeyDeleted,
+					IpAddress: "192.0.2.1",
+					Details:   "passkey=" + passkeyId.String(),
+				}).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			jwt := tc.jwt
+			if jwt == "" {
+				jwt = test_helper.TestUserJWT
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.DELETE, "/", nil)
+			req.Header.Set(authentication.AuthHeaderKey, jwt)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/user/passkeys/:id")
+
+			tc.expectations(t, s)
+
+			err := s.withAuth(t, func(ctx echo.Context) error {
+				return s.server.DeletePasskey(ctx, passkeyId)
+			})(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusNoContent {
+				assert.NoError(t, err)
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
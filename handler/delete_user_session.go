@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+
+	openapi_types "github.com/deepmap/oapi-codegen/pkg/types"
+	"github.com/labstack/echo/v4"
+)
+
+// Revoke a session of the user, its tokens stop working
+// (DELETE /v1/user/sessions/{id})
+func (s *Server) DeleteUserSession(ctx echo.Context, id openapi_types.UUID) error {
+	tracestr := "handler.DeleteUserSession"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	claims, err := authentication.ClaimsFromContext(ctx)
+	if err != nil {
+		ctx.Logger().Infof("%s, ClaimsFromContext failed, err: %+v", tracestr, err)
+		return response.AccessForbidden(ctx)
+	}
+
+	if err := s.revokeUserSession(ctx, id.String(), claims.Id); err != nil {
+		if err == sql.ErrNoRows {
+			return response.SessionNotFound(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed revokeUserSession, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// revokeUserSession revokes the session with its refresh tokens, then the access tokens issued for it.
+func (s *Server) revokeUserSession(ctx echo.Context, sessionId, userId string) error {
+	if _, err := s.Repository.RevokeUserSession(ctx.Request().Context(), nil, repository.RevokeUserSessionInput{
+		Id:     sessionId,
+		UserId: userId,
+	}); err != nil {
+		return err
+	}
+
+	return authentication.RevokeSession(ctx.Request().Context(), s.RevocationStore, s.Config.Secret, sessionId)
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func TestDeleteUserSession(t *testing.T) {
+
+	var (
+		currentSessionId = uuid.MustParse("c3d4e5f6-0a1b-4c2d-8e3f-4a5b6c7d8e03")
+		otherSessionId   = uuid.MustParse("d4e5f6a7-1b2c-4d3e-9f4a-5b6c7d8e9f04")
+	)
+
+	testCases := []struct {
+		title        string
+		jwt          string
+		sessionId    uuid.UUID
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode     int
+		expectedErrMsg       string
+		expectedTokenRevoked bool
+	}{
+		{
+			title:            "request aborted",
+			sessionId:        otherSessionId,
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "auth token invalid",
+			jwt:              "Bearer invalid-token",
+			sessionId:        otherSessionId,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.UnauthorizedErrorMsg,
+		},
+		{
+			title:     "error in Repository.RevokeUserSession",
+			sessionId: otherSessionId,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().RevokeUserSession(gomock.Any(), nil, gomock.Any()).
+					Return(repository.UserSession{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:     "session of another user or already revoked",
+			sessionId: otherSessionId,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().RevokeUserSession(gomock.Any(), nil, repository.RevokeUserSessionInput{
+					Id:     otherSessionId.String(),
+					UserId: test_helper.TestUserId,
+				}).
+					Return(repository.UserSession{}, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusNotFound,
+			expectedErrMsg:   response.SessionNotFoundErrorMsg,
+		},
+		{
+			title:     "revoking another session keeps the current token working",
+			sessionId: otherSessionId,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().RevokeUserSession(gomock.Any(), nil, repository.RevokeUserSessionInput{
+					Id:     otherSessionId.String(),
+					UserId: test_helper.TestUserId,
+				}).
+					Return(repository.UserSession{Id: otherSessionId.String()}, nil)
+			},
+			expectedHttpCode:     http.StatusNoContent,
+			expectedTokenRevoked: false,
+		},
+		{
+			title:     "revoking the current session revokes its tokens",
+			sessionId: currentSessionId,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().RevokeUserSession(gomock.Any(), nil, repository.RevokeUserSessionInput{
+					Id:     currentSessionId.String(),
+					UserId: test_helper.TestUserId,
+				}).
+					Return(repository.UserSession{Id: currentSessionId.String()}, nil)
+			},
+			expectedHttpCode:     http.StatusNoContent,
+			expectedTokenRevoked: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			jwt := tc.jwt
+			if jwt == "" {
+				token, err := s.server.KeyManager.GenerateSignedToken(logoutTestUser, currentSessionId.String())
+				assert.NoError(t, err)
+				jwt = "Bearer " + token
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.DELETE, "/", nil)
+			req.Header.Set(authentication.AuthHeaderKey, jwt)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/user/sessions/:id")
+
+			tc.expectations(t, s)
+
+			err := s.withAuth(t, func(ctx echo.Context) error {
+				return s.server.DeleteUserSession(ctx, tc.sessionId)
+			})(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusNoContent {
+				assert.NoError(t, err)
+
+				verifyCtx := e.NewContext(req, httptest.NewRecorder())
+				_, err = s.server.KeyManager.VerifyToken(verifyCtx, s.revocationStore)
+				if tc.expectedTokenRevoked {
+					assert.Equal(t, authentication.ErrTokenRevoked, err)
+				} else {
+					assert.NoError(t, err)
+				}
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
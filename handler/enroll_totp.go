@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/totp"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	totpUnavailableErrorMsg = "totp is not available"
+)
+
+// Start the enrollment of a TOTP authenticator app, a new secret replaces an enrollment that wasn't confirmed.
+// The enrollment is confirmed with the current password, an access token alone can't add a second factor the user doesn't hold
+// (POST /v1/user/mfa/totp)
+func (s *Server) EnrollTotp(ctx echo.Context) error {
+	tracestr := "handler.EnrollTotp"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	claims, err := authentication.ClaimsFromContext(ctx)
+	if err != nil {
+		ctx.Logger().Infof("%s, ClaimsFromContext failed, err: %+v", tracestr, err)
+		return response.AccessForbidden(ctx)
+	}
+
+	if !s.TOTPKeyring.CanEncrypt() {
+		return response.SingleErrorResponse(ctx, http.StatusNotImplemented, totpUnavailableErrorMsg)
+	}
+
+	var req generated.EnrollTotpJSONRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+
+	user, err := s.Repository.GetUser(ctx.Request().Context(), repository.GetUserInput{
+		Id: claims.Id,
+	})
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed GetUser by Id, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if user.TOTPEnabled {
+		return response.TOTPAlreadyEnabled(ctx)
+	}
+	if ok, err := s.checkCurrentPassword(ctx, tracestr, user, &req.CurrentPassword); !ok {
+		return err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed GenerateSecret, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	secretCiphertext, err := s.TOTPKeyring.Encrypt([]byte(secret), []byte(user.Id))
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed TOTPKeyring.Encrypt, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	err = s.Repository.UpsertUserTOTP(ctx.Request().Context(), nil, repository.UpsertUserTOTPInput{
+		UserId:           user.Id,
+		SecretCiphertext: secretCiphertext,
+	})
+	if err != nil {
+		// confirmed since the user was read
+		if err == sql.ErrNoRows {
+			return response.TOTPAlreadyEnabled(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed UpsertUserTOTP, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	return ctx.JSON(http.StatusCreated, generated.TotpEnrollmentResponse{
+		Secret:     secret,
+		OtpauthUri: totp.URI(s.Config.MFA.TOTP.GetIssuer(), user.PhoneNumber, secret),
+	})
+}
@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"user-service-sample/config"
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/encryption"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+)
+
+func TestEnrollTotp(t *testing.T) {
+	var (
+		phoneVerifiedAt = time.Now().Add(-24 * time.Hour)
+
+		validUser = repository.User{
+			Id:              test_helper.TestUserId,
+			PhoneNumber:     test_helper.TestUserPhone,
+			FullName:        test_helper.TestUserName,
+			PhoneVerifiedAt: &phoneVerifiedAt,
+			PasswordHash:    test_helper.TestUserArgon2idHash,
+		}
+
+		validReqBody = generated.EnrollTotpJSONRequestBody{
+			CurrentPassword: test_helper.TestUserPassword,
+		}
+	)
+
+	totpUser := validUser
+	totpUser.TOTPEnabled = true
+
+	expectGetUser := func(s *serverMock, user repository.User, err error) {
+		s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+			Id: test_helper.TestUserId,
+		}).
+			Return(user, err)
+	}
+	// the stored secret is encrypted for the user, the response holds it in plain text
+	var storedSecretCiphertext string
+	expectUpsertTOTP := func(s *serverMock, err error) {
+		s.repository.EXPECT().UpsertUserTOTP(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.UpsertUserTOTPInput{})).
+			DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.UpsertUserTOTPInput) error {
+				assert.Equal(t, validUser.Id, input.UserId)
+				assert.True(t, strings.HasPrefix(input.SecretCiphertext, "v1$"))
+				storedSecretCiphertext = input.SecretCiphertext
+				return err
+			})
+	}
+
+	testCases := []struct {
+		title        string
+		jwt          string
+		request      *generated.EnrollTotpJSONRequestBody
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+	}{
+		{
+			title:            "request aborted",
+			jwt:              test_helper.TestUserJWT,
+			request:          &validReqBody,
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "auth token invalid",
+			jwt:              "Bearer invalid-token",
+			request:          &validReqBody,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.UnauthorizedErrorMsg,
+		},
+		{
+			title:   "no encryption key configured",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				keyring, err := encryption.NewKeyring(config.EncryptionConfig{})
+				assert.NoError(t, err)
+				s.server.TOTPKeyring = keyring
+			},
+			expectedHttpCode: http.StatusNotImplemented,
+			expectedErrMsg:   totpUnavailableErrorMsg,
+		},
+		{
+			title:   "error in Repository.GetUser by Id",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, repository.User{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "TOTP already enabled",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, totpUser, nil)
+			},
+			expectedHttpCode: http.StatusConflict,
+			expectedErrMsg:   response.TOTPAlreadyEnabledErrorMsg,
+		},
+		{
+			title:            "empty request body",
+			jwt:              test_helper.TestUserJWT,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["currentPassword is a required field"]}`,
+		},
+		{
+			title: "incorrect current password",
+			jwt:   test_helper.TestUserJWT,
+			request: &generated.EnrollTotpJSONRequestBody{
+				CurrentPassword: "wrong-password",
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.IncorrectPasswordErrorMsg,
+		},
+		{
+			title:   "TOTP confirmed in the meantime",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectUpsertTOTP(s, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusConflict,
+			expectedErrMsg:   response.TOTPAlreadyEnabledErrorMsg,
+		},
+		{
+			title:   "error in Repository.UpsertUserTOTP",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectUpsertTOTP(s, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "success",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectUpsertTOTP(s, nil)
+			},
+			expectedHttpCode: http.StatusCreated,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+			storedSecretCiphertext = ""
+
+			var reqBody io.Reader
+			if tc.request != nil {
+				reqBodyJson, _ := json.Marshal(*tc.request)
+				reqBody = bytes.NewReader(reqBodyJson)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", reqBody)
+			req.Header.Set(authentication.AuthHeaderKey, tc.jwt)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/user/mfa/totp")
+
+			tc.expectations(t, s)
+
+			err := s.withAuth(t, s.server.EnrollTotp)(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusCreated {
+				var resp generated.TotpEnrollmentResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.Len(t, resp.Secret, 32)
+				assert.Equal(t, "otpauth://totp/User%20Service:%2B6281000000001?algorithm=SHA1&digits=6&issuer=User%20Service&period=30&secret="+resp.Secret, resp.OtpauthUri)
+
+				// the secret doesn't decrypt for another user
+				secret, decryptErr := s.server.TOTPKeyring.Decrypt(storedSecretCiphertext, []byte(validUser.Id))
+				assert.NoError(t, decryptErr)
+				assert.Equal(t, resp.Secret, string(secret))
+				_, decryptErr = s.server.TOTPKeyring.Decrypt(storedSecretCiphertext, []byte("another-user-id"))
+				assert.Equal(t, encryption.ErrInvalidCiphertext, decryptErr)
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
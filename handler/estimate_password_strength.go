@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/strength"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Estimate how hard a password is to guess, the password is never logged
+// (POST /v1/password/strength)
+func (s *Server) EstimatePasswordStrength(ctx echo.Context) error {
+	tracestr := "handler.EstimatePasswordStrength"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	allowed, err := s.StrengthRateLimiter.Allow(ctx.RealIP())
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed StrengthRateLimiter.Allow, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if !allowed {
+		rateLimit := s.Config.Password.Strength.GetRateLimit()
+		return response.TooManyRequests(ctx, rateLimit.Per/time.Duration(rateLimit.Requests))
+	}
+
+	var req generated.EstimatePasswordStrengthJSONRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+
+	var userInputs []string
+	if req.FullName != nil {
+		userInputs = append(userInputs, *req.FullName)
+	}
+	if req.PhoneNumber != nil {
+		userInputs = append(userInputs, s.canonicalPhoneNumber(*req.PhoneNumber))
+	}
+
+	result := strength.Estimate(req.Password, userInputs...)
+	resp := generated.PasswordStrengthResponse{
+		Score:            result.Score,
+		GuessesLog10:     result.GuessesLog10,
+		CrackTimeSeconds: result.CrackTimeSeconds,
+		CrackTimeDisplay: result.CrackTimeDisplay,
+		Suggestions:      append([]string{}, result.Feedback.Suggestions...),
+	}
+	if result.Feedback.Warning != "" {
+		resp.Warning = &result.Feedback.Warning
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"user-service-sample/config"
+	"user-service-sample/generated"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/labstack/echo/v4"
+	"github.com/xorcare/pointer"
+)
+
+func TestEstimatePasswordStrength(t *testing.T) {
+
+	// httptest requests come from this address
+	const clientIP = "192.0.2.1"
+
+	// exhausts the rate limit of the client IP address
+	exhaustRateLimit := func(t *testing.T, s *serverMock) {
+		for i := 0; i < 2; i++ {
+			allowed, err := s.server.StrengthRateLimiter.Allow(clientIP)
+			assert.NoError(t, err)
+			assert.True(t, allowed)
+		}
+	}
+
+	testCases := []struct {
+		title          string
+		request        *generated.EstimatePasswordStrengthJSONRequestBody
+		aborted        bool
+		rateLimit      *config.RateLimitConfig
+		trustedProxies []string
+		forwardedFor   string
+		setup          func(t *testing.T, s *serverMock)
+
+		expectedHttpCode    int
+		expectedErrMsg      string
+		expectedRetryAfter  string
+		expectedScore       int
+		expectedWarning     *string
+		expectedSuggestions []string
+	}{
+		{
+			title:            "request aborted",
+			request:          &generated.EstimatePasswordStrengthJSONRequestBody{Password: "password"},
+			aborted:          true,
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "empty request body",
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["password is a required field"]}`,
+		},
+		{
+			title:            "password too long",
+			request:          &generated.EstimatePasswordStrengthJSONRequestBody{Password: strings.Repeat("a", 129)},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `password must be a maximum of 128 characters in length`,
+		},
+		{
+			title:              "rate limited per client IP address",
+			request:            &generated.EstimatePasswordStrengthJSONRequestBody{Password: "password"},
+			rateLimit:          &config.RateLimitConfig{Requests: 2, Per: time.Minute},
+			setup:              exhaustRateLimit,
+			expectedHttpCode:   http.StatusTooManyRequests,
+			expectedErrMsg:     response.TooManyRequestsErrorMsg,
+			expectedRetryAfter: "30",
+		},
+		{
+			title:              "X-Forwarded-For sent by the client is ignored",
+			request:            &generated.EstimatePasswordStrengthJSONRequestBody{Password: "password"},
+			rateLimit:          &config.RateLimitConfig{Requests: 2, Per: time.Minute},
+			forwardedFor:       "203.0.113.7",
+			setup:              exhaustRateLimit,
+			expectedHttpCode:   http.StatusTooManyRequests,
+			expectedErrMsg:     response.TooManyRequestsErrorMsg,
+			expectedRetryAfter: "30",
+		},
+		{
+			title:            "X-Forwarded-For set by a trusted proxy is the client IP address",
+			request:          &generated.EstimatePasswordStrengthJSONRequestBody{Password: "password"},
+			rateLimit:        &config.RateLimitConfig{Requests: 2, Per: time.Minute},
+			trustedProxies:   []string{"192.0.2.0/24"},
+			forwardedFor:     "203.0.113.7",
+			setup:            exhaustRateLimit,
+			expectedHttpCode: http.StatusOK,
+			expectedScore:    0,
+			expectedWarning:  pointer.String("This is a top-10 common password"),
+			expectedSuggestions: []string{
+				"Add another word or two. Uncommon words are better.",
+			},
+		},
+		{
+			title:            "common password",
+			request:          &generated.EstimatePasswordStrengthJSONRequestBody{Password: "password"},
+			expectedHttpCode: http.StatusOK,
+			expectedScore:    0,
+			expectedWarning:  pointer.String("This is a top-10 common password"),
+			expectedSuggestions: []string{
+				"Add another word or two. Uncommon words are better.",
+			},
+		},
+		{
+			title:            "keyboard walk",
+			request:          &generated.EstimatePasswordStrengthJSONRequestBody{Password: "zxcvbnm,./"},
+			expectedHttpCode: http.StatusOK,
+			expectedScore:    1,
+			expectedWarning:  pointer.String("Straight rows of keys are easy to guess"),
+			expectedSuggestions: []string{
+				"Add another word or two. Uncommon words are better.",
+				"Use a longer keyboard pattern with more turns",
+			},
+		},
+		{
+			title:            "date",
+			request:          &generated.EstimatePasswordStrengthJSONRequestBody{Password: "17081945"},
+			expectedHttpCode: http.StatusOK,
+			expectedScore:    1,
+			expectedWarning:  pointer.String("Dates are often easy to guess"),
+			expectedSuggestions: []string{
+				"Add another word or two. Uncommon words are better.",
+				"Avoid dates and years that are associated with you",
+			},
+		},
+		{
+			title:            "common Indonesian word",
+			request:          &generated.EstimatePasswordStrengthJSONRequestBody{Password: "sayangku"},
+			expectedHttpCode: http.StatusOK,
+			expectedScore:    0,
+			expectedWarning:  pointer.String("A word by itself is easy to guess"),
+			expectedSuggestions: []string{
+				"Add another word or two. Uncommon words are better.",
+			},
+		},
+		{
+			title: "name of the user",
+			request: &generated.EstimatePasswordStrengthJSONRequestBody{
+				Password: "Budisantoso",
+				FullName: pointer.String("Budi Santoso"),
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedScore:    0,
+			expectedWarning:  pointer.String("Your name or phone number is easy to guess"),
+			expectedSuggestions: []string{
+				"Add another word or two. Uncommon words are better.",
+				"Capitalization doesn't help very much",
+			},
+		},
+		{
+			title:               "long passphrase",
+			request:             &generated.EstimatePasswordStrengthJSONRequestBody{Password: "kopi tubruk di ujung gang sempit"},
+			expectedHttpCode:    http.StatusOK,
+			expectedScore:       4,
+			expectedSuggestions: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			if tc.rateLimit != nil {
+				s.config.Password.Strength.RateLimit = *tc.rateLimit
+				server, err := NewServer(NewServerOptions{Config: s.config, Repository: s.repository})
+				assert.NoError(t, err)
+				s.server = server
+			}
+			if tc.setup != nil {
+				tc.setup(t, s)
+			}
+
+			var reqBody io.Reader
+			if tc.request != nil {
+				reqBodyJson, _ := json.Marshal(*tc.request)
+				reqBody = bytes.NewReader(reqBodyJson)
+			}
+
+			e := echo.New()
+			ipExtractor, err := request_helper.NewIPExtractor(tc.trustedProxies)
+			assert.NoError(t, err)
+			e.IPExtractor = ipExtractor
+
+			req := httptest.NewRequest(echo.POST, "/", reqBody)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tc.forwardedFor != "" {
+				req.Header.Set(echo.HeaderXForwardedFor, tc.forwardedFor)
+			}
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/password/strength")
+
+			err = s.server.EstimatePasswordStrength(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusOK {
+				var resp generated.PasswordStrengthResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedScore, resp.Score)
+				assert.Equal(t, tc.expectedWarning, resp.Warning)
+				assert.Equal(t, tc.expectedSuggestions, resp.Suggestions)
+				assert.NotEmpty(t, resp.CrackTimeDisplay)
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+				assert.Equal(t, tc.expectedRetryAfter, rec.Header().Get(echo.HeaderRetryAfter))
+			}
+		})
+	}
+}
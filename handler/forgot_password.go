@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/otp"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	forgotPasswordAcceptedMsg = "if the phone number is registered, a code to reset the password has been sent"
+	passwordResetMessage      = "Your password reset code is %s, it expires in %d minutes. Never share this code."
+)
+
+// Send a code to reset the password to the phone number, if it is registered
+// (POST /v1/password/forgot)
+func (s *Server) ForgotPassword(ctx echo.Context) error {
+	tracestr := "handler.ForgotPassword"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	var req generated.ForgotPasswordJSONRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+	req.PhoneNumber = s.canonicalPhoneNumber(req.PhoneNumber)
+
+	// the response must not tell registered phone numbers apart
+	accepted := generated.MessageResponse{
+		Message: forgotPasswordAcceptedMsg,
+	}
+
+	user, err := s.Repository.GetUser(ctx.Request().Context(), repository.GetUserInput{
+		PhoneNumber: req.PhoneNumber,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ctx.JSON(http.StatusAccepted, accepted)
+		}
+		ctx.Logger().Errorf("%s, failed GetUser by PhoneNumber, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	// a pending registration is registered again instead
+	if user.PhoneVerifiedAt == nil {
+		return ctx.JSON(http.StatusAccepted, accepted)
+	}
+	// a code sent while the codes of the user are locked couldn't be used
+	if otpLockedFor(user) > 0 {
+		return ctx.JSON(http.StatusAccepted, accepted)
+	}
+
+	// a failure past this point only concerns registered users, it is logged but not returned
+	if err := s.sendOneTimeCode(ctx, user.Id, user.PhoneNumber, otp.PurposePasswordReset, passwordResetMessage); err != nil {
+		ctx.Logger().Errorf("%s, failed sendOneTimeCode, err: %v", tracestr, err)
+	}
+
+	return ctx.JSON(http.StatusAccepted, accepted)
+}
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/otp"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+)
+
+// smsCodePattern reads the code back from the message written by the log sender
+var smsCodePattern = regexp.MustCompile(`to (\+\d+): .* code is (\d+),`)
+
+func TestForgotPassword(t *testing.T) {
+
+	var (
+		phoneVerifiedAt = time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+
+		validReqBody = generated.ForgotPasswordJSONRequestBody{
+			PhoneNumber: test_helper.TestUserPhone,
+		}
+
+		validUser = repository.User{
+			Id:              test_helper.TestUserId,
+			PhoneNumber:     test_helper.TestUserPhone,
+			FullName:        test_helper.TestUserName,
+			PhoneVerifiedAt: &phoneVerifiedAt,
+		}
+	)
+
+	expectGetUser := func(s *serverMock) {
+		s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+			PhoneNumber: validReqBody.PhoneNumber,
+		}).
+			Return(validUser, nil)
+	}
+	expectLatestCode := func(s *serverMock, code repository.OneTimeCode, err error) {
+		s.repository.EXPECT().GetActiveOneTimeCode(gomock.Any(), repository.GetActiveOneTimeCodeInput{
+			UserId:  validUser.Id,
+			Purpose: otp.PurposePasswordReset,
+		}).
+			Return(code, err)
+	}
+	// the code is stored hashed, the SMS carries the code itself
+	var insertedCodeHash string
+	expectInsertCode := func(s *serverMock, err error) {
+		s.repository.EXPECT().InsertOneTimeCode(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertOneTimeCodeInput{})).
+			DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.InsertOneTimeCodeInput) (repository.InsertOneTimeCodeOutput, error) {
+				assert.Equal(t, validUser.Id, input.UserId)
+				assert.Equal(t, otp.PurposePasswordReset, input.Purpose)
+				assert.True(t, input.ExpiresAt.After(time.Now().Add(9*time.Minute)))
+				assert.True(t, input.ExpiresAt.Before(time.Now().Add(11*time.Minute)))
+				insertedCodeHash = input.CodeHash
+				return repository.InsertOneTimeCodeOutput{Id: "e5f6a7b8-2c3d-4e4f-8a5b-6c7d8e9f0a05"}, err
+			})
+	}
+
+	testCases := []struct {
+		title        string
+		request      *generated.ForgotPasswordJSONRequestBody
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+		expectedSMS      bool
+	}{
+		{
+			title:            "request aborted",
+			request:          &validReqBody,
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "empty request body",
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["phoneNumber is a required field"]}`,
+		},
+		{
+			title: "phoneNumber not a mobile phone number is looked up as it is",
+			request: &generated.ForgotPasswordJSONRequestBody{
+				PhoneNumber: "+6591234567",
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: "+6591234567",
+				}).
+					Return(repository.User{}, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusAccepted,
+		},
+		{
+			title:   "error in Repository.GetUser",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Return(repository.User{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "phone number not registered gets the same response",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Return(repository.User{}, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusAccepted,
+		},
+		{
+			title:   "pending registration gets the same response without a code",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Return(repository.User{Id: validUser.Id, PhoneNumber: validUser.PhoneNumber}, nil)
+			},
+			expectedHttpCode: http.StatusAccepted,
+		},
+		{
+			title:   "locked user gets the same response without a code",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				lockedUntil := time.Now().Add(10 * time.Minute)
+				lockedUser := validUser
+				lockedUser.OTPLockedUntil = &lockedUntil
+				s.repository.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Return(lockedUser, nil)
+			},
+			expectedHttpCode: http.StatusAccepted,
+		},
+		{
+			title:   "error in Repository.GetActiveOneTimeCode only log error",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				expectLatestCode(s, repository.OneTimeCode{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusAccepted,
+		},
+		{
+			title:   "error in Repository.InsertOneTimeCode only log error",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				expectLatestCode(s, repository.OneTimeCode{}, sql.ErrNoRows)
+				expectInsertCode(s, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusAccepted,
+		},
+		{
+			title:   "code sent less than the resend interval ago is not sent again",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				expectLatestCode(s, repository.OneTimeCode{CreatedAt: time.Now().Add(-10 * time.Second)}, nil)
+			},
+			expectedHttpCode: http.StatusAccepted,
+		},
+		{
+			title:   "code sent past the resend interval is replaced",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				expectLatestCode(s, repository.OneTimeCode{CreatedAt: time.Now().Add(-2 * time.Minute)}, nil)
+				expectInsertCode(s, nil)
+			},
+			expectedHttpCode: http.StatusAccepted,
+			expectedSMS:      true,
+		},
+		{
+			title:   "success",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				expectLatestCode(s, repository.OneTimeCode{}, sql.ErrNoRows)
+				expectInsertCode(s, nil)
+			},
+			expectedHttpCode: http.StatusAccepted,
+			expectedSMS:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+			insertedCodeHash = ""
+
+			var reqBody io.Reader
+			if tc.request != nil {
+				reqBodyJson, _ := json.Marshal(*tc.request)
+				reqBody = bytes.NewReader(reqBodyJson)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", reqBody)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/password/forgot")
+
+			tc.expectations(t, s)
+
+			err := s.server.ForgotPassword(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusAccepted {
+				var resp generated.MessageResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.Equal(t, forgotPasswordAcceptedMsg, resp.Message)
+
+				sent := smsCodePattern.FindStringSubmatch(s.smsLog.String())
+				if tc.expectedSMS {
+					if assert.Len(t, sent, 3) {
+						assert.Equal(t, validUser.PhoneNumber, sent[1])
+						assert.Len(t, sent[2], 6)
+						assert.True(t, otp.Verify(insertedCodeHash, otp.PurposePasswordReset, validUser.Id, validUser.PhoneNumber, sent[2]))
+					}
+				} else {
+					assert.Empty(t, s.smsLog.String())
+				}
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/utils/context_helper"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	jwksCacheControl = "public, max-age=300"
+)
+
+// Public keys to verify issued JWTs, as a JSON Web Key Set
+// (GET /.well-known/jwks.json)
+func (s *Server) GetJwks(ctx echo.Context) error {
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	jwks := s.KeyManager.PublicJWKS()
+	resp := generated.JwksResponse{
+		Keys: make([]generated.Jwk, 0, len(jwks)),
+	}
+	for _, jwk := range jwks {
+		resp.Keys = append(resp.Keys, generated.Jwk{
+			Kty: generated.JwkKty(jwk.Kty),
+			Use: jwk.Use,
+			Alg: generated.JwkAlg(jwk.Alg),
+			Kid: jwk.Kid,
+			N:   jwkMember(jwk.N),
+			E:   jwkMember(jwk.E),
+			Crv: jwkMember(jwk.Crv),
+			X:   jwkMember(jwk.X),
+			Y:   jwkMember(jwk.Y),
+		})
+	}
+
+	ctx.Response().Header().Set(echo.HeaderCacheControl, jwksCacheControl)
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// jwkMember omits members that don't apply to the key type
+func jwkMember(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
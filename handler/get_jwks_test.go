@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-service-sample/config"
+	"user-service-sample/generated"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/labstack/echo/v4"
+)
+
+func TestGetJwks(t *testing.T) {
+
+	var (
+		recentlyRetiredAt = time.Now().Add(-time.Minute)
+		longRetiredAt     = time.Now().Add(-30 * 24 * time.Hour)
+
+		activeKey = config.SigningKeyConfig{
+			Kid:        "active-key",
+			PrivatePem: test_helper.TestRsaPrivatePem,
+		}
+	)
+
+	testCases := []struct {
+		title     string
+		aborted   bool
+		secretCfg *config.SecretConfig
+
+		expectedHttpCode int
+		expectedErrMsg   string
+		expectedKids     []string
+	}{
+		{
+			title:            "request aborted",
+			aborted:          true,
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "legacy single key gets a derived kid",
+			expectedHttpCode: http.StatusOK,
+			// RFC 7638 thumbprint of TestRsaPublicPem
+			expectedKids: []string{"UVSYOAYMLo8sxJkSZl2DZDHA6sP9UlyYSl2moTB7i9Q"},
+		},
+		{
+			title: "active and recently retired key are published",
+			secretCfg: &config.SecretConfig{
+				Keys: []config.SigningKeyConfig{activeKey, {
+					Kid:       "retired-key",
+					PublicPem: test_helper.TestRetiredRsaPublicPem,
+					RetiredAt: &recentlyRetiredAt,
+				}},
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedKids:     []string{"active-key", "retired-key"},
+		},
+		{
+			title: "aged out retired key is not published",
+			secretCfg: &config.SecretConfig{
+				Keys: []config.SigningKeyConfig{activeKey, {
+					Kid:        "retired-key",
+					PrivatePem: test_helper.TestRetiredRsaPrivatePem,
+					RetiredAt:  &longRetiredAt,
+				}},
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedKids:     []string{"active-key"},
+		},
+		{
+			title: "ES256 and EdDSA keys are published with their own members",
+			secretCfg: &config.SecretConfig{
+				Keys: []config.SigningKeyConfig{{
+					Kid:        "ec-key",
+					Algorithm:  "ES256",
+					PrivatePem: test_helper.TestEcPrivatePem,
+				}, {
+					Kid:       "ed-key",
+					Algorithm: "EdDSA",
+					PublicPem: test_helper.TestEdPublicPem,
+					RetiredAt: &recentlyRetiredAt,
+				}, {
+					Kid:       "rsa-key",
+					PublicPem: test_helper.TestRetiredRsaPublicPem,
+					RetiredAt: &recentlyRetiredAt,
+				}},
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedKids:     []string{"ec-key", "ed-key", "rsa-key"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			if tc.secretCfg != nil {
+				s.config.Secret = *tc.secretCfg
+				s.reloadKeys(t)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.GET, "/", nil)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/.well-known/jwks.json")
+
+			err := s.server.GetJwks(ctx)
+
+			// Assertions
+			if tc.expectedHttpCode >= http.StatusOK && // code 2XX
+				tc.expectedHttpCode <= http.StatusIMUsed {
+
+				var resp generated.JwksResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				kids := []string{}
+				for _, key := range resp.Keys {
+					assert.Equal(t, "sig", key.Use)
+					switch key.Kty {
+					case generated.RSA:
+						assert.Equal(t, generated.RS256, key.Alg)
+						assert.NotEmpty(t, key.N)
+						assert.Equal(t, "AQAB", *key.E)
+						assert.Nil(t, key.X)
+					case generated.EC:
+						assert.Equal(t, generated.ES256, key.Alg)
+						assert.Equal(t, "P-256", *key.Crv)
+						// 32 bytes coordinates, base64url without padding
+						assert.Len(t, *key.X, 43)
+						assert.Len(t, *key.Y, 43)
+						assert.Nil(t, key.N)
+					case generated.OKP:
+						assert.Equal(t, generated.EdDSA, key.Alg)
+						assert.Equal(t, "Ed25519", *key.Crv)
+						assert.Len(t, *key.X, 43)
+						assert.Nil(t, key.Y)
+					default:
+						t.Errorf("unexpected key type %s", key.Kty)
+					}
+					kids = append(kids, key.Kid)
+				}
+
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedHttpCode, rec.Code)
+				assert.Equal(t, tc.expectedKids, kids)
+			} else {
+				assert.Equal(t, tc.expectedHttpCode, rec.Code)
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
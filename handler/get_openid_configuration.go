@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"user-service-sample/generated"
+	"user-service-sample/utils/context_helper"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	openidConfigurationCacheControl = "public, max-age=300"
+	openidScope                     = "openid"
+)
+
+// OpenID Connect discovery document of the tokens of this service. It is not an OpenID provider, there is
+// no authorization endpoint: clients log in with /v1/login, the document tells resource servers where to
+// verify the tokens and read the user claims
+// (GET /.well-known/openid-configuration)
+func (s *Server) GetOpenidConfiguration(ctx echo.Context) error {
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	algorithms := s.KeyManager.SigningAlgorithms()
+
+	// endpoints are published under the issuer, never under the Host header: the document is cached
+	issuer := strings.TrimSuffix(s.Config.Secret.Issuer, "/")
+
+	scopes := append([]string{openidScope}, s.Config.Secret.Scopes...)
+	claims := []string{"sub", "iss", "aud", "exp", "iat", "name", "phone_number", "phone_number_verified", "updated_at"}
+	resp := generated.OpenidConfigurationResponse{
+		Issuer:                           issuer,
+		JwksUri:                          issuer + "/.well-known/jwks.json",
+		UserinfoEndpoint:                 issuer + "/userinfo",
+		ResponseTypesSupported:           []string{"id_token"},
+		SubjectTypesSupported:            []string{"public"},
+		IdTokenSigningAlgValuesSupported: algorithms,
+		ScopesSupported:                  &scopes,
+		ClaimsSupported:                  &claims,
+	}
+	if len(s.Config.OAuth.Clients) > 0 {
+		introspectionEndpoint := issuer + "/oauth/introspect"
+		authMethods := []string{"client_secret_basic"}
+		resp.IntrospectionEndpoint = &introspectionEndpoint
+		resp.IntrospectionEndpointAuthMethodsSupported = &authMethods
+	}
+
+	ctx.Response().Header().Set(echo.HeaderCacheControl, openidConfigurationCacheControl)
+	return ctx.JSON(http.StatusOK, resp)
+}
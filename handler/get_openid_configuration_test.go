@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"user-service-sample/config"
+	"user-service-sample/generated"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/labstack/echo/v4"
+)
+
+func TestGetOpenidConfiguration(t *testing.T) {
+
+	testCases := []struct {
+		title        string
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode      int
+		expectedErrMsg        string
+		expectedIssuer        string
+		expectedAlgorithms    []string
+		expectedIntrospection bool
+	}{
+		{
+			title:            "request aborted",
+			aborted:          true,
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:              "endpoints under the configured issuer",
+			expectedHttpCode:   http.StatusOK,
+			expectedIssuer:     test_helper.TestIssuer,
+			expectedAlgorithms: []string{"RS256"},
+		},
+		{
+			title: "endpoints under the issuer without its trailing slash",
+			expectations: func(t *testing.T, s *serverMock) {
+				s.config.Secret.Issuer = test_helper.TestIssuer + "/"
+			},
+			expectedHttpCode:   http.StatusOK,
+			expectedIssuer:     test_helper.TestIssuer,
+			expectedAlgorithms: []string{"RS256"},
+		},
+		{
+			title: "introspection published when clients are configured",
+			expectations: func(t *testing.T, s *serverMock) {
+				s.config.OAuth.Clients = []config.OAuthClientConfig{{Id: "legacy-billing", Secret: "billing-secret"}}
+				s.config.Secret.Keys = []config.SigningKeyConfig{{
+					Kid:        "ec-key",
+					Algorithm:  "ES256",
+					PrivatePem: test_helper.TestEcPrivatePem,
+				}}
+				s.reloadKeys(t)
+			},
+			expectedHttpCode:      http.StatusOK,
+			expectedIssuer:        test_helper.TestIssuer,
+			expectedAlgorithms:    []string{"ES256"},
+			expectedIntrospection: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.GET, "/", nil)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/.well-known/openid-configuration")
+
+			if tc.expectations != nil {
+				tc.expectations(t, s)
+			}
+
+			err := s.server.GetOpenidConfiguration(ctx)
+
+			// Assertions
+			if tc.expectedHttpCode >= http.StatusOK && // code 2XX
+				tc.expectedHttpCode <= http.StatusIMUsed {
+
+				var resp generated.OpenidConfigurationResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedHttpCode, rec.Code)
+				assert.Equal(t, tc.expectedIssuer, resp.Issuer)
+				assert.Equal(t, tc.expectedIssuer+"/.well-known/jwks.json", resp.JwksUri)
+				assert.Equal(t, tc.expectedIssuer+"/userinfo", resp.UserinfoEndpoint)
+				assert.Equal(t, tc.expectedAlgorithms, resp.IdTokenSigningAlgValuesSupported)
+				assert.Contains(t, *resp.ScopesSupported, "openid")
+				if tc.expectedIntrospection {
+					assert.Equal(t, tc.expectedIssuer+"/oauth/introspect", *resp.IntrospectionEndpoint)
+				} else {
+					assert.Nil(t, resp.IntrospectionEndpoint)
+				}
+			} else {
+				assert.Equal(t, tc.expectedHttpCode, rec.Code)
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/utils/context_helper"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Rules new passwords must follow
+// (GET /v1/password/policy)
+func (s *Server) GetPasswordPolicy(ctx echo.Context) error {
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	policy := s.PasswordPolicy
+	resp := generated.PasswordPolicyResponse{
+		MinLength:           policy.MinLength,
+		MaxLength:           policy.MaxLength,
+		RequiredClasses:     make([]generated.PasswordPolicyResponseRequiredClasses, 0, len(policy.RequiredClasses)),
+		DeniedWords:         append([]string{}, policy.DeniedWords...),
+		RejectsPersonalInfo: policy.RejectPersonalInfo,
+		MinScore:            policy.MinScore,
+	}
+	for _, class := range policy.RequiredClasses {
+		resp.RequiredClasses = append(resp.RequiredClasses, generated.PasswordPolicyResponseRequiredClasses(class))
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"user-service-sample/config"
+	"user-service-sample/generated"
+	"user-service-sample/utils/context_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/labstack/echo/v4"
+)
+
+func TestGetPasswordPolicy(t *testing.T) {
+
+	testCases := []struct {
+		title     string
+		aborted   bool
+		policyCfg *config.PasswordPolicyConfig
+
+		expectedHttpCode int
+		expectedErrMsg   string
+		expectedResp     generated.PasswordPolicyResponse
+	}{
+		{
+			title:            "request aborted",
+			aborted:          true,
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "default policy",
+			policyCfg:        &config.PasswordPolicyConfig{},
+			expectedHttpCode: http.StatusOK,
+			expectedResp: generated.PasswordPolicyResponse{
+				MinLength:           6,
+				MaxLength:           64,
+				RequiredClasses:     []generated.PasswordPolicyResponseRequiredClasses{"uppercase", "number", "special"},
+				DeniedWords:         []string{},
+				RejectsPersonalInfo: true,
+			},
+		},
+		{
+			title: "configured policy",
+			policyCfg: &config.PasswordPolicyConfig{
+				MinLength:         12,
+				MaxLength:         128,
+				RequiredClasses:   []string{},
+				DeniedWords:       []string{" Password", "qwerty", ""},
+				AllowPersonalInfo: true,
+				MinScore:          3,
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedResp: generated.PasswordPolicyResponse{
+				MinLength:           12,
+				MaxLength:           128,
+				RequiredClasses:     []generated.PasswordPolicyResponseRequiredClasses{},
+				DeniedWords:         []string{"password", "qwerty"},
+				RejectsPersonalInfo: false,
+				MinScore:            3,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			if tc.policyCfg != nil {
+				s.config.Password.Policy = *tc.policyCfg
+				server, err := NewServer(NewServerOptions{Config: s.config, Repository: s.repository})
+				assert.NoError(t, err)
+				s.server = server
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.GET, "/", nil)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/password/policy")
+
+			err := s.server.GetPasswordPolicy(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusOK {
+				var resp generated.PasswordPolicyResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedResp, resp)
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
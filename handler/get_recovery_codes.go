@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Get the number of unused recovery codes
+// (GET /v1/user/mfa/recovery-codes)
+func (s *Server) GetRecoveryCodes(ctx echo.Context) error {
+	tracestr := "handler.GetRecoveryCodes"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	claims, err := authentication.ClaimsFromContext(ctx)
+	if err != nil {
+		ctx.Logger().Infof("%s, ClaimsFromContext failed, err: %+v", tracestr, err)
+		return response.AccessForbidden(ctx)
+	}
+
+	remaining, err := s.Repository.CountMFARecoveryCodes(ctx.Request().Context(), claims.Id)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed CountMFARecoveryCodes, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	return ctx.JSON(http.StatusOK, generated.RecoveryCodesStatusResponse{
+		Remaining: remaining,
+	})
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+)
+
+func TestGetRecoveryCodes(t *testing.T) {
+	testCases := []struct {
+		title        string
+		jwt          string
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+		expectedResp     string
+	}{
+		{
+			title:            "request aborted",
+			jwt:              test_helper.TestUserJWT,
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "auth token invalid",
+			jwt:              "Bearer invalid-token",
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.UnauthorizedErrorMsg,
+		},
+		{
+			title: "error in Repository.CountMFARecoveryCodes",
+			jwt:   test_helper.TestUserJWT,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().CountMFARecoveryCodes(gomock.Any(), test_helper.TestUserId).
+					Return(0, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title: "success",
+			jwt:   test_helper.TestUserJWT,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().CountMFARecoveryCodes(gomock.Any(), test_helper.TestUserId).
+					Return(8, nil)
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedResp:     `{"remaining":8}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.GET, "/", nil)
+			req.Header.Set(authentication.AuthHeaderKey, tc.jwt)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/user/mfa/recovery-codes")
+
+			tc.expectations(t, s)
+
+			err := s.withAuth(t, s.server.GetRecoveryCodes)(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusOK {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedResp, strings.TrimSpace(rec.Body.String()))
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
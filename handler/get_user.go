@@ -10,6 +10,7 @@ import (
 	"user-service-sample/utils/response"
 
 	"github.com/labstack/echo/v4"
+	"github.com/xorcare/pointer"
 )
 
 // Retrieve user detail
@@ -20,9 +21,9 @@ func (s *Server) GetUser(ctx echo.Context) error {
 		return err
 	}
 
-	claims, err := authentication.VerifyToken(ctx, s.Config.Secret)
+	claims, err := authentication.ClaimsFromContext(ctx)
 	if err != nil {
-		ctx.Logger().Infof("%s, VerifyToken failed, err: %+v", tracestr, err)
+		ctx.Logger().Infof("%s, ClaimsFromContext failed, err: %+v", tracestr, err)
 		return response.AccessForbidden(ctx)
 	}
 
@@ -34,8 +35,18 @@ func (s *Server) GetUser(ctx echo.Context) error {
 		return response.InternalErrorResponse(ctx)
 	}
 
-	return ctx.JSON(http.StatusOK, generated.UserDataResponse{
-		FullName:    user.FullName,
-		PhoneNumber: user.PhoneNumber,
-	})
+	return ctx.JSON(http.StatusOK, userDataResponse(user))
+}
+
+func userDataResponse(user repository.User) generated.UserDataResponse {
+	resp := generated.UserDataResponse{
+		FullName:            user.FullName,
+		PhoneNumber:         user.PhoneNumber,
+		PhoneNumberVerified: user.PhoneVerifiedAt != nil,
+	}
+	if user.PendingPhoneNumber != "" {
+		resp.PendingPhoneNumber = pointer.String(user.PendingPhoneNumber)
+	}
+
+	return resp
 }
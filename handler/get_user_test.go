@@ -8,7 +8,9 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"user-service-sample/config"
 	"user-service-sample/generated"
 	"user-service-sample/repository"
 	"user-service-sample/utils/authentication"
@@ -24,10 +26,13 @@ import (
 func TestGetUser(t *testing.T) {
 
 	var (
+		phoneVerifiedAt = time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+
 		validUser = repository.User{
-			Id:          test_helper.TestUserId,
-			PhoneNumber: test_helper.TestUserPhone,
-			FullName:    test_helper.TestUserName,
+			Id:              test_helper.TestUserId,
+			PhoneNumber:     test_helper.TestUserPhone,
+			FullName:        test_helper.TestUserName,
+			PhoneVerifiedAt: &phoneVerifiedAt,
 		}
 	)
 
@@ -43,6 +48,7 @@ func TestGetUser(t *testing.T) {
 	}{
 		{
 			title:            "request aborted",
+			jwt:              test_helper.TestUserJWT,
 			aborted:          true,
 			expectations:     func(t *testing.T, s *serverMock) {},
 			expectedHttpCode: http.StatusGone,
@@ -52,8 +58,17 @@ func TestGetUser(t *testing.T) {
 			title:            "auth token invalid",
 			jwt:              "Bearer invalid-token",
 			expectations:     func(t *testing.T, s *serverMock) {},
-			expectedHttpCode: http.StatusForbidden,
-			expectedErrMsg:   response.AccessForbiddenErrorMsg,
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.UnauthorizedErrorMsg,
+		},
+		{
+			title: "auth token revoked",
+			jwt:   test_helper.TestUserJWT,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.revocationStore.RevokeUser(context.Background(), test_helper.TestUserId, time.Now(), time.Now().Add(time.Hour))
+			},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.UnauthorizedErrorMsg,
 		},
 		{
 			title: "error in Repository.GetUser",
@@ -78,8 +93,9 @@ func TestGetUser(t *testing.T) {
 			},
 			expectedHttpCode: http.StatusOK,
 			expectedResp: generated.UserDataResponse{
-				FullName:    validUser.FullName,
-				PhoneNumber: validUser.PhoneNumber,
+				FullName:            validUser.FullName,
+				PhoneNumber:         validUser.PhoneNumber,
+				PhoneNumberVerified: true,
 			},
 		},
 	}
@@ -107,7 +123,7 @@ func TestGetUser(t *testing.T) {
 
 			tc.expectations(t, s)
 
-			err := s.server.GetUser(ctx)
+			err := s.withAuth(t, s.server.GetUser)(ctx)
 
 			// Assertions
 			if tc.expectedHttpCode >= http.StatusOK && // code 2XX
@@ -125,3 +141,74 @@ func TestGetUser(t *testing.T) {
 		})
 	}
 }
+
+func TestGetUserAfterKeyRotation(t *testing.T) {
+	// Setup
+	s := setupServerMock(t)
+	defer s.cleanUp()
+
+	user := repository.User{
+		Id:          test_helper.TestUserId,
+		PhoneNumber: test_helper.TestUserPhone,
+		FullName:    test_helper.TestUserName,
+	}
+
+	// token issued before rotation, signed with the then active key
+	s.config.Secret = config.SecretConfig{
+		Keys: []config.SigningKeyConfig{{
+			Kid:        "old-key",
+			PrivatePem: test_helper.TestRetiredRsaPrivatePem,
+		}},
+	}
+	s.reloadKeys(t)
+	oldToken, err := s.server.KeyManager.GenerateSignedToken(user, "")
+	assert.NoError(t, err)
+
+	// rotate, the old key is retired and a new one becomes active
+	retiredAt := time.Now()
+	s.config.Secret = config.SecretConfig{
+		Keys: []config.SigningKeyConfig{{
+			Kid:        "new-key",
+			PrivatePem: test_helper.TestRsaPrivatePem,
+		}, {
+			Kid:       "old-key",
+			PublicPem: test_helper.TestRetiredRsaPublicPem,
+			RetiredAt: &retiredAt,
+		}},
+	}
+	s.reloadKeys(t)
+	newToken, err := s.server.KeyManager.GenerateSignedToken(user, "")
+	assert.NoError(t, err)
+
+	for _, token := range []string{oldToken, newToken} {
+		s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+			Id: test_helper.TestUserId,
+		}).
+			Return(user, nil)
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		req.Header.Set(authentication.AuthHeaderKey, "Bearer "+token)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
+		ctx.SetPath("/v1/user")
+
+		assert.NoError(t, s.withAuth(t, s.server.GetUser)(ctx))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	// once the retired key ages out its tokens are rejected
+	agedOutAt := time.Now().Add(-24 * time.Hour)
+	s.config.Secret.Keys[1].RetiredAt = &agedOutAt
+	s.reloadKeys(t)
+
+	e := echo.New()
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	req.Header.Set(authentication.AuthHeaderKey, "Bearer "+oldToken)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetPath("/v1/user")
+
+	s.withAuth(t, s.server.GetUser)(ctx)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+// OpenID Connect standard claims of the user the access token was issued to
+// (GET /userinfo)
+func (s *Server) GetUserinfo(ctx echo.Context) error {
+	tracestr := "handler.GetUserinfo"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	claims, err := authentication.ClaimsFromContext(ctx)
+	if err != nil {
+		ctx.Logger().Infof("%s, ClaimsFromContext failed, err: %+v", tracestr, err)
+		return response.AccessForbidden(ctx)
+	}
+
+	user, err := s.Repository.GetUser(ctx.Request().Context(), repository.GetUserInput{
+		Id: claims.Id,
+	})
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed GetUser by Id, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	standardClaims := authentication.NewStandardClaims(user)
+	resp := generated.UserinfoResponse{
+		Sub:                 user.Id,
+		Name:                standardClaims.Name,
+		PhoneNumber:         standardClaims.PhoneNumber,
+		PhoneNumberVerified: standardClaims.PhoneNumberVerified,
+	}
+	if standardClaims.UpdatedAt != 0 {
+		resp.UpdatedAt = &standardClaims.UpdatedAt
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+)
+
+func TestGetUserinfo(t *testing.T) {
+
+	var (
+		createdAt = time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+		updatedAt = time.Date(2023, 8, 2, 0, 0, 0, 0, time.UTC)
+
+		validUser = repository.User{
+			Id:          test_helper.TestUserId,
+			CreatedAt:   &createdAt,
+			PhoneNumber: test_helper.TestUserPhone,
+			FullName:    test_helper.TestUserName,
+		}
+	)
+
+	withUpdatedAt := validUser
+	withUpdatedAt.UpdatedAt = &updatedAt
+	withUpdatedAt.PhoneVerifiedAt = &createdAt
+
+	createdAtUnix := createdAt.Unix()
+	updatedAtUnix := updatedAt.Unix()
+
+	testCases := []struct {
+		title        string
+		jwt          string
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+		expectedResp     generated.UserinfoResponse
+	}{
+		{
+			title:            "request aborted",
+			jwt:              test_helper.TestUserJWT,
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "auth token invalid",
+			jwt:              "Bearer invalid-token",
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.UnauthorizedErrorMsg,
+		},
+		{
+			title: "error in Repository.GetUser",
+			jwt:   test_helper.TestUserJWT,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					Id: test_helper.TestUserId,
+				}).
+					Return(repository.User{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title: "never updated pending user reports creation time",
+			jwt:   test_helper.TestUserJWT,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					Id: test_helper.TestUserId,
+				}).
+					Return(validUser, nil)
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedResp: generated.UserinfoResponse{
+				Sub:                 test_helper.TestUserId,
+				Name:                test_helper.TestUserName,
+				PhoneNumber:         test_helper.TestUserPhone,
+				PhoneNumberVerified: false,
+				UpdatedAt:           &createdAtUnix,
+			},
+		},
+		{
+			title: "success",
+			jwt:   test_helper.TestUserJWT,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					Id: test_helper.TestUserId,
+				}).
+					Return(withUpdatedAt, nil)
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedResp: generated.UserinfoResponse{
+				Sub:                 test_helper.TestUserId,
+				Name:                test_helper.TestUserName,
+				PhoneNumber:         test_helper.TestUserPhone,
+				PhoneNumberVerified: true,
+				UpdatedAt:           &updatedAtUnix,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.GET, "/", nil)
+			req.Header.Set(authentication.AuthHeaderKey, tc.jwt)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/userinfo")
+
+			tc.expectations(t, s)
+
+			err := s.withAuth(t, s.server.GetUserinfo)(ctx)
+
+			// Assertions
+			if tc.expectedHttpCode >= http.StatusOK && // code 2XX
+				tc.expectedHttpCode <= http.StatusIMUsed {
+
+				expectedRespJson, _ := json.Marshal(tc.expectedResp)
+
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedHttpCode, rec.Code)
+				assert.Equal(t, string(expectedRespJson), strings.TrimSpace(rec.Body.String()))
+			} else {
+				assert.Equal(t, tc.expectedHttpCode, rec.Code)
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	introspectionTokenType = "Bearer"
+)
+
+// Introspect an access token (RFC 7662), for services that can't verify JWTs themselves
+// (POST /oauth/introspect)
+func (s *Server) IntrospectToken(ctx echo.Context) error {
+	tracestr := "handler.IntrospectToken"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	clientId, err := authentication.VerifyClient(ctx, s.Config.OAuth.Clients)
+	if err != nil {
+		ctx.Logger().Infof("%s, VerifyClient failed, err: %+v", tracestr, err)
+		return response.InvalidClient(ctx)
+	}
+
+	var req generated.IntrospectTokenFormdataRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+
+	introspection, err := s.KeyManager.IntrospectToken(ctx.Request().Context(), s.RevocationStore, req.Token)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed IntrospectToken for client %s, err: %v", tracestr, clientId, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	// introspection results must not be cached, the token may be revoked at any time
+	ctx.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+
+	resp := generated.IntrospectionResponse{
+		Active: introspection.Active,
+	}
+	if introspection.Active {
+		tokenType := introspectionTokenType
+		resp.Sub = &introspection.Subject
+		resp.Exp = &introspection.ExpiresAt
+		resp.TokenType = &tokenType
+		if introspection.IssuedAt != 0 {
+			resp.Iat = &introspection.IssuedAt
+		}
+		if introspection.Scope != "" {
+			resp.Scope = &introspection.Scope
+		}
+		if introspection.ClientId != "" {
+			resp.ClientId = &introspection.ClientId
+		}
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"user-service-sample/config"
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+func TestIntrospectToken(t *testing.T) {
+
+	var (
+		client = config.OAuthClientConfig{
+			Id:     "legacy-billing",
+			Secret: "billing-secret",
+		}
+
+		tokenCfg = config.SecretConfig{
+			RsaPrivatePem: test_helper.TestRsaPrivatePem,
+			RsaPublicPem:  test_helper.TestRsaPublicPem,
+			Issuer:        test_helper.TestIssuer,
+			Audiences:     []string{test_helper.TestAudience},
+			ClientId:      "user-service",
+			Scopes:        []string{"profile", "phone"},
+		}
+	)
+
+	tokenKeys, _ := authentication.NewKeyManager(tokenCfg)
+	validJWT, _ := tokenKeys.GenerateSignedToken(repository.User{
+		Id:       test_helper.TestUserId,
+		FullName: test_helper.TestUserName,
+	}, "")
+
+	invalidJWT := "invalid-token"
+
+	privateKey, _ := jwt.ParseRSAPrivateKeyFromPEM([]byte(test_helper.TestRsaPrivatePem))
+	expiredJWT, _ := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"id":  test_helper.TestUserId,
+		"sub": test_helper.TestUserId,
+		"iss": test_helper.TestIssuer,
+		"aud": []string{test_helper.TestAudience},
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	}).SignedString(privateKey)
+
+	testCases := []struct {
+		title        string
+		token        *string
+		clientId     string
+		clientSecret string
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+		expectedActive   bool
+	}{
+		{
+			title:            "request aborted",
+			token:            &validJWT,
+			clientId:         client.Id,
+			clientSecret:     client.Secret,
+			aborted:          true,
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "missing client credentials",
+			token:            &validJWT,
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.InvalidClientErrorMsg,
+		},
+		{
+			title:            "wrong client secret",
+			token:            &validJWT,
+			clientId:         client.Id,
+			clientSecret:     "not-the-secret",
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.InvalidClientErrorMsg,
+		},
+		{
+			title:            "unknown client",
+			token:            &validJWT,
+			clientId:         "unknown-client",
+			clientSecret:     client.Secret,
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.InvalidClientErrorMsg,
+		},
+		{
+			title:            "missing token",
+			clientId:         client.Id,
+			clientSecret:     client.Secret,
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   "token is a required field",
+		},
+		{
+			title:            "invalid token is inactive",
+			token:            &invalidJWT,
+			clientId:         client.Id,
+			clientSecret:     client.Secret,
+			expectedHttpCode: http.StatusOK,
+			expectedActive:   false,
+		},
+		{
+			title:            "expired token is inactive",
+			token:            &expiredJWT,
+			clientId:         client.Id,
+			clientSecret:     client.Secret,
+			expectedHttpCode: http.StatusOK,
+			expectedActive:   false,
+		},
+		{
+			title:        "revoked token is inactive",
+			token:        &validJWT,
+			clientId:     client.Id,
+			clientSecret: client.Secret,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.revocationStore.RevokeUser(context.Background(), test_helper.TestUserId, time.Now().Add(time.Minute), time.Now().Add(time.Hour))
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedActive:   false,
+		},
+		{
+			title:        "revocation store unavailable",
+			token:        &validJWT,
+			clientId:     client.Id,
+			clientSecret: client.Secret,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.server.RevocationStore = failingRevocationStore{}
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:            "active token",
+			token:            &validJWT,
+			clientId:         client.Id,
+			clientSecret:     client.Secret,
+			expectedHttpCode: http.StatusOK,
+			expectedActive:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			s.config.OAuth.Clients = []config.OAuthClientConfig{client}
+
+			form := url.Values{}
+			if tc.token != nil {
+				form.Set("token", *tc.token)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", strings.NewReader(form.Encode()))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			if tc.clientId != "" {
+				req.SetBasicAuth(tc.clientId, tc.clientSecret)
+			}
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/oauth/introspect")
+
+			if tc.expectations != nil {
+				tc.expectations(t, s)
+			}
+
+			err := s.server.IntrospectToken(ctx)
+
+			// Assertions
+			if tc.expectedHttpCode >= http.StatusOK && // code 2XX
+				tc.expectedHttpCode <= http.StatusIMUsed {
+
+				var resp generated.IntrospectionResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedHttpCode, rec.Code)
+				assert.Equal(t, "no-store", rec.Header().Get(echo.HeaderCacheControl))
+				assert.Equal(t, tc.expectedActive, resp.Active)
+				if tc.expectedActive {
+					assert.Equal(t, test_helper.TestUserId, *resp.Sub)
+					assert.Equal(t, "profile phone", *resp.Scope)
+					assert.Equal(t, "user-service", *resp.ClientId)
+					assert.NotNil(t, resp.Iat)
+					assert.True(t, *resp.Exp > time.Now().Unix())
+				} else {
+					// inactive tokens disclose nothing else
+					assert.Equal(t, generated.IntrospectionResponse{Active: false}, resp)
+				}
+			} else {
+				assert.Equal(t, tc.expectedHttpCode, rec.Code)
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+				if tc.expectedHttpCode == http.StatusUnauthorized {
+					assert.Equal(t, `Basic realm="user-service"`, rec.Header().Get(echo.HeaderWWWAuthenticate))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"user-service-sample/config"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/labstack/echo/v4"
+)
+
+// The benchmarks compare parsing the PEMs of the secret config on every request,
+// as handlers did before the key manager, with the keys parsed once at startup.
+//
+//	go test ./handler -run '^$' -bench KeyManager -benchmem
+
+var benchmarkSecretCfg = config.SecretConfig{
+	RsaPrivatePem: test_helper.TestRsaPrivatePem,
+	RsaPublicPem:  test_helper.TestRsaPublicPem,
+	Issuer:        test_helper.TestIssuer,
+	Audiences:     []string{test_helper.TestAudience},
+}
+
+var benchmarkUser = repository.User{
+	Id:       test_helper.TestUserId,
+	FullName: test_helper.TestUserName,
+}
+
+func newBenchmarkKeyManager(b *testing.B) *authentication.KeyManager {
+	b.Helper()
+
+	keyManager, err := authentication.NewKeyManager(benchmarkSecretCfg)
+	if err != nil {
+		b.Fatalf("failed NewKeyManager, err: %v", err)
+	}
+	return keyManager
+}
+
+func BenchmarkKeyManagerVerifyToken(b *testing.B) {
+	e := echo.New()
+	req := httptest.NewRequest(echo.GET, "/v1/user", nil)
+	req.Header.Set(authentication.AuthHeaderKey, test_helper.TestUserJWT)
+	ctx := e.NewContext(req, httptest.NewRecorder())
+
+	b.Run("parse PEM per request", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			keyManager, err := authentication.NewKeyManager(benchmarkSecretCfg)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := keyManager.VerifyToken(ctx, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("key manager", func(b *testing.B) {
+		keyManager := newBenchmarkKeyManager(b)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := keyManager.VerifyToken(ctx, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkKeyManagerGenerateSignedToken(b *testing.B) {
+	b.Run("parse PEM per request", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			keyManager, err := authentication.NewKeyManager(benchmarkSecretCfg)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := keyManager.GenerateSignedToken(benchmarkUser, ""); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("key manager", func(b *testing.B) {
+		keyManager := newBenchmarkKeyManager(b)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := keyManager.GenerateSignedToken(benchmarkUser, ""); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
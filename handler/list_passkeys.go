@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+// List the passkeys of the user
+// (GET /v1/user/passkeys)
+func (s *Server) ListPasskeys(ctx echo.Context) error {
+	tracestr := "handler.ListPasskeys"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	claims, err := authentication.ClaimsFromContext(ctx)
+	if err != nil {
+		ctx.Logger().Infof("%s, ClaimsFromContext failed, err: %+v", tracestr, err)
+		return response.AccessForbidden(ctx)
+	}
+
+	passkeys, err := s.Repository.ListPasskeys(ctx.Request().Context(), claims.Id)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed ListPasskeys, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	resp := generated.PasskeysResponse{
+		Passkeys: make([]generated.Passkey, 0, len(passkeys)),
+	}
+	for _, passkey := range passkeys {
+		resp.Passkeys = append(resp.Passkeys, passkeyResponse(passkey))
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
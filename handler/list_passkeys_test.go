@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+)
+
+func TestListPasskeys(t *testing.T) {
+
+	var (
+		now = time.Now().UTC().Truncate(time.Second)
+
+		phonePasskey = repository.Passkey{
+			Id:           "a7b8c9d0-7777-4a8b-8c9d-0e1f2a3b4c07",
+			CreatedAt:    now.Add(-48 * time.Hour),
+			UserId:       test_helper.TestUserId,
+			CredentialId: "cGhvbmUtY3JlZGVudGlhbA",
+			PublicKey:    []byte{0xa5},
+			SignCount:    12,
+			Transports:   []string{"internal", "hybrid"},
+			Name:         "iPhone",
+			BackedUp:     true,
+			LastUsedAt:   &now,
+		}
+		securityKey = repository.Passkey{
+			Id:           "b8c9d0e1-8888-4b9c-9d0e-1f2a3b4c5d08",
+			CreatedAt:    now.Add(-time.Hour),
+			UserId:       test_helper.TestUserId,
+			CredentialId: "c2VjdXJpdHkta2V5",
+			PublicKey:    []byte{0xa5},
+			Transports:   []string{"usb"},
+			Name:         "Security key",
+		}
+	)
+
+	testCases := []struct {
+		title        string
+		jwt          string
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+		expectedResp     generated.PasskeysResponse
+	}{
+		{
+			title:            "request aborted",
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "auth token invalid",
+			jwt:              "Bearer invalid-token",
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.UnauthorizedErrorMsg,
+		},
+		{
+			title: "error in Repository.ListPasskeys",
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().ListPasskeys(gomock.Any(), test_helper.TestUserId).
+					Return(nil, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title: "no passkeys",
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().ListPasskeys(gomock.Any(), test_helper.TestUserId).
+					Return([]repository.Passkey{}, nil)
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedResp: generated.PasskeysResponse{
+				Passkeys: []generated.Passkey{},
+			},
+		},
+		{
+			title: "passkeys without their keys",
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().ListPasskeys(gomock.Any(), test_helper.TestUserId).
+					Return([]repository.Passkey{phonePasskey, securityKey}, nil)
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedResp: generated.PasskeysResponse{
+				Passkeys: []generated.Passkey{
+					{
+						Id:         phonePasskey.Id,
+						Name:       "iPhone",
+						CreatedAt:  phonePasskey.CreatedAt,
+						LastUsedAt: &now,
+						BackedUp:   true,
+						Transports: []string{"internal", "hybrid"},
+					},
+					{
+						Id:         securityKey.Id,
+						Name:       "Security key",
+						CreatedAt:  securityKey.CreatedAt,
+						Transports: []string{"usb"},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			jwt := tc.jwt
+			if jwt == "" {
+				jwt = test_helper.TestUserJWT
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.GET, "/", nil)
+			req.Header.Set(authentication.AuthHeaderKey, jwt)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/user/passkeys")
+
+			tc.expectations(t, s)
+
+			err := s.withAuth(t, s.server.ListPasskeys)(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusOK {
+				var resp generated.PasskeysResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedResp, resp)
+				assert.NotContains(t, rec.Body.String(), phonePasskey.CredentialId)
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+// List the active log in sessions of the user
+// (GET /v1/user/sessions)
+func (s *Server) ListUserSessions(ctx echo.Context) error {
+	tracestr := "handler.ListUserSessions"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	claims, err := authentication.ClaimsFromContext(ctx)
+	if err != nil {
+		ctx.Logger().Infof("%s, ClaimsFromContext failed, err: %+v", tracestr, err)
+		return response.AccessForbidden(ctx)
+	}
+
+	sessions, err := s.Repository.ListUserSessions(ctx.Request().Context(), claims.Id)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed ListUserSessions, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	resp := generated.UserSessionsResponse{
+		Sessions: make([]generated.UserSession, 0, len(sessions)),
+	}
+	for _, session := range sessions {
+		item := generated.UserSession{
+			Id:         session.Id,
+			UserAgent:  session.UserAgent,
+			IpAddress:  session.IpAddress,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			Current:    session.Id == claims.SessionId,
+		}
+		if session.DeviceLabel != "" {
+			deviceLabel := session.DeviceLabel
+			item.DeviceLabel = &deviceLabel
+		}
+		resp.Sessions = append(resp.Sessions, item)
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+)
+
+func TestListUserSessions(t *testing.T) {
+
+	var (
+		now = time.Now().UTC().Truncate(time.Second)
+
+		currentSession = repository.UserSession{
+			Id:          "c3d4e5f6-0a1b-4c2d-8e3f-4a5b6c7d8e03",
+			CreatedAt:   now.Add(-time.Hour),
+			UserId:      test_helper.TestUserId,
+			UserAgent:   "unit-test-agent",
+			IpAddress:   "192.0.2.1",
+			DeviceLabel: "Work laptop",
+			LastUsedAt:  now,
+			ExpiresAt:   now.Add(24 * time.Hour),
+		}
+
+		otherSession = repository.UserSession{
+			Id:         "d4e5f6a7-1b2c-4d3e-9f4a-5b6c7d8e9f04",
+			CreatedAt:  now.Add(-48 * time.Hour),
+			UserId:     test_helper.TestUserId,
+			UserAgent:  "another-agent",
+			IpAddress:  "198.51.100.7",
+			LastUsedAt: now.Add(-24 * time.Hour),
+			ExpiresAt:  now.Add(24 * time.Hour),
+		}
+	)
+
+	deviceLabel := currentSession.DeviceLabel
+
+	testCases := []struct {
+		title        string
+		jwt          string
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+		expectedResp     generated.UserSessionsResponse
+	}{
+		{
+			title:            "request aborted",
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "auth token invalid",
+			jwt:              "Bearer invalid-token",
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.UnauthorizedErrorMsg,
+		},
+		{
+			title: "error in Repository.ListUserSessions",
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().ListUserSessions(gomock.Any(), test_helper.TestUserId).
+					Return(nil, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title: "no active session",
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().ListUserSessions(gomock.Any(), test_helper.TestUserId).
+					Return([]repository.UserSession{}, nil)
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedResp: generated.UserSessionsResponse{
+				Sessions: []generated.UserSession{},
+			},
+		},
+		{
+			title: "success marks the current session",
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().ListUserSessions(gomock.Any(), test_helper.TestUserId).
+					Return([]repository.UserSession{currentSession, otherSession}, nil)
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedResp: generated.UserSessionsResponse{
+				Sessions: []generated.UserSession{{
+					Id:          currentSession.Id,
+					DeviceLabel: &deviceLabel,
+					UserAgent:   currentSession.UserAgent,
+					IpAddress:   currentSession.IpAddress,
+					CreatedAt:   currentSession.CreatedAt,
+					LastUsedAt:  currentSession.LastUsedAt,
+					Current:     true,
+				}, {
+					Id:         otherSession.Id,
+					UserAgent:  otherSession.UserAgent,
+					IpAddress:  otherSession.IpAddress,
+					CreatedAt:  otherSession.CreatedAt,
+					LastUsedAt: otherSession.LastUsedAt,
+					Current:    false,
+				}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			jwt := tc.jwt
+			if jwt == "" {
+				token, err := s.server.KeyManager.GenerateSignedToken(logoutTestUser, currentSession.Id)
+				assert.NoError(t, err)
+				jwt = "Bearer " + token
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.GET, "/", nil)
+			req.Header.Set(authentication.AuthHeaderKey, jwt)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/user/sessions")
+
+			tc.expectations(t, s)
+
+			err := s.withAuth(t, s.server.ListUserSessions)(ctx)
+
+			// Assertions
+			if tc.expectedHttpCode >= http.StatusOK && // code 2XX
+				tc.expectedHttpCode <= http.StatusIMUsed {
+
+				var resp generated.UserSessionsResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedHttpCode, rec.Code)
+				assert.Equal(t, tc.expectedResp, resp)
+			} else {
+				assert.Equal(t, tc.expectedHttpCode, rec.Code)
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
@@ -8,14 +8,19 @@ import (
 	"user-service-sample/repository"
 	"user-service-sample/utils/authentication"
 	"user-service-sample/utils/context_helper"
-	"user-service-sample/utils/password"
 	"user-service-sample/utils/request_helper"
 	"user-service-sample/utils/response"
+	"user-service-sample/utils/string_helper"
 
 	"github.com/labstack/echo/v4"
 )
 
-// Log In as registered user, will return JWT
+const (
+	// sessionUserAgentMaxLen is the size of 'user_sessions.user_agent'
+	sessionUserAgentMaxLen = 512
+)
+
+// Log In as registered user, will return JWT, or an MFA challenge to complete at /v1/login/mfa once the user enabled TOTP
 // (POST /v1/login)
 func (s *Server) Login(ctx echo.Context) error {
 	tracestr := "handler.Login"
@@ -27,6 +32,7 @@ func (s *Server) Login(ctx echo.Context) error {
 	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
 		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
 	}
+	req.PhoneNumber = s.canonicalPhoneNumber(req.PhoneNumber)
 
 	user, err := s.Repository.GetUser(ctx.Request().Context(), repository.GetUserInput{
 		PhoneNumber: req.PhoneNumber,
@@ -39,24 +45,104 @@ func (s *Server) Login(ctx echo.Context) error {
 		return response.InternalErrorResponse(ctx)
 	}
 
-	if !password.CheckPassword(req.Password, user.PasswordHash, user.Salt) {
+	match, needsRehash, err := s.PasswordHasher.Verify(req.Password, user.PasswordHash, user.Salt)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed Verify password of user %s, err: %v", tracestr, user.Id, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if !match {
 		return response.IncorrectLoginCred(ctx)
 	}
+	if user.PhoneVerifiedAt == nil {
+		return response.PhoneNotVerified(ctx)
+	}
+	if needsRehash {
+		s.rehashPassword(ctx, tracestr, user.Id, req.Password)
+	}
+
+	return s.continueLogin(ctx, tracestr, user, string_helper.GetAndTrimPointerStringValue(req.DeviceLabel))
+}
+
+// continueLogin follows the first factor of a log in, a password or a code sent by SMS: the log in is completed,
+// or an MFA challenge is started once the user enabled TOTP.
+func (s *Server) continueLogin(ctx echo.Context, tracestr string, user repository.User, deviceLabel string) error {
+	if user.TOTPEnabled {
+		return s.startMFAChallenge(ctx, tracestr, user, deviceLabel)
+	}
 
-	// user & password correct
-	token, err := authentication.GenerateSignedToken(s.Config.Secret, user)
+	return s.completeLogin(ctx, tracestr, user, deviceLabel)
+}
+
+// completeLogin starts a session of the authenticated user with a new refresh token family
+// and responds with the tokens of the session. Every log in method ends here, so the login count
+// covers them all.
+func (s *Server) completeLogin(ctx echo.Context, tracestr string, user repository.User, deviceLabel string) error {
+	refreshToken, err := authentication.GenerateRefreshToken(s.Config.Secret)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed GenerateRefreshToken, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	session, err := s.Repository.InsertUserSession(ctx.Request().Context(), nil, repository.InsertUserSessionInput{
+		UserId:      user.Id,
+		UserAgent:   string_helper.Truncate(ctx.Request().UserAgent(), sessionUserAgentMaxLen),
+		IpAddress:   ctx.RealIP(),
+		DeviceLabel: deviceLabel,
+		ExpiresAt:   refreshToken.ExpiresAt,
+	})
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed InsertUserSession, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if _, err := s.Repository.InsertRefreshToken(ctx.Request().Context(), nil, repository.InsertRefreshTokenInput{
+		UserId:    user.Id,
+		SessionId: session.Id,
+		TokenHash: refreshToken.Hash,
+		ExpiresAt: refreshToken.ExpiresAt,
+	}); err != nil {
+		ctx.Logger().Errorf("%s, failed InsertRefreshToken, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	token, err := s.KeyManager.GenerateSignedToken(user, session.Id)
 	if err != nil {
 		ctx.Logger().Errorf("%s, failed GenerateSignedToken, err: %v", tracestr, err)
 		return response.InternalErrorResponse(ctx)
 	}
 
+	idToken, err := s.KeyManager.GenerateIDToken(user, session.Id, token)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed GenerateIDToken, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
 	// increment login count
 	if err := s.Repository.IncrementUserLoginCount(ctx.Request().Context(), nil, user); err != nil {
 		ctx.Logger().Infof("%s, failed IncrementUserLoginCount, err: %v", tracestr, err)
 	}
 
 	return ctx.JSON(http.StatusOK, generated.LoginResponse{
-		Id:    user.Id,
-		Token: token,
+		Id:           user.Id,
+		Token:        token,
+		IdToken:      idToken,
+		RefreshToken: refreshToken.Token,
 	})
 }
+
+// rehashPassword upgrades the stored hash to the configured algorithm & cost while the
+// plain password is known. Failing only delays the upgrade to the next log in.
+func (s *Server) rehashPassword(ctx echo.Context, tracestr, userId, plainPassword string) {
+	passwordHash, err := s.PasswordHasher.Hash(plainPassword)
+	if err != nil {
+		ctx.Logger().Infof("%s, failed Hash password for rehash, err: %v", tracestr, err)
+		return
+	}
+
+	err = s.Repository.UpdateUserCredentials(ctx.Request().Context(), nil, repository.UpdateUserCredentialsInput{
+		Id:           userId,
+		PasswordHash: passwordHash,
+		Rehash:       true,
+	})
+	if err != nil {
+		ctx.Logger().Infof("%s, failed UpdateUserCredentials, err: %v", tracestr, err)
+	}
+}
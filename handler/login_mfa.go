@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	mfaMethodTOTP         = "totp"
+	mfaMethodRecoveryCode = "recovery_code"
+)
+
+// startMFAChallenge responds to a log in of a user with MFA with an MFA token instead of a session,
+// the session is started by LoginMfa once a code is given with the token.
+func (s *Server) startMFAChallenge(ctx echo.Context, tracestr string, user repository.User, deviceLabel string) error {
+	mfaToken, err := authentication.GenerateMFAToken(s.Config.MFA)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed GenerateMFAToken, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	_, err = s.Repository.InsertMFAChallenge(ctx.Request().Context(), nil, repository.InsertMFAChallengeInput{
+		UserId:      user.Id,
+		TokenHash:   mfaToken.Hash,
+		DeviceLabel: deviceLabel,
+		ExpiresAt:   mfaToken.ExpiresAt,
+	})
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed InsertMFAChallenge, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	return ctx.JSON(http.StatusAccepted, generated.MfaChallengeResponse{
+		MfaToken:  mfaToken.Token,
+		ExpiresIn: int(s.Config.MFA.GetChallengeTTL().Seconds()),
+		Methods:   []string{mfaMethodTOTP, mfaMethodRecoveryCode},
+	})
+}
+
+// Complete the log in of a user with MFA with a code of the authenticator app or a recovery code, will return JWT
+// (POST /v1/login/mfa)
+func (s *Server) LoginMfa(ctx echo.Context) error {
+	tracestr := "handler.LoginMfa"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	var req generated.LoginMfaJSONRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+
+	challenge, err := s.Repository.GetActiveMFAChallenge(ctx.Request().Context(), authentication.HashRefreshToken(req.MfaToken))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return response.InvalidMFAChallenge(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed GetActiveMFAChallenge, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	// counted before the code is checked, a token only takes the configured number of guesses
+	err = s.Repository.IncrementMFAChallengeAttempts(ctx.Request().Context(), nil, repository.IncrementMFAChallengeAttemptsInput{
+		Id:          challenge.Id,
+		MaxAttempts: s.Config.MFA.GetChallengeMaxAttempts(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return response.InvalidMFAChallenge(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed IncrementMFAChallengeAttempts, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	user, err := s.Repository.GetUser(ctx.Request().Context(), repository.GetUserInput{
+		Id: challenge.UserId,
+	})
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed GetUser by Id, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	if wait := mfaLockedFor(user); wait > 0 {
+		return response.TooManyRequests(ctx, wait)
+	}
+
+	if req.RecoveryCode != nil {
+		remaining, err := s.useRecoveryCode(ctx, user.Id, *req.RecoveryCode)
+		if err != nil {
+			if err == errInvalidRecoveryCode {
+				return s.mfaFailed(ctx, tracestr, user.Id)
+			}
+			ctx.Logger().Errorf("%s, failed useRecoveryCode, err: %v", tracestr, err)
+			return response.InternalErrorResponse(ctx)
+		}
+		s.auditLog(ctx, tracestr, user.Id, auditEventRecoveryCodeUsed, fmt.Sprintf("remaining=%d", remaining))
+	} else {
+		userTOTP, err := s.Repository.GetUserTOTP(ctx.Request().Context(), user.Id)
+		if err != nil {
+			ctx.Logger().Errorf("%s, failed GetUserTOTP, err: %v", tracestr, err)
+			return response.InternalErrorResponse(ctx)
+		}
+
+		if err := s.verifyTOTP(ctx, userTOTP, *req.Code, false); err != nil {
+			if err == errInvalidTOTPCode {
+				return s.mfaFailed(ctx, tracestr, user.Id)
+			}
+			ctx.Logger().Errorf("%s, failed verifyTOTP, err: %v", tracestr, err)
+			return response.InternalErrorResponse(ctx)
+		}
+	}
+	s.mfaPassed(ctx, tracestr, user)
+
+	if err := s.Repository.ConsumeMFAChallenge(ctx.Request().Context(), nil, challenge.Id); err != nil {
+		// passed by a concurrent request with another code
+		if err == sql.ErrNoRows {
+			return response.InvalidMFAChallenge(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed ConsumeMFAChallenge, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	return s.completeLogin(ctx, tracestr, user, challenge.DeviceLabel)
+}
@@ -0,0 +1,552 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/recoverycode"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+	"user-service-sample/utils/totp"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/xorcare/pointer"
+)
+
+func TestLoginMfa(t *testing.T) {
+	var (
+		currentStep    = totp.Step(time.Now())
+		currentCode, _ = totp.Code(test_helper.TestTOTPSecret, currentStep)
+		staleCode, _   = totp.Code(test_helper.TestTOTPSecret, currentStep-10)
+
+		validReqBody = generated.LoginMfaJSONRequestBody{
+			MfaToken: "mfa-token-from-login",
+			Code:     &currentCode,
+		}
+
+		recoveryCode        = "7kq2-mx9d-4hpt-bw3z"
+		recoveryCodeReqBody = generated.LoginMfaJSONRequestBody{
+			MfaToken:     "mfa-token-from-login",
+			RecoveryCode: pointer.String(" 7KQ2 MX9D 4HPT BW3Z "),
+		}
+
+		phoneVerifiedAt = time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+		confirmedAt     = time.Now().Add(-24 * time.Hour)
+
+		validUser = repository.User{
+			Id:              test_helper.TestUserId,
+			PhoneNumber:     test_helper.TestUserPhone,
+			FullName:        test_helper.TestUserName,
+			PhoneVerifiedAt: &phoneVerifiedAt,
+			TOTPEnabled:     true,
+		}
+
+		lockedUntil = time.Now().Add(10 * time.Minute)
+		lockExpired = time.Now().Add(-time.Minute)
+
+		activeChallenge = repository.MFAChallenge{
+			Id:          "d0e1f2a3-7b8c-4d9e-8f0a-1b2c3d4e5f10",
+			CreatedAt:   time.Now().Add(-time.Minute),
+			UserId:      test_helper.TestUserId,
+			TokenHash:   authentication.HashRefreshToken("mfa-token-from-login"),
+			DeviceLabel: "Work laptop",
+			ExpiresAt:   time.Now().Add(4 * time.Minute),
+		}
+
+		validSession = repository.InsertUserSessionOutput{
+			Id: "e1f2a3b4-8c9d-4e0f-8a1b-2c3d4e5f6a11",
+		}
+	)
+
+	lockedUser := validUser
+	lockedUser.MFALockedUntil = &lockedUntil
+
+	// wrong codes before the right one, and a lockout that ended
+	failedUser := validUser
+	failedUser.MFAFailedAttempts = 3
+	failedUser.MFALockedUntil = &lockExpired
+
+	// userTOTP is the confirmed authenticator of the user, with the secret encrypted by the server mock
+	userTOTP := func(t *testing.T, s *serverMock) repository.UserTOTP {
+		secretCiphertext, err := s.server.TOTPKeyring.Encrypt([]byte(test_helper.TestTOTPSecret), []byte(test_helper.TestUserId))
+		assert.NoError(t, err)
+
+		return repository.UserTOTP{
+			UserId:           test_helper.TestUserId,
+			CreatedAt:        confirmedAt,
+			SecretCiphertext: secretCiphertext,
+			ConfirmedAt:      &confirmedAt,
+		}
+	}
+
+	expectActiveChallenge := func(s *serverMock, challenge repository.MFAChallenge, err error) {
+		s.repository.EXPECT().GetActiveMFAChallenge(gomock.Any(), activeChallenge.TokenHash).
+			Return(challenge, err)
+	}
+	expectIncrementAttempts := func(s *serverMock, err error) {
+		s.repository.EXPECT().IncrementMFAChallengeAttempts(gomock.Any(), nil, repository.IncrementMFAChallengeAttemptsInput{
+			Id:          activeChallenge.Id,
+			MaxAttempts: 5,
+		}).
+			Return(err)
+	}
+	expectGetUser := func(s *serverMock, user repository.User, err error) {
+		s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+			Id: test_helper.TestUserId,
+		}).
+			Return(user, err)
+	}
+	expectGetUserTOTP := func(s *serverMock, output repository.UserTOTP, err error) {
+		s.repository.EXPECT().GetUserTOTP(gomock.Any(), test_helper.TestUserId).
+			Return(output, err)
+	}
+	expectUseStep := func(s *serverMock, err error) {
+		s.repository.EXPECT().UseUserTOTPStep(gomock.Any(), nil, repository.UseUserTOTPStepInput{
+			UserId: test_helper.TestUserId,
+			Step:   currentStep,
+		}).
+			Return(err)
+	}
+	expectConsumeChallenge := func(s *serverMock, err error) {
+		s.repository.EXPECT().ConsumeMFAChallenge(gomock.Any(), nil, activeChallenge.Id).
+			Return(err)
+	}
+	expectUseRecoveryCode := func(s *serverMock, remaining int, err error) {
+		s.repository.EXPECT().UseMFARecoveryCode(gomock.Any(), nil, repository.UseMFARecoveryCodeInput{
+			UserId:   test_helper.TestUserId,
+			CodeHash: recoverycode.Hash(test_helper.TestUserId, recoveryCode),
+		}).
+			Return(remaining, err)
+	}
+	// the log in is recorded with the client of the request
+	expectAuditLog := func(s *serverMock, err error) {
+		s.repository.EXPECT().InsertAuditLog(gomock.Any(), nil, repository.InsertAuditLogInput{
+			UserId:    test_helper.TestUserId,
+			Event:     auditEventRecoveryCodeUsed,
+			IpAddress: "192.0.2.1",
+			UserAgent: "unit-test-agent",
+			Details:   "remaining=9",
+		}).
+			Return(err)
+	}
+	// the wrong code counts for the user, locked tells whether it locked MFA of the user
+	expectMFAFailure := func(s *serverMock, locked bool, err error) {
+		s.repository.EXPECT().RecordMFAFailure(gomock.Any(), nil, repository.RecordMFAFailureInput{
+			UserId:          test_helper.TestUserId,
+			MaxFailures:     10,
+			LockoutDuration: 15 * time.Minute,
+		}).
+			Return(locked, err)
+	}
+	// the challenge is open and the user is found
+	expectChallengeOfUserWithoutTOTP := func(s *serverMock) {
+		expectActiveChallenge(s, activeChallenge, nil)
+		expectIncrementAttempts(s, nil)
+		expectGetUser(s, validUser, nil)
+	}
+	// the challenge is open and the user & their authenticator are found
+	expectChallengeOfUser := func(t *testing.T, s *serverMock) {
+		expectActiveChallenge(s, activeChallenge, nil)
+		expectIncrementAttempts(s, nil)
+		expectGetUser(s, validUser, nil)
+		expectGetUserTOTP(s, userTOTP(t, s), nil)
+	}
+	// the session gets the device label given on log in
+	expectSession := func(s *serverMock, err error) {
+		s.repository.EXPECT().InsertUserSession(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertUserSessionInput{})).
+			DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.InsertUserSessionInput) (repository.InsertUserSessionOutput, error) {
+				assert.Equal(t, validUser.Id, input.UserId)
+				assert.Equal(t, activeChallenge.DeviceLabel, input.DeviceLabel)
+				return validSession, err
+			})
+	}
+
+	// the log in ends with a new session of the user
+	expectLoginCompletedOf := func(s *serverMock, user repository.User) {
+		expectConsumeChallenge(s, nil)
+		expectSession(s, nil)
+
+		s.repository.EXPECT().InsertRefreshToken(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertRefreshTokenInput{})).
+			Return(repository.InsertRefreshTokenOutput{}, nil)
+		s.repository.EXPECT().IncrementUserLoginCount(gomock.Any(), nil, user).
+			Return(nil)
+	}
+	expectLoginCompleted := func(s *serverMock) {
+		expectLoginCompletedOf(s, validUser)
+	}
+
+	testCases := []struct {
+		title        string
+		request      *generated.LoginMfaJSONRequestBody
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+	}{
+		{
+			title:            "request aborted",
+			request:          &validReqBody,
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "empty request body",
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["code is a required field when RecoveryCode not present","mfaToken is a required field","recoveryCode is a required field when Code not present"]}`,
+		},
+		{
+			title: "code not a number",
+			request: &generated.LoginMfaJSONRequestBody{
+				MfaToken: validReqBody.MfaToken,
+				Code:     pointer.String("12345a"),
+			},
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["code must be a valid numeric value"]}`,
+		},
+		{
+			title:   "error in Repository.GetActiveMFAChallenge",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectActiveChallenge(s, repository.MFAChallenge{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "mfa token unknown, expired or used",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectActiveChallenge(s, repository.MFAChallenge{}, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.InvalidMFAChallengeErrorMsg,
+		},
+		{
+			title:   "mfa token got too many wrong codes",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectActiveChallenge(s, activeChallenge, nil)
+				expectIncrementAttempts(s, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.InvalidMFAChallengeErrorMsg,
+		},
+		{
+			title:   "error in Repository.IncrementMFAChallengeAttempts",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectActiveChallenge(s, activeChallenge, nil)
+				expectIncrementAttempts(s, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "error in Repository.GetUser by Id",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectActiveChallenge(s, activeChallenge, nil)
+				expectIncrementAttempts(s, nil)
+				expectGetUser(s, repository.User{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "error in Repository.GetUserTOTP",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectActiveChallenge(s, activeChallenge, nil)
+				expectIncrementAttempts(s, nil)
+				expectGetUser(s, validUser, nil)
+				expectGetUserTOTP(s, repository.UserTOTP{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title: "wrong code",
+			request: &generated.LoginMfaJSONRequestBody{
+				MfaToken: validReqBody.MfaToken,
+				Code:     &staleCode,
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectChallengeOfUser(t, s)
+				expectMFAFailure(s, false, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title: "error in Repository.RecordMFAFailure",
+			request: &generated.LoginMfaJSONRequestBody{
+				MfaToken: validReqBody.MfaToken,
+				Code:     &staleCode,
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectChallengeOfUser(t, s)
+				expectMFAFailure(s, false, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title: "wrong code reaching the lockout threshold locks mfa of the user",
+			request: &generated.LoginMfaJSONRequestBody{
+				MfaToken: validReqBody.MfaToken,
+				Code:     &staleCode,
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectChallengeOfUser(t, s)
+				expectMFAFailure(s, true, nil)
+				s.repository.EXPECT().InsertAuditLog(gomock.Any(), nil, repository.InsertAuditLogInput{
+					UserId:    test_helper.TestUserId,
+					Event:     auditEventMFALocked,
+					IpAddress: "192.0.2.1",
+					UserAgent: "unit-test-agent",
+					Details:   "failures=10",
+				}).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusTooManyRequests,
+			expectedErrMsg:   response.TooManyRequestsErrorMsg,
+		},
+		{
+			title:   "mfa of the user locked, even with the right code of a new mfa token",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectActiveChallenge(s, activeChallenge, nil)
+				expectIncrementAttempts(s, nil)
+				expectGetUser(s, lockedUser, nil)
+			},
+			expectedHttpCode: http.StatusTooManyRequests,
+			expectedErrMsg:   response.TooManyRequestsErrorMsg,
+		},
+		{
+			title:   "code replayed within its time step",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				used := userTOTP(t, s)
+				used.LastUsedStep = currentStep
+
+				expectActiveChallenge(s, activeChallenge, nil)
+				expectIncrementAttempts(s, nil)
+				expectGetUser(s, validUser, nil)
+				expectGetUserTOTP(s, used, nil)
+				expectMFAFailure(s, false, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "code used by a concurrent request",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectChallengeOfUser(t, s)
+				expectUseStep(s, sql.ErrNoRows)
+				expectMFAFailure(s, false, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "mfa token passed by a concurrent request",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectChallengeOfUser(t, s)
+				expectUseStep(s, nil)
+				expectConsumeChallenge(s, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.InvalidMFAChallengeErrorMsg,
+		},
+		{
+			title:   "error in Repository.ConsumeMFAChallenge",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectChallengeOfUser(t, s)
+				expectUseStep(s, nil)
+				expectConsumeChallenge(s, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "error in Repository.InsertUserSession",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectChallengeOfUser(t, s)
+				expectUseStep(s, nil)
+				expectConsumeChallenge(s, nil)
+				expectSession(s, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title: "both code and recoveryCode",
+			request: &generated.LoginMfaJSONRequestBody{
+				MfaToken:     validReqBody.MfaToken,
+				Code:         &currentCode,
+				RecoveryCode: pointer.String(recoveryCode),
+			},
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["code must not be present along with RecoveryCode"]}`,
+		},
+		{
+			title:   "recovery code unknown or used",
+			request: &recoveryCodeReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectChallengeOfUserWithoutTOTP(s)
+				expectUseRecoveryCode(s, 0, sql.ErrNoRows)
+				expectMFAFailure(s, false, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "error in Repository.UseMFARecoveryCode",
+			request: &recoveryCodeReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectChallengeOfUserWithoutTOTP(s)
+				expectUseRecoveryCode(s, 0, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "error in Repository.InsertAuditLog only log error - login with a recovery code success",
+			request: &recoveryCodeReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectChallengeOfUserWithoutTOTP(s)
+				expectUseRecoveryCode(s, 9, nil)
+				expectAuditLog(s, errors.New(response.InternalServerErrorMsg))
+				expectLoginCompleted(s)
+			},
+			expectedHttpCode: http.StatusOK,
+		},
+		{
+			title:   "recovery code typed in another case & spacing - login with a recovery code success",
+			request: &recoveryCodeReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectChallengeOfUserWithoutTOTP(s)
+				expectUseRecoveryCode(s, 9, nil)
+				expectAuditLog(s, nil)
+				expectLoginCompleted(s)
+			},
+			expectedHttpCode: http.StatusOK,
+		},
+		{
+			title:   "error in Repository.ResetMFAFailures only log error - success forgetting the wrong codes",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectActiveChallenge(s, activeChallenge, nil)
+				expectIncrementAttempts(s, nil)
+				expectGetUser(s, failedUser, nil)
+				expectGetUserTOTP(s, userTOTP(t, s), nil)
+				expectUseStep(s, nil)
+				s.repository.EXPECT().ResetMFAFailures(gomock.Any(), nil, test_helper.TestUserId).
+					Return(errors.New(response.InternalServerErrorMsg))
+				expectLoginCompletedOf(s, failedUser)
+			},
+			expectedHttpCode: http.StatusOK,
+		},
+		{
+			title:   "success forgetting the wrong codes once the lockout ended",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectActiveChallenge(s, activeChallenge, nil)
+				expectIncrementAttempts(s, nil)
+				expectGetUser(s, failedUser, nil)
+				expectGetUserTOTP(s, userTOTP(t, s), nil)
+				expectUseStep(s, nil)
+				s.repository.EXPECT().ResetMFAFailures(gomock.Any(), nil, test_helper.TestUserId).
+					Return(nil)
+				expectLoginCompletedOf(s, failedUser)
+			},
+			expectedHttpCode: http.StatusOK,
+		},
+		{
+			title:   "success",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectChallengeOfUser(t, s)
+				expectUseStep(s, nil)
+				expectLoginCompleted(s)
+			},
+			expectedHttpCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			var reqBody io.Reader
+			if tc.request != nil {
+				reqBodyJson, _ := json.Marshal(*tc.request)
+				reqBody = bytes.NewReader(reqBodyJson)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", reqBody)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set("User-Agent", "unit-test-agent")
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/login/mfa")
+
+			tc.expectations(t, s)
+
+			err := s.server.LoginMfa(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusOK {
+				var resp generated.LoginResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.Equal(t, validUser.Id, resp.Id)
+				assert.NotEmpty(t, resp.RefreshToken)
+				assert.NotEmpty(t, resp.IdToken)
+
+				claims, parseErr := s.server.KeyManager.ParseToken(context.Background(), s.revocationStore, resp.Token)
+				assert.NoError(t, parseErr)
+				assert.Equal(t, validSession.Id, claims.SessionId)
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/string_helper"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Log In with a passkey, will return JWT. The passkey verified the user, no second factor is asked.
+// (POST /v1/login/passkey)
+func (s *Server) LoginPasskey(ctx echo.Context) error {
+	tracestr := "handler.LoginPasskey"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	var req generated.LoginPasskeyJSONRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+
+	credentialId, err := decodeBase64URL(req.Credential.Id)
+	if err != nil {
+		return response.InvalidPasskey(ctx)
+	}
+	clientDataJSON, err := decodeBase64URL(req.Credential.Response.ClientDataJSON)
+	if err != nil {
+		return response.InvalidPasskey(ctx)
+	}
+	authenticatorData, err := decodeBase64URL(req.Credential.Response.AuthenticatorData)
+	if err != nil {
+		return response.InvalidPasskey(ctx)
+	}
+	signature, err := decodeBase64URL(req.Credential.Response.Signature)
+	if err != nil {
+		return response.InvalidPasskey(ctx)
+	}
+
+	_, challengeValue, err := s.consumeWebAuthnChallenge(ctx, webAuthnCeremonyLogin, clientDataJSON)
+	if err != nil {
+		if err == errInvalidPasskey {
+			return response.InvalidPasskey(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed consumeWebAuthnChallenge, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	passkey, err := s.Repository.GetPasskeyByCredentialId(ctx.Request().Context(), base64.RawURLEncoding.EncodeToString(credentialId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return response.InvalidPasskey(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed GetPasskeyByCredentialId, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if req.Credential.Response.UserHandle != nil && *req.Credential.Response.UserHandle != "" {
+		userHandle, err := decodeBase64URL(*req.Credential.Response.UserHandle)
+		if err != nil || string(userHandle) != passkey.UserId {
+			return response.InvalidPasskey(ctx)
+		}
+	}
+
+	assertion, err := s.WebAuthn.VerifyAssertion(challengeValue, clientDataJSON, authenticatorData, signature, passkey.PublicKey, passkey.SignCount)
+	if err != nil {
+		ctx.Logger().Infof("%s, VerifyAssertion failed for passkey %s, err: %v", tracestr, passkey.Id, err)
+		return response.InvalidPasskey(ctx)
+	}
+
+	err = s.Repository.UsePasskey(ctx.Request().Context(), nil, repository.UsePasskeyInput{
+		Id:            passkey.Id,
+		PrevSignCount: passkey.SignCount,
+		SignCount:     assertion.SignCount,
+		BackedUp:      assertion.BackedUp,
+	})
+	if err != nil {
+		// the counter moved on with a concurrent assertion, e.g. of a cloned authenticator
+		if err == sql.ErrNoRows {
+			return response.InvalidPasskey(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed UsePasskey, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	user, err := s.Repository.GetUser(ctx.Request().Context(), repository.GetUserInput{
+		Id: passkey.UserId,
+	})
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed GetUser by Id, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if user.PhoneVerifiedAt == nil {
+		return response.PhoneNotVerified(ctx)
+	}
+
+	return s.completeLogin(ctx, tracestr, user, string_helper.GetAndTrimPointerStringValue(req.DeviceLabel))
+}
@@ -0,0 +1,332 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+	"user-service-sample/utils/webauthn"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/xorcare/pointer"
+)
+
+// loginPasskeyReqBody is the assertion of the authenticator sent by a browser
+func loginPasskeyReqBody(assertion test_helper.WebAuthnAssertion, deviceLabel *string) *generated.LoginPasskeyJSONRequestBody {
+	reqBody := generated.LoginPasskeyJSONRequestBody{
+		DeviceLabel: deviceLabel,
+	}
+	reqBody.Credential.Id = assertion.Id
+	reqBody.Credential.RawId = pointer.String(assertion.Id)
+	reqBody.Credential.Type = "public-key"
+	reqBody.Credential.Response.ClientDataJSON = assertion.ClientDataJSON
+	reqBody.Credential.Response.AuthenticatorData = assertion.AuthenticatorData
+	reqBody.Credential.Response.Signature = assertion.Signature
+	reqBody.Credential.Response.UserHandle = pointer.String(assertion.UserHandle)
+
+	return &reqBody
+}
+
+func TestLoginPasskey(t *testing.T) {
+	challenge, err := webauthn.NewChallenge()
+	assert.NoError(t, err)
+	registrationChallenge, err := webauthn.NewChallenge()
+	assert.NoError(t, err)
+
+	authenticator := test_helper.NewWebAuthnAuthenticator()
+	authenticator.Register(registrationChallenge.Value, test_helper.TestUserId)
+
+	var (
+		storedPasskey = repository.Passkey{
+			Id:           "e5f6a7b8-5555-4e6f-8a7b-8c9d0e1f2a05",
+			UserId:       test_helper.TestUserId,
+			CredentialId: authenticator.CredentialId,
+			PublicKey:    authenticator.PublicKey(),
+			SignCount:    authenticator.SignCount,
+			Name:         "iPhone",
+		}
+		validReqBody = loginPasskeyReqBody(authenticator.Assert(challenge.Value), pointer.String("Work laptop"))
+
+		// signed by another key for the same credential id
+		forgedReqBody = func() *generated.LoginPasskeyJSONRequestBody {
+			forger := test_helper.NewWebAuthnAuthenticator()
+			forger.CredentialId = authenticator.CredentialId
+			forger.UserHandle = authenticator.UserHandle
+			forger.SignCount = 1
+			return loginPasskeyReqBody(forger.Assert(challenge.Value), nil)
+		}()
+
+		phoneVerifiedAt = time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+		validUser       = repository.User{
+			Id:              test_helper.TestUserId,
+			PhoneNumber:     test_helper.TestUserPhone,
+			FullName:        test_helper.TestUserName,
+			PhoneVerifiedAt: &phoneVerifiedAt,
+			// a passkey verified the user, no TOTP code is asked
+			TOTPEnabled: true,
+		}
+		validSession = repository.InsertUserSessionOutput{
+			Id: "f6a7b8c9-6666-4f7a-9b8c-9d0e1f2a3b06",
+		}
+	)
+
+	expectConsumeChallenge := func(s *serverMock, err error) {
+		s.repository.EXPECT().ConsumeWebAuthnChallenge(gomock.Any(), nil, repository.ConsumeWebAuthnChallengeInput{
+			Ceremony:      webAuthnCeremonyLogin,
+			ChallengeHash: challenge.Hash,
+		}).
+			Return(repository.WebAuthnChallenge{Ceremony: webAuthnCeremonyLogin, ChallengeHash: challenge.Hash}, err)
+	}
+	expectGetPasskey := func(s *serverMock, output repository.Passkey, err error) {
+		s.repository.EXPECT().GetPasskeyByCredentialId(gomock.Any(), authenticator.CredentialId).
+			Return(output, err)
+	}
+	expectUsePasskey := func(s *serverMock, err error) {
+		s.repository.EXPECT().UsePasskey(gomock.Any(), nil, repository.UsePasskeyInput{
+			Id:            storedPasskey.Id,
+			PrevSignCount: 0,
+			SignCount:     1,
+		}).
+			Return(err)
+	}
+	expectGetUser := func(s *serverMock, output repository.User, err error) {
+		s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{Id: test_helper.TestUserId}).
+			Return(output, err)
+	}
+	// the passkey is verified & its counter recorded
+	expectPasskeyUsed := func(s *serverMock) {
+		expectConsumeChallenge(s, nil)
+		expectGetPasskey(s, storedPasskey, nil)
+		expectUsePasskey(s, nil)
+	}
+
+	testCases := []struct {
+		title        string
+		request      *generated.LoginPasskeyJSONRequestBody
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+	}{
+		{
+			title:            "request aborted",
+			request:          validReqBody,
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "empty request body",
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["id is a required field","authenticatorData is a required field","clientDataJSON is a required field","signature is a required field","type is a required field"]}`,
+		},
+		{
+			title:   "error in Repository.ConsumeWebAuthnChallenge",
+			request: validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectConsumeChallenge(s, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "challenge expired or already used",
+			request: validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectConsumeChallenge(s, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidPasskeyErrorMsg,
+		},
+		{
+			title:   "error in Repository.GetPasskeyByCredentialId",
+			request: validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectConsumeChallenge(s, nil)
+				expectGetPasskey(s, repository.Passkey{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "passkey not registered or deleted",
+			request: validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectConsumeChallenge(s, nil)
+				expectGetPasskey(s, repository.Passkey{}, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidPasskeyErrorMsg,
+		},
+		{
+			title: "user handle of another user",
+			request: func() *generated.LoginPasskeyJSONRequestBody {
+				reqBody := *validReqBody
+				reqBody.Credential.Response.UserHandle = pointer.String("b3RoZXItdXNlcg")
+				return &reqBody
+			}(),
+			expectations: func(t *testing.T, s *serverMock) {
+				expectConsumeChallenge(s, nil)
+				expectGetPasskey(s, storedPasskey, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidPasskeyErrorMsg,
+		},
+		{
+			title:   "signature of another key",
+			request: forgedReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectConsumeChallenge(s, nil)
+				expectGetPasskey(s, storedPasskey, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidPasskeyErrorMsg,
+		},
+		{
+			title:   "signature counter regressed, the authenticator may be cloned",
+			request: validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				usedPasskey := storedPasskey
+				usedPasskey.SignCount = 5
+				expectConsumeChallenge(s, nil)
+				expectGetPasskey(s, usedPasskey, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidPasskeyErrorMsg,
+		},
+		{
+			title:   "counter changed by a concurrent log in",
+			request: validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectConsumeChallenge(s, nil)
+				expectGetPasskey(s, storedPasskey, nil)
+				expectUsePasskey(s, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidPasskeyErrorMsg,
+		},
+		{
+			title:   "error in Repository.UsePasskey",
+			request: validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectConsumeChallenge(s, nil)
+				expectGetPasskey(s, storedPasskey, nil)
+				expectUsePasskey(s, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "error in Repository.GetUser",
+			request: validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectPasskeyUsed(s)
+				expectGetUser(s, repository.User{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "phone number not verified",
+			request: validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				pendingUser := validUser
+				pendingUser.PhoneVerifiedAt = nil
+				expectPasskeyUsed(s)
+				expectGetUser(s, pendingUser, nil)
+			},
+			expectedHttpCode: http.StatusForbidden,
+			expectedErrMsg:   response.PhoneNotVerifiedErrorMsg,
+		},
+		{
+			title:   "logged in with the passkey, without a second factor",
+			request: validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectPasskeyUsed(s)
+				expectGetUser(s, validUser, nil)
+				s.repository.EXPECT().InsertUserSession(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertUserSessionInput{})).
+					DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.InsertUserSessionInput) (repository.InsertUserSessionOutput, error) {
+						assert.Equal(t, validUser.Id, input.UserId)
+						assert.Equal(t, "Work laptop", input.DeviceLabel)
+						return validSession, nil
+					})
+				s.repository.EXPECT().InsertRefreshToken(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertRefreshTokenInput{})).
+					Return(repository.InsertRefreshTokenOutput{}, nil)
+				s.repository.EXPECT().IncrementUserLoginCount(gomock.Any(), nil, validUser).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			var reqBody io.Reader
+			if tc.request != nil {
+				reqBodyJson, _ := json.Marshal(*tc.request)
+				reqBody = bytes.NewReader(reqBodyJson)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", reqBody)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set("User-Agent", "unit-test-agent")
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/login/passkey")
+
+			tc.expectations(t, s)
+
+			err := s.server.LoginPasskey(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusOK {
+				var resp generated.LoginResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.Equal(t, validUser.Id, resp.Id)
+				assert.NotEmpty(t, resp.RefreshToken)
+				assert.NotEmpty(t, resp.IdToken)
+
+				// the same access token as a log in with a password
+				claims, parseErr := s.server.KeyManager.ParseToken(context.Background(), s.revocationStore, resp.Token)
+				assert.NoError(t, parseErr)
+				assert.Equal(t, validUser.Id, claims.Id)
+				assert.Equal(t, validSession.Id, claims.SessionId)
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
@@ -9,44 +9,156 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"user-service-sample/config"
 	"user-service-sample/generated"
 	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
 	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/password"
 	"user-service-sample/utils/response"
 	"user-service-sample/utils/test_helper"
 
 	"github.com/c2fo/testify/assert"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
+	"github.com/xorcare/pointer"
 )
 
 func TestLogin(t *testing.T) {
 
 	var (
+		phoneVerifiedAt = time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+
 		validReqBody = generated.LoginJSONRequestBody{
 			PhoneNumber: test_helper.TestUserPhone,
 			Password:    test_helper.TestUserPassword,
 		}
 
 		validUser = repository.User{
-			Id:           test_helper.TestUserId,
-			PhoneNumber:  test_helper.TestUserPhone,
-			FullName:     test_helper.TestUserName,
-			PasswordHash: test_helper.TestUserPasswordHash,
-			Salt:         test_helper.TestUserSalt,
+			Id:              test_helper.TestUserId,
+			PhoneNumber:     test_helper.TestUserPhone,
+			FullName:        test_helper.TestUserName,
+			PasswordHash:    test_helper.TestUserArgon2idHash,
+			PhoneVerifiedAt: &phoneVerifiedAt,
+		}
+
+		validSession = repository.InsertUserSessionOutput{
+			Id: "c3d4e5f6-0a1b-4c2d-8e3f-4a5b6c7d8e03",
 		}
 	)
 
+	legacyUser := validUser
+	legacyUser.PasswordHash = test_helper.TestUserPasswordHash
+	legacyUser.Salt = test_helper.TestUserSalt
+
+	bcryptUser := validUser
+	bcryptUser.PasswordHash = test_helper.TestUserBcryptHash
+
+	pendingUser := validUser
+	pendingUser.PhoneVerifiedAt = nil
+
+	// registered before the phone verification, migrations/001_phone_verification.sql sets
+	// phone_verified_at to the registration time
+	registeredAt := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	backfilledUser := legacyUser
+	backfilledUser.CreatedAt = &registeredAt
+	backfilledUser.PhoneVerifiedAt = &registeredAt
+
+	totpUser := validUser
+	totpUser.TOTPEnabled = true
+
+	landlineUser := validUser
+	landlineUser.PhoneNumber = "+62215550123"
+
+	landlineReqBody := validReqBody
+	landlineReqBody.PhoneNumber = landlineUser.PhoneNumber
+
+	withDeviceLabel := validReqBody
+	withDeviceLabel.DeviceLabel = pointer.String(" Work laptop ")
+
+	// the session is recorded with the request details and its refresh token expiry
+	expectSession := func(s *serverMock, deviceLabel string) {
+		s.repository.EXPECT().InsertUserSession(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertUserSessionInput{})).
+			DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.InsertUserSessionInput) (repository.InsertUserSessionOutput, error) {
+				assert.Equal(t, validUser.Id, input.UserId)
+				assert.Equal(t, "unit-test-agent", input.UserAgent)
+				assert.Equal(t, "192.0.2.1", input.IpAddress)
+				assert.Equal(t, deviceLabel, input.DeviceLabel)
+				assert.True(t, input.ExpiresAt.After(time.Now()))
+				return validSession, nil
+			})
+	}
+	expectRefreshToken := func(s *serverMock, err error) {
+		s.repository.EXPECT().InsertRefreshToken(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertRefreshTokenInput{})).
+			DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.InsertRefreshTokenInput) (repository.InsertRefreshTokenOutput, error) {
+				assert.Equal(t, validSession.Id, input.SessionId)
+				return repository.InsertRefreshTokenOutput{}, err
+			})
+	}
+
+	// the stored hash is replaced with a hash of the configured algorithm & cost
+	expectRehash := func(s *serverMock, err error) {
+		s.repository.EXPECT().UpdateUserCredentials(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.UpdateUserCredentialsInput{})).
+			DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.UpdateUserCredentialsInput) error {
+				assert.Equal(t, validUser.Id, input.Id)
+				assert.True(t, input.Rehash)
+				assert.True(t, strings.HasPrefix(input.PasswordHash, "$argon2id$v=19$m=64,t=1,p=1$"))
+
+				match, needsRehash, verifyErr := s.server.PasswordHasher.Verify(test_helper.TestUserPassword, input.PasswordHash, "")
+				assert.NoError(t, verifyErr)
+				assert.True(t, match)
+				assert.False(t, needsRehash)
+				return err
+			})
+	}
+
+	// the challenge keeps the device label for the session started once it is passed
+	expectMFAChallenge := func(s *serverMock, err error) {
+		s.repository.EXPECT().InsertMFAChallenge(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertMFAChallengeInput{})).
+			DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.InsertMFAChallengeInput) (repository.InsertMFAChallengeOutput, error) {
+				assert.Equal(t, validUser.Id, input.UserId)
+				assert.Equal(t, "Work laptop", input.DeviceLabel)
+				assert.Len(t, input.TokenHash, 64)
+				assert.True(t, input.ExpiresAt.After(time.Now()))
+				return repository.InsertMFAChallengeOutput{}, err
+			})
+	}
+
+	pepperV1 := config.PepperConfig{
+		CurrentVersion: "v1",
+		Keys: []config.PepperKeyConfig{{
+			Version: "v1",
+			Secret:  "pepper-v1-0123456789abcdef",
+		}},
+	}
+	// the stored hash is replaced with a hash peppered with the current pepper
+	expectPepperedRehash := func(s *serverMock, prefix string) {
+		s.repository.EXPECT().UpdateUserCredentials(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.UpdateUserCredentialsInput{})).
+			DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.UpdateUserCredentialsInput) error {
+				assert.True(t, input.Rehash)
+				assert.True(t, strings.HasPrefix(input.PasswordHash, prefix))
+
+				match, needsRehash, verifyErr := s.server.PasswordHasher.Verify(test_helper.TestUserPassword, input.PasswordHash, "")
+				assert.NoError(t, verifyErr)
+				assert.True(t, match)
+				assert.False(t, needsRehash)
+				return nil
+			})
+	}
+
 	testCases := []struct {
-		title           string
-		request         *generated.LoginJSONRequestBody
-		aborted         bool
-		invalidMime     bool
-		secretCfgNotSet bool
-		expectations    func(t *testing.T, s *serverMock)
+		title        string
+		request      *generated.LoginJSONRequestBody
+		aborted      bool
+		invalidMime  bool
+		expectations func(t *testing.T, s *serverMock)
 
 		expectedHttpCode int
 		expectedErrMsg   string
@@ -75,40 +187,56 @@ func TestLogin(t *testing.T) {
 			expectedErrMsg:   `Unsupported Media Type`,
 		},
 		{
-			title: "phoneNumber too short",
+			title: "phoneNumber too long",
 			request: &generated.LoginJSONRequestBody{
-				PhoneNumber: "54321",
+				PhoneNumber: strings.Repeat("123", 10),
 			},
 			expectations:     func(t *testing.T, s *serverMock) {},
 			expectedHttpCode: http.StatusBadRequest,
-			expectedErrMsg:   `phoneNumber must be at least 10 characters in length`,
+			expectedErrMsg:   `phoneNumber must be a maximum of 25 characters in length`,
 		},
 		{
-			title: "phoneNumber too long",
+			title: "password longer than the max length of the password policy",
 			request: &generated.LoginJSONRequestBody{
-				PhoneNumber: strings.Repeat("123", 10),
+				PhoneNumber: test_helper.TestUserPhone,
+				Password:    strings.Repeat("pAssW0$d", 9),
 			},
 			expectations:     func(t *testing.T, s *serverMock) {},
 			expectedHttpCode: http.StatusBadRequest,
-			expectedErrMsg:   `phoneNumber must be a maximum of 13 characters in length`,
+			expectedErrMsg:   `{"messages":["password must be a maximum of 64 characters in length"]}`,
 		},
 		{
-			title: "phoneNumber do not start with +62",
+			title: "phoneNumber not a mobile phone number is looked up as it is",
 			request: &generated.LoginJSONRequestBody{
-				PhoneNumber: "12345678901",
+				PhoneNumber: "+6591234567",
+				Password:    test_helper.TestUserPassword,
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: "+6591234567",
+				}).
+					Return(repository.User{}, sql.ErrNoRows)
 			},
-			expectations:     func(t *testing.T, s *serverMock) {},
 			expectedHttpCode: http.StatusBadRequest,
-			expectedErrMsg:   `phoneNumber should start with +62`,
+			expectedErrMsg:   response.IncorrectLoginErrorMsg,
 		},
 		{
-			title: "phoneNumber not following E.164 phone format",
-			request: &generated.LoginJSONRequestBody{
-				PhoneNumber: "+6212AbcDef",
+			title:   "success phone number stored before phone numbers were restricted to mobile numbers",
+			request: &landlineReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: landlineUser.PhoneNumber,
+				}).
+					Return(landlineUser, nil)
+
+				expectSession(s, "")
+				expectRefreshToken(s, nil)
+
+				s.repository.EXPECT().IncrementUserLoginCount(gomock.Any(), nil, landlineUser).
+					Return(nil)
 			},
-			expectations:     func(t *testing.T, s *serverMock) {},
-			expectedHttpCode: http.StatusBadRequest,
-			expectedErrMsg:   `phoneNumber must be a valid E.164 formatted phone number`,
+			expectedHttpCode: http.StatusOK,
+			expectedResp:     landlineUser.Id,
 		},
 		{
 			title:   "error in Repository.GetUser",
@@ -150,14 +278,88 @@ func TestLogin(t *testing.T) {
 			expectedErrMsg:   response.IncorrectLoginErrorMsg,
 		},
 		{
-			title:           "error in authentication.GenerateSignedToken because secret config not set",
-			request:         &validReqBody,
-			secretCfgNotSet: true,
+			title: "incorrect login wrong password of a pending user",
+			request: &generated.LoginJSONRequestBody{
+				PhoneNumber: test_helper.TestUserPhone,
+				Password:    "wrongP4$sWrd",
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: validReqBody.PhoneNumber,
+				}).
+					Return(pendingUser, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.IncorrectLoginErrorMsg,
+		},
+		{
+			title:   "phone number not verified",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: validReqBody.PhoneNumber,
+				}).
+					Return(pendingUser, nil)
+			},
+			expectedHttpCode: http.StatusForbidden,
+			expectedErrMsg:   response.PhoneNotVerifiedErrorMsg,
+		},
+		{
+			title: "incorrect login wrong password with legacy hash",
+			request: &generated.LoginJSONRequestBody{
+				PhoneNumber: test_helper.TestUserPhone,
+				Password:    "wrongP4$sWrd",
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: validReqBody.PhoneNumber,
+				}).
+					Return(legacyUser, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.IncorrectLoginErrorMsg,
+		},
+		{
+			title:   "error unknown password hash format",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				unknownHashUser := validUser
+				unknownHashUser.PasswordHash = "$scrypt$ln=15,r=8,p=1$c2FsdA$aGFzaA"
+
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: validReqBody.PhoneNumber,
+				}).
+					Return(unknownHashUser, nil)
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "error in Repository.InsertUserSession",
+			request: &validReqBody,
 			expectations: func(t *testing.T, s *serverMock) {
 				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
 					PhoneNumber: validReqBody.PhoneNumber,
 				}).
 					Return(validUser, nil)
+
+				s.repository.EXPECT().InsertUserSession(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertUserSessionInput{})).
+					Return(repository.InsertUserSessionOutput{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "error in Repository.InsertRefreshToken",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: validReqBody.PhoneNumber,
+				}).
+					Return(validUser, nil)
+
+				expectSession(s, "")
+				expectRefreshToken(s, errors.New(response.InternalServerErrorMsg))
 			},
 			expectedHttpCode: http.StatusInternalServerError,
 			expectedErrMsg:   response.InternalServerErrorMsg,
@@ -171,6 +373,9 @@ func TestLogin(t *testing.T) {
 				}).
 					Return(validUser, nil)
 
+				expectSession(s, "")
+				expectRefreshToken(s, nil)
+
 				s.repository.EXPECT().IncrementUserLoginCount(gomock.Any(), nil, validUser).
 					Return(errors.New(response.InternalServerErrorMsg))
 			},
@@ -178,14 +383,251 @@ func TestLogin(t *testing.T) {
 			expectedResp:     validUser.Id,
 		},
 		{
-			title:   "success",
+			title:   "legacy SHA-256 hash is upgraded - login success",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: validReqBody.PhoneNumber,
+				}).
+					Return(legacyUser, nil)
+
+				expectRehash(s, nil)
+				expectSession(s, "")
+				expectRefreshToken(s, nil)
+
+				s.repository.EXPECT().IncrementUserLoginCount(gomock.Any(), nil, legacyUser).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedResp:     validUser.Id,
+		},
+		{
+			title:   "user registered before the phone verification, verified by the migration - login success",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: validReqBody.PhoneNumber,
+				}).
+					Return(backfilledUser, nil)
+
+				expectRehash(s, nil)
+				expectSession(s, "")
+				expectRefreshToken(s, nil)
+
+				s.repository.EXPECT().IncrementUserLoginCount(gomock.Any(), nil, backfilledUser).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedResp:     validUser.Id,
+		},
+		{
+			title:   "bcrypt hash is upgraded to the configured argon2id - login success",
 			request: &validReqBody,
 			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: validReqBody.PhoneNumber,
+				}).
+					Return(bcryptUser, nil)
+
+				expectRehash(s, nil)
+				expectSession(s, "")
+				expectRefreshToken(s, nil)
+
+				s.repository.EXPECT().IncrementUserLoginCount(gomock.Any(), nil, bcryptUser).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedResp:     validUser.Id,
+		},
+		{
+			title:   "under-cost argon2id hash is upgraded - login success",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.config.Password.Hashing.Argon2id.Iterations = 2
+				server, err := NewServer(NewServerOptions{Config: s.config, Repository: s.repository})
+				assert.NoError(t, err)
+				s.server = server
+
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: validReqBody.PhoneNumber,
+				}).
+					Return(validUser, nil)
+
+				s.repository.EXPECT().UpdateUserCredentials(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.UpdateUserCredentialsInput{})).
+					DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.UpdateUserCredentialsInput) error {
+						assert.True(t, strings.HasPrefix(input.PasswordHash, "$argon2id$v=19$m=64,t=2,p=1$"))
+						return nil
+					})
+				expectSession(s, "")
+				expectRefreshToken(s, nil)
+
+				s.repository.EXPECT().IncrementUserLoginCount(gomock.Any(), nil, validUser).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedResp:     validUser.Id,
+		},
+		{
+			title:   "hash without pepper is upgraded to the current pepper - login success",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.config.Password.Hashing.Pepper = pepperV1
+				server, err := NewServer(NewServerOptions{Config: s.config, Repository: s.repository})
+				assert.NoError(t, err)
+				s.server = server
+
 				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
 					PhoneNumber: validReqBody.PhoneNumber,
 				}).
 					Return(validUser, nil)
 
+				expectPepperedRehash(s, "$pepper$v=v1$argon2id$v=19$m=64,t=1,p=1$")
+				expectSession(s, "")
+				expectRefreshToken(s, nil)
+
+				s.repository.EXPECT().IncrementUserLoginCount(gomock.Any(), nil, validUser).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedResp:     validUser.Id,
+		},
+		{
+			title:   "hash with a rotated out pepper is upgraded to the current pepper - login success",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				v1Hasher, err := password.NewHasher(config.PasswordHashingConfig{
+					Argon2id: test_helper.TestArgon2idConfig,
+					Pepper:   pepperV1,
+				})
+				assert.NoError(t, err)
+				v1Hash, err := v1Hasher.Hash(test_helper.TestUserPassword)
+				assert.NoError(t, err)
+				v1User := validUser
+				v1User.PasswordHash = v1Hash
+
+				// the current pepper is read from a file, the rotated out one stays configured
+				secretFile := filepath.Join(t.TempDir(), "pepper-v2")
+				assert.NoError(t, os.WriteFile(secretFile, []byte("pepper-v2-0123456789abcdef\n"), 0600))
+				s.config.Password.Hashing.Pepper = config.PepperConfig{
+					CurrentVersion: "v2",
+					Keys: append(pepperV1.Keys, config.PepperKeyConfig{
+						Version:    "v2",
+						SecretFile: secretFile,
+					}),
+				}
+				server, err := NewServer(NewServerOptions{Config: s.config, Repository: s.repository})
+				assert.NoError(t, err)
+				s.server = server
+
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: validReqBody.PhoneNumber,
+				}).
+					Return(v1User, nil)
+
+				expectPepperedRehash(s, "$pepper$v=v2$argon2id$v=19$m=64,t=1,p=1$")
+				expectSession(s, "")
+				expectRefreshToken(s, nil)
+
+				s.repository.EXPECT().IncrementUserLoginCount(gomock.Any(), nil, v1User).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedResp:     validUser.Id,
+		},
+		{
+			title:   "hash with an unknown pepper version",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				unknownPepperUser := validUser
+				unknownPepperUser.PasswordHash = "$pepper$v=v9" + test_helper.TestUserArgon2idHash
+
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: validReqBody.PhoneNumber,
+				}).
+					Return(unknownPepperUser, nil)
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "error in Repository.UpdateUserCredentials only log error - login success",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: validReqBody.PhoneNumber,
+				}).
+					Return(legacyUser, nil)
+
+				expectRehash(s, errors.New(response.InternalServerErrorMsg))
+				expectSession(s, "")
+				expectRefreshToken(s, nil)
+
+				s.repository.EXPECT().IncrementUserLoginCount(gomock.Any(), nil, legacyUser).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedResp:     validUser.Id,
+		},
+		{
+			title: "phoneNumber in a local format is looked up in E.164 - login success",
+			request: &generated.LoginJSONRequestBody{
+				PhoneNumber: "0810-0000-0001",
+				Password:    validReqBody.Password,
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: test_helper.TestUserPhone,
+				}).
+					Return(validUser, nil)
+
+				expectSession(s, "")
+				expectRefreshToken(s, nil)
+
+				s.repository.EXPECT().IncrementUserLoginCount(gomock.Any(), nil, validUser).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedResp:     validUser.Id,
+		},
+		{
+			title:   "error in Repository.InsertMFAChallenge",
+			request: &withDeviceLabel,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: validReqBody.PhoneNumber,
+				}).
+					Return(totpUser, nil)
+
+				expectMFAChallenge(s, errors.New("unexpected error"))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "user with TOTP enabled gets an MFA challenge instead of a session",
+			request: &withDeviceLabel,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: validReqBody.PhoneNumber,
+				}).
+					Return(totpUser, nil)
+
+				expectMFAChallenge(s, nil)
+			},
+			expectedHttpCode: http.StatusAccepted,
+		},
+		{
+			title:   "success",
+			request: &withDeviceLabel,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: validReqBody.PhoneNumber,
+				}).
+					Return(validUser, nil)
+
+				expectSession(s, "Work laptop")
+				expectRefreshToken(s, nil)
+
 				s.repository.EXPECT().IncrementUserLoginCount(gomock.Any(), nil, validUser).
 					Return(nil)
 			},
@@ -200,11 +642,6 @@ func TestLogin(t *testing.T) {
 			s := setupServerMock(t)
 			defer s.cleanUp()
 
-			if tc.secretCfgNotSet {
-				s.config.Secret.RsaPrivatePem = ""
-				s.config.Secret.RsaPublicPem = ""
-			}
-
 			var reqBody io.Reader
 			if tc.request != nil {
 				reqBodyJson, _ := json.Marshal(*tc.request)
@@ -213,6 +650,7 @@ func TestLogin(t *testing.T) {
 
 			e := echo.New()
 			req := httptest.NewRequest(echo.POST, "/", reqBody)
+			req.Header.Set("User-Agent", "unit-test-agent")
 			if !tc.invalidMime {
 				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 			}
@@ -233,12 +671,38 @@ func TestLogin(t *testing.T) {
 			err := s.server.Login(ctx)
 
 			// Assertions
-			if tc.expectedHttpCode >= http.StatusOK && // code 2XX
+			if tc.expectedHttpCode == http.StatusAccepted {
+				var resp generated.MfaChallengeResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedHttpCode, rec.Code)
+				assert.NotEmpty(t, resp.MfaToken)
+				assert.Equal(t, 300, resp.ExpiresIn)
+				assert.Equal(t, []string{"totp", "recovery_code"}, resp.Methods)
+				assert.NotContains(t, rec.Body.String(), "refreshToken")
+			} else if tc.expectedHttpCode >= http.StatusOK && // code 2XX
 				tc.expectedHttpCode <= http.StatusIMUsed {
 
+				var resp generated.LoginResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				// ID token is issued for the same user, bound to the access token
+				publicKey, _ := jwt.ParseRSAPublicKeyFromPEM([]byte(test_helper.TestRsaPublicPem))
+				idClaims := new(authentication.IDTokenClaims)
+				_, idTokenErr := jwt.ParseWithClaims(resp.IdToken, idClaims, func(token *jwt.Token) (interface{}, error) {
+					return publicKey, nil
+				})
+
 				assert.NoError(t, err)
 				assert.Equal(t, tc.expectedHttpCode, rec.Code)
 				assert.Contains(t, rec.Body.String(), tc.expectedResp)
+				assert.NoError(t, idTokenErr)
+				assert.Equal(t, resp.Id, idClaims.Subject)
+				assert.Equal(t, test_helper.TestUserName, idClaims.Name)
+				assert.True(t, idClaims.PhoneNumberVerified)
+				assert.NotEmpty(t, idClaims.AtHash)
+				assert.Equal(t, validSession.Id, idClaims.SessionId)
 			} else {
 				assert.Equal(t, tc.expectedHttpCode, rec.Code)
 				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
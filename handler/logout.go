@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Log out, revoking the access token used for this request
+// (POST /v1/logout)
+func (s *Server) Logout(ctx echo.Context) error {
+	tracestr := "handler.Logout"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	claims, err := authentication.ClaimsFromContext(ctx)
+	if err != nil {
+		ctx.Logger().Infof("%s, ClaimsFromContext failed, err: %+v", tracestr, err)
+		return response.AccessForbidden(ctx)
+	}
+
+	var req generated.LogoutJSONRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+
+	if err := authentication.RevokeToken(ctx.Request().Context(), s.RevocationStore, claims); err != nil {
+		ctx.Logger().Errorf("%s, failed RevokeToken, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	// end the session of this log in, its refresh tokens included
+	if claims.SessionId != "" {
+		if err := s.revokeUserSession(ctx, claims.SessionId, claims.Id); err != nil && err != sql.ErrNoRows {
+			ctx.Logger().Errorf("%s, failed revokeUserSession, err: %v", tracestr, err)
+			return response.InternalErrorResponse(ctx)
+		}
+	}
+
+	// revoke the refresh token family of this log in, only when it belongs to the same user
+	if req.RefreshToken != nil && *req.RefreshToken != "" {
+		refreshToken, err := s.Repository.GetRefreshToken(ctx.Request().Context(), repository.GetRefreshTokenInput{
+			TokenHash: authentication.HashRefreshToken(*req.RefreshToken),
+		})
+		if err != nil && err != sql.ErrNoRows {
+			ctx.Logger().Errorf("%s, failed GetRefreshToken, err: %v", tracestr, err)
+			return response.InternalErrorResponse(ctx)
+		}
+		if err == nil && refreshToken.UserId == claims.Id {
+			if err := s.Repository.RevokeRefreshTokenFamily(ctx.Request().Context(), nil, refreshToken.FamilyId); err != nil {
+				ctx.Logger().Errorf("%s, failed RevokeRefreshTokenFamily, err: %v", tracestr, err)
+				return response.InternalErrorResponse(ctx)
+			}
+		}
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// Log out everywhere, revoking every access and refresh token of the user
+// (POST /v1/logout/all)
+func (s *Server) LogoutAll(ctx echo.Context) error {
+	tracestr := "handler.LogoutAll"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	claims, err := authentication.ClaimsFromContext(ctx)
+	if err != nil {
+		ctx.Logger().Infof("%s, ClaimsFromContext failed, err: %+v", tracestr, err)
+		return response.AccessForbidden(ctx)
+	}
+
+	if err := s.revokeAllUserSessions(ctx, claims.Id); err != nil {
+		ctx.Logger().Errorf("%s, failed revokeAllUserSessions, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// revokeAllUserSessions ends every session of the user, with their access and refresh tokens.
+// Passkeys log in without a session, one added with a stolen token would outlive them, they are deleted too.
+func (s *Server) revokeAllUserSessions(ctx echo.Context, userId string) error {
+	if err := s.Repository.RevokeUserRefreshTokens(ctx.Request().Context(), nil, userId); err != nil {
+		return fmt.Errorf("RevokeUserRefreshTokens: %w", err)
+	}
+
+	// the access tokens of the sessions are all revoked with the ones of the user
+	if _, err := s.Repository.RevokeUserSessions(ctx.Request().Context(), nil, userId); err != nil {
+		return fmt.Errorf("RevokeUserSessions: %w", err)
+	}
+
+	deleted, err := s.Repository.DeleteUserPasskeys(ctx.Request().Context(), nil, userId)
+	if err != nil {
+		return fmt.Errorf("DeleteUserPasskeys: %w", err)
+	}
+
+	return s.userSessionsRevoked(ctx, userId, deleted)
+}
+
+// userSessionsRevoked revokes the access tokens of the user once every session, refresh token & passkey
+// of the user was revoked in the database, by revokeAllUserSessions or along with another change.
+func (s *Server) userSessionsRevoked(ctx echo.Context, userId string, deletedPasskeys int64) error {
+	if err := authentication.RevokeAllUserTokens(ctx.Request().Context(), s.RevocationStore, s.Config.Secret, userId); err != nil {
+		return fmt.Errorf("RevokeAllUserTokens: %w", err)
+	}
+
+	if deletedPasskeys > 0 {
+		s.auditLog(ctx, "handler.revokeAllUserSessions", userId, auditEventPasskeysDeleted, fmt.Sprintf("count=%d", deletedPasskeys))
+	}
+
+	return nil
+}
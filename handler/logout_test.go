@@ -0,0 +1,367 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/revocation"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/xorcare/pointer"
+)
+
+var (
+	logoutTestUser = repository.User{
+		Id:          test_helper.TestUserId,
+		PhoneNumber: test_helper.TestUserPhone,
+		FullName:    test_helper.TestUserName,
+	}
+
+	logoutTestSessionId = "c3d4e5f6-0a1b-4c2d-8e3f-4a5b6c7d8e03"
+)
+
+func TestLogout(t *testing.T) {
+
+	var (
+		validRefreshToken = repository.RefreshToken{
+			Id:       "a1f3c5f0-2d7e-4c55-9a3a-0c1f1f7d9e01",
+			UserId:   test_helper.TestUserId,
+			FamilyId: "b7c2e0a4-5e1b-4a8e-8d4f-3f6a2b9c1d02",
+		}
+	)
+
+	testCases := []struct {
+		title        string
+		invalidJwt   bool
+		sessionId    string
+		request      *generated.LogoutJSONRequestBody
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+	}{
+		{
+			title:            "request aborted",
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "auth token invalid",
+			invalidJwt:       true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.UnauthorizedErrorMsg,
+		},
+		{
+			title:   "error in Repository.GetRefreshToken",
+			request: &generated.LogoutJSONRequestBody{RefreshToken: pointer.String("refresh-token")},
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetRefreshToken(gomock.Any(), repository.GetRefreshTokenInput{
+					TokenHash: authentication.HashRefreshToken("refresh-token"),
+				}).
+					Return(repository.RefreshToken{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "refresh token of another user is ignored",
+			request: &generated.LogoutJSONRequestBody{RefreshToken: pointer.String("refresh-token")},
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetRefreshToken(gomock.Any(), gomock.Any()).
+					Return(repository.RefreshToken{UserId: "another-user", FamilyId: validRefreshToken.FamilyId}, nil)
+			},
+			expectedHttpCode: http.StatusNoContent,
+		},
+		{
+			title:   "unknown refresh token is ignored",
+			request: &generated.LogoutJSONRequestBody{RefreshToken: pointer.String("refresh-token")},
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetRefreshToken(gomock.Any(), gomock.Any()).
+					Return(repository.RefreshToken{}, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusNoContent,
+		},
+		{
+			title:   "success with refresh token",
+			request: &generated.LogoutJSONRequestBody{RefreshToken: pointer.String("refresh-token")},
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetRefreshToken(gomock.Any(), gomock.Any()).
+					Return(validRefreshToken, nil)
+
+				s.repository.EXPECT().RevokeRefreshTokenFamily(gomock.Any(), nil, validRefreshToken.FamilyId).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusNoContent,
+		},
+		{
+			title:     "error in Repository.RevokeUserSession",
+			sessionId: logoutTestSessionId,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().RevokeUserSession(gomock.Any(), nil, repository.RevokeUserSessionInput{
+					Id:     logoutTestSessionId,
+					UserId: test_helper.TestUserId,
+				}).
+					Return(repository.UserSession{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:     "already revoked session is ignored",
+			sessionId: logoutTestSessionId,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().RevokeUserSession(gomock.Any(), nil, gomock.Any()).
+					Return(repository.UserSession{}, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusNoContent,
+		},
+		{
+			title:     "success with session",
+			sessionId: logoutTestSessionId,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().RevokeUserSession(gomock.Any(), nil, repository.RevokeUserSessionInput{
+					Id:     logoutTestSessionId,
+					UserId: test_helper.TestUserId,
+				}).
+					Return(repository.UserSession{Id: logoutTestSessionId}, nil)
+			},
+			expectedHttpCode: http.StatusNoContent,
+		},
+		{
+			title:            "success",
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			jwt := "Bearer invalid-token"
+			if !tc.invalidJwt {
+				token, err := s.server.KeyManager.GenerateSignedToken(logoutTestUser, tc.sessionId)
+				assert.NoError(t, err)
+				jwt = "Bearer " + token
+			}
+
+			var reqBody io.Reader
+			if tc.request != nil {
+				reqBodyJson, _ := json.Marshal(*tc.request)
+				reqBody = bytes.NewReader(reqBodyJson)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", reqBody)
+			req.Header.Set(authentication.AuthHeaderKey, jwt)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/logout")
+
+			tc.expectations(t, s)
+
+			err := s.withAuth(t, s.server.Logout)(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusNoContent {
+				assert.NoError(t, err)
+
+				// the same token must be rejected afterwards
+				verifyCtx := e.NewContext(req, httptest.NewRecorder())
+				_, err = s.server.KeyManager.VerifyToken(verifyCtx, s.revocationStore)
+				assert.Equal(t, authentication.ErrTokenRevoked, err)
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
+
+func TestLogoutAll(t *testing.T) {
+
+	testCases := []struct {
+		title        string
+		invalidJwt   bool
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+	}{
+		{
+			title:            "request aborted",
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "auth token invalid",
+			invalidJwt:       true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.UnauthorizedErrorMsg,
+		},
+		{
+			title: "error in Repository.RevokeUserRefreshTokens",
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().RevokeUserRefreshTokens(gomock.Any(), nil, test_helper.TestUserId).
+					Return(errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title: "error in Repository.RevokeUserSessions",
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().RevokeUserRefreshTokens(gomock.Any(), nil, test_helper.TestUserId).
+					Return(nil)
+				s.repository.EXPECT().RevokeUserSessions(gomock.Any(), nil, test_helper.TestUserId).
+					Return(nil, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title: "success",
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().RevokeUserRefreshTokens(gomock.Any(), nil, test_helper.TestUserId).
+					Return(nil)
+				s.repository.EXPECT().RevokeUserSessions(gomock.Any(), nil, test_helper.TestUserId).
+					Return([]string{logoutTestSessionId}, nil)
+				s.repository.EXPECT().DeleteUserPasskeys(gomock.Any(), nil, test_helper.TestUserId).
+					Return(int64(0), nil)
+			},
+			expectedHttpCode: http.StatusNoContent,
+		},
+		{
+			title: "error in Repository.DeleteUserPasskeys",
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().RevokeUserRefreshTokens(gomock.Any(), nil, test_helper.TestUserId).
+					Return(nil)
+				s.repository.EXPECT().RevokeUserSessions(gomock.Any(), nil, test_helper.TestUserId).
+					Return([]string{logoutTestSessionId}, nil)
+				s.repository.EXPECT().DeleteUserPasskeys(gomock.Any(), nil, test_helper.TestUserId).
+					Return(int64(0), errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title: "success deleting the passkeys of the user",
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().RevokeUserRefreshTokens(gomock.Any(), nil, test_helper.TestUserId).
+					Return(nil)
+				s.repository.EXPECT().RevokeUserSessions(gomock.Any(), nil, test_helper.TestUserId).
+					Return([]string{logoutTestSessionId}, nil)
+				s.repository.EXPECT().DeleteUserPasskeys(gomock.Any(), nil, test_helper.TestUserId).
+					Return(int64(2), nil)
+				s.repository.EXPECT().InsertAuditLog(gomock.Any(), nil, repository.InsertAuditLogInput{
+					UserId:    test_helper.TestUserId,
+					Event:     auditEventPasskeysDeleted,
+					IpAddress: "192.0.2.1",
+					Details:   "count=2",
+				}).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			jwt := "Bearer invalid-token"
+			if !tc.invalidJwt {
+				token, err := s.server.KeyManager.GenerateSignedToken(logoutTestUser, "")
+				assert.NoError(t, err)
+				jwt = "Bearer " + token
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", nil)
+			req.Header.Set(authentication.AuthHeaderKey, jwt)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/logout/all")
+
+			tc.expectations(t, s)
+
+			startedAt := time.Now()
+			err := s.withAuth(t, s.server.LogoutAll)(ctx)
+			endedAt := time.Now()
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusNoContent {
+				assert.NoError(t, err)
+
+				// every token issued so far must be rejected afterwards, including legacy ones
+				for _, jwt := range []string{jwt, test_helper.TestUserJWT} {
+					req.Header.Set(authentication.AuthHeaderKey, jwt)
+					_, err = s.server.KeyManager.VerifyToken(e.NewContext(req, httptest.NewRecorder()), s.revocationStore)
+					assert.Equal(t, authentication.ErrTokenRevoked, err)
+				}
+
+				// a token issued in the same second may predate the revocation, logging in again is valid from the next second
+				revoked, err := s.revocationStore.IsRevoked(context.Background(), revocation.Token{
+					UserId:   test_helper.TestUserId,
+					IssuedAt: startedAt.Truncate(time.Second),
+				})
+				assert.NoError(t, err)
+				assert.True(t, revoked)
+				revoked, err = s.revocationStore.IsRevoked(context.Background(), revocation.Token{
+					UserId:   test_helper.TestUserId,
+					IssuedAt: endedAt.Truncate(time.Second).Add(time.Second),
+				})
+				assert.NoError(t, err)
+				assert.False(t, revoked)
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"user-service-sample/repository"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+// mfaLockedFor is how long MFA of the user stays locked, 0 when it isn't.
+func mfaLockedFor(user repository.User) time.Duration {
+	if user.MFALockedUntil == nil {
+		return 0
+	}
+
+	return time.Until(*user.MFALockedUntil)
+}
+
+// mfaFailed counts a wrong TOTP or recovery code of the user and responds to it. Wrong codes are counted
+// across MFA tokens, a new token doesn't give more guesses: the code reaching mfa.lockout_threshold locks
+// MFA of the user for mfa.lockout_duration, the lockout is audit logged.
+func (s *Server) mfaFailed(ctx echo.Context, tracestr, userId string) error {
+	locked, err := s.Repository.RecordMFAFailure(ctx.Request().Context(), nil, repository.RecordMFAFailureInput{
+		UserId:          userId,
+		MaxFailures:     s.Config.MFA.GetLockoutThreshold(),
+		LockoutDuration: s.Config.MFA.GetLockoutDuration(),
+	})
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed RecordMFAFailure, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	if locked {
+		s.auditLog(ctx, tracestr, userId, auditEventMFALocked, fmt.Sprintf("failures=%d", s.Config.MFA.GetLockoutThreshold()))
+		return response.TooManyRequests(ctx, s.Config.MFA.GetLockoutDuration())
+	}
+
+	return response.InvalidOneTimeCode(ctx)
+}
+
+// mfaPassed forgets the wrong codes of the user once a right one is given. Failing is only logged,
+// the code was right.
+func (s *Server) mfaPassed(ctx echo.Context, tracestr string, user repository.User) {
+	if user.MFAFailedAttempts == 0 && user.MFALockedUntil == nil {
+		return
+	}
+
+	if err := s.Repository.ResetMFAFailures(ctx.Request().Context(), nil, user.Id); err != nil {
+		ctx.Logger().Errorf("%s, failed ResetMFAFailures of user %s, err: %v", tracestr, user.Id, err)
+	}
+}
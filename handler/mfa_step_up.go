@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"fmt"
+
+	"user-service-sample/repository"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+// mfaStepUp confirms a sensitive action of a user with MFA, a stolen access token alone can't do it:
+// the request gives a current code of the authenticator app or a recovery code, which is used up.
+// Wrong codes count toward the MFA lockout of the user, as on log in. It returns false once it responded
+// to the refused step-up, the handler then returns the error of the response, nil when it was sent.
+func (s *Server) mfaStepUp(ctx echo.Context, tracestr string, user repository.User, code, recoveryCode *string) (bool, error) {
+	if code == nil && recoveryCode == nil {
+		return false, response.MFAStepUpRequired(ctx)
+	}
+
+	if wait := mfaLockedFor(user); wait > 0 {
+		return false, response.TooManyRequests(ctx, wait)
+	}
+
+	if recoveryCode != nil {
+		remaining, err := s.useRecoveryCode(ctx, user.Id, *recoveryCode)
+		if err != nil {
+			if err == errInvalidRecoveryCode {
+				return false, s.mfaFailed(ctx, tracestr, user.Id)
+			}
+			ctx.Logger().Errorf("%s, failed useRecoveryCode, err: %v", tracestr, err)
+			return false, response.InternalErrorResponse(ctx)
+		}
+		s.auditLog(ctx, tracestr, user.Id, auditEventRecoveryCodeUsed, fmt.Sprintf("remaining=%d", remaining))
+	} else {
+		userTOTP, err := s.Repository.GetUserTOTP(ctx.Request().Context(), user.Id)
+		if err != nil {
+			ctx.Logger().Errorf("%s, failed GetUserTOTP, err: %v", tracestr, err)
+			return false, response.InternalErrorResponse(ctx)
+		}
+
+		if err := s.verifyTOTP(ctx, userTOTP, *code, false); err != nil {
+			if err == errInvalidTOTPCode {
+				return false, s.mfaFailed(ctx, tracestr, user.Id)
+			}
+			ctx.Logger().Errorf("%s, failed verifyTOTP, err: %v", tracestr, err)
+			return false, response.InternalErrorResponse(ctx)
+		}
+	}
+	s.mfaPassed(ctx, tracestr, user)
+
+	return true, nil
+}
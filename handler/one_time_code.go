@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"user-service-sample/repository"
+	"user-service-sample/utils/otp"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// errInvalidOneTimeCode covers a wrong, expired, used or exhausted code alike
+	errInvalidOneTimeCode = errors.New("invalid one-time code")
+)
+
+// sendOneTimeCode sends a new code for the purpose by SMS, messageFormat gets the code and its TTL in minutes.
+// Nothing is sent when the previous code was sent less than the resend interval ago.
+func (s *Server) sendOneTimeCode(ctx echo.Context, userId, phoneNumber, purpose, messageFormat string) error {
+	cfg := s.Config.OTP
+
+	recent, err := s.hasRecentOneTimeCode(ctx, userId, purpose)
+	if err != nil {
+		return err
+	}
+	if recent {
+		return nil
+	}
+
+	code, err := otp.Generate(cfg.GetLength())
+	if err != nil {
+		return fmt.Errorf("otp.Generate: %w", err)
+	}
+
+	ttl := cfg.GetTTL()
+	_, err = s.Repository.InsertOneTimeCode(ctx.Request().Context(), nil, repository.InsertOneTimeCodeInput{
+		UserId:    userId,
+		Purpose:   purpose,
+		CodeHash:  otp.Hash(purpose, userId, phoneNumber, code),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return fmt.Errorf("InsertOneTimeCode: %w", err)
+	}
+
+	message := fmt.Sprintf(messageFormat, code, int(ttl/time.Minute))
+	if err := s.SMSSender.Send(ctx.Request().Context(), phoneNumber, message); err != nil {
+		return fmt.Errorf("SMSSender.Send: %w", err)
+	}
+
+	return nil
+}
+
+// hasRecentOneTimeCode reports whether the active code of the user for the purpose was sent
+// less than the resend interval ago.
+func (s *Server) hasRecentOneTimeCode(ctx echo.Context, userId, purpose string) (bool, error) {
+	latest, err := s.Repository.GetActiveOneTimeCode(ctx.Request().Context(), repository.GetActiveOneTimeCodeInput{
+		UserId:  userId,
+		Purpose: purpose,
+	})
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("GetActiveOneTimeCode: %w", err)
+	}
+
+	return time.Since(latest.CreatedAt) < s.Config.OTP.GetResendInterval(), nil
+}
+
+// verifyOneTimeCode checks the code against the active code of the user for the purpose, sent to phoneNumber,
+// and consumes it, returning errInvalidOneTimeCode when it doesn't match.
+func (s *Server) verifyOneTimeCode(ctx echo.Context, userId, phoneNumber, purpose, code string) error {
+	codeId, err := s.checkOneTimeCode(ctx, userId, phoneNumber, purpose, code)
+	if err != nil {
+		return err
+	}
+
+	return s.consumeOneTimeCode(ctx, codeId)
+}
+
+// checkOneTimeCode is verifyOneTimeCode without consuming the code, for callers with more to check
+// before the code is used up. It returns the id of the matching code for consumeOneTimeCode.
+// The attempt is counted first, a code only takes the configured number of guesses.
+func (s *Server) checkOneTimeCode(ctx echo.Context, userId, phoneNumber, purpose, code string) (string, error) {
+	active, err := s.Repository.GetActiveOneTimeCode(ctx.Request().Context(), repository.GetActiveOneTimeCodeInput{
+		UserId:  userId,
+		Purpose: purpose,
+	})
+	if err == sql.ErrNoRows {
+		return "", errInvalidOneTimeCode
+	}
+	if err != nil {
+		return "", fmt.Errorf("GetActiveOneTimeCode: %w", err)
+	}
+
+	err = s.Repository.IncrementOneTimeCodeAttempts(ctx.Request().Context(), nil, repository.IncrementOneTimeCodeAttemptsInput{
+		Id:          active.Id,
+		MaxAttempts: s.Config.OTP.GetMaxAttempts(),
+	})
+	if err == sql.ErrNoRows {
+		return "", errInvalidOneTimeCode
+	}
+	if err != nil {
+		return "", fmt.Errorf("IncrementOneTimeCodeAttempts: %w", err)
+	}
+
+	if !otp.Verify(active.CodeHash, purpose, userId, phoneNumber, code) {
+		return "", errInvalidOneTimeCode
+	}
+
+	return active.Id, nil
+}
+
+// consumeOneTimeCode returns errInvalidOneTimeCode when a concurrent request used the code first
+func (s *Server) consumeOneTimeCode(ctx echo.Context, codeId string) error {
+	err := s.Repository.ConsumeOneTimeCode(ctx.Request().Context(), nil, codeId)
+	if err == sql.ErrNoRows {
+		return errInvalidOneTimeCode
+	}
+	if err != nil {
+		return fmt.Errorf("ConsumeOneTimeCode: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"user-service-sample/repository"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+// otpLockedFor is how long the codes to log in & reset the password of the user stay locked, 0 when they aren't.
+func otpLockedFor(user repository.User) time.Duration {
+	if user.OTPLockedUntil == nil {
+		return 0
+	}
+
+	return time.Until(*user.OTPLockedUntil)
+}
+
+// otpFailed counts a wrong code of the user to log in or reset the password and responds to it. Wrong codes
+// are counted across codes, a new code doesn't give more guesses: the code reaching otp.lockout_threshold
+// locks these codes of the user for otp.lockout_duration, the lockout is audit logged. Unlike mfaFailed
+// the response stays the one of a wrong code, as for an unregistered phone number.
+func (s *Server) otpFailed(ctx echo.Context, tracestr, userId string) error {
+	locked, err := s.Repository.RecordOTPFailure(ctx.Request().Context(), nil, repository.RecordOTPFailureInput{
+		UserId:          userId,
+		MaxFailures:     s.Config.OTP.GetLockoutThreshold(),
+		LockoutDuration: s.Config.OTP.GetLockoutDuration(),
+	})
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed RecordOTPFailure, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	if locked {
+		s.auditLog(ctx, tracestr, userId, auditEventOTPLocked, fmt.Sprintf("failures=%d", s.Config.OTP.GetLockoutThreshold()))
+	}
+
+	return response.InvalidOneTimeCode(ctx)
+}
+
+// otpPassed forgets the wrong codes of the user once a right one is given. Failing is only logged,
+// the code was right.
+func (s *Server) otpPassed(ctx echo.Context, tracestr string, user repository.User) {
+	if user.OTPFailedAttempts == 0 && user.OTPLockedUntil == nil {
+		return
+	}
+
+	if err := s.Repository.ResetOTPFailures(ctx.Request().Context(), nil, user.Id); err != nil {
+		ctx.Logger().Errorf("%s, failed ResetOTPFailures of user %s, err: %v", tracestr, user.Id, err)
+	}
+}
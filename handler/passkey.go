@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/webauthn"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	webAuthnCeremonyRegistration = "registration"
+	webAuthnCeremonyLogin        = "login"
+
+	// webAuthnUserVerification is always required, a passkey is a complete sign in without a second factor
+	webAuthnUserVerification = "required"
+	webAuthnCredentialType   = "public-key"
+
+	defaultPasskeyName = "Passkey"
+)
+
+// errInvalidPasskey is a ceremony that can't be verified, the client should start over with new options
+var errInvalidPasskey = errors.New("invalid passkey")
+
+// newWebAuthnChallenge stores a new challenge of the ceremony, userId is empty for a log in.
+// steppedUp records that the user confirmed the ceremony with the current password or MFA.
+func (s *Server) newWebAuthnChallenge(ctx echo.Context, userId, ceremony string, steppedUp bool) (webauthn.Challenge, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return webauthn.Challenge{}, err
+	}
+
+	err = s.Repository.InsertWebAuthnChallenge(ctx.Request().Context(), nil, repository.InsertWebAuthnChallengeInput{
+		UserId:        userId,
+		Ceremony:      ceremony,
+		ChallengeHash: challenge.Hash,
+		ExpiresAt:     time.Now().Add(s.Config.WebAuthn.GetChallengeTTL()),
+		SteppedUp:     steppedUp,
+	})
+	if err != nil {
+		return webauthn.Challenge{}, err
+	}
+
+	return challenge, nil
+}
+
+// consumeWebAuthnChallenge uses up the challenge the client data was signed for, before the ceremony is verified
+// so a response can't be tried twice. It returns the challenge, errInvalidPasskey when it isn't an active challenge of the ceremony.
+func (s *Server) consumeWebAuthnChallenge(ctx echo.Context, ceremony string, clientDataJSON []byte) (repository.WebAuthnChallenge, string, error) {
+	value, err := webauthn.ChallengeOf(clientDataJSON)
+	if err != nil {
+		return repository.WebAuthnChallenge{}, "", errInvalidPasskey
+	}
+
+	challenge, err := s.Repository.ConsumeWebAuthnChallenge(ctx.Request().Context(), nil, repository.ConsumeWebAuthnChallengeInput{
+		Ceremony:      ceremony,
+		ChallengeHash: webauthn.HashChallenge(value),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return repository.WebAuthnChallenge{}, "", errInvalidPasskey
+		}
+		return repository.WebAuthnChallenge{}, "", err
+	}
+
+	return challenge, value, nil
+}
+
+func (s *Server) webAuthnTimeout() int {
+	return int(s.Config.WebAuthn.GetChallengeTTL().Milliseconds())
+}
+
+// decodeBase64URL decodes a binary field of a credential, browsers leave the padding out but some libraries don't.
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+func passkeyResponse(passkey repository.Passkey) generated.Passkey {
+	return generated.Passkey{
+		Id:         passkey.Id,
+		Name:       passkey.Name,
+		CreatedAt:  passkey.CreatedAt,
+		LastUsedAt: passkey.LastUsedAt,
+		BackedUp:   passkey.BackedUp,
+		Transports: passkey.Transports,
+	}
+}
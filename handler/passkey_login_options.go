@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Start a log in with a passkey, the options are passed to navigator.credentials.get()
+// (POST /v1/login/passkey/options)
+func (s *Server) PasskeyLoginOptions(ctx echo.Context) error {
+	tracestr := "handler.PasskeyLoginOptions"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	// every call stores a challenge, until it's pruned once expired
+	allowed, err := s.PasskeyLoginRateLimiter.Allow(ctx.RealIP())
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed PasskeyLoginRateLimiter.Allow, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if !allowed {
+		rateLimit := s.Config.WebAuthn.GetLoginOptionsRateLimit()
+		return response.TooManyRequests(ctx, rateLimit.Per/time.Duration(rateLimit.Requests))
+	}
+
+	challenge, err := s.newWebAuthnChallenge(ctx, "", webAuthnCeremonyLogin, false)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed newWebAuthnChallenge, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	return ctx.JSON(http.StatusOK, generated.PasskeyLoginOptionsResponse{
+		Challenge:        challenge.Value,
+		RpId:             s.WebAuthn.ID,
+		Timeout:          s.webAuthnTimeout(),
+		UserVerification: webAuthnUserVerification,
+		// discoverable credentials, the authenticator offers the passkeys it holds for the relying party
+		AllowCredentials: []generated.PasskeyCredentialDescriptor{},
+	})
+}
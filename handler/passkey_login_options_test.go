@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-service-sample/config"
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+	"user-service-sample/utils/webauthn"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+)
+
+func TestPasskeyLoginOptions(t *testing.T) {
+
+	var storedChallengeHash string
+
+	// httptest requests come from this address
+	const clientIP = "192.0.2.1"
+
+	testCases := []struct {
+		title        string
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode   int
+		expectedErrMsg     string
+		expectedRetryAfter string
+	}{
+		{
+			title:            "request aborted",
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title: "rate limited per client IP address, no challenge is stored",
+			expectations: func(t *testing.T, s *serverMock) {
+				s.config.WebAuthn.LoginOptionsRateLimit = config.RateLimitConfig{Requests: 2, Per: time.Minute}
+				s.server.PasskeyLoginRateLimiter = newRateLimiter(s.config.WebAuthn.GetLoginOptionsRateLimit())
+				for i := 0; i < 2; i++ {
+					allowed, err := s.server.PasskeyLoginRateLimiter.Allow(clientIP)
+					assert.NoError(t, err)
+					assert.True(t, allowed)
+				}
+			},
+			expectedHttpCode:   http.StatusTooManyRequests,
+			expectedErrMsg:     response.TooManyRequestsErrorMsg,
+			expectedRetryAfter: "30",
+		},
+		{
+			title: "error in Repository.InsertWebAuthnChallenge",
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().InsertWebAuthnChallenge(gomock.Any(), nil, gomock.Any()).
+					Return(errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title: "options with a challenge of nobody yet",
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().InsertWebAuthnChallenge(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertWebAuthnChallengeInput{})).
+					DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.InsertWebAuthnChallengeInput) error {
+						assert.Empty(t, input.UserId)
+						assert.Equal(t, webAuthnCeremonyLogin, input.Ceremony)
+						storedChallengeHash = input.ChallengeHash
+						return nil
+					})
+			},
+			expectedHttpCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", nil)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/login/passkey/options")
+
+			tc.expectations(t, s)
+
+			err := s.server.PasskeyLoginOptions(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusOK {
+				var resp generated.PasskeyLoginOptionsResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				// only the hash of the challenge is stored
+				assert.Equal(t, webauthn.HashChallenge(resp.Challenge), storedChallengeHash)
+				assert.Equal(t, test_helper.TestWebAuthnRPID, resp.RpId)
+				assert.Equal(t, 300000, resp.Timeout)
+				assert.Equal(t, "required", resp.UserVerification)
+				assert.Equal(t, []generated.PasskeyCredentialDescriptor{}, resp.AllowCredentials)
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+				assert.Equal(t, tc.expectedRetryAfter, rec.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
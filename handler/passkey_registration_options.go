@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/webauthn"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Start the registration of a passkey, the options are passed to navigator.credentials.create().
+// The user confirms it with the current password, or a user with MFA with a current code of the
+// authenticator app or a recovery code
+// (POST /v1/user/passkeys/options)
+func (s *Server) PasskeyRegistrationOptions(ctx echo.Context) error {
+	tracestr := "handler.PasskeyRegistrationOptions"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	claims, err := authentication.ClaimsFromContext(ctx)
+	if err != nil {
+		ctx.Logger().Infof("%s, ClaimsFromContext failed, err: %+v", tracestr, err)
+		return response.AccessForbidden(ctx)
+	}
+
+	var req generated.PasskeyRegistrationOptionsJSONRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+
+	user, err := s.Repository.GetUser(ctx.Request().Context(), repository.GetUserInput{
+		Id: claims.Id,
+	})
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed GetUser by Id, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	// a passkey logs in without the password nor the second factor, a stolen access token must not add one
+	if ok, err := s.reauthenticate(ctx, tracestr, user, req.CurrentPassword, req.Code, req.RecoveryCode); !ok {
+		return err
+	}
+
+	passkeys, err := s.Repository.ListPasskeys(ctx.Request().Context(), user.Id)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed ListPasskeys, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	challenge, err := s.newWebAuthnChallenge(ctx, user.Id, webAuthnCeremonyRegistration, true)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed newWebAuthnChallenge, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	resp := generated.PasskeyRegistrationOptionsResponse{
+		Challenge: challenge.Value,
+		Rp: generated.PasskeyRelyingParty{
+			Id:   s.WebAuthn.ID,
+			Name: s.WebAuthn.Name,
+		},
+		User: generated.PasskeyUser{
+			// the user handle is returned on log in, it must not identify the user to others
+			Id:          base64.RawURLEncoding.EncodeToString([]byte(user.Id)),
+			Name:        user.PhoneNumber,
+			DisplayName: user.FullName,
+		},
+		PubKeyCredParams:   make([]generated.PasskeyCredentialParameter, 0, len(webauthn.SupportedAlgorithms)),
+		Timeout:            s.webAuthnTimeout(),
+		ExcludeCredentials: make([]generated.PasskeyCredentialDescriptor, 0, len(passkeys)),
+		AuthenticatorSelection: generated.PasskeyAuthenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   webAuthnUserVerification,
+		},
+		Attestation: "none",
+	}
+	for _, alg := range webauthn.SupportedAlgorithms {
+		resp.PubKeyCredParams = append(resp.PubKeyCredParams, generated.PasskeyCredentialParameter{
+			Type: webAuthnCredentialType,
+			Alg:  int(alg),
+		})
+	}
+	for _, passkey := range passkeys {
+		transports := passkey.Transports
+		resp.ExcludeCredentials = append(resp.ExcludeCredentials, generated.PasskeyCredentialDescriptor{
+			Type:       webAuthnCredentialType,
+			Id:         passkey.CredentialId,
+			Transports: &transports,
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
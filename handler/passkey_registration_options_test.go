@@ -0,0 +1,314 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+	"user-service-sample/utils/totp"
+	"user-service-sample/utils/webauthn"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/xorcare/pointer"
+)
+
+func TestPasskeyRegistrationOptions(t *testing.T) {
+
+	var (
+		currentStep    = totp.Step(time.Now())
+		currentCode, _ = totp.Code(test_helper.TestTOTPSecret, currentStep)
+		staleCode, _   = totp.Code(test_helper.TestTOTPSecret, currentStep-10)
+
+		stepUpReqBody = generated.PasskeyRegistrationOptionsJSONRequestBody{
+			Code: &currentCode,
+		}
+		passwordReqBody = generated.PasskeyRegistrationOptionsJSONRequestBody{
+			CurrentPassword: pointer.String(test_helper.TestUserPassword),
+		}
+
+		confirmedAt = time.Now().Add(-24 * time.Hour)
+		lockedUntil = time.Now().Add(10 * time.Minute)
+
+		validUser = repository.User{
+			Id:           test_helper.TestUserId,
+			PhoneNumber:  test_helper.TestUserPhone,
+			FullName:     test_helper.TestUserName,
+			PasswordHash: test_helper.TestUserArgon2idHash,
+		}
+		registeredPasskey = repository.Passkey{
+			Id:           "e1f2a3b4-bbbb-4ecf-8a3b-4c5d6e7f8a11",
+			UserId:       test_helper.TestUserId,
+			CredentialId: "cmVnaXN0ZXJlZA",
+			Transports:   []string{"internal"},
+			Name:         "iPhone",
+		}
+	)
+
+	mfaUser := validUser
+	mfaUser.TOTPEnabled = true
+
+	lockedUser := mfaUser
+	lockedUser.MFALockedUntil = &lockedUntil
+
+	expectGetUser := func(s *serverMock, user repository.User, err error) {
+		s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{Id: test_helper.TestUserId}).
+			Return(user, err)
+	}
+	// expectUserTOTP finds the confirmed authenticator of the user, with the secret encrypted by the server mock
+	expectUserTOTP := func(t *testing.T, s *serverMock) {
+		secretCiphertext, err := s.server.TOTPKeyring.Encrypt([]byte(test_helper.TestTOTPSecret), []byte(test_helper.TestUserId))
+		assert.NoError(t, err)
+
+		s.repository.EXPECT().GetUserTOTP(gomock.Any(), test_helper.TestUserId).
+			Return(repository.UserTOTP{
+				UserId:           test_helper.TestUserId,
+				SecretCiphertext: secretCiphertext,
+				ConfirmedAt:      &confirmedAt,
+			}, nil)
+	}
+	expectListPasskeys := func(s *serverMock, err error) {
+		s.repository.EXPECT().ListPasskeys(gomock.Any(), test_helper.TestUserId).
+			Return([]repository.Passkey{registeredPasskey}, err)
+	}
+
+	testCases := []struct {
+		title        string
+		jwt          string
+		request      *generated.PasskeyRegistrationOptionsJSONRequestBody
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+	}{
+		{
+			title:            "request aborted",
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "auth token invalid",
+			jwt:              "Bearer invalid-token",
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.UnauthorizedErrorMsg,
+		},
+		{
+			title: "error in Repository.GetUser",
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "error in Repository.ListPasskeys",
+			request: &passwordReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectListPasskeys(s, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "error in Repository.InsertWebAuthnChallenge",
+			request: &passwordReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectListPasskeys(s, nil)
+				s.repository.EXPECT().InsertWebAuthnChallenge(gomock.Any(), nil, gomock.Any()).
+					Return(errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "options with a challenge of the user, excluding registered passkeys",
+			request: &passwordReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectListPasskeys(s, nil)
+				s.repository.EXPECT().InsertWebAuthnChallenge(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertWebAuthnChallengeInput{})).
+					DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.InsertWebAuthnChallengeInput) error {
+						assert.Equal(t, test_helper.TestUserId, input.UserId)
+						assert.Equal(t, webAuthnCeremonyRegistration, input.Ceremony)
+						assert.Len(t, input.ChallengeHash, 64)
+						assert.WithinDuration(t, time.Now().Add(5*time.Minute), input.ExpiresAt, time.Minute)
+						assert.True(t, input.SteppedUp)
+						return nil
+					})
+			},
+			expectedHttpCode: http.StatusOK,
+		},
+		{
+			title: "user without mfa without the current password",
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+			},
+			expectedHttpCode: http.StatusForbidden,
+			expectedErrMsg:   response.CurrentPasswordRequiredErrorMsg,
+		},
+		{
+			title: "user without mfa with an incorrect current password",
+			request: &generated.PasskeyRegistrationOptionsJSONRequestBody{
+				CurrentPassword: pointer.String("wrong-password"),
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.IncorrectPasswordErrorMsg,
+		},
+		{
+			title: "code not a number",
+			request: &generated.PasskeyRegistrationOptionsJSONRequestBody{
+				Code: pointer.String("12345a"),
+			},
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["code must be a valid numeric value"]}`,
+		},
+		{
+			title: "user with mfa without a code nor a recovery code",
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, mfaUser, nil)
+			},
+			expectedHttpCode: http.StatusForbidden,
+			expectedErrMsg:   response.MFAStepUpRequiredErrorMsg,
+		},
+		{
+			title:   "mfa of the user locked",
+			request: &stepUpReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, lockedUser, nil)
+			},
+			expectedHttpCode: http.StatusTooManyRequests,
+			expectedErrMsg:   response.TooManyRequestsErrorMsg,
+		},
+		{
+			title: "wrong code counts toward the mfa lockout",
+			request: &generated.PasskeyRegistrationOptionsJSONRequestBody{
+				Code: &staleCode,
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, mfaUser, nil)
+				expectUserTOTP(t, s)
+				s.repository.EXPECT().RecordMFAFailure(gomock.Any(), nil, repository.RecordMFAFailureInput{
+					UserId:          test_helper.TestUserId,
+					MaxFailures:     10,
+					LockoutDuration: 15 * time.Minute,
+				}).
+					Return(false, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "options of a user with mfa confirmed with a code",
+			request: &stepUpReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, mfaUser, nil)
+				expectUserTOTP(t, s)
+				s.repository.EXPECT().UseUserTOTPStep(gomock.Any(), nil, repository.UseUserTOTPStepInput{
+					UserId: test_helper.TestUserId,
+					Step:   currentStep,
+				}).
+					Return(nil)
+				expectListPasskeys(s, nil)
+				s.repository.EXPECT().InsertWebAuthnChallenge(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertWebAuthnChallengeInput{})).
+					DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.InsertWebAuthnChallengeInput) error {
+						assert.Equal(t, test_helper.TestUserId, input.UserId)
+						assert.True(t, input.SteppedUp)
+						return nil
+					})
+			},
+			expectedHttpCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			jwt := tc.jwt
+			if jwt == "" {
+				jwt = test_helper.TestUserJWT
+			}
+
+			var reqBody io.Reader
+			if tc.request != nil {
+				reqBodyJson, _ := json.Marshal(*tc.request)
+				reqBody = bytes.NewReader(reqBodyJson)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", reqBody)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(authentication.AuthHeaderKey, jwt)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/user/passkeys/options")
+
+			tc.expectations(t, s)
+
+			err := s.withAuth(t, s.server.PasskeyRegistrationOptions)(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusOK {
+				var resp generated.PasskeyRegistrationOptionsResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.NotEmpty(t, resp.Challenge)
+				assert.Equal(t, generated.PasskeyRelyingParty{Id: test_helper.TestWebAuthnRPID, Name: "User Service"}, resp.Rp)
+				assert.Equal(t, generated.PasskeyUser{
+					Id:          base64.RawURLEncoding.EncodeToString([]byte(test_helper.TestUserId)),
+					Name:        test_helper.TestUserPhone,
+					DisplayName: test_helper.TestUserName,
+				}, resp.User)
+				assert.Len(t, resp.PubKeyCredParams, len(webauthn.SupportedAlgorithms))
+				assert.Equal(t, 300000, resp.Timeout)
+				assert.Len(t, resp.ExcludeCredentials, 1)
+				assert.Equal(t, registeredPasskey.CredentialId, resp.ExcludeCredentials[0].Id)
+				assert.Equal(t, "required", resp.AuthenticatorSelection.UserVerification)
+				assert.Equal(t, "none", resp.Attestation)
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+				// the error response is sent, Echo's error handler has nothing left to respond
+				if !tc.aborted {
+					assert.NoError(t, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+
+	"user-service-sample/repository"
+	"user-service-sample/utils/password"
+
+	"github.com/labstack/echo/v4"
+)
+
+// isRecentPassword tells whether the password is the current password of the user
+// or one of the replaced passwords still remembered by the password history.
+// Hashes peppered with a pepper no longer configured can't be checked, they are skipped.
+func (s *Server) isRecentPassword(ctx echo.Context, user repository.User, plainPassword string) (bool, error) {
+	cfg := s.Config.Password.History
+	if cfg.Remember <= 0 {
+		return false, nil
+	}
+
+	match, _, err := s.PasswordHasher.Verify(plainPassword, user.PasswordHash, user.Salt)
+	if err != nil && !errors.Is(err, password.ErrUnknownPepperVersion) {
+		return false, fmt.Errorf("Verify current password: %w", err)
+	}
+	if match || cfg.HistorySize() == 0 {
+		return match, nil
+	}
+
+	history, err := s.Repository.GetPasswordHistory(ctx.Request().Context(), repository.GetPasswordHistoryInput{
+		UserId: user.Id,
+		Limit:  cfg.HistorySize(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("GetPasswordHistory: %w", err)
+	}
+
+	for _, entry := range history {
+		match, _, err := s.PasswordHasher.Verify(plainPassword, entry.PasswordHash, entry.Salt)
+		if errors.Is(err, password.ErrUnknownPepperVersion) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("Verify password history %s: %w", entry.Id, err)
+		}
+		if match {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"user-service-sample/repository"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+// reauthenticate makes sure a sensitive action is taken by the user, not only by a holder of their access token:
+// users with MFA confirm it with MFA as in mfaStepUp, the others with their current password. It returns false
+// once it responded to the refused action, the handler then returns the error of the response.
+func (s *Server) reauthenticate(ctx echo.Context, tracestr string, user repository.User, currentPassword, code, recoveryCode *string) (bool, error) {
+	if user.TOTPEnabled {
+		return s.mfaStepUp(ctx, tracestr, user, code, recoveryCode)
+	}
+
+	return s.checkCurrentPassword(ctx, tracestr, user, currentPassword)
+}
+
+// checkCurrentPassword confirms an action with the password the user logs in with, it returns false once it
+// responded to a missing or incorrect password.
+func (s *Server) checkCurrentPassword(ctx echo.Context, tracestr string, user repository.User, currentPassword *string) (bool, error) {
+	if currentPassword == nil {
+		return false, response.CurrentPasswordRequired(ctx)
+	}
+	match, _, err := s.PasswordHasher.Verify(*currentPassword, user.PasswordHash, user.Salt)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed Verify password of user %s, err: %v", tracestr, user.Id, err)
+		return false, response.InternalErrorResponse(ctx)
+	}
+	if !match {
+		return false, response.IncorrectPassword(ctx)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"user-service-sample/repository"
+	"user-service-sample/utils/recoverycode"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// errInvalidRecoveryCode covers an unknown and a used recovery code alike
+	errInvalidRecoveryCode = errors.New("invalid recovery code")
+)
+
+// replaceRecoveryCodes gives the user a new set of recovery codes, the previous codes stop working.
+// The codes are returned to be shown once, only their hashes are stored.
+func (s *Server) replaceRecoveryCodes(ctx echo.Context, userId string) ([]string, error) {
+	codes, err := recoverycode.Generate(s.Config.MFA.GetRecoveryCodes())
+	if err != nil {
+		return nil, fmt.Errorf("recoverycode.Generate: %w", err)
+	}
+
+	codeHashes := make([]string, len(codes))
+	for i, code := range codes {
+		codeHashes[i] = recoverycode.Hash(userId, code)
+	}
+
+	err = s.Repository.ReplaceMFARecoveryCodes(ctx.Request().Context(), nil, repository.ReplaceMFARecoveryCodesInput{
+		UserId:     userId,
+		CodeHashes: codeHashes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ReplaceMFARecoveryCodes: %w", err)
+	}
+
+	return codes, nil
+}
+
+// useRecoveryCode uses up the recovery code of the user and returns the number of codes left,
+// returning errInvalidRecoveryCode when the user has no such unused code.
+func (s *Server) useRecoveryCode(ctx echo.Context, userId, code string) (int, error) {
+	remaining, err := s.Repository.UseMFARecoveryCode(ctx.Request().Context(), nil, repository.UseMFARecoveryCodeInput{
+		UserId:   userId,
+		CodeHash: recoverycode.Hash(userId, code),
+	})
+	if err == sql.ErrNoRows {
+		return 0, errInvalidRecoveryCode
+	}
+	if err != nil {
+		return 0, fmt.Errorf("UseMFARecoveryCode: %w", err)
+	}
+
+	return remaining, nil
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Exchange a refresh token for a new access token and a rotated refresh token
+// (POST /v1/token/refresh)
+func (s *Server) RefreshToken(ctx echo.Context) error {
+	tracestr := "handler.RefreshToken"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	var req generated.RefreshTokenJSONRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+
+	current, err := s.Repository.GetRefreshToken(ctx.Request().Context(), repository.GetRefreshTokenInput{
+		TokenHash: authentication.HashRefreshToken(req.RefreshToken),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return response.InvalidRefreshToken(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed GetRefreshToken, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	// an already rotated token being presented again means it has leaked,
+	// revoke the whole family so neither party can keep using it
+	if current.RotatedAt != nil {
+		return s.revokeRefreshTokenFamily(ctx, tracestr, current)
+	}
+	if current.RevokedAt != nil || time.Now().After(current.ExpiresAt) {
+		return response.InvalidRefreshToken(ctx)
+	}
+
+	user, err := s.Repository.GetUser(ctx.Request().Context(), repository.GetUserInput{
+		Id: current.UserId,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return response.InvalidRefreshToken(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed GetUser by Id, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	next, err := authentication.GenerateRefreshToken(s.Config.Secret)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed GenerateRefreshToken, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	if _, err := s.Repository.RotateRefreshToken(ctx.Request().Context(), nil, repository.RotateRefreshTokenInput{
+		CurrentId: current.Id,
+		Next: repository.InsertRefreshTokenInput{
+			UserId:    current.UserId,
+			FamilyId:  current.FamilyId,
+			SessionId: current.SessionId,
+			TokenHash: next.Hash,
+			ExpiresAt: next.ExpiresAt,
+		},
+	}); err != nil {
+		if err == repository.ErrRefreshTokenAlreadyRotated {
+			// lost the race against another refresh using the same token
+			return s.revokeRefreshTokenFamily(ctx, tracestr, current)
+		}
+		ctx.Logger().Errorf("%s, failed RotateRefreshToken, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	if current.SessionId != "" {
+		if err := s.Repository.TouchUserSession(ctx.Request().Context(), nil, repository.TouchUserSessionInput{
+			Id:        current.SessionId,
+			ExpiresAt: next.ExpiresAt,
+		}); err != nil {
+			ctx.Logger().Infof("%s, failed TouchUserSession, err: %v", tracestr, err)
+		}
+	}
+
+	token, err := s.KeyManager.GenerateSignedToken(user, current.SessionId)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed GenerateSignedToken, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	idToken, err := s.KeyManager.GenerateIDToken(user, current.SessionId, token)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed GenerateIDToken, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	return ctx.JSON(http.StatusOK, generated.LoginResponse{
+		Id:           user.Id,
+		Token:        token,
+		IdToken:      idToken,
+		RefreshToken: next.Token,
+	})
+}
+
+func (s *Server) revokeRefreshTokenFamily(ctx echo.Context, tracestr string, reused repository.RefreshToken) error {
+	ctx.Logger().Warnf("%s, refresh token reuse detected, revoking family: %s, user: %s", tracestr, reused.FamilyId, reused.UserId)
+
+	if err := s.Repository.RevokeRefreshTokenFamily(ctx.Request().Context(), nil, reused.FamilyId); err != nil {
+		ctx.Logger().Errorf("%s, failed RevokeRefreshTokenFamily, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	// the access tokens of the session may have leaked along with the refresh token
+	if reused.SessionId != "" {
+		if err := s.revokeUserSession(ctx, reused.SessionId, reused.UserId); err != nil && err != sql.ErrNoRows {
+			ctx.Logger().Errorf("%s, failed revokeUserSession, err: %v", tracestr, err)
+			return response.InternalErrorResponse(ctx)
+		}
+	}
+
+	return response.InvalidRefreshToken(ctx)
+}
@@ -0,0 +1,331 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+)
+
+func TestRefreshToken(t *testing.T) {
+
+	var (
+		validReqBody = generated.RefreshTokenJSONRequestBody{
+			RefreshToken: "valid-refresh-token",
+		}
+
+		validRefreshToken = repository.RefreshToken{
+			Id:        "a1f3c5f0-2d7e-4c55-9a3a-0c1f1f7d9e01",
+			UserId:    test_helper.TestUserId,
+			FamilyId:  "b7c2e0a4-5e1b-4a8e-8d4f-3f6a2b9c1d02",
+			TokenHash: authentication.HashRefreshToken("valid-refresh-token"),
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+
+		rotatedAt = time.Now().Add(-time.Minute)
+
+		sessionId = "c3d4e5f6-0a1b-4c2d-8e3f-4a5b6c7d8e03"
+
+		validUser = repository.User{
+			Id:          test_helper.TestUserId,
+			PhoneNumber: test_helper.TestUserPhone,
+			FullName:    test_helper.TestUserName,
+		}
+	)
+
+	withRefreshToken := func(modify func(rt *repository.RefreshToken)) repository.RefreshToken {
+		rt := validRefreshToken
+		modify(&rt)
+		return rt
+	}
+
+	testCases := []struct {
+		title        string
+		request      *generated.RefreshTokenJSONRequestBody
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+	}{
+		{
+			title:            "request aborted",
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "empty request body",
+			request:          nil,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["refreshToken is a required field"]}`,
+		},
+		{
+			title:   "error in Repository.GetRefreshToken",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetRefreshToken(gomock.Any(), repository.GetRefreshTokenInput{
+					TokenHash: validRefreshToken.TokenHash,
+				}).
+					Return(repository.RefreshToken{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "refresh token not found",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetRefreshToken(gomock.Any(), gomock.Any()).
+					Return(repository.RefreshToken{}, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.InvalidRefreshTokenErrorMsg,
+		},
+		{
+			title:   "refresh token expired",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetRefreshToken(gomock.Any(), gomock.Any()).
+					Return(withRefreshToken(func(rt *repository.RefreshToken) {
+						rt.ExpiresAt = time.Now().Add(-time.Minute)
+					}), nil)
+			},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.InvalidRefreshTokenErrorMsg,
+		},
+		{
+			title:   "refresh token revoked",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetRefreshToken(gomock.Any(), gomock.Any()).
+					Return(withRefreshToken(func(rt *repository.RefreshToken) {
+						rt.RevokedAt = &rotatedAt
+					}), nil)
+			},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.InvalidRefreshTokenErrorMsg,
+		},
+		{
+			title:   "rotated refresh token reused revokes the family",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetRefreshToken(gomock.Any(), gomock.Any()).
+					Return(withRefreshToken(func(rt *repository.RefreshToken) {
+						rt.RotatedAt = &rotatedAt
+					}), nil)
+
+				s.repository.EXPECT().RevokeRefreshTokenFamily(gomock.Any(), nil, validRefreshToken.FamilyId).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.InvalidRefreshTokenErrorMsg,
+		},
+		{
+			title:   "rotated refresh token reused revokes its session",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetRefreshToken(gomock.Any(), gomock.Any()).
+					Return(withRefreshToken(func(rt *repository.RefreshToken) {
+						rt.RotatedAt = &rotatedAt
+						rt.SessionId = sessionId
+					}), nil)
+
+				s.repository.EXPECT().RevokeRefreshTokenFamily(gomock.Any(), nil, validRefreshToken.FamilyId).
+					Return(nil)
+
+				s.repository.EXPECT().RevokeUserSession(gomock.Any(), nil, repository.RevokeUserSessionInput{
+					Id:     sessionId,
+					UserId: validRefreshToken.UserId,
+				}).
+					Return(repository.UserSession{Id: sessionId}, nil)
+			},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.InvalidRefreshTokenErrorMsg,
+		},
+		{
+			title:   "error in Repository.RevokeRefreshTokenFamily",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetRefreshToken(gomock.Any(), gomock.Any()).
+					Return(withRefreshToken(func(rt *repository.RefreshToken) {
+						rt.RotatedAt = &rotatedAt
+					}), nil)
+
+				s.repository.EXPECT().RevokeRefreshTokenFamily(gomock.Any(), nil, validRefreshToken.FamilyId).
+					Return(errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "error in Repository.GetUser",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetRefreshToken(gomock.Any(), gomock.Any()).
+					Return(validRefreshToken, nil)
+
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					Id: validRefreshToken.UserId,
+				}).
+					Return(repository.User{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "concurrent refresh already rotated the token",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetRefreshToken(gomock.Any(), gomock.Any()).
+					Return(validRefreshToken, nil)
+
+				s.repository.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Return(validUser, nil)
+
+				s.repository.EXPECT().RotateRefreshToken(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.RotateRefreshTokenInput{})).
+					Return(repository.InsertRefreshTokenOutput{}, repository.ErrRefreshTokenAlreadyRotated)
+
+				s.repository.EXPECT().RevokeRefreshTokenFamily(gomock.Any(), nil, validRefreshToken.FamilyId).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.InvalidRefreshTokenErrorMsg,
+		},
+		{
+			title:   "error in Repository.RotateRefreshToken",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetRefreshToken(gomock.Any(), gomock.Any()).
+					Return(validRefreshToken, nil)
+
+				s.repository.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Return(validUser, nil)
+
+				s.repository.EXPECT().RotateRefreshToken(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.RotateRefreshTokenInput{})).
+					Return(repository.InsertRefreshTokenOutput{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "success",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetRefreshToken(gomock.Any(), gomock.Any()).
+					Return(validRefreshToken, nil)
+
+				s.repository.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Return(validUser, nil)
+
+				s.repository.EXPECT().RotateRefreshToken(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.RotateRefreshTokenInput{})).
+					DoAndReturn(func(_ context.Context, _ interface{}, input repository.RotateRefreshTokenInput) (repository.InsertRefreshTokenOutput, error) {
+						assert.Equal(t, validRefreshToken.Id, input.CurrentId)
+						assert.Equal(t, validRefreshToken.FamilyId, input.Next.FamilyId)
+						assert.NotEqual(t, validRefreshToken.TokenHash, input.Next.TokenHash)
+						return repository.InsertRefreshTokenOutput{FamilyId: input.Next.FamilyId}, nil
+					})
+			},
+			expectedHttpCode: http.StatusOK,
+		},
+		{
+			title:   "success within a session",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetRefreshToken(gomock.Any(), gomock.Any()).
+					Return(withRefreshToken(func(rt *repository.RefreshToken) {
+						rt.SessionId = sessionId
+					}), nil)
+
+				s.repository.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Return(validUser, nil)
+
+				s.repository.EXPECT().RotateRefreshToken(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.RotateRefreshTokenInput{})).
+					DoAndReturn(func(_ context.Context, _ interface{}, input repository.RotateRefreshTokenInput) (repository.InsertRefreshTokenOutput, error) {
+						assert.Equal(t, sessionId, input.Next.SessionId)
+						return repository.InsertRefreshTokenOutput{FamilyId: input.Next.FamilyId}, nil
+					})
+
+				// failing to record the refresh only logs the error
+				s.repository.EXPECT().TouchUserSession(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.TouchUserSessionInput{})).
+					DoAndReturn(func(_ context.Context, _ interface{}, input repository.TouchUserSessionInput) error {
+						assert.Equal(t, sessionId, input.Id)
+						assert.True(t, input.ExpiresAt.After(time.Now()))
+						return errors.New(response.InternalServerErrorMsg)
+					})
+			},
+			expectedHttpCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			var reqBody io.Reader
+			if tc.request != nil {
+				reqBodyJson, _ := json.Marshal(*tc.request)
+				reqBody = bytes.NewReader(reqBodyJson)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", reqBody)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/token/refresh")
+
+			tc.expectations(t, s)
+
+			err := s.server.RefreshToken(ctx)
+
+			// Assertions
+			if tc.expectedHttpCode >= http.StatusOK && // code 2XX
+				tc.expectedHttpCode <= http.StatusIMUsed {
+
+				var resp generated.LoginResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedHttpCode, rec.Code)
+				assert.Equal(t, validUser.Id, resp.Id)
+				assert.NotEmpty(t, resp.Token)
+				assert.NotEmpty(t, resp.IdToken)
+				assert.NotEmpty(t, resp.RefreshToken)
+				assert.NotEqual(t, validReqBody.RefreshToken, resp.RefreshToken)
+			} else {
+				assert.Equal(t, tc.expectedHttpCode, rec.Code)
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
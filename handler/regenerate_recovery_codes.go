@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Regenerate the recovery codes, the previous codes stop working. Confirmed with a current code
+// of the authenticator app or a recovery code
+// (POST /v1/user/mfa/recovery-codes)
+func (s *Server) RegenerateRecoveryCodes(ctx echo.Context) error {
+	tracestr := "handler.RegenerateRecoveryCodes"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	claims, err := authentication.ClaimsFromContext(ctx)
+	if err != nil {
+		ctx.Logger().Infof("%s, ClaimsFromContext failed, err: %+v", tracestr, err)
+		return response.AccessForbidden(ctx)
+	}
+
+	var req generated.RegenerateRecoveryCodesJSONRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+
+	user, err := s.Repository.GetUser(ctx.Request().Context(), repository.GetUserInput{
+		Id: claims.Id,
+	})
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed GetUser by Id, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if !user.TOTPEnabled {
+		return response.MFANotEnabled(ctx)
+	}
+
+	if ok, err := s.mfaStepUp(ctx, tracestr, user, req.Code, req.RecoveryCode); !ok {
+		return err
+	}
+
+	codes, err := s.replaceRecoveryCodes(ctx, user.Id)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed replaceRecoveryCodes, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	s.auditLog(ctx, tracestr, user.Id, auditEventRecoveryCodesRegenerated, "")
+
+	return ctx.JSON(http.StatusCreated, generated.RecoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+}
@@ -0,0 +1,327 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/recoverycode"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+	"user-service-sample/utils/totp"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/xorcare/pointer"
+)
+
+func TestRegenerateRecoveryCodes(t *testing.T) {
+	var (
+		currentStep    = totp.Step(time.Now())
+		currentCode, _ = totp.Code(test_helper.TestTOTPSecret, currentStep)
+		staleCode, _   = totp.Code(test_helper.TestTOTPSecret, currentStep-10)
+
+		validReqBody = generated.RegenerateRecoveryCodesJSONRequestBody{
+			Code: &currentCode,
+		}
+
+		recoveryCode        = "7kq2-mx9d-4hpt-bw3z"
+		recoveryCodeReqBody = generated.RegenerateRecoveryCodesJSONRequestBody{
+			RecoveryCode: &recoveryCode,
+		}
+
+		phoneVerifiedAt = time.Now().Add(-24 * time.Hour)
+		lockedUntil     = time.Now().Add(10 * time.Minute)
+
+		validUser = repository.User{
+			Id:              test_helper.TestUserId,
+			PhoneNumber:     test_helper.TestUserPhone,
+			FullName:        test_helper.TestUserName,
+			PhoneVerifiedAt: &phoneVerifiedAt,
+			TOTPEnabled:     true,
+		}
+	)
+
+	noMFAUser := validUser
+	noMFAUser.TOTPEnabled = false
+
+	lockedUser := validUser
+	lockedUser.MFALockedUntil = &lockedUntil
+
+	// expectUserTOTP finds the confirmed authenticator of the user, with the secret encrypted by the server mock
+	expectUserTOTP := func(t *testing.T, s *serverMock, err error) {
+		secretCiphertext, encryptErr := s.server.TOTPKeyring.Encrypt([]byte(test_helper.TestTOTPSecret), []byte(test_helper.TestUserId))
+		assert.NoError(t, encryptErr)
+
+		s.repository.EXPECT().GetUserTOTP(gomock.Any(), test_helper.TestUserId).
+			Return(repository.UserTOTP{
+				UserId:           test_helper.TestUserId,
+				SecretCiphertext: secretCiphertext,
+				ConfirmedAt:      &phoneVerifiedAt,
+			}, err)
+	}
+	// the step-up passes with the current code of the authenticator app
+	expectStepUp := func(t *testing.T, s *serverMock) {
+		expectUserTOTP(t, s, nil)
+		s.repository.EXPECT().UseUserTOTPStep(gomock.Any(), nil, repository.UseUserTOTPStepInput{
+			UserId: test_helper.TestUserId,
+			Step:   currentStep,
+		}).
+			Return(nil)
+	}
+
+	expectGetUser := func(s *serverMock, user repository.User, err error) {
+		s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+			Id: test_helper.TestUserId,
+		}).
+			Return(user, err)
+	}
+	// the new set replaces the previous one, the response holds the codes in plain text
+	var storedCodeHashes []string
+	expectReplaceRecoveryCodes := func(s *serverMock, err error) {
+		s.repository.EXPECT().ReplaceMFARecoveryCodes(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.ReplaceMFARecoveryCodesInput{})).
+			DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.ReplaceMFARecoveryCodesInput) error {
+				assert.Equal(t, validUser.Id, input.UserId)
+				assert.Len(t, input.CodeHashes, 10)
+				storedCodeHashes = input.CodeHashes
+				return err
+			})
+	}
+	expectAuditLog := func(s *serverMock, err error) {
+		s.repository.EXPECT().InsertAuditLog(gomock.Any(), nil, repository.InsertAuditLogInput{
+			UserId:    validUser.Id,
+			Event:     auditEventRecoveryCodesRegenerated,
+			IpAddress: "192.0.2.1",
+		}).
+			Return(err)
+	}
+
+	testCases := []struct {
+		title        string
+		jwt          string
+		request      *generated.RegenerateRecoveryCodesJSONRequestBody
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+	}{
+		{
+			title:            "request aborted",
+			jwt:              test_helper.TestUserJWT,
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "auth token invalid",
+			jwt:              "Bearer invalid-token",
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.UnauthorizedErrorMsg,
+		},
+		{
+			title: "error in Repository.GetUser by Id",
+			jwt:   test_helper.TestUserJWT,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, repository.User{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title: "MFA not enabled",
+			jwt:   test_helper.TestUserJWT,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, noMFAUser, nil)
+			},
+			expectedHttpCode: http.StatusConflict,
+			expectedErrMsg:   response.MFANotEnabledErrorMsg,
+		},
+		{
+			title: "access token without a code nor a recovery code",
+			jwt:   test_helper.TestUserJWT,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+			},
+			expectedHttpCode: http.StatusForbidden,
+			expectedErrMsg:   response.MFAStepUpRequiredErrorMsg,
+		},
+		{
+			title: "code not a number",
+			jwt:   test_helper.TestUserJWT,
+			request: &generated.RegenerateRecoveryCodesJSONRequestBody{
+				Code: pointer.String("12345a"),
+			},
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["code must be a valid numeric value"]}`,
+		},
+		{
+			title:   "mfa of the user locked",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, lockedUser, nil)
+			},
+			expectedHttpCode: http.StatusTooManyRequests,
+			expectedErrMsg:   response.TooManyRequestsErrorMsg,
+		},
+		{
+			title:   "error in Repository.GetUserTOTP",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectUserTOTP(t, s, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title: "wrong code counts toward the mfa lockout",
+			jwt:   test_helper.TestUserJWT,
+			request: &generated.RegenerateRecoveryCodesJSONRequestBody{
+				Code: &staleCode,
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectUserTOTP(t, s, nil)
+				s.repository.EXPECT().RecordMFAFailure(gomock.Any(), nil, repository.RecordMFAFailureInput{
+					UserId:          test_helper.TestUserId,
+					MaxFailures:     10,
+					LockoutDuration: 15 * time.Minute,
+				}).
+					Return(false, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "error in Repository.ReplaceMFARecoveryCodes",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectStepUp(t, s)
+				expectReplaceRecoveryCodes(s, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "error in Repository.InsertAuditLog only log error - success",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectStepUp(t, s)
+				expectReplaceRecoveryCodes(s, nil)
+				expectAuditLog(s, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusCreated,
+		},
+		{
+			title:   "success with a recovery code",
+			jwt:     test_helper.TestUserJWT,
+			request: &recoveryCodeReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				s.repository.EXPECT().UseMFARecoveryCode(gomock.Any(), nil, repository.UseMFARecoveryCodeInput{
+					UserId:   test_helper.TestUserId,
+					CodeHash: recoverycode.Hash(test_helper.TestUserId, recoveryCode),
+				}).
+					Return(9, nil)
+				s.repository.EXPECT().InsertAuditLog(gomock.Any(), nil, repository.InsertAuditLogInput{
+					UserId:    validUser.Id,
+					Event:     auditEventRecoveryCodeUsed,
+					IpAddress: "192.0.2.1",
+					Details:   "remaining=9",
+				}).
+					Return(nil)
+				expectReplaceRecoveryCodes(s, nil)
+				expectAuditLog(s, nil)
+			},
+			expectedHttpCode: http.StatusCreated,
+		},
+		{
+			title:   "success",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectStepUp(t, s)
+				expectReplaceRecoveryCodes(s, nil)
+				expectAuditLog(s, nil)
+			},
+			expectedHttpCode: http.StatusCreated,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+			storedCodeHashes = nil
+
+			var reqBody io.Reader
+			if tc.request != nil {
+				reqBodyJson, _ := json.Marshal(*tc.request)
+				reqBody = bytes.NewReader(reqBodyJson)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", reqBody)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(authentication.AuthHeaderKey, tc.jwt)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/user/mfa/recovery-codes")
+
+			tc.expectations(t, s)
+
+			err := s.withAuth(t, s.server.RegenerateRecoveryCodes)(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusCreated {
+				var resp generated.RecoveryCodesResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.Len(t, resp.RecoveryCodes, 10)
+				for i, code := range resp.RecoveryCodes {
+					assert.Equal(t, recoverycode.Hash(validUser.Id, code), storedCodeHashes[i])
+				}
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+				// the error response is sent, Echo's error handler has nothing left to respond
+				if !tc.aborted {
+					assert.NoError(t, err)
+				}
+			}
+		})
+	}
+}
@@ -1,11 +1,13 @@
 package handler
 
 import (
+	"database/sql"
 	"net/http"
 
 	"user-service-sample/generated"
 	"user-service-sample/repository"
 	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/otp"
 	"user-service-sample/utils/password"
 	"user-service-sample/utils/request_helper"
 	"user-service-sample/utils/response"
@@ -14,7 +16,12 @@ import (
 	"github.com/xorcare/pointer"
 )
 
-// Register new user to service
+const (
+	registrationPendingMsg   = "user registration pending, verify the phone number with the code sent to it"
+	phoneVerificationMessage = "Your registration code is %s, it expires in %d minutes. Never share this code."
+)
+
+// Register new user to service, the user is pending until the phone number is verified
 // (POST /v1/register)
 func (s *Server) Register(ctx echo.Context) error {
 	tracestr := "handler.Register"
@@ -26,27 +33,86 @@ func (s *Server) Register(ctx echo.Context) error {
 	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
 		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
 	}
+	req.PhoneNumber = s.canonicalPhoneNumber(req.PhoneNumber)
+	messages := s.PasswordPolicy.Validate("password", req.Password, password.PersonalInfo{
+		PhoneNumber: req.PhoneNumber,
+		FullName:    req.FullName,
+	})
+	if len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
 
-	// check phone number
-	if err := s.checkIsPhoneAlreadyRegistered(ctx, tracestr, req.PhoneNumber); err != nil {
-		return err
+	// check phone number, only a verified user holds it
+	existing, err := s.Repository.GetUser(ctx.Request().Context(), repository.GetUserInput{
+		PhoneNumber: req.PhoneNumber,
+	})
+	if err != nil && err != sql.ErrNoRows {
+		ctx.Logger().Errorf("%s, failed GetUser by PhoneNumber, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if err == nil && existing.PhoneVerifiedAt != nil {
+		return response.PhoneAlreadyRegistered(ctx)
 	}
 
-	hashedPassword, salt := password.SaltAndHashPassword(req.Password)
+	// replacing a pending registration invalidates its code, a code sent less than the resend interval ago
+	// is left to whoever received it: nothing is replaced nor sent, and the response doesn't tell
+	if existing.Id != "" {
+		recent, err := s.hasRecentOneTimeCode(ctx, existing.Id, otp.PurposePhoneVerification)
+		if err != nil {
+			ctx.Logger().Errorf("%s, failed hasRecentOneTimeCode, err: %v", tracestr, err)
+			return response.InternalErrorResponse(ctx)
+		}
+		if recent {
+			return registrationPending(ctx, existing.Id)
+		}
+	}
 
-	out, err := s.Repository.InsertUser(ctx.Request().Context(), nil, repository.InsertUserInput{
-		PhoneNumber:  req.PhoneNumber,
-		FullName:     req.FullName,
-		PasswordHash: hashedPassword,
-		Salt:         salt,
-	})
+	hashedPassword, err := s.PasswordHasher.Hash(req.Password)
 	if err != nil {
-		ctx.Logger().Errorf("%s, failed InsertUser, err: %v", tracestr, err)
+		ctx.Logger().Errorf("%s, failed Hash password, err: %v", tracestr, err)
 		return response.InternalErrorResponse(ctx)
 	}
 
+	userId := existing.Id
+	if userId != "" {
+		// a pending registration is replaced, whoever registered the number first
+		// can't keep its owner from registering. Its codes are invalidated with it.
+		err = s.Repository.ReplacePendingUser(ctx.Request().Context(), nil, repository.ReplacePendingUserInput{
+			Id:           userId,
+			FullName:     req.FullName,
+			PasswordHash: hashedPassword,
+		})
+		if err == sql.ErrNoRows {
+			return response.PhoneAlreadyRegistered(ctx)
+		}
+		if err != nil {
+			ctx.Logger().Errorf("%s, failed ReplacePendingUser, err: %v", tracestr, err)
+			return response.InternalErrorResponse(ctx)
+		}
+	} else {
+		out, err := s.Repository.InsertUser(ctx.Request().Context(), nil, repository.InsertUserInput{
+			PhoneNumber:  req.PhoneNumber,
+			FullName:     req.FullName,
+			PasswordHash: hashedPassword,
+		})
+		if err != nil {
+			ctx.Logger().Errorf("%s, failed InsertUser, err: %v", tracestr, err)
+			return response.InternalErrorResponse(ctx)
+		}
+		userId = out.Id
+	}
+
+	// the user is registered either way, a code failing to be sent can be resent
+	if err := s.sendOneTimeCode(ctx, userId, req.PhoneNumber, otp.PurposePhoneVerification, phoneVerificationMessage); err != nil {
+		ctx.Logger().Errorf("%s, failed sendOneTimeCode, err: %v", tracestr, err)
+	}
+
+	return registrationPending(ctx, userId)
+}
+
+func registrationPending(ctx echo.Context, userId string) error {
 	return ctx.JSON(http.StatusCreated, generated.RegisterResponse{
-		Id:      out.Id,
-		Message: pointer.String("user registration success"),
+		Id:      userId,
+		Message: pointer.String(registrationPendingMsg),
 	})
 }
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/string_helper"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Register a passkey created with the options of /v1/user/passkeys/options
+// (POST /v1/user/passkeys)
+func (s *Server) RegisterPasskey(ctx echo.Context) error {
+	tracestr := "handler.RegisterPasskey"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	claims, err := authentication.ClaimsFromContext(ctx)
+	if err != nil {
+		ctx.Logger().Infof("%s, ClaimsFromContext failed, err: %+v", tracestr, err)
+		return response.AccessForbidden(ctx)
+	}
+
+	var req generated.RegisterPasskeyJSONRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+
+	clientDataJSON, err := decodeBase64URL(req.Credential.Response.ClientDataJSON)
+	if err != nil {
+		return response.InvalidPasskey(ctx)
+	}
+	attestationObject, err := decodeBase64URL(req.Credential.Response.AttestationObject)
+	if err != nil {
+		return response.InvalidPasskey(ctx)
+	}
+
+	challenge, challengeValue, err := s.consumeWebAuthnChallenge(ctx, webAuthnCeremonyRegistration, clientDataJSON)
+	if err != nil {
+		if err == errInvalidPasskey {
+			return response.InvalidPasskey(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed consumeWebAuthnChallenge, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if challenge.UserId != claims.Id {
+		return response.InvalidPasskey(ctx)
+	}
+
+	// options not confirmed with the current password or MFA are refused, e.g. ones returned before it was required
+	if challenge.SteppedUpAt == nil {
+		user, err := s.Repository.GetUser(ctx.Request().Context(), repository.GetUserInput{
+			Id: claims.Id,
+		})
+		if err != nil {
+			ctx.Logger().Errorf("%s, failed GetUser by Id, err: %v", tracestr, err)
+			return response.InternalErrorResponse(ctx)
+		}
+		if user.TOTPEnabled {
+			return response.MFAStepUpRequired(ctx)
+		}
+		return response.CurrentPasswordRequired(ctx)
+	}
+
+	credential, err := s.WebAuthn.VerifyRegistration(challengeValue, clientDataJSON, attestationObject)
+	if err != nil {
+		ctx.Logger().Infof("%s, VerifyRegistration failed for user %s, err: %v", tracestr, claims.Id, err)
+		return response.InvalidPasskey(ctx)
+	}
+	credentialId := base64.RawURLEncoding.EncodeToString(credential.ID)
+	if reqCredentialId, err := decodeBase64URL(req.Credential.Id); err != nil || base64.RawURLEncoding.EncodeToString(reqCredentialId) != credentialId {
+		return response.InvalidPasskey(ctx)
+	}
+
+	passkey := repository.Passkey{
+		UserId:         claims.Id,
+		CredentialId:   credentialId,
+		PublicKey:      credential.PublicKey,
+		SignCount:      credential.SignCount,
+		Transports:     []string{},
+		Name:           string_helper.GetAndTrimPointerStringValue(req.Name),
+		BackupEligible: credential.BackupEligible,
+		BackedUp:       credential.BackedUp,
+	}
+	if passkey.Name == "" {
+		passkey.Name = defaultPasskeyName
+	}
+	if req.Credential.Response.Transports != nil {
+		passkey.Transports = *req.Credential.Response.Transports
+	}
+
+	output, err := s.Repository.InsertPasskey(ctx.Request().Context(), nil, repository.InsertPasskeyInput{
+		UserId:         passkey.UserId,
+		CredentialId:   passkey.CredentialId,
+		PublicKey:      passkey.PublicKey,
+		SignCount:      passkey.SignCount,
+		Transports:     passkey.Transports,
+		Name:           passkey.Name,
+		BackupEligible: passkey.BackupEligible,
+		BackedUp:       passkey.BackedUp,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return response.PasskeyAlreadyRegistered(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed InsertPasskey, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	passkey.Id = output.Id
+	passkey.CreatedAt = output.CreatedAt
+	passkey.UpdatedAt = output.CreatedAt
+	s.auditLog(ctx, tracestr, claims.Id, auditEventPasskeyRegistered, "passkey="+passkey.Id)
+
+	return ctx.JSON(http.StatusCreated, passkeyResponse(passkey))
+}
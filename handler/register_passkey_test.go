@@ -0,0 +1,337 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+	"user-service-sample/utils/webauthn"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/xorcare/pointer"
+)
+
+// registerPasskeyReqBody is the registration of the authenticator sent by a browser
+func registerPasskeyReqBody(registration test_helper.WebAuthnRegistration, name *string) *generated.RegisterPasskeyJSONRequestBody {
+	reqBody := generated.RegisterPasskeyJSONRequestBody{
+		Name: name,
+	}
+	reqBody.Credential.Id = registration.Id
+	reqBody.Credential.RawId = pointer.String(registration.Id)
+	reqBody.Credential.Type = "public-key"
+	reqBody.Credential.Response.ClientDataJSON = registration.ClientDataJSON
+	reqBody.Credential.Response.AttestationObject = registration.AttestationObject
+	reqBody.Credential.Response.Transports = &[]string{"internal", "hybrid"}
+
+	return &reqBody
+}
+
+func TestRegisterPasskey(t *testing.T) {
+	challenge, err := webauthn.NewChallenge()
+	assert.NoError(t, err)
+
+	var (
+		authenticator = test_helper.NewWebAuthnAuthenticator()
+		validReqBody  = registerPasskeyReqBody(authenticator.Register(challenge.Value, test_helper.TestUserId), pointer.String(" iPhone "))
+
+		// a page of another origin asked the authenticator
+		otherOrigin = func() *generated.RegisterPasskeyJSONRequestBody {
+			a := test_helper.NewWebAuthnAuthenticator()
+			a.Origin = "https://evil.example"
+			return registerPasskeyReqBody(a.Register(challenge.Value, test_helper.TestUserId), nil)
+		}()
+		// the authenticator didn't verify the user, e.g. a security key without PIN
+		notVerified = func() *generated.RegisterPasskeyJSONRequestBody {
+			a := test_helper.NewWebAuthnAuthenticator()
+			a.Flags = 0x01
+			return registerPasskeyReqBody(a.Register(challenge.Value, test_helper.TestUserId), nil)
+		}()
+
+		createdAt             = time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+		registrationChallenge = repository.WebAuthnChallenge{
+			Id:            "a1b2c3d4-1111-4a2b-8c3d-4e5f6a7b8c01",
+			UserId:        test_helper.TestUserId,
+			Ceremony:      webAuthnCeremonyRegistration,
+			ChallengeHash: challenge.Hash,
+			SteppedUpAt:   &createdAt,
+		}
+
+		validUser = repository.User{
+			Id:          test_helper.TestUserId,
+			PhoneNumber: test_helper.TestUserPhone,
+		}
+	)
+
+	unconfirmedChallenge := registrationChallenge
+	unconfirmedChallenge.SteppedUpAt = nil
+
+	mfaUser := validUser
+	mfaUser.TOTPEnabled = true
+
+	expectConsumeChallenge := func(s *serverMock, output repository.WebAuthnChallenge, err error) {
+		s.repository.EXPECT().ConsumeWebAuthnChallenge(gomock.Any(), nil, repository.ConsumeWebAuthnChallengeInput{
+			Ceremony:      webAuthnCeremonyRegistration,
+			ChallengeHash: challenge.Hash,
+		}).
+			Return(output, err)
+	}
+	expectGetUser := func(s *serverMock, user repository.User, err error) {
+		s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{Id: test_helper.TestUserId}).
+			Return(user, err)
+	}
+
+	testCases := []struct {
+		title        string
+		request      *generated.RegisterPasskeyJSONRequestBody
+		jwt          string
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+		expectedName     string
+	}{
+		{
+			title:            "request aborted",
+			request:          validReqBody,
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "auth token invalid",
+			request:          validReqBody,
+			jwt:              "Bearer invalid-token",
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.UnauthorizedErrorMsg,
+		},
+		{
+			title:            "empty request body",
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["id is a required field","attestationObject is a required field","clientDataJSON is a required field","type is a required field"]}`,
+		},
+		{
+			title: "client data not base64url",
+			request: func() *generated.RegisterPasskeyJSONRequestBody {
+				reqBody := *validReqBody
+				reqBody.Credential.Response.ClientDataJSON = "not base64url!"
+				return &reqBody
+			}(),
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidPasskeyErrorMsg,
+		},
+		{
+			title:   "error in Repository.ConsumeWebAuthnChallenge",
+			request: validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectConsumeChallenge(s, repository.WebAuthnChallenge{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "challenge expired or already used",
+			request: validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectConsumeChallenge(s, repository.WebAuthnChallenge{}, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidPasskeyErrorMsg,
+		},
+		{
+			title:   "challenge of another user",
+			request: validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				otherUserChallenge := registrationChallenge
+				otherUserChallenge.UserId = "b2c3d4e5-2222-4b3c-9d4e-5f6a7b8c9d02"
+				expectConsumeChallenge(s, otherUserChallenge, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidPasskeyErrorMsg,
+		},
+		{
+			title:   "error in Repository.GetUser",
+			request: validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectConsumeChallenge(s, unconfirmedChallenge, nil)
+				expectGetUser(s, repository.User{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "options not confirmed, of a user with mfa",
+			request: validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectConsumeChallenge(s, unconfirmedChallenge, nil)
+				expectGetUser(s, mfaUser, nil)
+			},
+			expectedHttpCode: http.StatusForbidden,
+			expectedErrMsg:   response.MFAStepUpRequiredErrorMsg,
+		},
+		{
+			title:   "options not confirmed, of a user without mfa",
+			request: validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectConsumeChallenge(s, unconfirmedChallenge, nil)
+				expectGetUser(s, validUser, nil)
+			},
+			expectedHttpCode: http.StatusForbidden,
+			expectedErrMsg:   response.CurrentPasswordRequiredErrorMsg,
+		},
+		{
+			title:   "origin not allowed",
+			request: otherOrigin,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectConsumeChallenge(s, registrationChallenge, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidPasskeyErrorMsg,
+		},
+		{
+			title:   "user not verified by the authenticator",
+			request: notVerified,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectConsumeChallenge(s, registrationChallenge, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidPasskeyErrorMsg,
+		},
+		{
+			title: "credential id not the one of the attestation",
+			request: func() *generated.RegisterPasskeyJSONRequestBody {
+				reqBody := *validReqBody
+				reqBody.Credential.Id = "b3RoZXItY3JlZGVudGlhbA"
+				reqBody.Credential.RawId = nil
+				return &reqBody
+			}(),
+			expectations: func(t *testing.T, s *serverMock) {
+				expectConsumeChallenge(s, registrationChallenge, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidPasskeyErrorMsg,
+		},
+		{
+			title:   "credential already registered",
+			request: validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectConsumeChallenge(s, registrationChallenge, nil)
+				s.repository.EXPECT().InsertPasskey(gomock.Any(), nil, gomock.Any()).
+					Return(repository.InsertPasskeyOutput{}, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusConflict,
+			expectedErrMsg:   response.PasskeyAlreadyRegisteredErrorMsg,
+		},
+		{
+			title:   "error in Repository.InsertPasskey",
+			request: validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectConsumeChallenge(s, registrationChallenge, nil)
+				s.repository.EXPECT().InsertPasskey(gomock.Any(), nil, gomock.Any()).
+					Return(repository.InsertPasskeyOutput{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "passkey registered with the public key of the authenticator",
+			request: validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectConsumeChallenge(s, registrationChallenge, nil)
+				s.repository.EXPECT().InsertPasskey(gomock.Any(), nil, repository.InsertPasskeyInput{
+					UserId:       test_helper.TestUserId,
+					CredentialId: authenticator.CredentialId,
+					PublicKey:    authenticator.PublicKey(),
+					SignCount:    0,
+					Transports:   []string{"internal", "hybrid"},
+					Name:         "iPhone",
+				}).
+					Return(repository.InsertPasskeyOutput{Id: "c3d4e5f6-3333-4c4d-8e5f-6a7b8c9d0e03", CreatedAt: createdAt}, nil)
+				s.repository.EXPECT().InsertAuditLog(gomock.Any(), nil, repository.InsertAuditLogInput{
+					UserId:    test_helper.TestUserId,
+					Event:     auditEventPasskeyRegistered,
+					IpAddress: "192.0.2.1",
+					UserAgent: "unit-test-agent",
+					Details:   "passkey=c3d4e5f6-3333-4c4d-8e5f-6a7b8c9d0e03",
+				}).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusCreated,
+			expectedName:     "iPhone",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			var reqBody io.Reader
+			if tc.request != nil {
+				reqBodyJson, _ := json.Marshal(*tc.request)
+				reqBody = bytes.NewReader(reqBodyJson)
+			}
+
+			jwt := tc.jwt
+			if jwt == "" {
+				jwt = test_helper.TestUserJWT
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", reqBody)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(authentication.AuthHeaderKey, jwt)
+			req.Header.Set("User-Agent", "unit-test-agent")
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/user/passkeys")
+
+			tc.expectations(t, s)
+
+			err := s.withAuth(t, s.server.RegisterPasskey)(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusCreated {
+				var resp generated.Passkey
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.Equal(t, "c3d4e5f6-3333-4c4d-8e5f-6a7b8c9d0e03", resp.Id)
+				assert.Equal(t, tc.expectedName, resp.Name)
+				assert.Equal(t, createdAt, resp.CreatedAt)
+				assert.Equal(t, []string{"internal", "hybrid"}, resp.Transports)
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
@@ -11,10 +11,12 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"user-service-sample/generated"
 	"user-service-sample/repository"
 	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/otp"
 	"user-service-sample/utils/response"
 	"user-service-sample/utils/test_helper"
 
@@ -32,23 +34,75 @@ func TestRegister(t *testing.T) {
 			PhoneNumber: test_helper.TestUserPhone,
 		}
 
+		phoneVerifiedAt = time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+
 		validUser = repository.User{
+			Id:              test_helper.TestUserId,
+			PhoneNumber:     test_helper.TestUserPhone,
+			FullName:        test_helper.TestUserName,
+			PhoneVerifiedAt: &phoneVerifiedAt,
+		}
+
+		pendingUser = repository.User{
 			Id:          test_helper.TestUserId,
 			PhoneNumber: test_helper.TestUserPhone,
-			FullName:    test_helper.TestUserName,
+			FullName:    "Someone Else",
 		}
 	)
 
+	expectGetUser := func(s *serverMock, user repository.User, err error) {
+		s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+			PhoneNumber: validReqBody.PhoneNumber,
+		}).
+			Return(user, err)
+	}
+	// the code is stored hashed, the SMS carries the code itself
+	var insertedCodeHash string
+	expectInsertCode := func(s *serverMock, err error) {
+		s.repository.EXPECT().InsertOneTimeCode(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertOneTimeCodeInput{})).
+			DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.InsertOneTimeCodeInput) (repository.InsertOneTimeCodeOutput, error) {
+				assert.Equal(t, validUser.Id, input.UserId)
+				assert.Equal(t, otp.PurposePhoneVerification, input.Purpose)
+				insertedCodeHash = input.CodeHash
+				return repository.InsertOneTimeCodeOutput{Id: "f6a7b8c9-3d4e-4f5a-9b6c-7d8e9f0a1b06"}, err
+			})
+	}
+	expectSendCode := func(s *serverMock, err error) {
+		s.repository.EXPECT().GetActiveOneTimeCode(gomock.Any(), repository.GetActiveOneTimeCodeInput{
+			UserId:  validUser.Id,
+			Purpose: otp.PurposePhoneVerification,
+		}).
+			Return(repository.OneTimeCode{}, sql.ErrNoRows)
+		expectInsertCode(s, err)
+	}
+	// the code of the pending registration was sent before the resend interval
+	expectNoRecentCode := func(s *serverMock) {
+		s.repository.EXPECT().GetActiveOneTimeCode(gomock.Any(), repository.GetActiveOneTimeCodeInput{
+			UserId:  pendingUser.Id,
+			Purpose: otp.PurposePhoneVerification,
+		}).
+			Return(repository.OneTimeCode{CreatedAt: time.Now().Add(-2 * time.Minute)}, nil)
+	}
+	// the password is stored as a PHC string of the configured algorithm
+	assertPasswordHash := func(s *serverMock, passwordHash string) {
+		assert.True(t, strings.HasPrefix(passwordHash, "$argon2id$v=19$m=64,t=1,p=1$"))
+		match, _, err := s.server.PasswordHasher.Verify(validReqBody.Password, passwordHash, "")
+		assert.NoError(t, err)
+		assert.True(t, match)
+	}
+
 	testCases := []struct {
 		title        string
 		request      *generated.RegisterJSONRequestBody
 		aborted      bool
 		invalidMime  bool
+		minScore     int
 		expectations func(t *testing.T, s *serverMock)
 
 		expectedHttpCode int
 		expectedErrMsg   string
 		expectedResp     generated.RegisterResponse
+		expectedSMS      bool
 	}{
 		{
 			title:            "request aborted",
@@ -98,7 +152,7 @@ func TestRegister(t *testing.T) {
 			},
 			expectations:     func(t *testing.T, s *serverMock) {},
 			expectedHttpCode: http.StatusBadRequest,
-			expectedErrMsg:   `phoneNumber must be at least 10 characters in length`,
+			expectedErrMsg:   `phoneNumber must be a mobile phone number from Indonesia`,
 		},
 		{
 			title: "phoneNumber too long",
@@ -108,27 +162,37 @@ func TestRegister(t *testing.T) {
 			},
 			expectations:     func(t *testing.T, s *serverMock) {},
 			expectedHttpCode: http.StatusBadRequest,
-			expectedErrMsg:   `phoneNumber must be a maximum of 13 characters in length`,
+			expectedErrMsg:   `phoneNumber must be a maximum of 25 characters in length`,
 		},
 		{
-			title: "phoneNumber do not start with +62",
+			title: "phoneNumber of a country not allowed",
 			request: &generated.RegisterJSONRequestBody{
 				FullName:    test_helper.TestUserName,
-				PhoneNumber: "12345678901",
+				PhoneNumber: "+6591234567",
 			},
 			expectations:     func(t *testing.T, s *serverMock) {},
 			expectedHttpCode: http.StatusBadRequest,
-			expectedErrMsg:   `phoneNumber should start with +62`,
+			expectedErrMsg:   `phoneNumber must be a mobile phone number from Indonesia`,
 		},
 		{
-			title: "phoneNumber not following E.164 phone format",
+			title: "phoneNumber not a mobile phone number",
+			request: &generated.RegisterJSONRequestBody{
+				FullName:    test_helper.TestUserName,
+				PhoneNumber: "+62215551234",
+			},
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `phoneNumber must be a mobile phone number from Indonesia`,
+		},
+		{
+			title: "phoneNumber not a number",
 			request: &generated.RegisterJSONRequestBody{
 				FullName:    test_helper.TestUserName,
 				PhoneNumber: "+6212AbcDef",
 			},
 			expectations:     func(t *testing.T, s *serverMock) {},
 			expectedHttpCode: http.StatusBadRequest,
-			expectedErrMsg:   `phoneNumber must be a valid E.164 formatted phone number`,
+			expectedErrMsg:   `phoneNumber must be a mobile phone number from Indonesia`,
 		},
 		{
 			title: "password too short",
@@ -161,7 +225,7 @@ func TestRegister(t *testing.T) {
 			},
 			expectations:     func(t *testing.T, s *serverMock) {},
 			expectedHttpCode: http.StatusBadRequest,
-			expectedErrMsg:   `password must contain at least 1 capital characters, 1 number, and 1 special (non alpha-numeric) character`,
+			expectedErrMsg:   `{"messages":["password must contain at least 1 uppercase letter","password must contain at least 1 number","password must contain at least 1 special (non alpha-numeric) character"]}`,
 		},
 		{
 			title: "password does not contain number",
@@ -172,7 +236,7 @@ func TestRegister(t *testing.T) {
 			},
 			expectations:     func(t *testing.T, s *serverMock) {},
 			expectedHttpCode: http.StatusBadRequest,
-			expectedErrMsg:   `password must contain at least 1 capital characters, 1 number, and 1 special (non alpha-numeric) character`,
+			expectedErrMsg:   `{"messages":["password must contain at least 1 number","password must contain at least 1 special (non alpha-numeric) character"]}`,
 		},
 		{
 			title: "password does not contain special character",
@@ -183,16 +247,58 @@ func TestRegister(t *testing.T) {
 			},
 			expectations:     func(t *testing.T, s *serverMock) {},
 			expectedHttpCode: http.StatusBadRequest,
-			expectedErrMsg:   `password must contain at least 1 capital characters, 1 number, and 1 special (non alpha-numeric) character`,
+			expectedErrMsg:   `{"messages":["password must contain at least 1 special (non alpha-numeric) character"]}`,
+		},
+		{
+			title: "password contains a denied word",
+			request: &generated.RegisterJSONRequestBody{
+				FullName:    test_helper.TestUserName,
+				PhoneNumber: test_helper.TestUserPhone,
+				Password:    "Qwerty12$",
+			},
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["password must not contain \"qwerty\""]}`,
+		},
+		{
+			title: "password contains phone number and name",
+			request: &generated.RegisterJSONRequestBody{
+				FullName:    test_helper.TestUserName,
+				PhoneNumber: test_helper.TestUserPhone,
+				Password:    "Unit$081000000001",
+			},
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["password must not contain your phone number","password must not contain your name"]}`,
+		},
+		{
+			title: "password too easy to guess for the min score",
+			request: &generated.RegisterJSONRequestBody{
+				FullName:    test_helper.TestUserName,
+				PhoneNumber: test_helper.TestUserPhone,
+				Password:    "Sayang123!",
+			},
+			minScore:         2,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["password is too easy to guess"]}`,
+		},
+		{
+			title: "password found in a data breach",
+			request: &generated.RegisterJSONRequestBody{
+				FullName:    test_helper.TestUserName,
+				PhoneNumber: test_helper.TestUserPhone,
+				Password:    test_helper.TestBreachedPassword,
+			},
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `password has appeared in a data breach, please choose a different password`,
 		},
 		{
 			title:   "error in Repository.GetUser",
 			request: &validReqBody,
 			expectations: func(t *testing.T, s *serverMock) {
-				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
-					PhoneNumber: validReqBody.PhoneNumber,
-				}).
-					Return(repository.User{}, errors.New(response.InternalServerErrorMsg))
+				expectGetUser(s, repository.User{}, errors.New(response.InternalServerErrorMsg))
 			},
 			expectedHttpCode: http.StatusInternalServerError,
 			expectedErrMsg:   response.InternalServerErrorMsg,
@@ -201,10 +307,7 @@ func TestRegister(t *testing.T) {
 			title:   "phone number already registered",
 			request: &validReqBody,
 			expectations: func(t *testing.T, s *serverMock) {
-				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
-					PhoneNumber: validReqBody.PhoneNumber,
-				}).
-					Return(validUser, nil)
+				expectGetUser(s, validUser, nil)
 			},
 			expectedHttpCode: http.StatusConflict,
 			expectedErrMsg:   response.PhoneAlreadyRegisteredErrorMsg,
@@ -213,10 +316,7 @@ func TestRegister(t *testing.T) {
 			title:   "error in Repository.InsertUser",
 			request: &validReqBody,
 			expectations: func(t *testing.T, s *serverMock) {
-				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
-					PhoneNumber: validReqBody.PhoneNumber,
-				}).
-					Return(repository.User{}, sql.ErrNoRows)
+				expectGetUser(s, repository.User{}, sql.ErrNoRows)
 
 				s.repository.EXPECT().InsertUser(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertUserInput{})).
 					Return(repository.InsertUserOutput{}, errors.New(response.InternalServerErrorMsg))
@@ -225,24 +325,149 @@ func TestRegister(t *testing.T) {
 			expectedErrMsg:   response.InternalServerErrorMsg,
 		},
 		{
-			title:   "success",
+			title:   "error in Repository.GetActiveOneTimeCode of the pending registration",
 			request: &validReqBody,
 			expectations: func(t *testing.T, s *serverMock) {
-				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
-					PhoneNumber: validReqBody.PhoneNumber,
+				expectGetUser(s, pendingUser, nil)
+
+				s.repository.EXPECT().GetActiveOneTimeCode(gomock.Any(), gomock.Any()).
+					Return(repository.OneTimeCode{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "pending registration with a code sent within the resend interval is left as is",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, pendingUser, nil)
+
+				s.repository.EXPECT().GetActiveOneTimeCode(gomock.Any(), repository.GetActiveOneTimeCodeInput{
+					UserId:  pendingUser.Id,
+					Purpose: otp.PurposePhoneVerification,
 				}).
-					Return(repository.User{}, sql.ErrNoRows)
+					Return(repository.OneTimeCode{CreatedAt: time.Now().Add(-10 * time.Second)}, nil)
+			},
+			expectedHttpCode: http.StatusCreated,
+			expectedResp: generated.RegisterResponse{
+				Id:      test_helper.TestUserId,
+				Message: pointer.String(registrationPendingMsg),
+			},
+		},
+		{
+			title:   "error in Repository.ReplacePendingUser",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, pendingUser, nil)
+				expectNoRecentCode(s)
+
+				s.repository.EXPECT().ReplacePendingUser(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.ReplacePendingUserInput{})).
+					Return(errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "pending registration verified in the meantime",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, pendingUser, nil)
+				expectNoRecentCode(s)
+
+				s.repository.EXPECT().ReplacePendingUser(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.ReplacePendingUserInput{})).
+					Return(sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusConflict,
+			expectedErrMsg:   response.PhoneAlreadyRegisteredErrorMsg,
+		},
+		{
+			title:   "pending registration is replaced, its codes are invalidated and a new one is sent",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, pendingUser, nil)
+				expectNoRecentCode(s)
+
+				s.repository.EXPECT().ReplacePendingUser(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.ReplacePendingUserInput{})).
+					DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.ReplacePendingUserInput) error {
+						assert.Equal(t, pendingUser.Id, input.Id)
+						assert.Equal(t, validReqBody.FullName, input.FullName)
+						assertPasswordHash(s, input.PasswordHash)
+						return nil
+					})
+				expectSendCode(s, nil)
+			},
+			expectedHttpCode: http.StatusCreated,
+			expectedResp: generated.RegisterResponse{
+				Id:      test_helper.TestUserId,
+				Message: pointer.String(registrationPendingMsg),
+			},
+			expectedSMS: true,
+		},
+		{
+			title:   "error in Repository.InsertOneTimeCode only log error",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, repository.User{}, sql.ErrNoRows)
+
+				s.repository.EXPECT().InsertUser(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertUserInput{})).
+					Return(repository.InsertUserOutput{Id: test_helper.TestUserId}, nil)
+				expectSendCode(s, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusCreated,
+			expectedResp: generated.RegisterResponse{
+				Id:      test_helper.TestUserId,
+				Message: pointer.String(registrationPendingMsg),
+			},
+		},
+		{
+			title: "phoneNumber in a local format is stored in E.164",
+			request: &generated.RegisterJSONRequestBody{
+				FullName:    validReqBody.FullName,
+				Password:    validReqBody.Password,
+				PhoneNumber: "0810 0000 0001",
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, repository.User{}, sql.ErrNoRows)
+
+				s.repository.EXPECT().InsertUser(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertUserInput{})).
+					DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.InsertUserInput) (repository.InsertUserOutput, error) {
+						assert.Equal(t, test_helper.TestUserPhone, input.PhoneNumber)
+
+						return repository.InsertUserOutput{
+							Id: test_helper.TestUserId,
+						}, nil
+					})
+				expectSendCode(s, nil)
+			},
+			expectedHttpCode: http.StatusCreated,
+			expectedResp: generated.RegisterResponse{
+				Id:      test_helper.TestUserId,
+				Message: pointer.String(registrationPendingMsg),
+			},
+			expectedSMS: true,
+		},
+		{
+			title:   "success",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, repository.User{}, sql.ErrNoRows)
 
 				s.repository.EXPECT().InsertUser(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertUserInput{})).
-					Return(repository.InsertUserOutput{
-						Id: test_helper.TestUserId,
-					}, nil)
+					DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.InsertUserInput) (repository.InsertUserOutput, error) {
+						assertPasswordHash(s, input.PasswordHash)
+
+						return repository.InsertUserOutput{
+							Id: test_helper.TestUserId,
+						}, nil
+					})
+				expectSendCode(s, nil)
 			},
 			expectedHttpCode: http.StatusCreated,
 			expectedResp: generated.RegisterResponse{
 				Id:      test_helper.TestUserId,
-				Message: pointer.String("user registration success"),
+				Message: pointer.String(registrationPendingMsg),
 			},
+			expectedSMS: true,
 		},
 	}
 
@@ -251,6 +476,14 @@ func TestRegister(t *testing.T) {
 			// Setup
 			s := setupServerMock(t)
 			defer s.cleanUp()
+			insertedCodeHash = ""
+
+			if tc.minScore > 0 {
+				s.config.Password.Policy.MinScore = tc.minScore
+				server, err := NewServer(NewServerOptions{Config: s.config, Repository: s.repository})
+				assert.NoError(t, err)
+				s.server = server
+			}
 
 			var reqBody io.Reader
 			if tc.request != nil {
@@ -288,6 +521,16 @@ func TestRegister(t *testing.T) {
 				assert.NoError(t, err)
 				assert.Equal(t, tc.expectedHttpCode, rec.Code)
 				assert.Equal(t, string(expectedRespJson), strings.TrimSpace(rec.Body.String()))
+
+				sent := smsCodePattern.FindStringSubmatch(s.smsLog.String())
+				if tc.expectedSMS {
+					if assert.Len(t, sent, 3) {
+						assert.Equal(t, validReqBody.PhoneNumber, sent[1])
+						assert.True(t, otp.Verify(insertedCodeHash, otp.PurposePhoneVerification, test_helper.TestUserId, sent[1], sent[2]))
+					}
+				} else {
+					assert.Empty(t, s.smsLog.String())
+				}
 			} else {
 				assert.Equal(t, tc.expectedHttpCode, rec.Code)
 				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
@@ -295,3 +538,66 @@ func TestRegister(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterTwiceWithinResendInterval(t *testing.T) {
+	// Setup
+	s := setupServerMock(t)
+	defer s.cleanUp()
+
+	reqBody := generated.RegisterJSONRequestBody{
+		FullName:    test_helper.TestUserName,
+		Password:    test_helper.TestUserPassword,
+		PhoneNumber: test_helper.TestUserPhone,
+	}
+	pendingUser := repository.User{
+		Id:          test_helper.TestUserId,
+		PhoneNumber: test_helper.TestUserPhone,
+		FullName:    "Someone Else",
+	}
+
+	// the active code is the one last inserted, replacing the registration invalidates it
+	activeCode := repository.OneTimeCode{CreatedAt: time.Now().Add(-2 * time.Minute)}
+	hasActiveCode := true
+	s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{PhoneNumber: reqBody.PhoneNumber}).
+		Return(pendingUser, nil).Times(2)
+	s.repository.EXPECT().GetActiveOneTimeCode(gomock.Any(), repository.GetActiveOneTimeCodeInput{
+		UserId:  pendingUser.Id,
+		Purpose: otp.PurposePhoneVerification,
+	}).
+		DoAndReturn(func(ctx context.Context, input repository.GetActiveOneTimeCodeInput) (repository.OneTimeCode, error) {
+			if !hasActiveCode {
+				return repository.OneTimeCode{}, sql.ErrNoRows
+			}
+			return activeCode, nil
+		}).AnyTimes()
+	s.repository.EXPECT().ReplacePendingUser(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.ReplacePendingUserInput{})).
+		DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.ReplacePendingUserInput) error {
+			hasActiveCode = false
+			return nil
+		}).Times(1)
+	s.repository.EXPECT().InsertOneTimeCode(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertOneTimeCodeInput{})).
+		DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.InsertOneTimeCodeInput) (repository.InsertOneTimeCodeOutput, error) {
+			activeCode = repository.OneTimeCode{CreatedAt: time.Now()}
+			hasActiveCode = true
+			return repository.InsertOneTimeCodeOutput{Id: "f6a7b8c9-3d4e-4f5a-9b6c-7d8e9f0a1b06"}, nil
+		}).Times(1)
+
+	for i := 0; i < 2; i++ {
+		reqBodyJson, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(echo.POST, "/", bytes.NewReader(reqBodyJson))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		ctx := echo.New().NewContext(req, rec)
+		ctx.SetPath("/v1/register")
+
+		err := s.server.Register(ctx)
+
+		// Assertions
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	}
+
+	// only the first registration sent a code, the second one left it valid
+	assert.Len(t, smsCodePattern.FindAllString(s.smsLog.String(), -1), 1)
+}
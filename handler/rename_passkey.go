@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+
+	openapi_types "github.com/deepmap/oapi-codegen/pkg/types"
+	"github.com/labstack/echo/v4"
+)
+
+// Rename a passkey of the user
+// (PATCH /v1/user/passkeys/{id})
+func (s *Server) RenamePasskey(ctx echo.Context, id openapi_types.UUID) error {
+	tracestr := "handler.RenamePasskey"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	claims, err := authentication.ClaimsFromContext(ctx)
+	if err != nil {
+		ctx.Logger().Infof("%s, ClaimsFromContext failed, err: %+v", tracestr, err)
+		return response.AccessForbidden(ctx)
+	}
+
+	var req generated.RenamePasskeyJSONRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, []string{"name is a required field"})
+	}
+
+	passkey, err := s.Repository.UpdatePasskeyName(ctx.Request().Context(), nil, repository.UpdatePasskeyNameInput{
+		Id:     id.String(),
+		UserId: claims.Id,
+		Name:   name,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return response.PasskeyNotFound(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed UpdatePasskeyName, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	return ctx.JSON(http.StatusOK, passkeyResponse(passkey))
+}
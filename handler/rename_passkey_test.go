@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func TestRenamePasskey(t *testing.T) {
+
+	var (
+		passkeyId = uuid.MustParse("d0e1f2a3-aaaa-4dbe-9f2a-3b4c5d6e7f10")
+
+		validReqBody = generated.RenamePasskeyJSONRequestBody{
+			Name: " Work laptop ",
+		}
+
+		renamedPasskey = repository.Passkey{
+			Id:         passkeyId.String(),
+			CreatedAt:  time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC),
+			UserId:     test_helper.TestUserId,
+			Transports: []string{"internal"},
+			Name:       "Work laptop",
+		}
+	)
+
+	expectUpdatePasskeyName := func(s *serverMock, output repository.Passkey, err error) {
+		s.repository.EXPECT().UpdatePasskeyName(gomock.Any(), nil, repository.UpdatePasskeyNameInput{
+			Id:     passkeyId.String(),
+			UserId: test_helper.TestUserId,
+			Name:   "Work laptop",
+		}).
+			Return(output, err)
+	}
+
+	testCases := []struct {
+		title        string
+		request      *generated.RenamePasskeyJSONRequestBody
+		jwt          string
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+	}{
+		{
+			title:            "request aborted",
+			request:          &validReqBody,
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "auth token invalid",
+			request:          &validReqBody,
+			jwt:              "Bearer invalid-token",
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.UnauthorizedErrorMsg,
+		},
+		{
+			title:            "empty request body",
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["name is a required field"]}`,
+		},
+		{
+			title:            "blank name",
+			request:          &generated.RenamePasskeyJSONRequestBody{Name: "   "},
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["name is a required field"]}`,
+		},
+		{
+			title:            "name too long",
+			request:          &generated.RenamePasskeyJSONRequestBody{Name: strings.Repeat("a", 101)},
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["name must be a maximum of 100 characters in length"]}`,
+		},
+		{
+			title:   "error in Repository.UpdatePasskeyName",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectUpdatePasskeyName(s, repository.Passkey{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "passkey of another user",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectUpdatePasskeyName(s, repository.Passkey{}, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusNotFound,
+			expectedErrMsg:   response.PasskeyNotFoundErrorMsg,
+		},
+		{
+			title:   "passkey renamed",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectUpdatePasskeyName(s, renamedPasskey, nil)
+			},
+			expectedHttpCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			var reqBody io.Reader
+			if tc.request != nil {
+				reqBodyJson, _ := json.Marshal(*tc.request)
+				reqBody = bytes.NewReader(reqBodyJson)
+			}
+
+			jwt := tc.jwt
+			if jwt == "" {
+				jwt = test_helper.TestUserJWT
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.PATCH, "/", reqBody)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(authentication.AuthHeaderKey, jwt)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/user/passkeys/:id")
+
+			tc.expectations(t, s)
+
+			err := s.withAuth(t, func(ctx echo.Context) error {
+				return s.server.RenamePasskey(ctx, passkeyId)
+			})(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusOK {
+				var resp generated.Passkey
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.Equal(t, passkeyResponse(renamedPasskey), resp)
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
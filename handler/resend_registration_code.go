@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/otp"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	resendRegistrationCodeAcceptedMsg = "if a registration is pending for the phone number, a new code has been sent"
+)
+
+// Send a new code to verify the phone number of a pending registration
+// (POST /v1/register/resend)
+func (s *Server) ResendRegistrationCode(ctx echo.Context) error {
+	tracestr := "handler.ResendRegistrationCode"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	var req generated.ResendRegistrationCodeJSONRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+	req.PhoneNumber = s.canonicalPhoneNumber(req.PhoneNumber)
+
+	// the response must not tell pending registrations apart
+	accepted := generated.MessageResponse{
+		Message: resendRegistrationCodeAcceptedMsg,
+	}
+
+	user, err := s.Repository.GetUser(ctx.Request().Context(), repository.GetUserInput{
+		PhoneNumber: req.PhoneNumber,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ctx.JSON(http.StatusAccepted, accepted)
+		}
+		ctx.Logger().Errorf("%s, failed GetUser by PhoneNumber, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if user.PhoneVerifiedAt != nil {
+		return ctx.JSON(http.StatusAccepted, accepted)
+	}
+
+	// the resend interval of sendOneTimeCode applies
+	if err := s.sendOneTimeCode(ctx, user.Id, user.PhoneNumber, otp.PurposePhoneVerification, phoneVerificationMessage); err != nil {
+		ctx.Logger().Errorf("%s, failed sendOneTimeCode, err: %v", tracestr, err)
+	}
+
+	return ctx.JSON(http.StatusAccepted, accepted)
+}
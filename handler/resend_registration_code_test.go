@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/otp"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+)
+
+func TestResendRegistrationCode(t *testing.T) {
+
+	var (
+		phoneVerifiedAt = time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+
+		validReqBody = generated.ResendRegistrationCodeJSONRequestBody{
+			PhoneNumber: test_helper.TestUserPhone,
+		}
+
+		pendingUser = repository.User{
+			Id:          test_helper.TestUserId,
+			PhoneNumber: test_helper.TestUserPhone,
+			FullName:    test_helper.TestUserName,
+		}
+	)
+
+	verifiedUser := pendingUser
+	verifiedUser.PhoneVerifiedAt = &phoneVerifiedAt
+
+	expectGetUser := func(s *serverMock, user repository.User, err error) {
+		s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+			PhoneNumber: validReqBody.PhoneNumber,
+		}).
+			Return(user, err)
+	}
+	expectLatestCode := func(s *serverMock, code repository.OneTimeCode, err error) {
+		s.repository.EXPECT().GetActiveOneTimeCode(gomock.Any(), repository.GetActiveOneTimeCodeInput{
+			UserId:  pendingUser.Id,
+			Purpose: otp.PurposePhoneVerification,
+		}).
+			Return(code, err)
+	}
+	var insertedCodeHash string
+	expectInsertCode := func(s *serverMock) {
+		s.repository.EXPECT().InsertOneTimeCode(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertOneTimeCodeInput{})).
+			DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.InsertOneTimeCodeInput) (repository.InsertOneTimeCodeOutput, error) {
+				assert.Equal(t, pendingUser.Id, input.UserId)
+				assert.Equal(t, otp.PurposePhoneVerification, input.Purpose)
+				insertedCodeHash = input.CodeHash
+				return repository.InsertOneTimeCodeOutput{Id: "a7b8c9d0-4e5f-4a6b-8c7d-8e9f0a1b2c07"}, nil
+			})
+	}
+
+	testCases := []struct {
+		title        string
+		request      *generated.ResendRegistrationCodeJSONRequestBody
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+		expectedSMS      bool
+	}{
+		{
+			title:            "request aborted",
+			request:          &validReqBody,
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "empty request body",
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["phoneNumber is a required field"]}`,
+		},
+		{
+			title:   "error in Repository.GetUser",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, repository.User{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "phone number not registered gets the same response",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, repository.User{}, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusAccepted,
+		},
+		{
+			title:   "phone number already verified gets the same response",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, verifiedUser, nil)
+			},
+			expectedHttpCode: http.StatusAccepted,
+		},
+		{
+			title:   "code sent less than the resend interval ago is not sent again",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, pendingUser, nil)
+				expectLatestCode(s, repository.OneTimeCode{CreatedAt: time.Now().Add(-10 * time.Second)}, nil)
+			},
+			expectedHttpCode: http.StatusAccepted,
+		},
+		{
+			title:   "success",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, pendingUser, nil)
+				expectLatestCode(s, repository.OneTimeCode{CreatedAt: time.Now().Add(-2 * time.Minute)}, nil)
+				expectInsertCode(s)
+			},
+			expectedHttpCode: http.StatusAccepted,
+			expectedSMS:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+			insertedCodeHash = ""
+
+			var reqBody io.Reader
+			if tc.request != nil {
+				reqBodyJson, _ := json.Marshal(*tc.request)
+				reqBody = bytes.NewReader(reqBodyJson)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", reqBody)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/register/resend")
+
+			tc.expectations(t, s)
+
+			err := s.server.ResendRegistrationCode(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusAccepted {
+				var resp generated.MessageResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.Equal(t, resendRegistrationCodeAcceptedMsg, resp.Message)
+
+				sent := smsCodePattern.FindStringSubmatch(s.smsLog.String())
+				if tc.expectedSMS {
+					if assert.Len(t, sent, 3) {
+						assert.Equal(t, pendingUser.PhoneNumber, sent[1])
+						assert.True(t, otp.Verify(insertedCodeHash, otp.PurposePhoneVerification, pendingUser.Id, pendingUser.PhoneNumber, sent[2]))
+					}
+				} else {
+					assert.Empty(t, s.smsLog.String())
+				}
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/otp"
+	"user-service-sample/utils/password"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Set a new password with the code sent to the phone number, every session is logged out
+// (POST /v1/password/reset)
+func (s *Server) ResetPassword(ctx echo.Context) error {
+	tracestr := "handler.ResetPassword"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	var req generated.ResetPasswordJSONRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+	req.PhoneNumber = s.canonicalPhoneNumber(req.PhoneNumber)
+
+	// an unregistered phone number gets the same response as a wrong code
+	user, err := s.Repository.GetUser(ctx.Request().Context(), repository.GetUserInput{
+		PhoneNumber: req.PhoneNumber,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return response.InvalidOneTimeCode(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed GetUser by PhoneNumber, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	// so does a locked user, the lockout must not tell registered phone numbers apart either
+	if otpLockedFor(user) > 0 {
+		return response.InvalidOneTimeCode(ctx)
+	}
+
+	codeId, err := s.checkOneTimeCode(ctx, user.Id, user.PhoneNumber, otp.PurposePasswordReset, req.Code)
+	if err != nil {
+		if err == errInvalidOneTimeCode {
+			return s.otpFailed(ctx, tracestr, user.Id)
+		}
+		ctx.Logger().Errorf("%s, failed checkOneTimeCode, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	// the policy is checked once the code proved the phone number, as the messages tell about the user,
+	// and before the code is consumed, so a password not following it can be fixed with the same code
+	messages := s.PasswordPolicy.Validate("newPassword", req.NewPassword, password.PersonalInfo{
+		PhoneNumber: user.PhoneNumber,
+		FullName:    user.FullName,
+	})
+	if len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+
+	reused, err := s.isRecentPassword(ctx, user, req.NewPassword)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed isRecentPassword, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if reused {
+		return response.PasswordReused(ctx, s.Config.Password.History.Remember)
+	}
+
+	passwordHash, err := s.PasswordHasher.Hash(req.NewPassword)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed Hash password, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	// the code is used up with the new password, and whoever knew the old password is logged out,
+	// all of it or none: a failure leaves the code for the user to try again
+	deletedPasskeys, err := s.Repository.ResetUserPassword(ctx.Request().Context(), nil, repository.ResetUserPasswordInput{
+		CodeId: codeId,
+		Credentials: repository.UpdateUserCredentialsInput{
+			Id:           user.Id,
+			PasswordHash: passwordHash,
+			HistorySize:  s.Config.Password.History.HistorySize(),
+		},
+	})
+	if err != nil {
+		// a concurrent request used the code first
+		if err == sql.ErrNoRows {
+			return response.InvalidOneTimeCode(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed ResetUserPassword, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	s.otpPassed(ctx, tracestr, user)
+
+	if err := s.userSessionsRevoked(ctx, user.Id, deletedPasskeys); err != nil {
+		ctx.Logger().Errorf("%s, failed userSessionsRevoked, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
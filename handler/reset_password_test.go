@@ -0,0 +1,456 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-service-sample/config"
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/otp"
+	"user-service-sample/utils/password"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+)
+
+func TestResetPassword(t *testing.T) {
+
+	var (
+		validReqBody = generated.ResetPasswordJSONRequestBody{
+			PhoneNumber: test_helper.TestUserPhone,
+			Code:        "123456",
+			NewPassword: "n3wPassW0$d",
+		}
+
+		validUser = repository.User{
+			Id:           test_helper.TestUserId,
+			PhoneNumber:  test_helper.TestUserPhone,
+			FullName:     test_helper.TestUserName,
+			PasswordHash: test_helper.TestUserArgon2idHash,
+		}
+
+		activeCode = repository.OneTimeCode{
+			Id:        "e5f6a7b8-2c3d-4e4f-8a5b-6c7d8e9f0a05",
+			CreatedAt: time.Now().Add(-time.Minute),
+			UserId:    test_helper.TestUserId,
+			Purpose:   otp.PurposePasswordReset,
+			CodeHash:  otp.Hash(otp.PurposePasswordReset, test_helper.TestUserId, test_helper.TestUserPhone, "123456"),
+			ExpiresAt: time.Now().Add(9 * time.Minute),
+		}
+	)
+
+	expectGetUser := func(s *serverMock) {
+		s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+			PhoneNumber: validReqBody.PhoneNumber,
+		}).
+			Return(validUser, nil)
+	}
+	expectActiveCode := func(s *serverMock) {
+		s.repository.EXPECT().GetActiveOneTimeCode(gomock.Any(), repository.GetActiveOneTimeCodeInput{
+			UserId:  validUser.Id,
+			Purpose: otp.PurposePasswordReset,
+		}).
+			Return(activeCode, nil)
+	}
+	expectIncrementAttempts := func(s *serverMock, err error) {
+		s.repository.EXPECT().IncrementOneTimeCodeAttempts(gomock.Any(), nil, repository.IncrementOneTimeCodeAttemptsInput{
+			Id:          activeCode.Id,
+			MaxAttempts: 5,
+		}).
+			Return(err)
+	}
+	// the wrong code counts toward the lockout of the codes of the user, shared with the log in codes
+	expectOTPFailure := func(s *serverMock) {
+		s.repository.EXPECT().RecordOTPFailure(gomock.Any(), nil, repository.RecordOTPFailureInput{
+			UserId:          validUser.Id,
+			MaxFailures:     10,
+			LockoutDuration: 15 * time.Minute,
+		}).
+			Return(false, nil)
+	}
+	// the mock config remembers 3 passwords, the current one and 2 from the history
+	expectPasswordHistory := func(s *serverMock, history []repository.PasswordHistory, err error) {
+		s.repository.EXPECT().GetPasswordHistory(gomock.Any(), repository.GetPasswordHistoryInput{
+			UserId: validUser.Id,
+			Limit:  2,
+		}).
+			Return(history, err)
+	}
+	// the code is consumed with the new password stored as a fresh hash, in one transaction
+	// revoking the sessions, refresh tokens & passkeys of the user
+	expectResetPassword := func(s *serverMock, deletedPasskeys int64, err error) {
+		s.repository.EXPECT().ResetUserPassword(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.ResetUserPasswordInput{})).
+			DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.ResetUserPasswordInput) (int64, error) {
+				assert.Equal(t, activeCode.Id, input.CodeId)
+				assert.Equal(t, validUser.Id, input.Credentials.Id)
+				assert.Equal(t, 2, input.Credentials.HistorySize)
+				match, _, verifyErr := s.server.PasswordHasher.Verify(validReqBody.NewPassword, input.Credentials.PasswordHash, "")
+				assert.NoError(t, verifyErr)
+				assert.True(t, match)
+				return deletedPasskeys, err
+			})
+	}
+
+	testCases := []struct {
+		title        string
+		request      *generated.ResetPasswordJSONRequestBody
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+	}{
+		{
+			title:            "request aborted",
+			request:          &validReqBody,
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "empty request body",
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["code is a required field","newPassword is a required field","phoneNumber is a required field"]}`,
+		},
+		{
+			title: "code not numeric",
+			request: &generated.ResetPasswordJSONRequestBody{
+				PhoneNumber: validReqBody.PhoneNumber,
+				Code:        "12ab56",
+				NewPassword: validReqBody.NewPassword,
+			},
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `code must be a valid numeric value`,
+		},
+		{
+			title: "new password not following password requirement",
+			request: &generated.ResetPasswordJSONRequestBody{
+				PhoneNumber: validReqBody.PhoneNumber,
+				Code:        validReqBody.Code,
+				NewPassword: "password",
+			},
+			// rejected with the request body, the code is not even checked
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["newPassword must contain at least 1 uppercase letter","newPassword must contain at least 1 number","newPassword must contain at least 1 special (non alpha-numeric) character"]}`,
+		},
+		{
+			title: "new password with the phone number and a wrong code only tells the code is invalid",
+			request: &generated.ResetPasswordJSONRequestBody{
+				PhoneNumber: validReqBody.PhoneNumber,
+				Code:        "654321",
+				NewPassword: "Unit$081000000001",
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				expectActiveCode(s)
+				expectIncrementAttempts(s, nil)
+				expectOTPFailure(s)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title: "new password found in a data breach",
+			request: &generated.ResetPasswordJSONRequestBody{
+				PhoneNumber: validReqBody.PhoneNumber,
+				Code:        validReqBody.Code,
+				NewPassword: test_helper.TestBreachedPassword,
+			},
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `newPassword has appeared in a data breach, please choose a different password`,
+		},
+		{
+			title:   "error in Repository.GetUser",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Return(repository.User{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "phone number not registered",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				s.repository.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Return(repository.User{}, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "locked user gets the same response as a wrong code, even with the right code",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				lockedUntil := time.Now().Add(10 * time.Minute)
+				lockedUser := validUser
+				lockedUser.OTPLockedUntil = &lockedUntil
+				s.repository.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Return(lockedUser, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "error in Repository.GetActiveOneTimeCode",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				s.repository.EXPECT().GetActiveOneTimeCode(gomock.Any(), gomock.Any()).
+					Return(repository.OneTimeCode{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "no active code",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				s.repository.EXPECT().GetActiveOneTimeCode(gomock.Any(), gomock.Any()).
+					Return(repository.OneTimeCode{}, sql.ErrNoRows)
+				expectOTPFailure(s)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "attempts exhausted",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				expectActiveCode(s)
+				expectIncrementAttempts(s, sql.ErrNoRows)
+				expectOTPFailure(s)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title: "wrong code",
+			request: &generated.ResetPasswordJSONRequestBody{
+				PhoneNumber: validReqBody.PhoneNumber,
+				Code:        "654321",
+				NewPassword: validReqBody.NewPassword,
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				expectActiveCode(s)
+				expectIncrementAttempts(s, nil)
+				expectOTPFailure(s)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title: "new password is the current password",
+			request: &generated.ResetPasswordJSONRequestBody{
+				PhoneNumber: validReqBody.PhoneNumber,
+				Code:        validReqBody.Code,
+				NewPassword: test_helper.TestUserPassword,
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				expectActiveCode(s)
+				expectIncrementAttempts(s, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   fmt.Sprintf(response.PasswordReusedErrorMsg, 3),
+		},
+		{
+			title:   "new password is one of the remembered passwords",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				expectActiveCode(s)
+				expectIncrementAttempts(s, nil)
+				reusedHash, err := s.server.PasswordHasher.Hash(validReqBody.NewPassword)
+				assert.NoError(t, err)
+				expectPasswordHistory(s, []repository.PasswordHistory{
+					{Id: "f6a7b8c9-3d4e-4f5a-9b6c-7d8e9f0a1b06", PasswordHash: reusedHash},
+				}, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   fmt.Sprintf(response.PasswordReusedErrorMsg, 3),
+		},
+		{
+			title:   "error in Repository.GetPasswordHistory",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				expectActiveCode(s)
+				expectIncrementAttempts(s, nil)
+				expectPasswordHistory(s, nil, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "code used by a concurrent request",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				expectActiveCode(s)
+				expectIncrementAttempts(s, nil)
+				expectPasswordHistory(s, []repository.PasswordHistory{}, nil)
+				expectResetPassword(s, 0, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "error in Repository.ResetUserPassword",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				expectActiveCode(s)
+				expectIncrementAttempts(s, nil)
+				expectPasswordHistory(s, []repository.PasswordHistory{}, nil)
+				expectResetPassword(s, 0, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "hashes peppered with a dropped pepper are skipped",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				// the current and a remembered hash were peppered with v1
+				v1Hasher, err := password.NewHasher(config.PasswordHashingConfig{
+					Argon2id: test_helper.TestArgon2idConfig,
+					Pepper: config.PepperConfig{
+						CurrentVersion: "v1",
+						Keys:           []config.PepperKeyConfig{{Version: "v1", Secret: "pepper-v1-0123456789abcdef"}},
+					},
+				})
+				assert.NoError(t, err)
+				currentHash, err := v1Hasher.Hash(test_helper.TestUserPassword)
+				assert.NoError(t, err)
+				rememberedHash, err := v1Hasher.Hash(validReqBody.NewPassword)
+				assert.NoError(t, err)
+
+				// v1 is rotated out to v2 and dropped from the config
+				s.config.Password.Hashing.Pepper = config.PepperConfig{
+					CurrentVersion: "v2",
+					Keys:           []config.PepperKeyConfig{{Version: "v2", Secret: "pepper-v2-0123456789abcdef"}},
+				}
+				s.server.PasswordHasher, err = password.NewHasher(s.config.Password.Hashing)
+				assert.NoError(t, err)
+
+				v1User := validUser
+				v1User.PasswordHash = currentHash
+				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+					PhoneNumber: validReqBody.PhoneNumber,
+				}).
+					Return(v1User, nil)
+				expectActiveCode(s)
+				expectIncrementAttempts(s, nil)
+				expectPasswordHistory(s, []repository.PasswordHistory{
+					{Id: "f6a7b8c9-3d4e-4f5a-9b6c-7d8e9f0a1b06", PasswordHash: rememberedHash},
+				}, nil)
+				expectResetPassword(s, 0, nil)
+			},
+			expectedHttpCode: http.StatusNoContent,
+		},
+		{
+			title:   "success deleting the passkeys of the user",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				expectActiveCode(s)
+				expectIncrementAttempts(s, nil)
+				expectPasswordHistory(s, []repository.PasswordHistory{}, nil)
+				expectResetPassword(s, 1, nil)
+				s.repository.EXPECT().InsertAuditLog(gomock.Any(), nil, repository.InsertAuditLogInput{
+					UserId:    test_helper.TestUserId,
+					Event:     auditEventPasskeysDeleted,
+					IpAddress: "192.0.2.1",
+					Details:   "count=1",
+				}).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusNoContent,
+		},
+		{
+			title:   "success",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s)
+				expectActiveCode(s)
+				expectIncrementAttempts(s, nil)
+				expectPasswordHistory(s, []repository.PasswordHistory{}, nil)
+				expectResetPassword(s, 0, nil)
+			},
+			expectedHttpCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			// a token issued before the reset must stop working
+			token, err := s.server.KeyManager.GenerateSignedToken(logoutTestUser, logoutTestSessionId)
+			assert.NoError(t, err)
+
+			var reqBody io.Reader
+			if tc.request != nil {
+				reqBodyJson, _ := json.Marshal(*tc.request)
+				reqBody = bytes.NewReader(reqBodyJson)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", reqBody)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/password/reset")
+
+			tc.expectations(t, s)
+
+			err = s.server.ResetPassword(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusNoContent {
+				assert.NoError(t, err)
+
+				_, err = s.server.KeyManager.ParseToken(context.Background(), s.revocationStore, token)
+				assert.Equal(t, authentication.ErrTokenRevoked, err)
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
@@ -1,31 +1,125 @@
 package handler
 
 import (
+	"os"
+	"time"
+
 	"user-service-sample/config"
 	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/breach"
+	"user-service-sample/utils/encryption"
+	"user-service-sample/utils/password"
+	"user-service-sample/utils/phone"
+	"user-service-sample/utils/revocation"
+	"user-service-sample/utils/sms"
 	"user-service-sample/utils/structvalidator"
+	"user-service-sample/utils/webauthn"
+
+	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
 )
 
 type Server struct {
-	Validator  *structvalidator.StructValidator
-	Config     *config.Config
-	Repository repository.RepositoryInterface
+	Validator       *structvalidator.StructValidator
+	Config          *config.Config
+	Repository      repository.RepositoryInterface
+	RevocationStore revocation.Store
+	// KeyManager holds the signing keys parsed from Config.Secret
+	KeyManager *authentication.KeyManager
+	// PasswordHasher hashes passwords with the algorithm & cost of Config.Password.Hashing
+	PasswordHasher *password.Hasher
+	// PasswordPolicy validates new passwords with the rules of Config.Password.Policy
+	PasswordPolicy *password.Policy
+	// PhoneNormalizer brings phone numbers of the countries allowed by Config.Phone to E.164
+	PhoneNormalizer *phone.Normalizer
+	// TOTPKeyring encrypts TOTP secrets with the keys of Config.MFA.TOTP.Encryption
+	TOTPKeyring *encryption.Keyring
+	// WebAuthn verifies the passkey ceremonies of the relying party of Config.WebAuthn
+	WebAuthn  *webauthn.RelyingParty
+	SMSSender sms.Sender
+	// StrengthRateLimiter limits password strength estimates per client IP address with Config.Password.Strength
+	StrengthRateLimiter middleware.RateLimiterStore
+	// PasskeyLoginRateLimiter limits passkey log in options per client IP address with Config.WebAuthn
+	PasskeyLoginRateLimiter middleware.RateLimiterStore
 }
 
 type NewServerOptions struct {
-	Config     *config.Config
-	Repository repository.RepositoryInterface
+	Config          *config.Config
+	Repository      repository.RepositoryInterface
+	RevocationStore revocation.Store
+	SMSSender       sms.Sender
+	// BreachedPasswords screens new passwords, none are screened when nil
+	BreachedPasswords breach.Index
 }
 
-func NewServer(opts NewServerOptions) *Server {
+// NewServer fails when the signing keys in the secret config can't be loaded,
+// or the password hashing, password policy, phone or TOTP encryption config is invalid.
+func NewServer(opts NewServerOptions) (*Server, error) {
+	if opts.RevocationStore == nil {
+		opts.RevocationStore = revocation.NewMemoryStore()
+	}
+	if opts.SMSSender == nil {
+		opts.SMSSender = sms.NewLogSender(os.Stderr)
+	}
+
+	keyManager, err := authentication.NewKeyManager(opts.Config.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordHasher, err := password.NewHasher(opts.Config.Password.Hashing)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordPolicy, err := password.NewPolicy(opts.Config.Password.Policy)
+	if err != nil {
+		return nil, err
+	}
+
+	phoneNormalizer, err := phone.NewNormalizer(opts.Config.Phone.GetAllowedCountries(), opts.Config.Phone.GetDefaultCountry())
+	if err != nil {
+		return nil, err
+	}
+
+	totpKeyring, err := encryption.NewKeyring(opts.Config.MFA.TOTP.Encryption)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Server{
 		Validator: structvalidator.NewWithOptions(
 			structvalidator.WithFieldTag("json"),
-			structvalidator.WithCustomTranslation("startswith", "{0} should start with {1}"),
 			structvalidator.WithCustomTranslation("required_without_all", "{0} is a required field when {1} not present"),
-			structvalidator.WithPasswordValidationTag(),
+			structvalidator.WithCustomTranslation("required_without", "{0} is a required field when {1} not present"),
+			structvalidator.WithCustomTranslation("excluded_with", "{0} must not be present along with {1}"),
+			structvalidator.WithPasswordValidationTags(passwordPolicy),
+			structvalidator.WithBreachedPasswordValidationTag(opts.BreachedPasswords),
+			structvalidator.WithPhoneNumberValidationTag(phoneNormalizer),
 		),
-		Config:     opts.Config,
-		Repository: opts.Repository,
-	}
+		Config:          opts.Config,
+		Repository:      opts.Repository,
+		RevocationStore: opts.RevocationStore,
+		KeyManager:      keyManager,
+		PasswordHasher:  passwordHasher,
+		PasswordPolicy:  passwordPolicy,
+		PhoneNormalizer: phoneNormalizer,
+		TOTPKeyring:     totpKeyring,
+		WebAuthn:        webauthn.NewRelyingParty(opts.Config.WebAuthn),
+		SMSSender:       opts.SMSSender,
+
+		StrengthRateLimiter:     newRateLimiter(opts.Config.Password.Strength.GetRateLimit()),
+		PasskeyLoginRateLimiter: newRateLimiter(opts.Config.WebAuthn.GetLoginOptionsRateLimit()),
+	}, nil
+}
+
+// newRateLimiter allows a burst of the requests of the rate limit, refilled evenly over its period.
+// The rate limit has its defaults set, e.g. by WebAuthnConfig.GetLoginOptionsRateLimit.
+func newRateLimiter(rateLimit config.RateLimitConfig) middleware.RateLimiterStore {
+	return middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+		Rate:      rate.Every(rateLimit.Per / time.Duration(rateLimit.Requests)),
+		Burst:     rateLimit.Requests,
+		ExpiresIn: rateLimit.Per,
+	})
 }
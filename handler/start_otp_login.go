@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/otp"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	otpLoginAcceptedMsg = "if the phone number is registered, a code to log in has been sent"
+	otpLoginMessage     = "Your log in code is %s, it expires in %d minutes. Never share this code."
+)
+
+// Send a code to log in without the password to the phone number, if it is registered
+// (POST /v1/login/otp/start)
+func (s *Server) StartOtpLogin(ctx echo.Context) error {
+	tracestr := "handler.StartOtpLogin"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	var req generated.StartOtpLoginJSONRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+	req.PhoneNumber = s.canonicalPhoneNumber(req.PhoneNumber)
+
+	// the response must not tell registered phone numbers apart
+	accepted := generated.MessageResponse{
+		Message: otpLoginAcceptedMsg,
+	}
+
+	user, err := s.Repository.GetUser(ctx.Request().Context(), repository.GetUserInput{
+		PhoneNumber: req.PhoneNumber,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ctx.JSON(http.StatusAccepted, accepted)
+		}
+		ctx.Logger().Errorf("%s, failed GetUser by PhoneNumber, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	// a pending user can't log in
+	if user.PhoneVerifiedAt == nil {
+		return ctx.JSON(http.StatusAccepted, accepted)
+	}
+	// a code sent while the codes of the user are locked couldn't be used
+	if otpLockedFor(user) > 0 {
+		return ctx.JSON(http.StatusAccepted, accepted)
+	}
+
+	// the resend interval of sendOneTimeCode throttles the codes sent to the phone number
+	if err := s.sendOneTimeCode(ctx, user.Id, user.PhoneNumber, otp.PurposeLogin, otpLoginMessage); err != nil {
+		ctx.Logger().Errorf("%s, failed sendOneTimeCode, err: %v", tracestr, err)
+	}
+
+	return ctx.JSON(http.StatusAccepted, accepted)
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"user-service-sample/repository"
+	"user-service-sample/utils/totp"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// errInvalidTOTPCode covers a wrong code and a code of a time step already used alike
+	errInvalidTOTPCode = errors.New("invalid TOTP code")
+)
+
+// verifyTOTP checks the code against the TOTP secret of the user and records its time step, so the code
+// can't be used twice, returning errInvalidTOTPCode when it doesn't match or was used.
+// confirm accepts the first code of an enrollment, otherwise the enrollment must be confirmed.
+func (s *Server) verifyTOTP(ctx echo.Context, userTOTP repository.UserTOTP, code string, confirm bool) error {
+	// the user id is bound to the ciphertext, a secret copied to another user doesn't decrypt
+	secret, err := s.TOTPKeyring.Decrypt(userTOTP.SecretCiphertext, []byte(userTOTP.UserId))
+	if err != nil {
+		return fmt.Errorf("TOTPKeyring.Decrypt: %w", err)
+	}
+
+	step, ok, err := totp.Validate(string(secret), code, time.Now())
+	if err != nil {
+		return fmt.Errorf("totp.Validate: %w", err)
+	}
+	if !ok || step <= userTOTP.LastUsedStep {
+		return errInvalidTOTPCode
+	}
+
+	// guarded by the last used step, a concurrent use of the same code fails here
+	err = s.Repository.UseUserTOTPStep(ctx.Request().Context(), nil, repository.UseUserTOTPStepInput{
+		UserId:  userTOTP.UserId,
+		Step:    step,
+		Confirm: confirm,
+	})
+	if err == sql.ErrNoRows {
+		return errInvalidTOTPCode
+	}
+	if err != nil {
+		return fmt.Errorf("UseUserTOTPStep: %w", err)
+	}
+
+	return nil
+}
@@ -1,12 +1,15 @@
 package handler
 
 import (
+	"database/sql"
 	"net/http"
+	"time"
 
 	"user-service-sample/generated"
 	"user-service-sample/repository"
 	"user-service-sample/utils/authentication"
 	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/otp"
 	"user-service-sample/utils/request_helper"
 	"user-service-sample/utils/response"
 	"user-service-sample/utils/string_helper"
@@ -14,7 +17,12 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-// Update user data
+const (
+	phoneChangeMessage             = "Your phone number change code is %s, it expires in %d minutes. Never share this code."
+	phoneChangeStartedNotification = "A change of the phone number of your account was requested. If this wasn't you, change your password right away."
+)
+
+// Update user data, a new phone number is pending until confirmed with the code sent to it
 // (PATCH /v1/user)
 func (s *Server) UpdateUser(ctx echo.Context) error {
 	tracestr := "handler.UpdateUser"
@@ -22,9 +30,9 @@ func (s *Server) UpdateUser(ctx echo.Context) error {
 		return err
 	}
 
-	claims, err := authentication.VerifyToken(ctx, s.Config.Secret)
+	claims, err := authentication.ClaimsFromContext(ctx)
 	if err != nil {
-		ctx.Logger().Infof("%s, VerifyToken failed, err: %+v", tracestr, err)
+		ctx.Logger().Infof("%s, ClaimsFromContext failed, err: %+v", tracestr, err)
 		return response.AccessForbidden(ctx)
 	}
 
@@ -44,10 +52,34 @@ func (s *Server) UpdateUser(ctx echo.Context) error {
 
 	// check phone number if req not empty & not the same with user current phone number
 	reqPhoneNumber := string_helper.GetAndTrimPointerStringValue(req.PhoneNumber)
-	if reqPhoneNumber != "" && reqPhoneNumber != user.PhoneNumber {
+	if reqPhoneNumber != "" {
+		reqPhoneNumber = s.canonicalPhoneNumber(reqPhoneNumber)
+	}
+	changePhoneNumber := reqPhoneNumber != "" && reqPhoneNumber != user.PhoneNumber
+	if changePhoneNumber {
+		if ok, err := s.reauthenticate(ctx, tracestr, user, req.CurrentPassword, req.Code, req.RecoveryCode); !ok {
+			return err
+		}
+
 		if err := s.checkIsPhoneAlreadyRegistered(ctx, tracestr, reqPhoneNumber); err != nil {
 			return err
 		}
+
+		// no code would be sent to another new phone number before the resend interval,
+		// the client is told to wait instead of waiting for a code that never comes
+		if user.PendingPhoneNumber != "" && reqPhoneNumber != user.PendingPhoneNumber {
+			latest, err := s.Repository.GetActiveOneTimeCode(ctx.Request().Context(), repository.GetActiveOneTimeCodeInput{
+				UserId:  user.Id,
+				Purpose: otp.PurposePhoneChange,
+			})
+			if err != nil && err != sql.ErrNoRows {
+				ctx.Logger().Errorf("%s, failed GetActiveOneTimeCode, err: %v", tracestr, err)
+				return response.InternalErrorResponse(ctx)
+			}
+			if wait := s.Config.OTP.GetResendInterval() - time.Since(latest.CreatedAt); err == nil && wait > 0 {
+				return response.TooManyRequests(ctx, wait)
+			}
+		}
 	}
 
 	// update current user data
@@ -58,8 +90,33 @@ func (s *Server) UpdateUser(ctx echo.Context) error {
 		}
 	}
 
-	return ctx.JSON(http.StatusOK, generated.UserDataResponse{
-		FullName:    user.FullName,
-		PhoneNumber: user.PhoneNumber,
+	if !changePhoneNumber {
+		return ctx.JSON(http.StatusOK, userDataResponse(user))
+	}
+
+	previousPendingPhoneNumber := user.PendingPhoneNumber
+	err = s.Repository.SetPendingPhoneNumber(ctx.Request().Context(), nil, repository.SetPendingPhoneNumberInput{
+		UserId:      user.Id,
+		PhoneNumber: reqPhoneNumber,
 	})
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed SetPendingPhoneNumber, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	user.PendingPhoneNumber = reqPhoneNumber
+
+	// the code is bound to the new phone number, a code sent to a previous one can't confirm it
+	if err := s.sendOneTimeCode(ctx, user.Id, reqPhoneNumber, otp.PurposePhoneChange, phoneChangeMessage); err != nil {
+		ctx.Logger().Errorf("%s, failed sendOneTimeCode, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	// the owner of the current phone number learns about a change they didn't start
+	if reqPhoneNumber != previousPendingPhoneNumber {
+		if err := s.SMSSender.Send(ctx.Request().Context(), user.PhoneNumber, phoneChangeStartedNotification); err != nil {
+			ctx.Logger().Errorf("%s, failed SMSSender.Send, err: %v", tracestr, err)
+		}
+	}
+
+	return ctx.JSON(http.StatusAccepted, userDataResponse(user))
 }
@@ -11,14 +11,17 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"user-service-sample/generated"
 	"user-service-sample/repository"
 	"user-service-sample/utils/authentication"
 	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/otp"
 	"user-service-sample/utils/response"
 	"user-service-sample/utils/string_helper"
 	"user-service-sample/utils/test_helper"
+	"user-service-sample/utils/totp"
 
 	"github.com/c2fo/testify/assert"
 	"github.com/golang/mock/gomock"
@@ -29,23 +32,93 @@ import (
 func TestUpdateUser(t *testing.T) {
 	var (
 		validReqBody = generated.UpdateUserJSONRequestBody{
-			FullName:    pointer.String(test_helper.TestUserName),
-			PhoneNumber: pointer.String(test_helper.TestUserPhone),
+			FullName:        pointer.String("user name updated"),
+			PhoneNumber:     pointer.String(test_helper.TestUserPhone),
+			CurrentPassword: pointer.String(test_helper.TestUserPassword),
 		}
 		validReqBodyNameOnly = generated.UpdateUserJSONRequestBody{
 			FullName: pointer.String("user name updated"),
 		}
 		validReqBodyPhoneOnly = generated.UpdateUserJSONRequestBody{
-			PhoneNumber: pointer.String("+625638301212"),
+			PhoneNumber:     pointer.String("+6285638301212"),
+			CurrentPassword: pointer.String(test_helper.TestUserPassword),
 		}
 
+		currentStep    = totp.Step(time.Now())
+		currentCode, _ = totp.Code(test_helper.TestTOTPSecret, currentStep)
+
+		phoneVerifiedAt = time.Now().Add(-24 * time.Hour)
+
 		validUser = repository.User{
-			Id:          test_helper.TestUserId,
-			PhoneNumber: "+6212345678900",
-			FullName:    test_helper.TestUserName,
+			Id:              test_helper.TestUserId,
+			PhoneNumber:     "+6281234567890",
+			FullName:        test_helper.TestUserName,
+			PasswordHash:    test_helper.TestUserArgon2idHash,
+			PhoneVerifiedAt: &phoneVerifiedAt,
 		}
 	)
 
+	mfaUser := validUser
+	mfaUser.TOTPEnabled = true
+
+	// another phone number change is in progress
+	pendingChangeUser := validUser
+	pendingChangeUser.PendingPhoneNumber = "+628111111111"
+
+	var insertedCodeHash string
+	expectSetPendingPhoneNumber := func(s *serverMock, phoneNumber string, err error) {
+		s.repository.EXPECT().SetPendingPhoneNumber(gomock.Any(), nil, repository.SetPendingPhoneNumberInput{
+			UserId:      validUser.Id,
+			PhoneNumber: phoneNumber,
+		}).
+			Return(err)
+	}
+	expectActiveCode := func(s *serverMock, code repository.OneTimeCode, err error) {
+		s.repository.EXPECT().GetActiveOneTimeCode(gomock.Any(), repository.GetActiveOneTimeCodeInput{
+			UserId:  validUser.Id,
+			Purpose: otp.PurposePhoneChange,
+		}).
+			Return(code, err)
+	}
+	expectInsertCode := func(s *serverMock, err error) {
+		s.repository.EXPECT().InsertOneTimeCode(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertOneTimeCodeInput{})).
+			DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.InsertOneTimeCodeInput) (repository.InsertOneTimeCodeOutput, error) {
+				assert.Equal(t, validUser.Id, input.UserId)
+				assert.Equal(t, otp.PurposePhoneChange, input.Purpose)
+				insertedCodeHash = input.CodeHash
+				return repository.InsertOneTimeCodeOutput{Id: "b8c9d0e1-5f6a-4b7c-9d8e-9f0a1b2c3d08"}, err
+			})
+	}
+	expectPhoneNumberAvailable := func(s *serverMock, phoneNumber string) {
+		s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+			PhoneNumber: phoneNumber,
+		}).
+			Return(repository.User{}, sql.ErrNoRows)
+	}
+	// the step-up of a user with MFA passes with the current code of the authenticator app
+	expectStepUp := func(t *testing.T, s *serverMock) {
+		secretCiphertext, err := s.server.TOTPKeyring.Encrypt([]byte(test_helper.TestTOTPSecret), []byte(test_helper.TestUserId))
+		assert.NoError(t, err)
+
+		s.repository.EXPECT().GetUserTOTP(gomock.Any(), test_helper.TestUserId).
+			Return(repository.UserTOTP{
+				UserId:           test_helper.TestUserId,
+				SecretCiphertext: secretCiphertext,
+				ConfirmedAt:      &phoneVerifiedAt,
+			}, nil)
+		s.repository.EXPECT().UseUserTOTPStep(gomock.Any(), nil, repository.UseUserTOTPStepInput{
+			UserId: test_helper.TestUserId,
+			Step:   currentStep,
+		}).
+			Return(nil)
+	}
+	expectGetUser := func(s *serverMock, user repository.User) {
+		s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+			Id: test_helper.TestUserId,
+		}).
+			Return(user, nil)
+	}
+
 	testCases := []struct {
 		title        string
 		jwt          string
@@ -57,9 +130,14 @@ func TestUpdateUser(t *testing.T) {
 		expectedHttpCode int
 		expectedErrMsg   string
 		expectedResp     generated.UserDataResponse
+		// expectedSMS is the phone number a code is sent to
+		expectedSMS string
+		// expectedNotified is whether the current phone number is notified of the change
+		expectedNotified bool
 	}{
 		{
 			title:            "request aborted",
+			jwt:              test_helper.TestUserJWT,
 			aborted:          true,
 			expectations:     func(t *testing.T, s *serverMock) {},
 			expectedHttpCode: http.StatusGone,
@@ -69,8 +147,8 @@ func TestUpdateUser(t *testing.T) {
 			title:            "auth token invalid",
 			jwt:              "Bearer invalid-token",
 			expectations:     func(t *testing.T, s *serverMock) {},
-			expectedHttpCode: http.StatusForbidden,
-			expectedErrMsg:   response.AccessForbiddenErrorMsg,
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.UnauthorizedErrorMsg,
 		},
 		{
 			title:            "empty request body",
@@ -117,7 +195,7 @@ func TestUpdateUser(t *testing.T) {
 			},
 			expectations:     func(t *testing.T, s *serverMock) {},
 			expectedHttpCode: http.StatusBadRequest,
-			expectedErrMsg:   `phoneNumber must be at least 10 characters in length`,
+			expectedErrMsg:   `phoneNumber must be a mobile phone number from Indonesia`,
 		},
 		{
 			title: "phoneNumber too long",
@@ -127,27 +205,27 @@ func TestUpdateUser(t *testing.T) {
 			},
 			expectations:     func(t *testing.T, s *serverMock) {},
 			expectedHttpCode: http.StatusBadRequest,
-			expectedErrMsg:   `phoneNumber must be a maximum of 13 characters in length`,
+			expectedErrMsg:   `phoneNumber must be a maximum of 25 characters in length`,
 		},
 		{
-			title: "phoneNumber do not start with +62",
+			title: "phoneNumber of a country not allowed",
 			jwt:   test_helper.TestUserJWT,
 			request: &generated.UpdateUserJSONRequestBody{
-				PhoneNumber: pointer.String("12345678901"),
+				PhoneNumber: pointer.String("+6591234567"),
 			},
 			expectations:     func(t *testing.T, s *serverMock) {},
 			expectedHttpCode: http.StatusBadRequest,
-			expectedErrMsg:   `phoneNumber should start with +62`,
+			expectedErrMsg:   `phoneNumber must be a mobile phone number from Indonesia`,
 		},
 		{
-			title: "phoneNumber not following E.164 phone format",
+			title: "phoneNumber not a number",
 			jwt:   test_helper.TestUserJWT,
 			request: &generated.UpdateUserJSONRequestBody{
 				PhoneNumber: pointer.String("+6212AbcDef"),
 			},
 			expectations:     func(t *testing.T, s *serverMock) {},
 			expectedHttpCode: http.StatusBadRequest,
-			expectedErrMsg:   `phoneNumber must be a valid E.164 formatted phone number`,
+			expectedErrMsg:   `phoneNumber must be a mobile phone number from Indonesia`,
 		},
 		{
 			title:   "error in Repository.GetUser by Id",
@@ -162,6 +240,41 @@ func TestUpdateUser(t *testing.T) {
 			expectedHttpCode: http.StatusInternalServerError,
 			expectedErrMsg:   response.InternalServerErrorMsg,
 		},
+		{
+			title: "phoneNumber change without the current password",
+			jwt:   test_helper.TestUserJWT,
+			request: &generated.UpdateUserJSONRequestBody{
+				PhoneNumber: validReqBodyPhoneOnly.PhoneNumber,
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser)
+			},
+			expectedHttpCode: http.StatusForbidden,
+			expectedErrMsg:   response.CurrentPasswordRequiredErrorMsg,
+		},
+		{
+			title: "phoneNumber change with an incorrect current password",
+			jwt:   test_helper.TestUserJWT,
+			request: &generated.UpdateUserJSONRequestBody{
+				PhoneNumber:     validReqBodyPhoneOnly.PhoneNumber,
+				CurrentPassword: pointer.String("wrong-password"),
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.IncorrectPasswordErrorMsg,
+		},
+		{
+			title:   "phoneNumber change of a user with MFA without a code",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBodyPhoneOnly,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, mfaUser)
+			},
+			expectedHttpCode: http.StatusForbidden,
+			expectedErrMsg:   response.MFAStepUpRequiredErrorMsg,
+		},
 		{
 			title:   "error in Repository.GetUser by PhoneNumber",
 			jwt:     test_helper.TestUserJWT,
@@ -201,17 +314,9 @@ func TestUpdateUser(t *testing.T) {
 		{
 			title:   "error in Repository.UpdateUser",
 			jwt:     test_helper.TestUserJWT,
-			request: &validReqBody,
+			request: &validReqBodyNameOnly,
 			expectations: func(t *testing.T, s *serverMock) {
-				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
-					Id: test_helper.TestUserId,
-				}).
-					Return(validUser, nil)
-
-				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
-					PhoneNumber: string_helper.GetAndTrimPointerStringValue(validReqBody.PhoneNumber),
-				}).
-					Return(repository.User{}, sql.ErrNoRows)
+				expectGetUser(s, validUser)
 
 				s.repository.EXPECT().UpdateUser(gomock.Any(), nil, gomock.Any()).
 					Return(errors.New(response.InternalServerErrorMsg))
@@ -220,51 +325,178 @@ func TestUpdateUser(t *testing.T) {
 			expectedErrMsg:   response.InternalServerErrorMsg,
 		},
 		{
-			title:   "success - both fullName and phoneNumber updated",
+			title:   "error in Repository.SetPendingPhoneNumber",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBodyPhoneOnly,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser)
+				expectPhoneNumberAvailable(s, *validReqBodyPhoneOnly.PhoneNumber)
+				expectSetPendingPhoneNumber(s, *validReqBodyPhoneOnly.PhoneNumber, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "error in Repository.InsertOneTimeCode",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBodyPhoneOnly,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser)
+				expectPhoneNumberAvailable(s, *validReqBodyPhoneOnly.PhoneNumber)
+				expectSetPendingPhoneNumber(s, *validReqBodyPhoneOnly.PhoneNumber, nil)
+				expectActiveCode(s, repository.OneTimeCode{}, sql.ErrNoRows)
+				expectInsertCode(s, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "success - fullName updated and code sent to the new phoneNumber",
 			jwt:     test_helper.TestUserJWT,
 			request: &validReqBody,
 			expectations: func(t *testing.T, s *serverMock) {
-				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
-					Id: test_helper.TestUserId,
-				}).
-					Return(validUser, nil)
+				expectGetUser(s, validUser)
+				expectPhoneNumberAvailable(s, *validReqBody.PhoneNumber)
 
-				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
-					PhoneNumber: string_helper.GetAndTrimPointerStringValue(validReqBody.PhoneNumber),
-				}).
-					Return(repository.User{}, sql.ErrNoRows)
+				s.repository.EXPECT().UpdateUser(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.User{})).
+					DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.User) error {
+						// the phone number only changes once confirmed
+						assert.Equal(t, validUser.PhoneNumber, input.PhoneNumber)
+						assert.Equal(t, *validReqBody.FullName, input.FullName)
+						return nil
+					})
 
-				s.repository.EXPECT().UpdateUser(gomock.Any(), nil, gomock.Any()).
-					Return(nil)
+				expectSetPendingPhoneNumber(s, *validReqBody.PhoneNumber, nil)
+				expectActiveCode(s, repository.OneTimeCode{}, sql.ErrNoRows)
+				expectInsertCode(s, nil)
 			},
-			expectedHttpCode: http.StatusOK,
+			expectedHttpCode: http.StatusAccepted,
 			expectedResp: generated.UserDataResponse{
-				FullName:    string_helper.GetAndTrimPointerStringValue(validReqBody.FullName),
-				PhoneNumber: string_helper.GetAndTrimPointerStringValue(validReqBody.PhoneNumber),
+				FullName:            string_helper.GetAndTrimPointerStringValue(validReqBody.FullName),
+				PhoneNumber:         validUser.PhoneNumber,
+				PhoneNumberVerified: true,
+				PendingPhoneNumber:  validReqBody.PhoneNumber,
 			},
+			expectedSMS:      *validReqBody.PhoneNumber,
+			expectedNotified: true,
 		},
 		{
-			title:   "success - only phoneNumber updated",
+			title:   "success - code sent to the new phoneNumber",
 			jwt:     test_helper.TestUserJWT,
 			request: &validReqBodyPhoneOnly,
 			expectations: func(t *testing.T, s *serverMock) {
-				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
-					Id: test_helper.TestUserId,
-				}).
-					Return(validUser, nil)
-
-				s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
-					PhoneNumber: string_helper.GetAndTrimPointerStringValue(validReqBodyPhoneOnly.PhoneNumber),
-				}).
-					Return(repository.User{}, sql.ErrNoRows)
+				expectGetUser(s, validUser)
+				expectPhoneNumberAvailable(s, *validReqBodyPhoneOnly.PhoneNumber)
+				expectSetPendingPhoneNumber(s, *validReqBodyPhoneOnly.PhoneNumber, nil)
+				expectActiveCode(s, repository.OneTimeCode{}, sql.ErrNoRows)
+				expectInsertCode(s, nil)
+			},
+			expectedHttpCode: http.StatusAccepted,
+			expectedResp: generated.UserDataResponse{
+				FullName:            validUser.FullName,
+				PhoneNumber:         validUser.PhoneNumber,
+				PhoneNumberVerified: true,
+				PendingPhoneNumber:  validReqBodyPhoneOnly.PhoneNumber,
+			},
+			expectedSMS:      *validReqBodyPhoneOnly.PhoneNumber,
+			expectedNotified: true,
+		},
+		{
+			title: "success - code sent to the new phoneNumber of a user with MFA",
+			jwt:   test_helper.TestUserJWT,
+			request: &generated.UpdateUserJSONRequestBody{
+				PhoneNumber: validReqBodyPhoneOnly.PhoneNumber,
+				Code:        &currentCode,
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, mfaUser)
+				expectStepUp(t, s)
+				expectPhoneNumberAvailable(s, *validReqBodyPhoneOnly.PhoneNumber)
+				expectSetPendingPhoneNumber(s, *validReqBodyPhoneOnly.PhoneNumber, nil)
+				expectActiveCode(s, repository.OneTimeCode{}, sql.ErrNoRows)
+				expectInsertCode(s, nil)
+			},
+			expectedHttpCode: http.StatusAccepted,
+			expectedResp: generated.UserDataResponse{
+				FullName:            validUser.FullName,
+				PhoneNumber:         validUser.PhoneNumber,
+				PhoneNumberVerified: true,
+				PendingPhoneNumber:  validReqBodyPhoneOnly.PhoneNumber,
+			},
+			expectedSMS:      *validReqBodyPhoneOnly.PhoneNumber,
+			expectedNotified: true,
+		},
+		{
+			title:   "another new phoneNumber too soon after the last code",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBodyPhoneOnly,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, pendingChangeUser)
+				expectPhoneNumberAvailable(s, *validReqBodyPhoneOnly.PhoneNumber)
+				expectActiveCode(s, repository.OneTimeCode{CreatedAt: time.Now().Add(-10 * time.Second)}, nil)
+			},
+			expectedHttpCode: http.StatusTooManyRequests,
+			expectedErrMsg:   response.TooManyRequestsErrorMsg,
+		},
+		{
+			title:   "success - another new phoneNumber after the resend interval",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBodyPhoneOnly,
+			expectations: func(t *testing.T, s *serverMock) {
+				lastCode := repository.OneTimeCode{CreatedAt: time.Now().Add(-2 * time.Minute)}
 
-				s.repository.EXPECT().UpdateUser(gomock.Any(), nil, gomock.Any()).
-					Return(nil)
+				expectGetUser(s, pendingChangeUser)
+				expectPhoneNumberAvailable(s, *validReqBodyPhoneOnly.PhoneNumber)
+				expectActiveCode(s, lastCode, nil)
+				expectSetPendingPhoneNumber(s, *validReqBodyPhoneOnly.PhoneNumber, nil)
+				expectActiveCode(s, lastCode, nil)
+				expectInsertCode(s, nil)
+			},
+			expectedHttpCode: http.StatusAccepted,
+			expectedResp: generated.UserDataResponse{
+				FullName:            validUser.FullName,
+				PhoneNumber:         validUser.PhoneNumber,
+				PhoneNumberVerified: true,
+				PendingPhoneNumber:  validReqBodyPhoneOnly.PhoneNumber,
+			},
+			expectedSMS:      *validReqBodyPhoneOnly.PhoneNumber,
+			expectedNotified: true,
+		},
+		{
+			title: "success - same pending phoneNumber again does not send another code",
+			jwt:   test_helper.TestUserJWT,
+			request: &generated.UpdateUserJSONRequestBody{
+				PhoneNumber:     pointer.String(pendingChangeUser.PendingPhoneNumber),
+				CurrentPassword: pointer.String(test_helper.TestUserPassword),
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, pendingChangeUser)
+				expectPhoneNumberAvailable(s, pendingChangeUser.PendingPhoneNumber)
+				expectSetPendingPhoneNumber(s, pendingChangeUser.PendingPhoneNumber, nil)
+				expectActiveCode(s, repository.OneTimeCode{CreatedAt: time.Now().Add(-10 * time.Second)}, nil)
+			},
+			expectedHttpCode: http.StatusAccepted,
+			expectedResp: generated.UserDataResponse{
+				FullName:            validUser.FullName,
+				PhoneNumber:         validUser.PhoneNumber,
+				PhoneNumberVerified: true,
+				PendingPhoneNumber:  pointer.String(pendingChangeUser.PendingPhoneNumber),
+			},
+		},
+		{
+			title: "success - current phoneNumber is not a change",
+			jwt:   test_helper.TestUserJWT,
+			request: &generated.UpdateUserJSONRequestBody{
+				PhoneNumber: pointer.String(validUser.PhoneNumber),
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser)
 			},
 			expectedHttpCode: http.StatusOK,
 			expectedResp: generated.UserDataResponse{
-				FullName:    validUser.FullName,
-				PhoneNumber: string_helper.GetAndTrimPointerStringValue(validReqBodyPhoneOnly.PhoneNumber),
+				FullName:            validUser.FullName,
+				PhoneNumber:         validUser.PhoneNumber,
+				PhoneNumberVerified: true,
 			},
 		},
 		{
@@ -282,8 +514,9 @@ func TestUpdateUser(t *testing.T) {
 			},
 			expectedHttpCode: http.StatusOK,
 			expectedResp: generated.UserDataResponse{
-				FullName:    string_helper.GetAndTrimPointerStringValue(validReqBodyNameOnly.FullName),
-				PhoneNumber: validUser.PhoneNumber,
+				FullName:            string_helper.GetAndTrimPointerStringValue(validReqBodyNameOnly.FullName),
+				PhoneNumber:         validUser.PhoneNumber,
+				PhoneNumberVerified: true,
 			},
 		},
 	}
@@ -320,7 +553,7 @@ func TestUpdateUser(t *testing.T) {
 
 			tc.expectations(t, s)
 
-			err := s.server.UpdateUser(ctx)
+			err := s.withAuth(t, s.server.UpdateUser)(ctx)
 
 			// Assertions
 			if tc.expectedHttpCode >= http.StatusOK && // code 2XX
@@ -331,6 +564,17 @@ func TestUpdateUser(t *testing.T) {
 				assert.NoError(t, err)
 				assert.Equal(t, tc.expectedHttpCode, rec.Code)
 				assert.Equal(t, string(expectedRespJson), strings.TrimSpace(rec.Body.String()))
+
+				sent := smsCodePattern.FindStringSubmatch(s.smsLog.String())
+				if tc.expectedSMS != "" {
+					if assert.Len(t, sent, 3) {
+						assert.Equal(t, tc.expectedSMS, sent[1])
+						assert.True(t, otp.Verify(insertedCodeHash, otp.PurposePhoneChange, test_helper.TestUserId, tc.expectedSMS, sent[2]))
+					}
+				} else {
+					assert.Empty(t, s.smsLog.String())
+				}
+				assert.Equal(t, tc.expectedNotified, strings.Contains(s.smsLog.String(), "to "+validUser.PhoneNumber+": "+phoneChangeStartedNotification))
 			} else {
 				assert.Equal(t, tc.expectedHttpCode, rec.Code)
 				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
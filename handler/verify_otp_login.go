@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/otp"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/string_helper"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Log In with the code sent to the phone number, will return JWT, or an MFA challenge to complete at /v1/login/mfa once the user enabled TOTP
+// (POST /v1/login/otp/verify)
+func (s *Server) VerifyOtpLogin(ctx echo.Context) error {
+	tracestr := "handler.VerifyOtpLogin"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	var req generated.VerifyOtpLoginJSONRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+	req.PhoneNumber = s.canonicalPhoneNumber(req.PhoneNumber)
+
+	// an unregistered phone number gets the same response as a wrong code
+	user, err := s.Repository.GetUser(ctx.Request().Context(), repository.GetUserInput{
+		PhoneNumber: req.PhoneNumber,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return response.InvalidOneTimeCode(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed GetUser by PhoneNumber, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if user.PhoneVerifiedAt == nil {
+		return response.InvalidOneTimeCode(ctx)
+	}
+	// so does a locked user, the lockout must not tell registered phone numbers apart either
+	if otpLockedFor(user) > 0 {
+		return response.InvalidOneTimeCode(ctx)
+	}
+
+	if err := s.verifyOneTimeCode(ctx, user.Id, user.PhoneNumber, otp.PurposeLogin, req.Code); err != nil {
+		if err == errInvalidOneTimeCode {
+			return s.otpFailed(ctx, tracestr, user.Id)
+		}
+		ctx.Logger().Errorf("%s, failed verifyOneTimeCode, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	s.otpPassed(ctx, tracestr, user)
+
+	return s.continueLogin(ctx, tracestr, user, string_helper.GetAndTrimPointerStringValue(req.DeviceLabel))
+}
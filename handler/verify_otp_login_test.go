@@ -0,0 +1,512 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/otp"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/xorcare/pointer"
+)
+
+func TestVerifyOtpLogin(t *testing.T) {
+
+	var (
+		validReqBody = generated.VerifyOtpLoginJSONRequestBody{
+			PhoneNumber: test_helper.TestUserPhone,
+			Code:        "123456",
+			DeviceLabel: pointer.String(" Work laptop "),
+		}
+
+		phoneVerifiedAt = time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+		validUser       = repository.User{
+			Id:              test_helper.TestUserId,
+			PhoneNumber:     test_helper.TestUserPhone,
+			FullName:        test_helper.TestUserName,
+			PasswordHash:    test_helper.TestUserArgon2idHash,
+			PhoneVerifiedAt: &phoneVerifiedAt,
+			LoginCount:      3,
+		}
+
+		activeCode = repository.OneTimeCode{
+			Id:        "f2a3b4c5-cccc-4f0a-9b4c-5d6e7f8a9b12",
+			CreatedAt: time.Now().Add(-time.Minute),
+			UserId:    test_helper.TestUserId,
+			Purpose:   otp.PurposeLogin,
+			CodeHash:  otp.Hash(otp.PurposeLogin, test_helper.TestUserId, test_helper.TestUserPhone, "123456"),
+			ExpiresAt: time.Now().Add(9 * time.Minute),
+		}
+
+		validSession = repository.InsertUserSessionOutput{
+			Id: "a3b4c5d6-dddd-4a1b-8c5d-6e7f8a9b0c13",
+		}
+	)
+
+	expectGetUser := func(s *serverMock, user repository.User, err error) {
+		s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+			PhoneNumber: validReqBody.PhoneNumber,
+		}).
+			Return(user, err)
+	}
+	expectActiveCode := func(s *serverMock, code repository.OneTimeCode, err error) {
+		s.repository.EXPECT().GetActiveOneTimeCode(gomock.Any(), repository.GetActiveOneTimeCodeInput{
+			UserId:  validUser.Id,
+			Purpose: otp.PurposeLogin,
+		}).
+			Return(code, err)
+	}
+	expectIncrementAttempts := func(s *serverMock, err error) {
+		s.repository.EXPECT().IncrementOneTimeCodeAttempts(gomock.Any(), nil, repository.IncrementOneTimeCodeAttemptsInput{
+			Id:          activeCode.Id,
+			MaxAttempts: 5,
+		}).
+			Return(err)
+	}
+	expectConsumeCode := func(s *serverMock, err error) {
+		s.repository.EXPECT().ConsumeOneTimeCode(gomock.Any(), nil, activeCode.Id).
+			Return(err)
+	}
+	// the wrong code counts toward the lockout of the codes of the user, locking them or not
+	expectOTPFailure := func(s *serverMock, locked bool, err error) {
+		s.repository.EXPECT().RecordOTPFailure(gomock.Any(), nil, repository.RecordOTPFailureInput{
+			UserId:          validUser.Id,
+			MaxFailures:     10,
+			LockoutDuration: 15 * time.Minute,
+		}).
+			Return(locked, err)
+	}
+	// the code of the user is found, counted and matches
+	expectCodeChecked := func(s *serverMock) {
+		expectGetUser(s, validUser, nil)
+		expectActiveCode(s, activeCode, nil)
+		expectIncrementAttempts(s, nil)
+	}
+
+	testCases := []struct {
+		title        string
+		request      *generated.VerifyOtpLoginJSONRequestBody
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+	}{
+		{
+			title:            "request aborted",
+			request:          &validReqBody,
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "empty request body",
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["code is a required field","phoneNumber is a required field"]}`,
+		},
+		{
+			title: "code not a number",
+			request: &generated.VerifyOtpLoginJSONRequestBody{
+				PhoneNumber: test_helper.TestUserPhone,
+				Code:        "12345a",
+			},
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["code must be a valid numeric value"]}`,
+		},
+		{
+			title:   "error in Repository.GetUser",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, repository.User{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "phone number not registered gets the same response as a wrong code",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, repository.User{}, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "pending registration can't log in",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				pendingUser := validUser
+				pendingUser.PhoneVerifiedAt = nil
+				expectGetUser(s, pendingUser, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "no code sent or expired",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectActiveCode(s, repository.OneTimeCode{}, sql.ErrNoRows)
+				expectOTPFailure(s, false, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "error in Repository.GetActiveOneTimeCode",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectActiveCode(s, repository.OneTimeCode{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "too many attempts",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectActiveCode(s, activeCode, nil)
+				expectIncrementAttempts(s, sql.ErrNoRows)
+				expectOTPFailure(s, false, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title: "wrong code is counted",
+			request: &generated.VerifyOtpLoginJSONRequestBody{
+				PhoneNumber: test_helper.TestUserPhone,
+				Code:        "654321",
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectCodeChecked(s)
+				expectOTPFailure(s, false, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title: "wrong code locking the codes of the user is audit logged",
+			request: &generated.VerifyOtpLoginJSONRequestBody{
+				PhoneNumber: test_helper.TestUserPhone,
+				Code:        "654321",
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectCodeChecked(s)
+				expectOTPFailure(s, true, nil)
+				s.repository.EXPECT().InsertAuditLog(gomock.Any(), nil, repository.InsertAuditLogInput{
+					UserId:    validUser.Id,
+					Event:     auditEventOTPLocked,
+					IpAddress: "192.0.2.1",
+					Details:   "failures=10",
+				}).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title: "error in Repository.RecordOTPFailure",
+			request: &generated.VerifyOtpLoginJSONRequestBody{
+				PhoneNumber: test_helper.TestUserPhone,
+				Code:        "654321",
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectCodeChecked(s)
+				expectOTPFailure(s, false, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "locked user gets the same response as a wrong code, even with the right code",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				lockedUntil := time.Now().Add(10 * time.Minute)
+				lockedUser := validUser
+				lockedUser.OTPLockedUntil = &lockedUntil
+				expectGetUser(s, lockedUser, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "code of another purpose",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				resetCode := activeCode
+				resetCode.CodeHash = otp.Hash(otp.PurposePasswordReset, test_helper.TestUserId, test_helper.TestUserPhone, "123456")
+				expectGetUser(s, validUser, nil)
+				expectActiveCode(s, resetCode, nil)
+				expectIncrementAttempts(s, nil)
+				expectOTPFailure(s, false, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "code used by a concurrent request",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectCodeChecked(s)
+				expectConsumeCode(s, sql.ErrNoRows)
+				expectOTPFailure(s, false, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "user with TOTP gets an MFA challenge",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				mfaUser := validUser
+				mfaUser.TOTPEnabled = true
+				expectGetUser(s, mfaUser, nil)
+				expectActiveCode(s, activeCode, nil)
+				expectIncrementAttempts(s, nil)
+				expectConsumeCode(s, nil)
+				s.repository.EXPECT().InsertMFAChallenge(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertMFAChallengeInput{})).
+					DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.InsertMFAChallengeInput) (repository.InsertMFAChallengeOutput, error) {
+						assert.Equal(t, validUser.Id, input.UserId)
+						assert.Equal(t, "Work laptop", input.DeviceLabel)
+						return repository.InsertMFAChallengeOutput{Id: "b4c5d6e7-eeee-4b2c-9d6e-7f8a9b0c1d14"}, nil
+					})
+			},
+			expectedHttpCode: http.StatusAccepted,
+		},
+		{
+			title:   "right code forgets the wrong codes of the user",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				failedUser := validUser
+				failedUser.TOTPEnabled = true
+				failedUser.OTPFailedAttempts = 3
+				expectGetUser(s, failedUser, nil)
+				expectActiveCode(s, activeCode, nil)
+				expectIncrementAttempts(s, nil)
+				expectConsumeCode(s, nil)
+				s.repository.EXPECT().ResetOTPFailures(gomock.Any(), nil, validUser.Id).
+					Return(nil)
+				s.repository.EXPECT().InsertMFAChallenge(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertMFAChallengeInput{})).
+					Return(repository.InsertMFAChallengeOutput{Id: "b4c5d6e7-eeee-4b2c-9d6e-7f8a9b0c1d14"}, nil)
+			},
+			expectedHttpCode: http.StatusAccepted,
+		},
+		{
+			title:   "logged in and counted like a log in with the password",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectCodeChecked(s)
+				expectConsumeCode(s, nil)
+				s.repository.EXPECT().InsertUserSession(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertUserSessionInput{})).
+					DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.InsertUserSessionInput) (repository.InsertUserSessionOutput, error) {
+						assert.Equal(t, validUser.Id, input.UserId)
+						assert.Equal(t, "Work laptop", input.DeviceLabel)
+						return validSession, nil
+					})
+				s.repository.EXPECT().InsertRefreshToken(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertRefreshTokenInput{})).
+					Return(repository.InsertRefreshTokenOutput{}, nil)
+				s.repository.EXPECT().IncrementUserLoginCount(gomock.Any(), nil, validUser).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			var reqBody io.Reader
+			if tc.request != nil {
+				reqBodyJson, _ := json.Marshal(*tc.request)
+				reqBody = bytes.NewReader(reqBodyJson)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", reqBody)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/login/otp/verify")
+
+			tc.expectations(t, s)
+
+			err := s.server.VerifyOtpLogin(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			switch tc.expectedHttpCode {
+			case http.StatusOK:
+				var resp generated.LoginResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.Equal(t, validUser.Id, resp.Id)
+				assert.NotEmpty(t, resp.RefreshToken)
+				assert.NotEmpty(t, resp.IdToken)
+
+				claims, parseErr := s.server.KeyManager.ParseToken(context.Background(), s.revocationStore, resp.Token)
+				assert.NoError(t, parseErr)
+				assert.Equal(t, validSession.Id, claims.SessionId)
+			case http.StatusAccepted:
+				var resp generated.MfaChallengeResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.NotEmpty(t, resp.MfaToken)
+				assert.Equal(t, []string{mfaMethodTOTP, mfaMethodRecoveryCode}, resp.Methods)
+			default:
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
+
+func TestOtpLoginLockoutAcrossCodes(t *testing.T) {
+	// Setup
+	s := setupServerMock(t)
+	defer s.cleanUp()
+
+	// 3 guesses per code, the 8th wrong code in a row locks the codes of the user
+	s.config.OTP.MaxAttempts = 3
+	s.config.OTP.LockoutThreshold = 8
+
+	phoneVerifiedAt := time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+	user := repository.User{
+		Id:              test_helper.TestUserId,
+		PhoneNumber:     test_helper.TestUserPhone,
+		FullName:        test_helper.TestUserName,
+		PhoneVerifiedAt: &phoneVerifiedAt,
+	}
+
+	// the repository keeps the active code of the user and the wrong codes counted on the user
+	var (
+		activeCode *repository.OneTimeCode
+		locks      int
+	)
+	s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{PhoneNumber: user.PhoneNumber}).
+		DoAndReturn(func(ctx context.Context, input repository.GetUserInput) (repository.User, error) {
+			return user, nil
+		}).AnyTimes()
+	s.repository.EXPECT().GetActiveOneTimeCode(gomock.Any(), repository.GetActiveOneTimeCodeInput{UserId: user.Id, Purpose: otp.PurposeLogin}).
+		DoAndReturn(func(ctx context.Context, input repository.GetActiveOneTimeCodeInput) (repository.OneTimeCode, error) {
+			if activeCode == nil {
+				return repository.OneTimeCode{}, sql.ErrNoRows
+			}
+			return *activeCode, nil
+		}).AnyTimes()
+	s.repository.EXPECT().InsertOneTimeCode(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertOneTimeCodeInput{})).
+		DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.InsertOneTimeCodeInput) (repository.InsertOneTimeCodeOutput, error) {
+			// the resend interval passed by the time the next code is asked for
+			activeCode = &repository.OneTimeCode{
+				Id:        "f2a3b4c5-cccc-4f0a-9b4c-5d6e7f8a9b12",
+				CreatedAt: time.Now().Add(-time.Hour),
+				UserId:    input.UserId,
+				Purpose:   input.Purpose,
+				CodeHash:  input.CodeHash,
+				ExpiresAt: input.ExpiresAt,
+			}
+			return repository.InsertOneTimeCodeOutput{Id: activeCode.Id}, nil
+		}).AnyTimes()
+	s.repository.EXPECT().IncrementOneTimeCodeAttempts(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.IncrementOneTimeCodeAttemptsInput{})).
+		DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.IncrementOneTimeCodeAttemptsInput) error {
+			if activeCode.Attempts >= input.MaxAttempts {
+				return sql.ErrNoRows
+			}
+			activeCode.Attempts++
+			return nil
+		}).AnyTimes()
+	s.repository.EXPECT().RecordOTPFailure(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.RecordOTPFailureInput{})).
+		DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.RecordOTPFailureInput) (bool, error) {
+			if user.OTPFailedAttempts+1 < input.MaxFailures {
+				user.OTPFailedAttempts++
+				return false, nil
+			}
+			lockedUntil := time.Now().Add(input.LockoutDuration)
+			user.OTPFailedAttempts, user.OTPLockedUntil = 0, &lockedUntil
+			return true, nil
+		}).AnyTimes()
+	s.repository.EXPECT().InsertAuditLog(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.InsertAuditLogInput{})).
+		DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.InsertAuditLogInput) error {
+			assert.Equal(t, auditEventOTPLocked, input.Event)
+			locks++
+			return nil
+		}).AnyTimes()
+
+	start := func() {
+		reqBodyJson, _ := json.Marshal(generated.StartOtpLoginJSONRequestBody{PhoneNumber: user.PhoneNumber})
+		req := httptest.NewRequest(echo.POST, "/", bytes.NewReader(reqBodyJson))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		assert.NoError(t, s.server.StartOtpLogin(echo.New().NewContext(req, rec)))
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	}
+	verify := func(code string) *httptest.ResponseRecorder {
+		reqBodyJson, _ := json.Marshal(generated.VerifyOtpLoginJSONRequestBody{PhoneNumber: user.PhoneNumber, Code: code})
+		req := httptest.NewRequest(echo.POST, "/", bytes.NewReader(reqBodyJson))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		assert.NoError(t, s.server.VerifyOtpLogin(echo.New().NewContext(req, rec)))
+		return rec
+	}
+	sentCodes := func() [][]string {
+		return smsCodePattern.FindAllStringSubmatch(s.smsLog.String(), -1)
+	}
+
+	// every new code only gives the guesses left to the user, not 3 more: the third code locks them
+	// after 2 wrong guesses, with a guess of its own left
+	for failures := 0; failures < 8; {
+		start()
+		for j := 0; j < 3 && failures < 8; j++ {
+			rec := verify("000000")
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), response.InvalidOneTimeCodeErrorMsg)
+			failures++
+		}
+	}
+	assert.Len(t, sentCodes(), 3)
+	assert.Equal(t, 1, locks)
+
+	// once locked no code is sent, and the right code of the last one is refused
+	start()
+	sent := sentCodes()
+	assert.Len(t, sent, 3)
+
+	rec := verify(sent[len(sent)-1][2])
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), response.InvalidOneTimeCodeErrorMsg)
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/otp"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	phoneChangedNotification = "The phone number of your account was changed. If this wasn't you, contact us right away."
+)
+
+// Confirm the pending phone number with the code sent to it, the previous phone number is notified
+// and every session is logged out
+// (POST /v1/user/phone/verify)
+func (s *Server) VerifyPhoneNumberChange(ctx echo.Context) error {
+	tracestr := "handler.VerifyPhoneNumberChange"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	claims, err := authentication.ClaimsFromContext(ctx)
+	if err != nil {
+		ctx.Logger().Infof("%s, ClaimsFromContext failed, err: %+v", tracestr, err)
+		return response.AccessForbidden(ctx)
+	}
+
+	var req generated.VerifyPhoneNumberChangeJSONRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+
+	user, err := s.Repository.GetUser(ctx.Request().Context(), repository.GetUserInput{
+		Id: claims.Id,
+	})
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed GetUser by Id, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if user.PendingPhoneNumber == "" {
+		return response.InvalidOneTimeCode(ctx)
+	}
+
+	codeId, err := s.checkOneTimeCode(ctx, user.Id, user.PendingPhoneNumber, otp.PurposePhoneChange, req.Code)
+	if err != nil {
+		if err == errInvalidOneTimeCode {
+			return response.InvalidOneTimeCode(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed checkOneTimeCode, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	// the phone number may have been registered since the code was sent,
+	// the code is kept for the user to retry with another phone number
+	if err := s.checkIsPhoneAlreadyRegistered(ctx, tracestr, user.PendingPhoneNumber); err != nil {
+		return err
+	}
+
+	if err := s.consumeOneTimeCode(ctx, codeId); err != nil {
+		if err == errInvalidOneTimeCode {
+			return response.InvalidOneTimeCode(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed consumeOneTimeCode, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	err = s.Repository.ChangeUserPhoneNumber(ctx.Request().Context(), nil, repository.ChangeUserPhoneNumberInput{
+		UserId:      user.Id,
+		PhoneNumber: user.PendingPhoneNumber,
+	})
+	if err != nil {
+		// replaced by another change since the user was read
+		if err == sql.ErrNoRows {
+			return response.InvalidOneTimeCode(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed ChangeUserPhoneNumber, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	previousPhoneNumber := user.PhoneNumber
+	user.PhoneNumber, user.PendingPhoneNumber = user.PendingPhoneNumber, ""
+
+	// the owner of the previous phone number learns about a change they didn't make
+	if err := s.SMSSender.Send(ctx.Request().Context(), previousPhoneNumber, phoneChangedNotification); err != nil {
+		ctx.Logger().Errorf("%s, failed SMSSender.Send, err: %v", tracestr, err)
+	}
+
+	// whoever holds a token of the user, a thief of one included, is logged out
+	if err := s.revokeAllUserSessions(ctx, user.Id); err != nil {
+		ctx.Logger().Errorf("%s, failed revokeAllUserSessions, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	return ctx.JSON(http.StatusOK, userDataResponse(user))
+}
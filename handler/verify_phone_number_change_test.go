@@ -0,0 +1,359 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/otp"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+)
+
+func TestVerifyPhoneNumberChange(t *testing.T) {
+	var (
+		validReqBody = generated.VerifyPhoneNumberChangeJSONRequestBody{
+			Code: "123456",
+		}
+
+		phoneVerifiedAt = time.Now().Add(-24 * time.Hour)
+
+		validUser = repository.User{
+			Id:                 test_helper.TestUserId,
+			PhoneNumber:        test_helper.TestUserPhone,
+			FullName:           test_helper.TestUserName,
+			PhoneVerifiedAt:    &phoneVerifiedAt,
+			PendingPhoneNumber: "+628111111111",
+		}
+
+		activeCode = repository.OneTimeCode{
+			Id:        "c9d0e1f2-6a7b-4c8d-8e9f-0a1b2c3d4e09",
+			CreatedAt: time.Now().Add(-time.Minute),
+			UserId:    test_helper.TestUserId,
+			Purpose:   otp.PurposePhoneChange,
+			CodeHash:  otp.Hash(otp.PurposePhoneChange, test_helper.TestUserId, "+628111111111", "123456"),
+			ExpiresAt: time.Now().Add(9 * time.Minute),
+		}
+	)
+
+	noPendingChangeUser := validUser
+	noPendingChangeUser.PendingPhoneNumber = ""
+
+	// the code was sent to a previous pending phone number
+	otherPhoneCode := activeCode
+	otherPhoneCode.CodeHash = otp.Hash(otp.PurposePhoneChange, test_helper.TestUserId, "+628222222222", "123456")
+
+	expectGetUser := func(s *serverMock, user repository.User, err error) {
+		s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+			Id: test_helper.TestUserId,
+		}).
+			Return(user, err)
+	}
+	expectActiveCode := func(s *serverMock, code repository.OneTimeCode, err error) {
+		s.repository.EXPECT().GetActiveOneTimeCode(gomock.Any(), repository.GetActiveOneTimeCodeInput{
+			UserId:  validUser.Id,
+			Purpose: otp.PurposePhoneChange,
+		}).
+			Return(code, err)
+	}
+	expectIncrementAttempts := func(s *serverMock, err error) {
+		s.repository.EXPECT().IncrementOneTimeCodeAttempts(gomock.Any(), nil, repository.IncrementOneTimeCodeAttemptsInput{
+			Id:          activeCode.Id,
+			MaxAttempts: 5,
+		}).
+			Return(err)
+	}
+	expectPhoneNumberOwner := func(s *serverMock, user repository.User, err error) {
+		s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+			PhoneNumber: validUser.PendingPhoneNumber,
+		}).
+			Return(user, err)
+	}
+	expectConsumeCode := func(s *serverMock, err error) {
+		s.repository.EXPECT().ConsumeOneTimeCode(gomock.Any(), nil, activeCode.Id).
+			Return(err)
+	}
+	expectChangePhoneNumber := func(s *serverMock, err error) {
+		s.repository.EXPECT().ChangeUserPhoneNumber(gomock.Any(), nil, repository.ChangeUserPhoneNumberInput{
+			UserId:      validUser.Id,
+			PhoneNumber: validUser.PendingPhoneNumber,
+		}).
+			Return(err)
+	}
+	// the code is checked & consumed, the new phone number is still available
+	expectValidCode := func(s *serverMock) {
+		expectGetUser(s, validUser, nil)
+		expectActiveCode(s, activeCode, nil)
+		expectIncrementAttempts(s, nil)
+		expectPhoneNumberOwner(s, repository.User{}, sql.ErrNoRows)
+		expectConsumeCode(s, nil)
+	}
+
+	testCases := []struct {
+		title        string
+		jwt          string
+		request      *generated.VerifyPhoneNumberChangeJSONRequestBody
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+		expectedResp     generated.UserDataResponse
+		// expectedNotification is true once the phone number changed
+		expectedNotification bool
+	}{
+		{
+			title:            "request aborted",
+			jwt:              test_helper.TestUserJWT,
+			request:          &validReqBody,
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "auth token invalid",
+			jwt:              "Bearer invalid-token",
+			request:          &validReqBody,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.UnauthorizedErrorMsg,
+		},
+		{
+			title:            "empty request body",
+			jwt:              test_helper.TestUserJWT,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["code is a required field"]}`,
+		},
+		{
+			title:   "error in Repository.GetUser by Id",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, repository.User{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "no pending phone number change",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, noPendingChangeUser, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "no active code",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectActiveCode(s, repository.OneTimeCode{}, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "error in Repository.IncrementOneTimeCodeAttempts",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectActiveCode(s, activeCode, nil)
+				expectIncrementAttempts(s, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title: "wrong code",
+			jwt:   test_helper.TestUserJWT,
+			request: &generated.VerifyPhoneNumberChangeJSONRequestBody{
+				Code: "654321",
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectActiveCode(s, activeCode, nil)
+				expectIncrementAttempts(s, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "code sent to another phone number",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectActiveCode(s, otherPhoneCode, nil)
+				expectIncrementAttempts(s, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "new phone number registered in the meantime",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectActiveCode(s, activeCode, nil)
+				expectIncrementAttempts(s, nil)
+				expectPhoneNumberOwner(s, repository.User{Id: "d0e1f2a3-7b8c-4d9e-9f0a-1b2c3d4e5f10"}, nil)
+			},
+			expectedHttpCode: http.StatusConflict,
+			expectedErrMsg:   response.PhoneAlreadyRegisteredErrorMsg,
+		},
+		{
+			title:   "code used by a concurrent request",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectActiveCode(s, activeCode, nil)
+				expectIncrementAttempts(s, nil)
+				expectPhoneNumberOwner(s, repository.User{}, sql.ErrNoRows)
+				expectConsumeCode(s, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "pending phone number replaced by another change",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectValidCode(s)
+				expectChangePhoneNumber(s, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "error in Repository.ChangeUserPhoneNumber",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectValidCode(s)
+				expectChangePhoneNumber(s, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "error in Repository.RevokeUserRefreshTokens",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectValidCode(s)
+				expectChangePhoneNumber(s, nil)
+				s.repository.EXPECT().RevokeUserRefreshTokens(gomock.Any(), nil, test_helper.TestUserId).
+					Return(errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode:     http.StatusInternalServerError,
+			expectedErrMsg:       response.InternalServerErrorMsg,
+			expectedNotification: true,
+		},
+		{
+			title:   "success",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectValidCode(s)
+				expectChangePhoneNumber(s, nil)
+				s.repository.EXPECT().RevokeUserRefreshTokens(gomock.Any(), nil, test_helper.TestUserId).
+					Return(nil)
+				s.repository.EXPECT().RevokeUserSessions(gomock.Any(), nil, test_helper.TestUserId).
+					Return([]string{logoutTestSessionId}, nil)
+				s.repository.EXPECT().DeleteUserPasskeys(gomock.Any(), nil, test_helper.TestUserId).
+					Return(int64(0), nil)
+			},
+			expectedHttpCode: http.StatusOK,
+			expectedResp: generated.UserDataResponse{
+				FullName:            validUser.FullName,
+				PhoneNumber:         validUser.PendingPhoneNumber,
+				PhoneNumberVerified: true,
+			},
+			expectedNotification: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			// a token issued before the change must stop working
+			token, err := s.server.KeyManager.GenerateSignedToken(validUser, logoutTestSessionId)
+			assert.NoError(t, err)
+
+			var reqBody io.Reader
+			if tc.request != nil {
+				reqBodyJson, _ := json.Marshal(*tc.request)
+				reqBody = bytes.NewReader(reqBodyJson)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", reqBody)
+			req.Header.Set(authentication.AuthHeaderKey, tc.jwt)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/user/phone/verify")
+
+			tc.expectations(t, s)
+
+			err = s.withAuth(t, s.server.VerifyPhoneNumberChange)(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusOK {
+				expectedRespJson, _ := json.Marshal(tc.expectedResp)
+
+				assert.NoError(t, err)
+				assert.Equal(t, string(expectedRespJson), strings.TrimSpace(rec.Body.String()))
+
+				_, err = s.server.KeyManager.ParseToken(context.Background(), s.revocationStore, token)
+				assert.Equal(t, authentication.ErrTokenRevoked, err)
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+
+			// the previous phone number is told about the change
+			if tc.expectedNotification {
+				assert.Contains(t, s.smsLog.String(), "to "+validUser.PhoneNumber+": "+phoneChangedNotification)
+			} else {
+				assert.Empty(t, s.smsLog.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/otp"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	registrationVerifiedMsg = "phone number verified, the user may log in"
+)
+
+// Verify the phone number of a pending registration with the code sent to it
+// (POST /v1/register/verify)
+func (s *Server) VerifyRegistration(ctx echo.Context) error {
+	tracestr := "handler.VerifyRegistration"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	var req generated.VerifyRegistrationJSONRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+	req.PhoneNumber = s.canonicalPhoneNumber(req.PhoneNumber)
+
+	// an unknown or already verified phone number gets the same response as a wrong code
+	user, err := s.Repository.GetUser(ctx.Request().Context(), repository.GetUserInput{
+		PhoneNumber: req.PhoneNumber,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return response.InvalidOneTimeCode(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed GetUser by PhoneNumber, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if user.PhoneVerifiedAt != nil {
+		return response.InvalidOneTimeCode(ctx)
+	}
+
+	// the code only proves the phone number is held, the password proves the registration is the
+	// one of the holder: a registration replaced after the code was sent is not verified by it
+	codeId, err := s.checkOneTimeCode(ctx, user.Id, user.PhoneNumber, otp.PurposePhoneVerification, req.Code)
+	if err != nil {
+		if err == errInvalidOneTimeCode {
+			return response.InvalidOneTimeCode(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed checkOneTimeCode, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	match, _, err := s.PasswordHasher.Verify(req.Password, user.PasswordHash, user.Salt)
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed Verify password of user %s, err: %v", tracestr, user.Id, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if !match {
+		return response.RegistrationPasswordMismatch(ctx)
+	}
+	if err := s.consumeOneTimeCode(ctx, codeId); err != nil {
+		if err == errInvalidOneTimeCode {
+			return response.InvalidOneTimeCode(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed consumeOneTimeCode, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	err = s.Repository.VerifyUserPhone(ctx.Request().Context(), nil, user.Id)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.Logger().Errorf("%s, failed VerifyUserPhone, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	return ctx.JSON(http.StatusOK, generated.MessageResponse{
+		Message: registrationVerifiedMsg,
+	})
+}
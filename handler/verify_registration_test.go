@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/otp"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+)
+
+func TestVerifyRegistration(t *testing.T) {
+
+	var (
+		phoneVerifiedAt = time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+
+		validReqBody = generated.VerifyRegistrationJSONRequestBody{
+			PhoneNumber: test_helper.TestUserPhone,
+			Code:        "123456",
+			Password:    test_helper.TestUserPassword,
+		}
+
+		pendingUser = repository.User{
+			Id:           test_helper.TestUserId,
+			PhoneNumber:  test_helper.TestUserPhone,
+			FullName:     test_helper.TestUserName,
+			PasswordHash: test_helper.TestUserArgon2idHash,
+		}
+
+		activeCode = repository.OneTimeCode{
+			Id:        "a7b8c9d0-4e5f-4a6b-8c7d-8e9f0a1b2c07",
+			CreatedAt: time.Now().Add(-time.Minute),
+			UserId:    test_helper.TestUserId,
+			Purpose:   otp.PurposePhoneVerification,
+			CodeHash:  otp.Hash(otp.PurposePhoneVerification, test_helper.TestUserId, test_helper.TestUserPhone, "123456"),
+			ExpiresAt: time.Now().Add(9 * time.Minute),
+		}
+	)
+
+	verifiedUser := pendingUser
+	verifiedUser.PhoneVerifiedAt = &phoneVerifiedAt
+
+	expectGetUser := func(s *serverMock, user repository.User, err error) {
+		s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{
+			PhoneNumber: validReqBody.PhoneNumber,
+		}).
+			Return(user, err)
+	}
+	expectActiveCode := func(s *serverMock) {
+		s.repository.EXPECT().GetActiveOneTimeCode(gomock.Any(), repository.GetActiveOneTimeCodeInput{
+			UserId:  pendingUser.Id,
+			Purpose: otp.PurposePhoneVerification,
+		}).
+			Return(activeCode, nil)
+	}
+	expectIncrementAttempts := func(s *serverMock, err error) {
+		s.repository.EXPECT().IncrementOneTimeCodeAttempts(gomock.Any(), nil, repository.IncrementOneTimeCodeAttemptsInput{
+			Id:          activeCode.Id,
+			MaxAttempts: 5,
+		}).
+			Return(err)
+	}
+	expectConsumeCode := func(s *serverMock, err error) {
+		s.repository.EXPECT().ConsumeOneTimeCode(gomock.Any(), nil, activeCode.Id).
+			Return(err)
+	}
+
+	testCases := []struct {
+		title        string
+		request      *generated.VerifyRegistrationJSONRequestBody
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+	}{
+		{
+			title:            "request aborted",
+			request:          &validReqBody,
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "empty request body",
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["code is a required field","password is a required field","phoneNumber is a required field"]}`,
+		},
+		{
+			title: "code not numeric",
+			request: &generated.VerifyRegistrationJSONRequestBody{
+				PhoneNumber: validReqBody.PhoneNumber,
+				Code:        "12ab56",
+				Password:    validReqBody.Password,
+			},
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `code must be a valid numeric value`,
+		},
+		{
+			title:   "error in Repository.GetUser",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, repository.User{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "phone number not registered",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, repository.User{}, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "phone number already verified",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, verifiedUser, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "no active code",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, pendingUser, nil)
+				s.repository.EXPECT().GetActiveOneTimeCode(gomock.Any(), gomock.Any()).
+					Return(repository.OneTimeCode{}, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "attempts exhausted",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, pendingUser, nil)
+				expectActiveCode(s)
+				expectIncrementAttempts(s, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title: "wrong code",
+			request: &generated.VerifyRegistrationJSONRequestBody{
+				PhoneNumber: validReqBody.PhoneNumber,
+				Code:        "654321",
+				Password:    validReqBody.Password,
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, pendingUser, nil)
+				expectActiveCode(s)
+				expectIncrementAttempts(s, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title: "registration replaced since the code was sent",
+			request: &generated.VerifyRegistrationJSONRequestBody{
+				PhoneNumber: validReqBody.PhoneNumber,
+				Code:        validReqBody.Code,
+				Password:    "an0ther$Passw0rd",
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, pendingUser, nil)
+				expectActiveCode(s)
+				expectIncrementAttempts(s, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.RegistrationPasswordMismatchErrorMsg,
+		},
+		{
+			title:   "code used by a concurrent request",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, pendingUser, nil)
+				expectActiveCode(s)
+				expectIncrementAttempts(s, nil)
+				expectConsumeCode(s, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "error in Repository.VerifyUserPhone",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, pendingUser, nil)
+				expectActiveCode(s)
+				expectIncrementAttempts(s, nil)
+				expectConsumeCode(s, nil)
+				s.repository.EXPECT().VerifyUserPhone(gomock.Any(), nil, pendingUser.Id).
+					Return(errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "success",
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, pendingUser, nil)
+				expectActiveCode(s)
+				expectIncrementAttempts(s, nil)
+				expectConsumeCode(s, nil)
+				s.repository.EXPECT().VerifyUserPhone(gomock.Any(), nil, pendingUser.Id).
+					Return(nil)
+			},
+			expectedHttpCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			var reqBody io.Reader
+			if tc.request != nil {
+				reqBodyJson, _ := json.Marshal(*tc.request)
+				reqBody = bytes.NewReader(reqBodyJson)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", reqBody)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/register/verify")
+
+			tc.expectations(t, s)
+
+			err := s.server.VerifyRegistration(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusOK {
+				var resp generated.MessageResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.Equal(t, registrationVerifiedMsg, resp.Message)
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
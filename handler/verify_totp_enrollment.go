@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/request_helper"
+	"user-service-sample/utils/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Confirm the TOTP enrollment with a code of the authenticator app and the current password, log in then asks for a code.
+// The user gets recovery codes for when the authenticator app is lost
+// (POST /v1/user/mfa/totp/verify)
+func (s *Server) VerifyTotpEnrollment(ctx echo.Context) error {
+	tracestr := "handler.VerifyTotpEnrollment"
+	if err := context_helper.CheckCtxErr(ctx); err != nil {
+		return err
+	}
+
+	claims, err := authentication.ClaimsFromContext(ctx)
+	if err != nil {
+		ctx.Logger().Infof("%s, ClaimsFromContext failed, err: %+v", tracestr, err)
+		return response.AccessForbidden(ctx)
+	}
+
+	var req generated.VerifyTotpEnrollmentJSONRequestBody
+	if messages := request_helper.BindAndValidateReqBody(ctx, s.Validator, &req); len(messages) > 0 {
+		return response.StandardErrorResponse(ctx, http.StatusBadRequest, messages)
+	}
+
+	user, err := s.Repository.GetUser(ctx.Request().Context(), repository.GetUserInput{
+		Id: claims.Id,
+	})
+	if err != nil {
+		ctx.Logger().Errorf("%s, failed GetUser by Id, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if ok, err := s.checkCurrentPassword(ctx, tracestr, user, &req.CurrentPassword); !ok {
+		return err
+	}
+
+	userTOTP, err := s.Repository.GetUserTOTP(ctx.Request().Context(), claims.Id)
+	if err != nil {
+		// no enrollment in progress
+		if err == sql.ErrNoRows {
+			return response.InvalidOneTimeCode(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed GetUserTOTP, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+	if userTOTP.ConfirmedAt != nil {
+		return response.TOTPAlreadyEnabled(ctx)
+	}
+
+	if err := s.verifyTOTP(ctx, userTOTP, req.Code, true); err != nil {
+		if err == errInvalidTOTPCode {
+			return response.InvalidOneTimeCode(ctx)
+		}
+		ctx.Logger().Errorf("%s, failed verifyTOTP, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	codes, err := s.replaceRecoveryCodes(ctx, userTOTP.UserId)
+	if err != nil {
+		// TOTP is enabled regardless, the user may regenerate the codes
+		ctx.Logger().Errorf("%s, failed replaceRecoveryCodes, err: %v", tracestr, err)
+		return response.InternalErrorResponse(ctx)
+	}
+
+	return ctx.JSON(http.StatusOK, generated.RecoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+}
@@ -0,0 +1,329 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-service-sample/generated"
+	"user-service-sample/repository"
+	"user-service-sample/utils/authentication"
+	"user-service-sample/utils/context_helper"
+	"user-service-sample/utils/recoverycode"
+	"user-service-sample/utils/response"
+	"user-service-sample/utils/test_helper"
+	"user-service-sample/utils/totp"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+)
+
+func TestVerifyTotpEnrollment(t *testing.T) {
+	var (
+		currentStep    = totp.Step(time.Now())
+		currentCode, _ = totp.Code(test_helper.TestTOTPSecret, currentStep)
+		staleCode, _   = totp.Code(test_helper.TestTOTPSecret, currentStep-10)
+
+		validReqBody = generated.VerifyTotpEnrollmentJSONRequestBody{
+			Code:            currentCode,
+			CurrentPassword: test_helper.TestUserPassword,
+		}
+
+		confirmedAt = time.Now().Add(-time.Hour)
+
+		validUser = repository.User{
+			Id:           test_helper.TestUserId,
+			PhoneNumber:  test_helper.TestUserPhone,
+			PasswordHash: test_helper.TestUserArgon2idHash,
+		}
+	)
+
+	// userTOTP is the enrollment of the user with the secret encrypted by the server mock for ownerId
+	userTOTP := func(t *testing.T, s *serverMock, ownerId string) repository.UserTOTP {
+		secretCiphertext, err := s.server.TOTPKeyring.Encrypt([]byte(test_helper.TestTOTPSecret), []byte(ownerId))
+		assert.NoError(t, err)
+
+		return repository.UserTOTP{
+			UserId:           test_helper.TestUserId,
+			CreatedAt:        time.Now().Add(-time.Minute),
+			SecretCiphertext: secretCiphertext,
+		}
+	}
+	expectGetUser := func(s *serverMock, user repository.User, err error) {
+		s.repository.EXPECT().GetUser(gomock.Any(), repository.GetUserInput{Id: test_helper.TestUserId}).
+			Return(user, err)
+	}
+	expectGetUserTOTP := func(s *serverMock, output repository.UserTOTP, err error) {
+		s.repository.EXPECT().GetUserTOTP(gomock.Any(), test_helper.TestUserId).
+			Return(output, err)
+	}
+	expectUseStep := func(s *serverMock, err error) {
+		s.repository.EXPECT().UseUserTOTPStep(gomock.Any(), nil, repository.UseUserTOTPStepInput{
+			UserId:  test_helper.TestUserId,
+			Step:    currentStep,
+			Confirm: true,
+		}).
+			Return(err)
+	}
+	// a set of 10 recovery codes is stored for the user, the response holds them in plain text
+	var storedCodeHashes []string
+	expectReplaceRecoveryCodes := func(s *serverMock, err error) {
+		s.repository.EXPECT().ReplaceMFARecoveryCodes(gomock.Any(), nil, gomock.AssignableToTypeOf(repository.ReplaceMFARecoveryCodesInput{})).
+			DoAndReturn(func(ctx context.Context, tx *sql.Tx, input repository.ReplaceMFARecoveryCodesInput) error {
+				assert.Equal(t, test_helper.TestUserId, input.UserId)
+				assert.Len(t, input.CodeHashes, 10)
+				storedCodeHashes = input.CodeHashes
+				return err
+			})
+	}
+
+	testCases := []struct {
+		title        string
+		jwt          string
+		request      *generated.VerifyTotpEnrollmentJSONRequestBody
+		aborted      bool
+		expectations func(t *testing.T, s *serverMock)
+
+		expectedHttpCode int
+		expectedErrMsg   string
+	}{
+		{
+			title:            "request aborted",
+			jwt:              test_helper.TestUserJWT,
+			request:          &validReqBody,
+			aborted:          true,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusGone,
+			expectedErrMsg:   context_helper.ErrRequestCanceled.Error(),
+		},
+		{
+			title:            "auth token invalid",
+			jwt:              "Bearer invalid-token",
+			request:          &validReqBody,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedErrMsg:   response.UnauthorizedErrorMsg,
+		},
+		{
+			title:            "empty request body",
+			jwt:              test_helper.TestUserJWT,
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["code is a required field","currentPassword is a required field"]}`,
+		},
+		{
+			title: "code not 6 digits",
+			jwt:   test_helper.TestUserJWT,
+			request: &generated.VerifyTotpEnrollmentJSONRequestBody{
+				Code:            "1234",
+				CurrentPassword: test_helper.TestUserPassword,
+			},
+			expectations:     func(t *testing.T, s *serverMock) {},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   `{"messages":["code must be 6 characters in length"]}`,
+		},
+		{
+			title:   "error in Repository.GetUser by Id",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, repository.User{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title: "incorrect current password",
+			jwt:   test_helper.TestUserJWT,
+			request: &generated.VerifyTotpEnrollmentJSONRequestBody{
+				Code:            validReqBody.Code,
+				CurrentPassword: "wrong-password",
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.IncorrectPasswordErrorMsg,
+		},
+		{
+			title:   "error in Repository.GetUserTOTP",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectGetUserTOTP(s, repository.UserTOTP{}, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "no enrollment in progress",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectGetUserTOTP(s, repository.UserTOTP{}, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "TOTP already enabled",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				confirmed := userTOTP(t, s, test_helper.TestUserId)
+				confirmed.ConfirmedAt = &confirmedAt
+				expectGetUser(s, validUser, nil)
+				expectGetUserTOTP(s, confirmed, nil)
+			},
+			expectedHttpCode: http.StatusConflict,
+			expectedErrMsg:   response.TOTPAlreadyEnabledErrorMsg,
+		},
+		{
+			title:   "secret encrypted for another user",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectGetUserTOTP(s, userTOTP(t, s, "another-user-id"), nil)
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title: "wrong code",
+			jwt:   test_helper.TestUserJWT,
+			request: &generated.VerifyTotpEnrollmentJSONRequestBody{
+				Code:            staleCode,
+				CurrentPassword: test_helper.TestUserPassword,
+			},
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectGetUserTOTP(s, userTOTP(t, s, test_helper.TestUserId), nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "code of a time step already used",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				used := userTOTP(t, s, test_helper.TestUserId)
+				used.LastUsedStep = currentStep
+				expectGetUser(s, validUser, nil)
+				expectGetUserTOTP(s, used, nil)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "code used by a concurrent request",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectGetUserTOTP(s, userTOTP(t, s, test_helper.TestUserId), nil)
+				expectUseStep(s, sql.ErrNoRows)
+			},
+			expectedHttpCode: http.StatusBadRequest,
+			expectedErrMsg:   response.InvalidOneTimeCodeErrorMsg,
+		},
+		{
+			title:   "error in Repository.UseUserTOTPStep",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectGetUserTOTP(s, userTOTP(t, s, test_helper.TestUserId), nil)
+				expectUseStep(s, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "error in Repository.ReplaceMFARecoveryCodes",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectGetUserTOTP(s, userTOTP(t, s, test_helper.TestUserId), nil)
+				expectUseStep(s, nil)
+				expectReplaceRecoveryCodes(s, errors.New(response.InternalServerErrorMsg))
+			},
+			expectedHttpCode: http.StatusInternalServerError,
+			expectedErrMsg:   response.InternalServerErrorMsg,
+		},
+		{
+			title:   "success",
+			jwt:     test_helper.TestUserJWT,
+			request: &validReqBody,
+			expectations: func(t *testing.T, s *serverMock) {
+				expectGetUser(s, validUser, nil)
+				expectGetUserTOTP(s, userTOTP(t, s, test_helper.TestUserId), nil)
+				expectUseStep(s, nil)
+				expectReplaceRecoveryCodes(s, nil)
+			},
+			expectedHttpCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			// Setup
+			s := setupServerMock(t)
+			defer s.cleanUp()
+
+			var reqBody io.Reader
+			if tc.request != nil {
+				reqBodyJson, _ := json.Marshal(*tc.request)
+				reqBody = bytes.NewReader(reqBodyJson)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", reqBody)
+			req.Header.Set(authentication.AuthHeaderKey, tc.jwt)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			if tc.aborted {
+				// simulate request aborted
+				c, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(c)
+			}
+
+			ctx := e.NewContext(req, rec)
+			ctx.SetPath("/v1/user/mfa/totp/verify")
+
+			tc.expectations(t, s)
+
+			err := s.withAuth(t, s.server.VerifyTotpEnrollment)(ctx)
+
+			// Assertions
+			assert.Equal(t, tc.expectedHttpCode, rec.Code)
+			if tc.expectedHttpCode == http.StatusOK {
+				var resp generated.RecoveryCodesResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				assert.NoError(t, err)
+				assert.Len(t, resp.RecoveryCodes, 10)
+				for i, code := range resp.RecoveryCodes {
+					assert.Regexp(t, `^[2-9a-z]{4}(-[2-9a-z]{4}){3}$`, code)
+					assert.Equal(t, recoverycode.Hash(test_helper.TestUserId, code), storedCodeHashes[i])
+				}
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.expectedErrMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+-- Phone verification of existing databases, new databases are created from database.sql with these columns.
+-- Run it once before deploying the phone verification: a NULL 'phone_verified_at' then means a pending
+-- registration, users registered before it would no longer be able to log in nor reset their password.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS "phone_verified_at" timestamp;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS "pending_phone_number" VARCHAR(20);
+
+-- users registered before the verification are verified since their registration
+UPDATE users SET phone_verified_at = created_at WHERE phone_verified_at IS NULL;
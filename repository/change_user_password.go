@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ChangeUserPassword replaces the password of the user and revokes every other session
+// in the same transaction, so no session outlives the password it logged in with.
+// It returns the ids of the revoked sessions, see UpdateUserCredentials & RevokeOtherUserSessions.
+func (r *Repository) ChangeUserPassword(ctx context.Context, tx *sql.Tx, input ChangeUserPasswordInput) (revokedIds []string, err error) {
+	if input.Credentials.Id == "" {
+		return nil, ErrInvalidInputParam
+	}
+
+	if tx == nil {
+		tx, err = r.Db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			err = tx.Commit()
+		}()
+	}
+
+	if err = r.UpdateUserCredentials(ctx, tx, input.Credentials); err != nil {
+		return nil, err
+	}
+
+	return r.RevokeOtherUserSessions(ctx, tx, RevokeOtherUserSessionsInput{
+		UserId:        input.Credentials.Id,
+		KeepSessionId: input.KeepSessionId,
+	})
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// ChangeUserPhoneNumber swaps the phone number of the user for the pending one, it returns sql.ErrNoRows
+// when the pending phone number is no longer input.PhoneNumber, e.g. replaced by another change.
+func (r *Repository) ChangeUserPhoneNumber(ctx context.Context, tx *sql.Tx, input ChangeUserPhoneNumberInput) (err error) {
+	if input.UserId == "" || input.PhoneNumber == "" {
+		return ErrInvalidInputParam
+	}
+
+	updatedAt := time.Now().UTC()
+	query := `
+		UPDATE users
+		SET
+			updated_at = $2,
+			phone_number = pending_phone_number,
+			pending_phone_number = NULL,
+			phone_verified_at = $2
+		WHERE id = $1
+			AND pending_phone_number = $3
+		RETURNING id
+	`
+	params := []interface{}{
+		input.UserId,
+		updatedAt,
+		input.PhoneNumber,
+	}
+
+	var changedId string
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, query, params...).Scan(&changedId)
+	} else {
+		err = r.Db.QueryRowContext(ctx, query, params...).Scan(&changedId)
+	}
+
+	return err
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// ConsumeMFAChallenge marks the challenge as passed, it returns sql.ErrNoRows when it already was.
+func (r *Repository) ConsumeMFAChallenge(ctx context.Context, tx *sql.Tx, id string) (err error) {
+	if id == "" {
+		return ErrInvalidInputParam
+	}
+
+	consumedAt := time.Now().UTC()
+	query := `
+		UPDATE mfa_challenges
+		SET 
+			consumed_at = $2
+		WHERE id = $1
+			AND consumed_at IS NULL
+		RETURNING id
+	`
+
+	var consumedId string
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, query, id, consumedAt).Scan(&consumedId)
+	} else {
+		err = r.Db.QueryRowContext(ctx, query, id, consumedAt).Scan(&consumedId)
+	}
+
+	return err
+}
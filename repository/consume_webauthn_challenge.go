@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// ConsumeWebAuthnChallenge marks the active challenge of the ceremony as used and returns it, so it can't be answered twice.
+// It returns sql.ErrNoRows when there is no such challenge, or it is consumed or expired.
+func (r *Repository) ConsumeWebAuthnChallenge(ctx context.Context, tx *sql.Tx, input ConsumeWebAuthnChallengeInput) (output WebAuthnChallenge, err error) {
+	if input.Ceremony == "" || input.ChallengeHash == "" {
+		return output, ErrInvalidInputParam
+	}
+
+	now := time.Now().UTC()
+	query := `
+		UPDATE webauthn_challenges
+		SET 
+			consumed_at = $3
+		WHERE challenge_hash = $1
+			AND ceremony = $2
+			AND consumed_at IS NULL
+			AND expires_at > $3
+		RETURNING
+			id,
+			created_at,
+			user_id,
+			ceremony,
+			challenge_hash,
+			expires_at,
+			consumed_at,
+			stepped_up_at
+	`
+
+	var userId sql.NullString
+	dest := []interface{}{
+		&output.Id,
+		&output.CreatedAt,
+		&userId,
+		&output.Ceremony,
+		&output.ChallengeHash,
+		&output.ExpiresAt,
+		&output.ConsumedAt,
+		&output.SteppedUpAt,
+	}
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, query, input.ChallengeHash, input.Ceremony, now).Scan(dest...)
+	} else {
+		err = r.Db.QueryRowContext(ctx, query, input.ChallengeHash, input.Ceremony, now).Scan(dest...)
+	}
+	if err != nil {
+		return WebAuthnChallenge{}, err
+	}
+	output.UserId = userId.String
+
+	return output, nil
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+)
+
+// CountMFARecoveryCodes returns the number of unused recovery codes of the user.
+func (r *Repository) CountMFARecoveryCodes(ctx context.Context, userId string) (remaining int, err error) {
+	if userId == "" {
+		return 0, ErrInvalidInputParam
+	}
+
+	q := `
+	SELECT COUNT(*)
+	FROM mfa_recovery_codes
+	WHERE user_id = $1
+		AND used_at IS NULL
+	`
+
+	err = r.Db.QueryRowContext(ctx, q, userId).Scan(&remaining)
+	if err != nil {
+		return 0, err
+	}
+
+	return remaining, nil
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DeletePasskey removes a passkey of the user, it returns sql.ErrNoRows when the user has no such passkey.
+func (r *Repository) DeletePasskey(ctx context.Context, tx *sql.Tx, input DeletePasskeyInput) (err error) {
+	if input.Id == "" || input.UserId == "" {
+		return ErrInvalidInputParam
+	}
+
+	query := `
+		DELETE FROM passkeys
+		WHERE id = $1
+			AND user_id = $2
+		RETURNING id
+	`
+
+	var deletedId string
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, query, input.Id, input.UserId).Scan(&deletedId)
+	} else {
+		err = r.Db.QueryRowContext(ctx, query, input.Id, input.UserId).Scan(&deletedId)
+	}
+
+	return err
+}
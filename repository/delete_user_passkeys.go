@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DeleteUserPasskeys removes every passkey of the user, it returns how many were removed.
+func (r *Repository) DeleteUserPasskeys(ctx context.Context, tx *sql.Tx, userId string) (deleted int64, err error) {
+	if userId == "" {
+		return 0, ErrInvalidInputParam
+	}
+
+	query := `
+		DELETE FROM passkeys
+		WHERE user_id = $1
+	`
+
+	var result sql.Result
+	if tx != nil {
+		result, err = tx.ExecContext(ctx, query, userId)
+	} else {
+		result, err = r.Db.ExecContext(ctx, query, userId)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
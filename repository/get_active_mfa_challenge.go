@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// GetActiveMFAChallenge returns the challenge of the token hash that is neither consumed nor expired.
+// It returns sql.ErrNoRows when there is none.
+func (r *Repository) GetActiveMFAChallenge(ctx context.Context, tokenHash string) (output MFAChallenge, err error) {
+	if tokenHash == "" {
+		return output, ErrInvalidInputParam
+	}
+
+	q := `
+	SELECT
+		id,
+		created_at,
+		user_id,
+		token_hash,
+		device_label,
+		attempts,
+		expires_at,
+		consumed_at
+	FROM mfa_challenges
+	WHERE token_hash = $1
+		AND consumed_at IS NULL
+		AND expires_at > $2
+	`
+
+	err = r.Db.QueryRowContext(ctx, q, tokenHash, time.Now().UTC()).Scan(
+		&output.Id,
+		&output.CreatedAt,
+		&output.UserId,
+		&output.TokenHash,
+		&output.DeviceLabel,
+		&output.Attempts,
+		&output.ExpiresAt,
+		&output.ConsumedAt,
+	)
+	if err != nil {
+		return output, err
+	}
+
+	return output, nil
+}
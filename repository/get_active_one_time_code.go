@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// GetActiveOneTimeCode returns the latest code of the user for the purpose that is neither
+// consumed nor expired. It returns sql.ErrNoRows when there is none.
+func (r *Repository) GetActiveOneTimeCode(ctx context.Context, input GetActiveOneTimeCodeInput) (output OneTimeCode, err error) {
+	if input.UserId == "" || input.Purpose == "" {
+		return output, ErrInvalidInputParam
+	}
+
+	q := `
+	SELECT
+		id,
+		created_at,
+		user_id,
+		purpose,
+		code_hash,
+		attempts,
+		expires_at,
+		consumed_at
+	FROM one_time_codes
+	WHERE user_id = $1
+		AND purpose = $2
+		AND consumed_at IS NULL
+		AND expires_at > $3
+	ORDER BY created_at DESC
+	LIMIT 1
+	`
+
+	err = r.Db.QueryRowContext(ctx, q, input.UserId, input.Purpose, time.Now().UTC()).Scan(
+		&output.Id,
+		&output.CreatedAt,
+		&output.UserId,
+		&output.Purpose,
+		&output.CodeHash,
+		&output.Attempts,
+		&output.ExpiresAt,
+		&output.ConsumedAt,
+	)
+	if err != nil {
+		return output, err
+	}
+
+	return output, nil
+}
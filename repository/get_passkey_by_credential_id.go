@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+)
+
+// GetPasskeyByCredentialId returns the passkey of the base64url credential id, sql.ErrNoRows when there is none.
+func (r *Repository) GetPasskeyByCredentialId(ctx context.Context, credentialId string) (output Passkey, err error) {
+	if credentialId == "" {
+		return output, ErrInvalidInputParam
+	}
+
+	q := `
+	SELECT` + passkeyColumns + `
+	FROM passkeys
+	WHERE credential_id = $1
+	`
+
+	return scanPasskey(r.Db.QueryRowContext(ctx, q, credentialId))
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+)
+
+// GetPasswordHistory returns the replaced password hashes of the user, most recent first.
+func (r *Repository) GetPasswordHistory(ctx context.Context, input GetPasswordHistoryInput) (output []PasswordHistory, err error) {
+	if input.UserId == "" || input.Limit < 0 {
+		return output, ErrInvalidInputParam
+	}
+
+	q := `
+	SELECT
+		id,
+		created_at,
+		user_id,
+		password_hash,
+		COALESCE(salt, '')
+	FROM password_history
+	WHERE user_id = $1
+	ORDER BY created_at DESC
+	LIMIT $2
+	`
+
+	rows, err := r.Db.QueryContext(ctx, q, input.UserId, input.Limit)
+	if err != nil {
+		return output, err
+	}
+	defer rows.Close()
+
+	output = []PasswordHistory{}
+	for rows.Next() {
+		var entry PasswordHistory
+		if err := rows.Scan(
+			&entry.Id,
+			&entry.CreatedAt,
+			&entry.UserId,
+			&entry.PasswordHash,
+			&entry.Salt,
+		); err != nil {
+			return nil, err
+		}
+		output = append(output, entry)
+	}
+
+	return output, rows.Err()
+}
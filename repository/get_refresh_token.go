@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+)
+
+func (r *Repository) GetRefreshToken(ctx context.Context, input GetRefreshTokenInput) (output RefreshToken, err error) {
+	if input.TokenHash == "" {
+		return output, ErrInvalidInputParam
+	}
+
+	q := `
+	SELECT
+		id,
+		created_at,
+		user_id,
+		family_id,
+		COALESCE(session_id::text, ''),
+		token_hash,
+		expires_at,
+		rotated_at,
+		revoked_at
+	FROM refresh_tokens
+	WHERE token_hash = $1
+	`
+
+	err = r.Db.QueryRowContext(ctx, q, input.TokenHash).Scan(
+		&output.Id,
+		&output.CreatedAt,
+		&output.UserId,
+		&output.FamilyId,
+		&output.SessionId,
+		&output.TokenHash,
+		&output.ExpiresAt,
+		&output.RotatedAt,
+		&output.RevokedAt,
+	)
+	if err != nil {
+		return output, err
+	}
+
+	return output, nil
+}
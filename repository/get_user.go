@@ -14,8 +14,15 @@ func (r *Repository) GetUser(ctx context.Context, input GetUserInput) (output Us
 		phone_number,
 		full_name,
 		password_hash,
-		salt,
-		login_count
+		COALESCE(salt, ''),
+		login_count,
+		phone_verified_at,
+		COALESCE(pending_phone_number, ''),
+		EXISTS (SELECT 1 FROM user_totp WHERE user_totp.user_id = users.id AND user_totp.confirmed_at IS NOT NULL),
+		mfa_failed_attempts,
+		mfa_locked_until,
+		otp_failed_attempts,
+		otp_locked_until
 	FROM users
 	`
 	var param interface{}
@@ -45,6 +52,13 @@ func (r *Repository) GetUser(ctx context.Context, input GetUserInput) (output Us
 		&output.PasswordHash,
 		&output.Salt,
 		&output.LoginCount,
+		&output.PhoneVerifiedAt,
+		&output.PendingPhoneNumber,
+		&output.TOTPEnabled,
+		&output.MFAFailedAttempts,
+		&output.MFALockedUntil,
+		&output.OTPFailedAttempts,
+		&output.OTPLockedUntil,
 	)
 	if err != nil {
 		return output, err
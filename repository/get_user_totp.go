@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+)
+
+// GetUserTOTP returns the TOTP authenticator of the user, confirmed or not.
+// It returns sql.ErrNoRows when the user has none.
+func (r *Repository) GetUserTOTP(ctx context.Context, userId string) (output UserTOTP, err error) {
+	if userId == "" {
+		return output, ErrInvalidInputParam
+	}
+
+	q := `
+	SELECT
+		user_id,
+		created_at,
+		secret_ciphertext,
+		confirmed_at,
+		last_used_step
+	FROM user_totp
+	WHERE user_id = $1
+	`
+
+	err = r.Db.QueryRowContext(ctx, q, userId).Scan(
+		&output.UserId,
+		&output.CreatedAt,
+		&output.SecretCiphertext,
+		&output.ConfirmedAt,
+		&output.LastUsedStep,
+	)
+	if err != nil {
+		return output, err
+	}
+
+	return output, nil
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// IncrementMFAChallengeAttempts counts a code tried for the challenge before it is checked,
+// so concurrent guesses can't go past the limit. It returns sql.ErrNoRows once the challenge
+// has MaxAttempts attempts or is consumed.
+func (r *Repository) IncrementMFAChallengeAttempts(ctx context.Context, tx *sql.Tx, input IncrementMFAChallengeAttemptsInput) (err error) {
+	if input.Id == "" || input.MaxAttempts <= 0 {
+		return ErrInvalidInputParam
+	}
+
+	query := `
+		UPDATE mfa_challenges
+		SET 
+			attempts = attempts + 1
+		WHERE id = $1
+			AND attempts < $2
+			AND consumed_at IS NULL
+		RETURNING attempts
+	`
+
+	var attempts int
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, query, input.Id, input.MaxAttempts).Scan(&attempts)
+	} else {
+		err = r.Db.QueryRowContext(ctx, query, input.Id, input.MaxAttempts).Scan(&attempts)
+	}
+
+	return err
+}
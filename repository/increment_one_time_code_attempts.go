@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// IncrementOneTimeCodeAttempts counts a verification of the code before it is compared,
+// so concurrent guesses can't go past the limit. It returns sql.ErrNoRows once the code
+// has MaxAttempts attempts or is consumed.
+func (r *Repository) IncrementOneTimeCodeAttempts(ctx context.Context, tx *sql.Tx, input IncrementOneTimeCodeAttemptsInput) (err error) {
+	if input.Id == "" || input.MaxAttempts <= 0 {
+		return ErrInvalidInputParam
+	}
+
+	query := `
+		UPDATE one_time_codes
+		SET 
+			attempts = attempts + 1
+		WHERE id = $1
+			AND attempts < $2
+			AND consumed_at IS NULL
+		RETURNING attempts
+	`
+
+	var attempts int
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, query, input.Id, input.MaxAttempts).Scan(&attempts)
+	} else {
+		err = r.Db.QueryRowContext(ctx, query, input.Id, input.MaxAttempts).Scan(&attempts)
+	}
+
+	return err
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InsertAuditLog records a security relevant event of the user.
+func (r *Repository) InsertAuditLog(ctx context.Context, tx *sql.Tx, input InsertAuditLogInput) (err error) {
+	if input.UserId == "" || input.Event == "" {
+		return ErrInvalidInputParam
+	}
+
+	createdAt := time.Now().UTC()
+	query := `
+		INSERT INTO audit_logs (id, created_at, user_id, event, ip_address, user_agent, details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	params := []interface{}{
+		uuid.NewString(),
+		createdAt,
+		input.UserId,
+		input.Event,
+		input.IpAddress,
+		input.UserAgent,
+		input.Details,
+	}
+
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, params...)
+	} else {
+		_, err = r.Db.ExecContext(ctx, query, params...)
+	}
+
+	return err
+}
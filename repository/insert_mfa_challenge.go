@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InsertMFAChallenge stores the log in of a user waiting for the second factor.
+func (r *Repository) InsertMFAChallenge(ctx context.Context, tx *sql.Tx, input InsertMFAChallengeInput) (output InsertMFAChallengeOutput, err error) {
+	if input.UserId == "" || input.TokenHash == "" {
+		return output, ErrInvalidInputParam
+	}
+
+	id := uuid.NewString()
+	createdAt := time.Now().UTC()
+	query := `
+		INSERT INTO mfa_challenges (id, created_at, user_id, token_hash, device_label, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	params := []interface{}{
+		id,
+		createdAt,
+		input.UserId,
+		input.TokenHash,
+		input.DeviceLabel,
+		input.ExpiresAt.UTC(),
+	}
+
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, params...)
+	} else {
+		_, err = r.Db.ExecContext(ctx, query, params...)
+	}
+	if err != nil {
+		return output, err
+	}
+
+	return InsertMFAChallengeOutput{
+		Id: id,
+	}, nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InsertOneTimeCode stores a new code of the user for the purpose,
+// earlier codes for the same purpose are consumed so only the latest one works.
+func (r *Repository) InsertOneTimeCode(ctx context.Context, tx *sql.Tx, input InsertOneTimeCodeInput) (output InsertOneTimeCodeOutput, err error) {
+	if input.UserId == "" || input.Purpose == "" || input.CodeHash == "" {
+		return output, ErrInvalidInputParam
+	}
+
+	if tx == nil {
+		tx, err = r.Db.BeginTx(ctx, nil)
+		if err != nil {
+			return output, err
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			err = tx.Commit()
+		}()
+	}
+
+	now := time.Now().UTC()
+	query := `
+		UPDATE one_time_codes
+		SET 
+			consumed_at = $3
+		WHERE user_id = $1
+			AND purpose = $2
+			AND consumed_at IS NULL
+	`
+	if _, err = tx.ExecContext(ctx, query, input.UserId, input.Purpose, now); err != nil {
+		return output, err
+	}
+
+	id := uuid.NewString()
+	query = `
+		INSERT INTO one_time_codes (id, created_at, user_id, purpose, code_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	params := []interface{}{
+		id,
+		now,
+		input.UserId,
+		input.Purpose,
+		input.CodeHash,
+		input.ExpiresAt.UTC(),
+	}
+	if _, err = tx.ExecContext(ctx, query, params...); err != nil {
+		return output, err
+	}
+
+	return InsertOneTimeCodeOutput{
+		Id: id,
+	}, nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InsertPasskey stores a registered passkey of the user.
+// It returns sql.ErrNoRows when the credential is already registered, to this user or another one.
+func (r *Repository) InsertPasskey(ctx context.Context, tx *sql.Tx, input InsertPasskeyInput) (output InsertPasskeyOutput, err error) {
+	if input.UserId == "" || input.CredentialId == "" || len(input.PublicKey) == 0 {
+		return output, ErrInvalidInputParam
+	}
+
+	createdAt := time.Now().UTC()
+	query := `
+		INSERT INTO passkeys (id, created_at, updated_at, user_id, credential_id, public_key, sign_count, transports, name, backup_eligible, backed_up)
+		VALUES ($1, $2, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (credential_id) DO NOTHING
+		RETURNING id
+	`
+	params := []interface{}{
+		uuid.NewString(),
+		createdAt,
+		input.UserId,
+		input.CredentialId,
+		input.PublicKey,
+		int64(input.SignCount),
+		joinTransports(input.Transports),
+		input.Name,
+		input.BackupEligible,
+		input.BackedUp,
+	}
+
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, query, params...).Scan(&output.Id)
+	} else {
+		err = r.Db.QueryRowContext(ctx, query, params...).Scan(&output.Id)
+	}
+	if err != nil {
+		return InsertPasskeyOutput{}, err
+	}
+	output.CreatedAt = createdAt
+
+	return output, nil
+}
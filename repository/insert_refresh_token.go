@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+func (r *Repository) InsertRefreshToken(ctx context.Context, tx *sql.Tx, input InsertRefreshTokenInput) (output InsertRefreshTokenOutput, err error) {
+	if input.UserId == "" || input.TokenHash == "" {
+		return output, ErrInvalidInputParam
+	}
+
+	id := uuid.NewString()
+	familyId := input.FamilyId
+	if familyId == "" {
+		familyId = id
+	}
+
+	query := `
+		INSERT INTO refresh_tokens (id, user_id, family_id, session_id, token_hash, expires_at)
+		VALUES ( $1, $2, $3, NULLIF($4, '')::uuid, $5, $6)
+	`
+	params := []interface{}{
+		id,
+		input.UserId,
+		familyId,
+		input.SessionId,
+		input.TokenHash,
+		input.ExpiresAt.UTC(),
+	}
+
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, params...)
+	} else {
+		_, err = r.Db.ExecContext(ctx, query, params...)
+	}
+	if err != nil {
+		return InsertRefreshTokenOutput{}, err
+	}
+
+	return InsertRefreshTokenOutput{
+		Id:       id,
+		FamilyId: familyId,
+	}, nil
+}
@@ -10,15 +10,14 @@ import (
 func (r *Repository) InsertUser(ctx context.Context, tx *sql.Tx, input InsertUserInput) (output InsertUserOutput, err error) {
 	id := uuid.NewString()
 	query := `
-		INSERT INTO users (id, phone_number, full_name, password_hash, salt)
-		VALUES ( $1, $2, $3, $4, $5)
+		INSERT INTO users (id, phone_number, full_name, password_hash)
+		VALUES ( $1, $2, $3, $4)
 	`
 	params := []interface{}{
 		id,
 		input.PhoneNumber,
 		input.FullName,
 		input.PasswordHash,
-		input.Salt,
 	}
 
 	if tx != nil {
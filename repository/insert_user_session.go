@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (r *Repository) InsertUserSession(ctx context.Context, tx *sql.Tx, input InsertUserSessionInput) (output InsertUserSessionOutput, err error) {
+	if input.UserId == "" {
+		return output, ErrInvalidInputParam
+	}
+
+	id := uuid.NewString()
+	lastUsedAt := time.Now().UTC()
+
+	query := `
+		INSERT INTO user_sessions (id, user_id, user_agent, ip_address, device_label, last_used_at, expires_at)
+		VALUES ( $1, $2, $3, $4, $5, $6, $7)
+	`
+	params := []interface{}{
+		id,
+		input.UserId,
+		input.UserAgent,
+		input.IpAddress,
+		input.DeviceLabel,
+		lastUsedAt,
+		input.ExpiresAt.UTC(),
+	}
+
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, params...)
+	} else {
+		_, err = r.Db.ExecContext(ctx, query, params...)
+	}
+	if err != nil {
+		return InsertUserSessionOutput{}, err
+	}
+
+	return InsertUserSessionOutput{
+		Id: id,
+	}, nil
+}
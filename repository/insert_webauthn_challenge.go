@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InsertWebAuthnChallenge stores the challenge of a passkey ceremony, SteppedUp records that the user confirmed it with the current password or MFA.
+func (r *Repository) InsertWebAuthnChallenge(ctx context.Context, tx *sql.Tx, input InsertWebAuthnChallengeInput) (err error) {
+	if input.Ceremony == "" || input.ChallengeHash == "" {
+		return ErrInvalidInputParam
+	}
+
+	var userId interface{}
+	if input.UserId != "" {
+		userId = input.UserId
+	}
+
+	createdAt := time.Now().UTC()
+	var steppedUpAt *time.Time
+	if input.SteppedUp {
+		steppedUpAt = &createdAt
+	}
+
+	query := `
+		INSERT INTO webauthn_challenges (id, created_at, user_id, ceremony, challenge_hash, expires_at, stepped_up_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	params := []interface{}{
+		uuid.NewString(),
+		createdAt,
+		userId,
+		input.Ceremony,
+		input.ChallengeHash,
+		input.ExpiresAt.UTC(),
+		steppedUpAt,
+	}
+
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, params...)
+	} else {
+		_, err = r.Db.ExecContext(ctx, query, params...)
+	}
+
+	return err
+}
@@ -12,6 +12,53 @@ import (
 type RepositoryInterface interface {
 	GetUser(ctx context.Context, input GetUserInput) (output User, err error)
 	InsertUser(ctx context.Context, tx *sql.Tx, input InsertUserInput) (output InsertUserOutput, err error)
+	ReplacePendingUser(ctx context.Context, tx *sql.Tx, input ReplacePendingUserInput) (err error)
+	VerifyUserPhone(ctx context.Context, tx *sql.Tx, userId string) (err error)
 	IncrementUserLoginCount(ctx context.Context, tx *sql.Tx, input User) (err error)
 	UpdateUser(ctx context.Context, tx *sql.Tx, input User) (err error)
+	SetPendingPhoneNumber(ctx context.Context, tx *sql.Tx, input SetPendingPhoneNumberInput) (err error)
+	ChangeUserPhoneNumber(ctx context.Context, tx *sql.Tx, input ChangeUserPhoneNumberInput) (err error)
+	UpdateUserCredentials(ctx context.Context, tx *sql.Tx, input UpdateUserCredentialsInput) (err error)
+	ChangeUserPassword(ctx context.Context, tx *sql.Tx, input ChangeUserPasswordInput) (revokedIds []string, err error)
+	ResetUserPassword(ctx context.Context, tx *sql.Tx, input ResetUserPasswordInput) (deletedPasskeys int64, err error)
+	GetPasswordHistory(ctx context.Context, input GetPasswordHistoryInput) (output []PasswordHistory, err error)
+	InsertRefreshToken(ctx context.Context, tx *sql.Tx, input InsertRefreshTokenInput) (output InsertRefreshTokenOutput, err error)
+	GetRefreshToken(ctx context.Context, input GetRefreshTokenInput) (output RefreshToken, err error)
+	RotateRefreshToken(ctx context.Context, tx *sql.Tx, input RotateRefreshTokenInput) (output InsertRefreshTokenOutput, err error)
+	RevokeRefreshTokenFamily(ctx context.Context, tx *sql.Tx, familyId string) (err error)
+	RevokeUserRefreshTokens(ctx context.Context, tx *sql.Tx, userId string) (err error)
+	InsertUserSession(ctx context.Context, tx *sql.Tx, input InsertUserSessionInput) (output InsertUserSessionOutput, err error)
+	ListUserSessions(ctx context.Context, userId string) (output []UserSession, err error)
+	TouchUserSession(ctx context.Context, tx *sql.Tx, input TouchUserSessionInput) (err error)
+	RevokeUserSession(ctx context.Context, tx *sql.Tx, input RevokeUserSessionInput) (output UserSession, err error)
+	RevokeUserSessions(ctx context.Context, tx *sql.Tx, userId string) (revokedIds []string, err error)
+	RevokeOtherUserSessions(ctx context.Context, tx *sql.Tx, input RevokeOtherUserSessionsInput) (revokedIds []string, err error)
+	InsertOneTimeCode(ctx context.Context, tx *sql.Tx, input InsertOneTimeCodeInput) (output InsertOneTimeCodeOutput, err error)
+	GetActiveOneTimeCode(ctx context.Context, input GetActiveOneTimeCodeInput) (output OneTimeCode, err error)
+	IncrementOneTimeCodeAttempts(ctx context.Context, tx *sql.Tx, input IncrementOneTimeCodeAttemptsInput) (err error)
+	ConsumeOneTimeCode(ctx context.Context, tx *sql.Tx, id string) (err error)
+	RecordOTPFailure(ctx context.Context, tx *sql.Tx, input RecordOTPFailureInput) (locked bool, err error)
+	ResetOTPFailures(ctx context.Context, tx *sql.Tx, userId string) (err error)
+	UpsertUserTOTP(ctx context.Context, tx *sql.Tx, input UpsertUserTOTPInput) (err error)
+	GetUserTOTP(ctx context.Context, userId string) (output UserTOTP, err error)
+	UseUserTOTPStep(ctx context.Context, tx *sql.Tx, input UseUserTOTPStepInput) (err error)
+	InsertMFAChallenge(ctx context.Context, tx *sql.Tx, input InsertMFAChallengeInput) (output InsertMFAChallengeOutput, err error)
+	GetActiveMFAChallenge(ctx context.Context, tokenHash string) (output MFAChallenge, err error)
+	IncrementMFAChallengeAttempts(ctx context.Context, tx *sql.Tx, input IncrementMFAChallengeAttemptsInput) (err error)
+	ConsumeMFAChallenge(ctx context.Context, tx *sql.Tx, id string) (err error)
+	RecordMFAFailure(ctx context.Context, tx *sql.Tx, input RecordMFAFailureInput) (locked bool, err error)
+	ResetMFAFailures(ctx context.Context, tx *sql.Tx, userId string) (err error)
+	ReplaceMFARecoveryCodes(ctx context.Context, tx *sql.Tx, input ReplaceMFARecoveryCodesInput) (err error)
+	CountMFARecoveryCodes(ctx context.Context, userId string) (remaining int, err error)
+	UseMFARecoveryCode(ctx context.Context, tx *sql.Tx, input UseMFARecoveryCodeInput) (remaining int, err error)
+	InsertAuditLog(ctx context.Context, tx *sql.Tx, input InsertAuditLogInput) (err error)
+	InsertWebAuthnChallenge(ctx context.Context, tx *sql.Tx, input InsertWebAuthnChallengeInput) (err error)
+	ConsumeWebAuthnChallenge(ctx context.Context, tx *sql.Tx, input ConsumeWebAuthnChallengeInput) (output WebAuthnChallenge, err error)
+	InsertPasskey(ctx context.Context, tx *sql.Tx, input InsertPasskeyInput) (output InsertPasskeyOutput, err error)
+	ListPasskeys(ctx context.Context, userId string) (output []Passkey, err error)
+	GetPasskeyByCredentialId(ctx context.Context, credentialId string) (output Passkey, err error)
+	UpdatePasskeyName(ctx context.Context, tx *sql.Tx, input UpdatePasskeyNameInput) (output Passkey, err error)
+	DeletePasskey(ctx context.Context, tx *sql.Tx, input DeletePasskeyInput) (err error)
+	DeleteUserPasskeys(ctx context.Context, tx *sql.Tx, userId string) (deleted int64, err error)
+	UsePasskey(ctx context.Context, tx *sql.Tx, input UsePasskeyInput) (err error)
 }
@@ -35,6 +35,197 @@ func (m *MockRepositoryInterface) EXPECT() *MockRepositoryInterfaceMockRecorder
 	return m.recorder
 }
 
+// ChangeUserPassword mocks base method.
+func (m *MockRepositoryInterface) ChangeUserPassword(ctx context.Context, tx *sql.Tx, input ChangeUserPasswordInput) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeUserPassword", ctx, tx, input)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangeUserPassword indicates an expected call of ChangeUserPassword.
+func (mr *MockRepositoryInterfaceMockRecorder) ChangeUserPassword(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeUserPassword", reflect.TypeOf((*MockRepositoryInterface)(nil).ChangeUserPassword), ctx, tx, input)
+}
+
+// ChangeUserPhoneNumber mocks base method.
+func (m *MockRepositoryInterface) ChangeUserPhoneNumber(ctx context.Context, tx *sql.Tx, input ChangeUserPhoneNumberInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeUserPhoneNumber", ctx, tx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangeUserPhoneNumber indicates an expected call of ChangeUserPhoneNumber.
+func (mr *MockRepositoryInterfaceMockRecorder) ChangeUserPhoneNumber(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeUserPhoneNumber", reflect.TypeOf((*MockRepositoryInterface)(nil).ChangeUserPhoneNumber), ctx, tx, input)
+}
+
+// ConsumeMFAChallenge mocks base method.
+func (m *MockRepositoryInterface) ConsumeMFAChallenge(ctx context.Context, tx *sql.Tx, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeMFAChallenge", ctx, tx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConsumeMFAChallenge indicates an expected call of ConsumeMFAChallenge.
+func (mr *MockRepositoryInterfaceMockRecorder) ConsumeMFAChallenge(ctx, tx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeMFAChallenge", reflect.TypeOf((*MockRepositoryInterface)(nil).ConsumeMFAChallenge), ctx, tx, id)
+}
+
+// ConsumeOneTimeCode mocks base method.
+func (m *MockRepositoryInterface) ConsumeOneTimeCode(ctx context.Context, tx *sql.Tx, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeOneTimeCode", ctx, tx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConsumeOneTimeCode indicates an expected call of ConsumeOneTimeCode.
+func (mr *MockRepositoryInterfaceMockRecorder) ConsumeOneTimeCode(ctx, tx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeOneTimeCode", reflect.TypeOf((*MockRepositoryInterface)(nil).ConsumeOneTimeCode), ctx, tx, id)
+}
+
+// ConsumeWebAuthnChallenge mocks base method.
+func (m *MockRepositoryInterface) ConsumeWebAuthnChallenge(ctx context.Context, tx *sql.Tx, input ConsumeWebAuthnChallengeInput) (WebAuthnChallenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeWebAuthnChallenge", ctx, tx, input)
+	ret0, _ := ret[0].(WebAuthnChallenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeWebAuthnChallenge indicates an expected call of ConsumeWebAuthnChallenge.
+func (mr *MockRepositoryInterfaceMockRecorder) ConsumeWebAuthnChallenge(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeWebAuthnChallenge", reflect.TypeOf((*MockRepositoryInterface)(nil).ConsumeWebAuthnChallenge), ctx, tx, input)
+}
+
+// CountMFARecoveryCodes mocks base method.
+func (m *MockRepositoryInterface) CountMFARecoveryCodes(ctx context.Context, userId string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountMFARecoveryCodes", ctx, userId)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountMFARecoveryCodes indicates an expected call of CountMFARecoveryCodes.
+func (mr *MockRepositoryInterfaceMockRecorder) CountMFARecoveryCodes(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountMFARecoveryCodes", reflect.TypeOf((*MockRepositoryInterface)(nil).CountMFARecoveryCodes), ctx, userId)
+}
+
+// DeletePasskey mocks base method.
+func (m *MockRepositoryInterface) DeletePasskey(ctx context.Context, tx *sql.Tx, input DeletePasskeyInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePasskey", ctx, tx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePasskey indicates an expected call of DeletePasskey.
+func (mr *MockRepositoryInterfaceMockRecorder) DeletePasskey(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePasskey", reflect.TypeOf((*MockRepositoryInterface)(nil).DeletePasskey), ctx, tx, input)
+}
+
+// DeleteUserPasskeys mocks base method.
+func (m *MockRepositoryInterface) DeleteUserPasskeys(ctx context.Context, tx *sql.Tx, userId string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserPasskeys", ctx, tx, userId)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteUserPasskeys indicates an expected call of DeleteUserPasskeys.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteUserPasskeys(ctx, tx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserPasskeys", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteUserPasskeys), ctx, tx, userId)
+}
+
+// GetActiveMFAChallenge mocks base method.
+func (m *MockRepositoryInterface) GetActiveMFAChallenge(ctx context.Context, tokenHash string) (MFAChallenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActiveMFAChallenge", ctx, tokenHash)
+	ret0, _ := ret[0].(MFAChallenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActiveMFAChallenge indicates an expected call of GetActiveMFAChallenge.
+func (mr *MockRepositoryInterfaceMockRecorder) GetActiveMFAChallenge(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveMFAChallenge", reflect.TypeOf((*MockRepositoryInterface)(nil).GetActiveMFAChallenge), ctx, tokenHash)
+}
+
+// GetActiveOneTimeCode mocks base method.
+func (m *MockRepositoryInterface) GetActiveOneTimeCode(ctx context.Context, input GetActiveOneTimeCodeInput) (OneTimeCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActiveOneTimeCode", ctx, input)
+	ret0, _ := ret[0].(OneTimeCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActiveOneTimeCode indicates an expected call of GetActiveOneTimeCode.
+func (mr *MockRepositoryInterfaceMockRecorder) GetActiveOneTimeCode(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveOneTimeCode", reflect.TypeOf((*MockRepositoryInterface)(nil).GetActiveOneTimeCode), ctx, input)
+}
+
+// GetPasskeyByCredentialId mocks base method.
+func (m *MockRepositoryInterface) GetPasskeyByCredentialId(ctx context.Context, credentialId string) (Passkey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPasskeyByCredentialId", ctx, credentialId)
+	ret0, _ := ret[0].(Passkey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPasskeyByCredentialId indicates an expected call of GetPasskeyByCredentialId.
+func (mr *MockRepositoryInterfaceMockRecorder) GetPasskeyByCredentialId(ctx, credentialId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasskeyByCredentialId", reflect.TypeOf((*MockRepositoryInterface)(nil).GetPasskeyByCredentialId), ctx, credentialId)
+}
+
+// GetPasswordHistory mocks base method.
+func (m *MockRepositoryInterface) GetPasswordHistory(ctx context.Context, input GetPasswordHistoryInput) ([]PasswordHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPasswordHistory", ctx, input)
+	ret0, _ := ret[0].([]PasswordHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPasswordHistory indicates an expected call of GetPasswordHistory.
+func (mr *MockRepositoryInterfaceMockRecorder) GetPasswordHistory(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasswordHistory", reflect.TypeOf((*MockRepositoryInterface)(nil).GetPasswordHistory), ctx, input)
+}
+
+// GetRefreshToken mocks base method.
+func (m *MockRepositoryInterface) GetRefreshToken(ctx context.Context, input GetRefreshTokenInput) (RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRefreshToken", ctx, input)
+	ret0, _ := ret[0].(RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefreshToken indicates an expected call of GetRefreshToken.
+func (mr *MockRepositoryInterfaceMockRecorder) GetRefreshToken(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefreshToken", reflect.TypeOf((*MockRepositoryInterface)(nil).GetRefreshToken), ctx, input)
+}
+
 // GetUser mocks base method.
 func (m *MockRepositoryInterface) GetUser(ctx context.Context, input GetUserInput) (User, error) {
 	m.ctrl.T.Helper()
@@ -50,6 +241,49 @@ func (mr *MockRepositoryInterfaceMockRecorder) GetUser(ctx, input interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockRepositoryInterface)(nil).GetUser), ctx, input)
 }
 
+// GetUserTOTP mocks base method.
+func (m *MockRepositoryInterface) GetUserTOTP(ctx context.Context, userId string) (UserTOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserTOTP", ctx, userId)
+	ret0, _ := ret[0].(UserTOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserTOTP indicates an expected call of GetUserTOTP.
+func (mr *MockRepositoryInterfaceMockRecorder) GetUserTOTP(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserTOTP", reflect.TypeOf((*MockRepositoryInterface)(nil).GetUserTOTP), ctx, userId)
+}
+
+// IncrementMFAChallengeAttempts mocks base method.
+func (m *MockRepositoryInterface) IncrementMFAChallengeAttempts(ctx context.Context, tx *sql.Tx, input IncrementMFAChallengeAttemptsInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementMFAChallengeAttempts", ctx, tx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrementMFAChallengeAttempts indicates an expected call of IncrementMFAChallengeAttempts.
+func (mr *MockRepositoryInterfaceMockRecorder) IncrementMFAChallengeAttempts(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementMFAChallengeAttempts", reflect.TypeOf((*MockRepositoryInterface)(nil).IncrementMFAChallengeAttempts), ctx, tx, input)
+}
+
+// IncrementOneTimeCodeAttempts mocks base method.
+func (m *MockRepositoryInterface) IncrementOneTimeCodeAttempts(ctx context.Context, tx *sql.Tx, input IncrementOneTimeCodeAttemptsInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementOneTimeCodeAttempts", ctx, tx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrementOneTimeCodeAttempts indicates an expected call of IncrementOneTimeCodeAttempts.
+func (mr *MockRepositoryInterfaceMockRecorder) IncrementOneTimeCodeAttempts(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementOneTimeCodeAttempts", reflect.TypeOf((*MockRepositoryInterface)(nil).IncrementOneTimeCodeAttempts), ctx, tx, input)
+}
+
 // IncrementUserLoginCount mocks base method.
 func (m *MockRepositoryInterface) IncrementUserLoginCount(ctx context.Context, tx *sql.Tx, input User) error {
 	m.ctrl.T.Helper()
@@ -64,6 +298,80 @@ func (mr *MockRepositoryInterfaceMockRecorder) IncrementUserLoginCount(ctx, tx,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementUserLoginCount", reflect.TypeOf((*MockRepositoryInterface)(nil).IncrementUserLoginCount), ctx, tx, input)
 }
 
+// InsertAuditLog mocks base method.
+func (m *MockRepositoryInterface) InsertAuditLog(ctx context.Context, tx *sql.Tx, input InsertAuditLogInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertAuditLog", ctx, tx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertAuditLog indicates an expected call of InsertAuditLog.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertAuditLog(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertAuditLog", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertAuditLog), ctx, tx, input)
+}
+
+// InsertMFAChallenge mocks base method.
+func (m *MockRepositoryInterface) InsertMFAChallenge(ctx context.Context, tx *sql.Tx, input InsertMFAChallengeInput) (InsertMFAChallengeOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertMFAChallenge", ctx, tx, input)
+	ret0, _ := ret[0].(InsertMFAChallengeOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertMFAChallenge indicates an expected call of InsertMFAChallenge.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertMFAChallenge(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertMFAChallenge", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertMFAChallenge), ctx, tx, input)
+}
+
+// InsertOneTimeCode mocks base method.
+func (m *MockRepositoryInterface) InsertOneTimeCode(ctx context.Context, tx *sql.Tx, input InsertOneTimeCodeInput) (InsertOneTimeCodeOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertOneTimeCode", ctx, tx, input)
+	ret0, _ := ret[0].(InsertOneTimeCodeOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertOneTimeCode indicates an expected call of InsertOneTimeCode.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertOneTimeCode(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertOneTimeCode", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertOneTimeCode), ctx, tx, input)
+}
+
+// InsertPasskey mocks base method.
+func (m *MockRepositoryInterface) InsertPasskey(ctx context.Context, tx *sql.Tx, input InsertPasskeyInput) (InsertPasskeyOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertPasskey", ctx, tx, input)
+	ret0, _ := ret[0].(InsertPasskeyOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertPasskey indicates an expected call of InsertPasskey.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertPasskey(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertPasskey", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertPasskey), ctx, tx, input)
+}
+
+// InsertRefreshToken mocks base method.
+func (m *MockRepositoryInterface) InsertRefreshToken(ctx context.Context, tx *sql.Tx, input InsertRefreshTokenInput) (InsertRefreshTokenOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertRefreshToken", ctx, tx, input)
+	ret0, _ := ret[0].(InsertRefreshTokenOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertRefreshToken indicates an expected call of InsertRefreshToken.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertRefreshToken(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertRefreshToken", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertRefreshToken), ctx, tx, input)
+}
+
 // InsertUser mocks base method.
 func (m *MockRepositoryInterface) InsertUser(ctx context.Context, tx *sql.Tx, input InsertUserInput) (InsertUserOutput, error) {
 	m.ctrl.T.Helper()
@@ -79,6 +387,297 @@ func (mr *MockRepositoryInterfaceMockRecorder) InsertUser(ctx, tx, input interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertUser", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertUser), ctx, tx, input)
 }
 
+// InsertUserSession mocks base method.
+func (m *MockRepositoryInterface) InsertUserSession(ctx context.Context, tx *sql.Tx, input InsertUserSessionInput) (InsertUserSessionOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertUserSession", ctx, tx, input)
+	ret0, _ := ret[0].(InsertUserSessionOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertUserSession indicates an expected call of InsertUserSession.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertUserSession(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertUserSession", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertUserSession), ctx, tx, input)
+}
+
+// InsertWebAuthnChallenge mocks base method.
+func (m *MockRepositoryInterface) InsertWebAuthnChallenge(ctx context.Context, tx *sql.Tx, input InsertWebAuthnChallengeInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertWebAuthnChallenge", ctx, tx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertWebAuthnChallenge indicates an expected call of InsertWebAuthnChallenge.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertWebAuthnChallenge(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertWebAuthnChallenge", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertWebAuthnChallenge), ctx, tx, input)
+}
+
+// ListPasskeys mocks base method.
+func (m *MockRepositoryInterface) ListPasskeys(ctx context.Context, userId string) ([]Passkey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPasskeys", ctx, userId)
+	ret0, _ := ret[0].([]Passkey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPasskeys indicates an expected call of ListPasskeys.
+func (mr *MockRepositoryInterfaceMockRecorder) ListPasskeys(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPasskeys", reflect.TypeOf((*MockRepositoryInterface)(nil).ListPasskeys), ctx, userId)
+}
+
+// ListUserSessions mocks base method.
+func (m *MockRepositoryInterface) ListUserSessions(ctx context.Context, userId string) ([]UserSession, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUserSessions", ctx, userId)
+	ret0, _ := ret[0].([]UserSession)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUserSessions indicates an expected call of ListUserSessions.
+func (mr *MockRepositoryInterfaceMockRecorder) ListUserSessions(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUserSessions", reflect.TypeOf((*MockRepositoryInterface)(nil).ListUserSessions), ctx, userId)
+}
+
+// RecordMFAFailure mocks base method.
+func (m *MockRepositoryInterface) RecordMFAFailure(ctx context.Context, tx *sql.Tx, input RecordMFAFailureInput) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordMFAFailure", ctx, tx, input)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecordMFAFailure indicates an expected call of RecordMFAFailure.
+func (mr *MockRepositoryInterfaceMockRecorder) RecordMFAFailure(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordMFAFailure", reflect.TypeOf((*MockRepositoryInterface)(nil).RecordMFAFailure), ctx, tx, input)
+}
+
+// RecordOTPFailure mocks base method.
+func (m *MockRepositoryInterface) RecordOTPFailure(ctx context.Context, tx *sql.Tx, input RecordOTPFailureInput) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordOTPFailure", ctx, tx, input)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecordOTPFailure indicates an expected call of RecordOTPFailure.
+func (mr *MockRepositoryInterfaceMockRecorder) RecordOTPFailure(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordOTPFailure", reflect.TypeOf((*MockRepositoryInterface)(nil).RecordOTPFailure), ctx, tx, input)
+}
+
+// ReplaceMFARecoveryCodes mocks base method.
+func (m *MockRepositoryInterface) ReplaceMFARecoveryCodes(ctx context.Context, tx *sql.Tx, input ReplaceMFARecoveryCodesInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceMFARecoveryCodes", ctx, tx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceMFARecoveryCodes indicates an expected call of ReplaceMFARecoveryCodes.
+func (mr *MockRepositoryInterfaceMockRecorder) ReplaceMFARecoveryCodes(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceMFARecoveryCodes", reflect.TypeOf((*MockRepositoryInterface)(nil).ReplaceMFARecoveryCodes), ctx, tx, input)
+}
+
+// ReplacePendingUser mocks base method.
+func (m *MockRepositoryInterface) ReplacePendingUser(ctx context.Context, tx *sql.Tx, input ReplacePendingUserInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplacePendingUser", ctx, tx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplacePendingUser indicates an expected call of ReplacePendingUser.
+func (mr *MockRepositoryInterfaceMockRecorder) ReplacePendingUser(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplacePendingUser", reflect.TypeOf((*MockRepositoryInterface)(nil).ReplacePendingUser), ctx, tx, input)
+}
+
+// ResetMFAFailures mocks base method.
+func (m *MockRepositoryInterface) ResetMFAFailures(ctx context.Context, tx *sql.Tx, userId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetMFAFailures", ctx, tx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetMFAFailures indicates an expected call of ResetMFAFailures.
+func (mr *MockRepositoryInterfaceMockRecorder) ResetMFAFailures(ctx, tx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetMFAFailures", reflect.TypeOf((*MockRepositoryInterface)(nil).ResetMFAFailures), ctx, tx, userId)
+}
+
+// ResetOTPFailures mocks base method.
+func (m *MockRepositoryInterface) ResetOTPFailures(ctx context.Context, tx *sql.Tx, userId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetOTPFailures", ctx, tx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetOTPFailures indicates an expected call of ResetOTPFailures.
+func (mr *MockRepositoryInterfaceMockRecorder) ResetOTPFailures(ctx, tx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetOTPFailures", reflect.TypeOf((*MockRepositoryInterface)(nil).ResetOTPFailures), ctx, tx, userId)
+}
+
+// ResetUserPassword mocks base method.
+func (m *MockRepositoryInterface) ResetUserPassword(ctx context.Context, tx *sql.Tx, input ResetUserPasswordInput) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetUserPassword", ctx, tx, input)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResetUserPassword indicates an expected call of ResetUserPassword.
+func (mr *MockRepositoryInterfaceMockRecorder) ResetUserPassword(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetUserPassword", reflect.TypeOf((*MockRepositoryInterface)(nil).ResetUserPassword), ctx, tx, input)
+}
+
+// RevokeOtherUserSessions mocks base method.
+func (m *MockRepositoryInterface) RevokeOtherUserSessions(ctx context.Context, tx *sql.Tx, input RevokeOtherUserSessionsInput) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeOtherUserSessions", ctx, tx, input)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeOtherUserSessions indicates an expected call of RevokeOtherUserSessions.
+func (mr *MockRepositoryInterfaceMockRecorder) RevokeOtherUserSessions(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOtherUserSessions", reflect.TypeOf((*MockRepositoryInterface)(nil).RevokeOtherUserSessions), ctx, tx, input)
+}
+
+// RevokeRefreshTokenFamily mocks base method.
+func (m *MockRepositoryInterface) RevokeRefreshTokenFamily(ctx context.Context, tx *sql.Tx, familyId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeRefreshTokenFamily", ctx, tx, familyId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeRefreshTokenFamily indicates an expected call of RevokeRefreshTokenFamily.
+func (mr *MockRepositoryInterfaceMockRecorder) RevokeRefreshTokenFamily(ctx, tx, familyId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRefreshTokenFamily", reflect.TypeOf((*MockRepositoryInterface)(nil).RevokeRefreshTokenFamily), ctx, tx, familyId)
+}
+
+// RevokeUserRefreshTokens mocks base method.
+func (m *MockRepositoryInterface) RevokeUserRefreshTokens(ctx context.Context, tx *sql.Tx, userId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserRefreshTokens", ctx, tx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeUserRefreshTokens indicates an expected call of RevokeUserRefreshTokens.
+func (mr *MockRepositoryInterfaceMockRecorder) RevokeUserRefreshTokens(ctx, tx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserRefreshTokens", reflect.TypeOf((*MockRepositoryInterface)(nil).RevokeUserRefreshTokens), ctx, tx, userId)
+}
+
+// RevokeUserSession mocks base method.
+func (m *MockRepositoryInterface) RevokeUserSession(ctx context.Context, tx *sql.Tx, input RevokeUserSessionInput) (UserSession, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserSession", ctx, tx, input)
+	ret0, _ := ret[0].(UserSession)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeUserSession indicates an expected call of RevokeUserSession.
+func (mr *MockRepositoryInterfaceMockRecorder) RevokeUserSession(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserSession", reflect.TypeOf((*MockRepositoryInterface)(nil).RevokeUserSession), ctx, tx, input)
+}
+
+// RevokeUserSessions mocks base method.
+func (m *MockRepositoryInterface) RevokeUserSessions(ctx context.Context, tx *sql.Tx, userId string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserSessions", ctx, tx, userId)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeUserSessions indicates an expected call of RevokeUserSessions.
+func (mr *MockRepositoryInterfaceMockRecorder) RevokeUserSessions(ctx, tx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserSessions", reflect.TypeOf((*MockRepositoryInterface)(nil).RevokeUserSessions), ctx, tx, userId)
+}
+
+// RotateRefreshToken mocks base method.
+func (m *MockRepositoryInterface) RotateRefreshToken(ctx context.Context, tx *sql.Tx, input RotateRefreshTokenInput) (InsertRefreshTokenOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateRefreshToken", ctx, tx, input)
+	ret0, _ := ret[0].(InsertRefreshTokenOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateRefreshToken indicates an expected call of RotateRefreshToken.
+func (mr *MockRepositoryInterfaceMockRecorder) RotateRefreshToken(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateRefreshToken", reflect.TypeOf((*MockRepositoryInterface)(nil).RotateRefreshToken), ctx, tx, input)
+}
+
+// SetPendingPhoneNumber mocks base method.
+func (m *MockRepositoryInterface) SetPendingPhoneNumber(ctx context.Context, tx *sql.Tx, input SetPendingPhoneNumberInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPendingPhoneNumber", ctx, tx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPendingPhoneNumber indicates an expected call of SetPendingPhoneNumber.
+func (mr *MockRepositoryInterfaceMockRecorder) SetPendingPhoneNumber(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPendingPhoneNumber", reflect.TypeOf((*MockRepositoryInterface)(nil).SetPendingPhoneNumber), ctx, tx, input)
+}
+
+// TouchUserSession mocks base method.
+func (m *MockRepositoryInterface) TouchUserSession(ctx context.Context, tx *sql.Tx, input TouchUserSessionInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchUserSession", ctx, tx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchUserSession indicates an expected call of TouchUserSession.
+func (mr *MockRepositoryInterfaceMockRecorder) TouchUserSession(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchUserSession", reflect.TypeOf((*MockRepositoryInterface)(nil).TouchUserSession), ctx, tx, input)
+}
+
+// UpdatePasskeyName mocks base method.
+func (m *MockRepositoryInterface) UpdatePasskeyName(ctx context.Context, tx *sql.Tx, input UpdatePasskeyNameInput) (Passkey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePasskeyName", ctx, tx, input)
+	ret0, _ := ret[0].(Passkey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdatePasskeyName indicates an expected call of UpdatePasskeyName.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdatePasskeyName(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasskeyName", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdatePasskeyName), ctx, tx, input)
+}
+
 // UpdateUser mocks base method.
 func (m *MockRepositoryInterface) UpdateUser(ctx context.Context, tx *sql.Tx, input User) error {
 	m.ctrl.T.Helper()
@@ -92,3 +691,88 @@ func (mr *MockRepositoryInterfaceMockRecorder) UpdateUser(ctx, tx, input interfa
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateUser), ctx, tx, input)
 }
+
+// UpdateUserCredentials mocks base method.
+func (m *MockRepositoryInterface) UpdateUserCredentials(ctx context.Context, tx *sql.Tx, input UpdateUserCredentialsInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserCredentials", ctx, tx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserCredentials indicates an expected call of UpdateUserCredentials.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateUserCredentials(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserCredentials", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateUserCredentials), ctx, tx, input)
+}
+
+// UpsertUserTOTP mocks base method.
+func (m *MockRepositoryInterface) UpsertUserTOTP(ctx context.Context, tx *sql.Tx, input UpsertUserTOTPInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertUserTOTP", ctx, tx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertUserTOTP indicates an expected call of UpsertUserTOTP.
+func (mr *MockRepositoryInterfaceMockRecorder) UpsertUserTOTP(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertUserTOTP", reflect.TypeOf((*MockRepositoryInterface)(nil).UpsertUserTOTP), ctx, tx, input)
+}
+
+// UseMFARecoveryCode mocks base method.
+func (m *MockRepositoryInterface) UseMFARecoveryCode(ctx context.Context, tx *sql.Tx, input UseMFARecoveryCodeInput) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseMFARecoveryCode", ctx, tx, input)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseMFARecoveryCode indicates an expected call of UseMFARecoveryCode.
+func (mr *MockRepositoryInterfaceMockRecorder) UseMFARecoveryCode(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseMFARecoveryCode", reflect.TypeOf((*MockRepositoryInterface)(nil).UseMFARecoveryCode), ctx, tx, input)
+}
+
+// UsePasskey mocks base method.
+func (m *MockRepositoryInterface) UsePasskey(ctx context.Context, tx *sql.Tx, input UsePasskeyInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UsePasskey", ctx, tx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UsePasskey indicates an expected call of UsePasskey.
+func (mr *MockRepositoryInterfaceMockRecorder) UsePasskey(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UsePasskey", reflect.TypeOf((*MockRepositoryInterface)(nil).UsePasskey), ctx, tx, input)
+}
+
+// UseUserTOTPStep mocks base method.
+func (m *MockRepositoryInterface) UseUserTOTPStep(ctx context.Context, tx *sql.Tx, input UseUserTOTPStepInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseUserTOTPStep", ctx, tx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseUserTOTPStep indicates an expected call of UseUserTOTPStep.
+func (mr *MockRepositoryInterfaceMockRecorder) UseUserTOTPStep(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseUserTOTPStep", reflect.TypeOf((*MockRepositoryInterface)(nil).UseUserTOTPStep), ctx, tx, input)
+}
+
+// VerifyUserPhone mocks base method.
+func (m *MockRepositoryInterface) VerifyUserPhone(ctx context.Context, tx *sql.Tx, userId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyUserPhone", ctx, tx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyUserPhone indicates an expected call of VerifyUserPhone.
+func (mr *MockRepositoryInterfaceMockRecorder) VerifyUserPhone(ctx, tx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyUserPhone", reflect.TypeOf((*MockRepositoryInterface)(nil).VerifyUserPhone), ctx, tx, userId)
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+)
+
+// ListPasskeys returns the passkeys of the user, oldest first.
+func (r *Repository) ListPasskeys(ctx context.Context, userId string) (output []Passkey, err error) {
+	if userId == "" {
+		return output, ErrInvalidInputParam
+	}
+
+	q := `
+	SELECT` + passkeyColumns + `
+	FROM passkeys
+	WHERE user_id = $1
+	ORDER BY created_at ASC
+	`
+
+	rows, err := r.Db.QueryContext(ctx, q, userId)
+	if err != nil {
+		return output, err
+	}
+	defer rows.Close()
+
+	output = []Passkey{}
+	for rows.Next() {
+		passkey, err := scanPasskey(rows)
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, passkey)
+	}
+
+	return output, rows.Err()
+}
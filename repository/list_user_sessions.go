@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// ListUserSessions returns the active sessions of the user, most recently used first.
+func (r *Repository) ListUserSessions(ctx context.Context, userId string) (output []UserSession, err error) {
+	if userId == "" {
+		return output, ErrInvalidInputParam
+	}
+
+	q := `
+	SELECT
+		id,
+		created_at,
+		user_id,
+		user_agent,
+		ip_address,
+		device_label,
+		last_used_at,
+		expires_at,
+		revoked_at
+	FROM user_sessions
+	WHERE user_id = $1
+		AND revoked_at IS NULL
+		AND expires_at > $2
+	ORDER BY last_used_at DESC
+	`
+
+	rows, err := r.Db.QueryContext(ctx, q, userId, time.Now().UTC())
+	if err != nil {
+		return output, err
+	}
+	defer rows.Close()
+
+	output = []UserSession{}
+	for rows.Next() {
+		var session UserSession
+		if err := rows.Scan(
+			&session.Id,
+			&session.CreatedAt,
+			&session.UserId,
+			&session.UserAgent,
+			&session.IpAddress,
+			&session.DeviceLabel,
+			&session.LastUsedAt,
+			&session.ExpiresAt,
+			&session.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		output = append(output, session)
+	}
+
+	return output, rows.Err()
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"strings"
+)
+
+// passkeyColumns are the columns scanned by scanPasskey, in order
+const passkeyColumns = `
+		id,
+		created_at,
+		updated_at,
+		user_id,
+		credential_id,
+		public_key,
+		sign_count,
+		transports,
+		name,
+		backup_eligible,
+		backed_up,
+		last_used_at
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPasskey(row rowScanner) (output Passkey, err error) {
+	var signCount int64
+	var transports string
+	err = row.Scan(
+		&output.Id,
+		&output.CreatedAt,
+		&output.UpdatedAt,
+		&output.UserId,
+		&output.CredentialId,
+		&output.PublicKey,
+		&signCount,
+		&transports,
+		&output.Name,
+		&output.BackupEligible,
+		&output.BackedUp,
+		&output.LastUsedAt,
+	)
+	if err != nil {
+		return Passkey{}, err
+	}
+
+	output.SignCount = uint32(signCount)
+	output.Transports = splitTransports(transports)
+
+	return output, nil
+}
+
+func joinTransports(transports []string) string {
+	return strings.Join(transports, ",")
+}
+
+func splitTransports(transports string) []string {
+	if transports == "" {
+		return []string{}
+	}
+
+	return strings.Split(transports, ",")
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// Prune deletes the one-time codes, MFA challenges & passkey challenges that already expired,
+// consumed or not, an expired one is never used again.
+func (r *Repository) Prune(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	for _, query := range []string{
+		`DELETE FROM one_time_codes WHERE expires_at <= $1`,
+		`DELETE FROM mfa_challenges WHERE expires_at <= $1`,
+		`DELETE FROM webauthn_challenges WHERE expires_at <= $1`,
+	} {
+		if _, err := r.Db.ExecContext(ctx, query, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
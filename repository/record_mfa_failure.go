@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// RecordMFAFailure counts a wrong MFA code of the user, whatever the MFA challenge. Reaching MaxFailures
+// wrong codes in a row locks MFA of the user for LockoutDuration and starts counting again, locked is
+// true for the wrong code that locked it.
+func (r *Repository) RecordMFAFailure(ctx context.Context, tx *sql.Tx, input RecordMFAFailureInput) (locked bool, err error) {
+	if input.UserId == "" || input.MaxFailures <= 0 || input.LockoutDuration <= 0 {
+		return false, ErrInvalidInputParam
+	}
+
+	lockedUntil := time.Now().UTC().Add(input.LockoutDuration)
+	query := `
+		UPDATE users
+		SET 
+			mfa_failed_attempts = CASE WHEN mfa_failed_attempts + 1 >= $2 THEN 0 ELSE mfa_failed_attempts + 1 END,
+			mfa_locked_until = CASE WHEN mfa_failed_attempts + 1 >= $2 THEN $3 ELSE mfa_locked_until END
+		WHERE id = $1
+		RETURNING mfa_failed_attempts = 0
+	`
+	params := []interface{}{
+		input.UserId,
+		input.MaxFailures,
+		lockedUntil,
+	}
+
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, query, params...).Scan(&locked)
+	} else {
+		err = r.Db.QueryRowContext(ctx, query, params...).Scan(&locked)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return locked, nil
+}
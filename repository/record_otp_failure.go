@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// RecordOTPFailure counts a wrong code of the user to log in or reset the password, whatever the code.
+// Reaching MaxFailures wrong codes in a row locks these codes of the user for LockoutDuration and starts
+// counting again, locked is true for the wrong code that locked them.
+func (r *Repository) RecordOTPFailure(ctx context.Context, tx *sql.Tx, input RecordOTPFailureInput) (locked bool, err error) {
+	if input.UserId == "" || input.MaxFailures <= 0 || input.LockoutDuration <= 0 {
+		return false, ErrInvalidInputParam
+	}
+
+	lockedUntil := time.Now().UTC().Add(input.LockoutDuration)
+	query := `
+		UPDATE users
+		SET 
+			otp_failed_attempts = CASE WHEN otp_failed_attempts + 1 >= $2 THEN 0 ELSE otp_failed_attempts + 1 END,
+			otp_locked_until = CASE WHEN otp_failed_attempts + 1 >= $2 THEN $3 ELSE otp_locked_until END
+		WHERE id = $1
+		RETURNING otp_failed_attempts = 0
+	`
+	params := []interface{}{
+		input.UserId,
+		input.MaxFailures,
+		lockedUntil,
+	}
+
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, query, params...).Scan(&locked)
+	} else {
+		err = r.Db.QueryRowContext(ctx, query, params...).Scan(&locked)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return locked, nil
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReplaceMFARecoveryCodes stores a new set of recovery codes of the user, the previous set is deleted
+// so its unused codes stop working.
+func (r *Repository) ReplaceMFARecoveryCodes(ctx context.Context, tx *sql.Tx, input ReplaceMFARecoveryCodesInput) (err error) {
+	if input.UserId == "" || len(input.CodeHashes) == 0 {
+		return ErrInvalidInputParam
+	}
+
+	if tx == nil {
+		tx, err = r.Db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			err = tx.Commit()
+		}()
+	}
+
+	query := `
+		DELETE FROM mfa_recovery_codes
+		WHERE user_id = $1
+	`
+	if _, err = tx.ExecContext(ctx, query, input.UserId); err != nil {
+		return err
+	}
+
+	createdAt := time.Now().UTC()
+	query = `
+		INSERT INTO mfa_recovery_codes (id, created_at, user_id, code_hash)
+		VALUES ($1, $2, $3, $4)
+	`
+	for _, codeHash := range input.CodeHashes {
+		if _, err = tx.ExecContext(ctx, query, uuid.NewString(), createdAt, input.UserId, codeHash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
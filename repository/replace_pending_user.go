@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// ReplacePendingUser overwrites the name & password of a pending registration, the codes sent
+// for the replaced registration are marked used so none of them verifies the new one.
+// It returns sql.ErrNoRows when the phone number was verified in the meantime.
+func (r *Repository) ReplacePendingUser(ctx context.Context, tx *sql.Tx, input ReplacePendingUserInput) (err error) {
+	if input.Id == "" || input.PasswordHash == "" {
+		return ErrInvalidInputParam
+	}
+
+	if tx == nil {
+		tx, err = r.Db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			err = tx.Commit()
+		}()
+	}
+
+	updatedAt := time.Now().UTC()
+	query := `
+		UPDATE users
+		SET
+			updated_at = $2,
+			full_name = $3,
+			password_hash = $4,
+			salt = NULL
+		WHERE id = $1
+			AND phone_verified_at IS NULL
+		RETURNING id
+	`
+	params := []interface{}{
+		input.Id,
+		updatedAt,
+		input.FullName,
+		input.PasswordHash,
+	}
+
+	var replacedId string
+	if err = tx.QueryRowContext(ctx, query, params...).Scan(&replacedId); err != nil {
+		return err
+	}
+
+	// a pending user can't log in, every code it has was sent to verify the phone number
+	query = `
+		UPDATE one_time_codes
+		SET
+			consumed_at = $2
+		WHERE user_id = $1
+			AND consumed_at IS NULL
+	`
+	_, err = tx.ExecContext(ctx, query, input.Id, updatedAt)
+
+	return err
+}
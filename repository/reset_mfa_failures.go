@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ResetMFAFailures forgets the wrong MFA codes of the user once a right one is given.
+func (r *Repository) ResetMFAFailures(ctx context.Context, tx *sql.Tx, userId string) (err error) {
+	if userId == "" {
+		return ErrInvalidInputParam
+	}
+
+	query := `
+		UPDATE users
+		SET 
+			mfa_failed_attempts = 0,
+			mfa_locked_until = NULL
+		WHERE id = $1
+	`
+
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, userId)
+	} else {
+		_, err = r.Db.ExecContext(ctx, query, userId)
+	}
+
+	return err
+}
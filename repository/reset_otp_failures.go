@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ResetOTPFailures forgets the wrong codes of the user to log in or reset the password once a right one is given.
+func (r *Repository) ResetOTPFailures(ctx context.Context, tx *sql.Tx, userId string) (err error) {
+	if userId == "" {
+		return ErrInvalidInputParam
+	}
+
+	query := `
+		UPDATE users
+		SET 
+			otp_failed_attempts = 0,
+			otp_locked_until = NULL
+		WHERE id = $1
+	`
+
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, userId)
+	} else {
+		_, err = r.Db.ExecContext(ctx, query, userId)
+	}
+
+	return err
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ResetUserPassword consumes the one-time code, replaces the password of the user and ends every session
+// of the user with its refresh tokens and passkeys in the same transaction: the code is only used up along
+// with the new password, and no session outlives the reset. It returns sql.ErrNoRows when the code was
+// already consumed, see ConsumeOneTimeCode, UpdateUserCredentials, RevokeUserSessions & DeleteUserPasskeys.
+func (r *Repository) ResetUserPassword(ctx context.Context, tx *sql.Tx, input ResetUserPasswordInput) (deletedPasskeys int64, err error) {
+	if input.CodeId == "" || input.Credentials.Id == "" {
+		return 0, ErrInvalidInputParam
+	}
+
+	if tx == nil {
+		tx, err = r.Db.BeginTx(ctx, nil)
+		if err != nil {
+			return 0, err
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			err = tx.Commit()
+		}()
+	}
+
+	if err = r.ConsumeOneTimeCode(ctx, tx, input.CodeId); err != nil {
+		return 0, err
+	}
+	if err = r.UpdateUserCredentials(ctx, tx, input.Credentials); err != nil {
+		return 0, err
+	}
+	if err = r.RevokeUserRefreshTokens(ctx, tx, input.Credentials.Id); err != nil {
+		return 0, err
+	}
+	if _, err = r.RevokeUserSessions(ctx, tx, input.Credentials.Id); err != nil {
+		return 0, err
+	}
+
+	return r.DeleteUserPasskeys(ctx, tx, input.Credentials.Id)
+}
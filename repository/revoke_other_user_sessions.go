@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// RevokeOtherUserSessions revokes every active session of the user but KeepSessionId,
+// together with their refresh tokens and the refresh tokens issued before sessions were recorded.
+// It returns the ids of the revoked sessions.
+func (r *Repository) RevokeOtherUserSessions(ctx context.Context, tx *sql.Tx, input RevokeOtherUserSessionsInput) (revokedIds []string, err error) {
+	if input.UserId == "" {
+		return nil, ErrInvalidInputParam
+	}
+
+	if tx == nil {
+		tx, err = r.Db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			err = tx.Commit()
+		}()
+	}
+
+	revokedAt := time.Now().UTC()
+	query := `
+		UPDATE user_sessions
+		SET 
+			revoked_at = $3
+		WHERE user_id = $1
+			AND id IS DISTINCT FROM NULLIF($2, '')::uuid
+			AND revoked_at IS NULL
+		RETURNING id
+	`
+	rows, err := tx.QueryContext(ctx, query, input.UserId, input.KeepSessionId, revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revokedIds = []string{}
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		revokedIds = append(revokedIds, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `
+		UPDATE refresh_tokens
+		SET 
+			revoked_at = $3
+		WHERE user_id = $1
+			AND session_id IS DISTINCT FROM NULLIF($2, '')::uuid
+			AND revoked_at IS NULL
+	`
+	if _, err = tx.ExecContext(ctx, query, input.UserId, input.KeepSessionId, revokedAt); err != nil {
+		return nil, err
+	}
+
+	return revokedIds, nil
+}
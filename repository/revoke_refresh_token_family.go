@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+func (r *Repository) RevokeRefreshTokenFamily(ctx context.Context, tx *sql.Tx, familyId string) (err error) {
+	if familyId == "" {
+		return ErrInvalidInputParam
+	}
+
+	revokedAt := time.Now().UTC()
+	query := `
+		UPDATE refresh_tokens
+		SET 
+			revoked_at = $2
+		WHERE family_id = $1
+			AND revoked_at IS NULL
+	`
+	params := []interface{}{
+		familyId,
+		revokedAt,
+	}
+
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, params...)
+	} else {
+		_, err = r.Db.ExecContext(ctx, query, params...)
+	}
+
+	return err
+}
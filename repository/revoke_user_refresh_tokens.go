@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+func (r *Repository) RevokeUserRefreshTokens(ctx context.Context, tx *sql.Tx, userId string) (err error) {
+	if userId == "" {
+		return ErrInvalidInputParam
+	}
+
+	revokedAt := time.Now().UTC()
+	query := `
+		UPDATE refresh_tokens
+		SET 
+			revoked_at = $2
+		WHERE user_id = $1
+			AND revoked_at IS NULL
+	`
+	params := []interface{}{
+		userId,
+		revokedAt,
+	}
+
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, params...)
+	} else {
+		_, err = r.Db.ExecContext(ctx, query, params...)
+	}
+
+	return err
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// RevokeUserSession revokes an active session of the user together with its refresh tokens.
+// It returns sql.ErrNoRows when the user has no such active session.
+func (r *Repository) RevokeUserSession(ctx context.Context, tx *sql.Tx, input RevokeUserSessionInput) (output UserSession, err error) {
+	if input.Id == "" || input.UserId == "" {
+		return output, ErrInvalidInputParam
+	}
+
+	if tx == nil {
+		tx, err = r.Db.BeginTx(ctx, nil)
+		if err != nil {
+			return output, err
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			err = tx.Commit()
+		}()
+	}
+
+	revokedAt := time.Now().UTC()
+	query := `
+		UPDATE user_sessions
+		SET 
+			revoked_at = $3
+		WHERE id = $1
+			AND user_id = $2
+			AND revoked_at IS NULL
+		RETURNING
+			id,
+			created_at,
+			user_id,
+			user_agent,
+			ip_address,
+			device_label,
+			last_used_at,
+			expires_at,
+			revoked_at
+	`
+
+	err = tx.QueryRowContext(ctx, query, input.Id, input.UserId, revokedAt).Scan(
+		&output.Id,
+		&output.CreatedAt,
+		&output.UserId,
+		&output.UserAgent,
+		&output.IpAddress,
+		&output.DeviceLabel,
+		&output.LastUsedAt,
+		&output.ExpiresAt,
+		&output.RevokedAt,
+	)
+	if err != nil {
+		return UserSession{}, err
+	}
+
+	query = `
+		UPDATE refresh_tokens
+		SET 
+			revoked_at = $2
+		WHERE session_id = $1
+			AND revoked_at IS NULL
+	`
+	if _, err = tx.ExecContext(ctx, query, input.Id, revokedAt); err != nil {
+		return UserSession{}, err
+	}
+
+	return output, nil
+}
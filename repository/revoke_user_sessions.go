@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// RevokeUserSessions revokes every active session of the user and returns the ids of the revoked sessions.
+func (r *Repository) RevokeUserSessions(ctx context.Context, tx *sql.Tx, userId string) (revokedIds []string, err error) {
+	if userId == "" {
+		return nil, ErrInvalidInputParam
+	}
+
+	revokedAt := time.Now().UTC()
+	query := `
+		UPDATE user_sessions
+		SET 
+			revoked_at = $2
+		WHERE user_id = $1
+			AND revoked_at IS NULL
+		RETURNING id
+	`
+	params := []interface{}{
+		userId,
+		revokedAt,
+	}
+
+	var rows *sql.Rows
+	if tx != nil {
+		rows, err = tx.QueryContext(ctx, query, params...)
+	} else {
+		rows, err = r.Db.QueryContext(ctx, query, params...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revokedIds = []string{}
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		revokedIds = append(revokedIds, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revokedIds, nil
+}
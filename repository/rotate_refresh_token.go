@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// RotateRefreshToken marks the current refresh token as rotated and inserts its successor
+// in the same family. It returns ErrRefreshTokenAlreadyRotated when the current token has
+// been rotated or revoked in the meantime, so two concurrent refreshes can't both succeed.
+func (r *Repository) RotateRefreshToken(ctx context.Context, tx *sql.Tx, input RotateRefreshTokenInput) (output InsertRefreshTokenOutput, err error) {
+	if input.CurrentId == "" || input.Next.FamilyId == "" {
+		return output, ErrInvalidInputParam
+	}
+
+	if tx == nil {
+		tx, err = r.Db.BeginTx(ctx, nil)
+		if err != nil {
+			return output, err
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			err = tx.Commit()
+		}()
+	}
+
+	rotatedAt := time.Now().UTC()
+	query := `
+		UPDATE refresh_tokens
+		SET 
+			rotated_at = $2
+		WHERE id = $1
+			AND rotated_at IS NULL
+			AND revoked_at IS NULL
+	`
+
+	res, err := tx.ExecContext(ctx, query, input.CurrentId, rotatedAt)
+	if err != nil {
+		return output, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return output, err
+	}
+	if affected == 0 {
+		return output, ErrRefreshTokenAlreadyRotated
+	}
+
+	return r.InsertRefreshToken(ctx, tx, input.Next)
+}
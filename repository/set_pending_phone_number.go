@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SetPendingPhoneNumber records the new phone number of the user until it is confirmed with ChangeUserPhoneNumber,
+// it replaces a previous pending phone number.
+func (r *Repository) SetPendingPhoneNumber(ctx context.Context, tx *sql.Tx, input SetPendingPhoneNumberInput) (err error) {
+	if input.UserId == "" || input.PhoneNumber == "" {
+		return ErrInvalidInputParam
+	}
+
+	updatedAt := time.Now().UTC()
+	query := `
+		UPDATE users
+		SET
+			updated_at = $2,
+			pending_phone_number = $3
+		WHERE id = $1
+	`
+	params := []interface{}{
+		input.UserId,
+		updatedAt,
+		input.PhoneNumber,
+	}
+
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, params...)
+	} else {
+		_, err = r.Db.ExecContext(ctx, query, params...)
+	}
+
+	return err
+}
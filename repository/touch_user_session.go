@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// TouchUserSession records a refresh of the session, extending it to the new refresh token expiry.
+func (r *Repository) TouchUserSession(ctx context.Context, tx *sql.Tx, input TouchUserSessionInput) (err error) {
+	if input.Id == "" {
+		return ErrInvalidInputParam
+	}
+
+	lastUsedAt := time.Now().UTC()
+	query := `
+		UPDATE user_sessions
+		SET 
+			last_used_at = $2,
+			expires_at = $3
+		WHERE id = $1
+			AND revoked_at IS NULL
+	`
+	params := []interface{}{
+		input.Id,
+		lastUsedAt,
+		input.ExpiresAt.UTC(),
+	}
+
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, params...)
+	} else {
+		_, err = r.Db.ExecContext(ctx, query, params...)
+	}
+
+	return err
+}
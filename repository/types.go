@@ -10,14 +10,15 @@ import (
 )
 
 var (
-	ErrInvalidInputParam = errors.New("invalid input param")
+	ErrInvalidInputParam          = errors.New("invalid input param")
+	ErrRefreshTokenAlreadyRotated = errors.New("refresh token already rotated or revoked")
 )
 
 type InsertUserInput struct {
-	PhoneNumber  string
-	FullName     string
+	PhoneNumber string
+	FullName    string
+	// PasswordHash is a PHC string, the salt is part of it
 	PasswordHash string
-	Salt         string
 }
 
 type InsertUserOutput struct {
@@ -37,10 +38,353 @@ type User struct {
 	PhoneNumber  string
 	FullName     string
 	PasswordHash string
-	Salt         string
-	LoginCount   uint32
+	// Salt is only set for legacy SHA-256 password hashes
+	Salt       string
+	LoginCount uint32
+	// PhoneVerifiedAt is nil while the registration is pending, a pending user can't log in
+	PhoneVerifiedAt *time.Time
+	// PendingPhoneNumber is the new phone number waiting for the code sent to it, empty without a change in progress
+	PendingPhoneNumber string
+	// TOTPEnabled is true once the user confirmed a TOTP authenticator, log in then asks for a code of it
+	TOTPEnabled bool
+	// MFAFailedAttempts is the number of wrong MFA codes in a row, MFALockedUntil is set once they reached the lockout threshold
+	MFAFailedAttempts int
+	MFALockedUntil    *time.Time
+	// OTPFailedAttempts is the number of wrong codes to log in or reset the password in a row, OTPLockedUntil is set
+	// once they reached the lockout threshold
+	OTPFailedAttempts int
+	OTPLockedUntil    *time.Time
+}
+
+// ReplacePendingUserInput is a new registration for the phone number of a pending user
+type ReplacePendingUserInput struct {
+	Id       string
+	FullName string
+	// PasswordHash is a PHC string
+	PasswordHash string
+}
+
+type SetPendingPhoneNumberInput struct {
+	UserId      string
+	PhoneNumber string
+}
+
+type ChangeUserPhoneNumberInput struct {
+	UserId string
+	// PhoneNumber is the pending phone number the code was sent to
+	PhoneNumber string
+}
+
+type UpdateUserCredentialsInput struct {
+	Id string
+	// PasswordHash is a PHC string, the legacy salt is cleared along with the old hash
+	PasswordHash string
+	// HistorySize is the number of replaced hashes kept in the password history, the replaced
+	// hash is added and older ones are pruned. 0 keeps none.
+	HistorySize int
+	// Rehash replaces the hash of the same password, e.g. with a stronger algorithm,
+	// the password history is left as is.
+	Rehash bool
+}
+
+type ChangeUserPasswordInput struct {
+	Credentials UpdateUserCredentialsInput
+	// KeepSessionId stays active, every session is revoked when empty
+	KeepSessionId string
+}
+
+type ResetUserPasswordInput struct {
+	// CodeId is the one-time code of the reset, see ConsumeOneTimeCode
+	CodeId      string
+	Credentials UpdateUserCredentialsInput
+}
+
+type PasswordHistory struct {
+	Id           string
+	CreatedAt    time.Time
+	UserId       string
+	PasswordHash string
+	// Salt is only set for legacy hashes
+	Salt string
+}
+
+type GetPasswordHistoryInput struct {
+	UserId string
+	Limit  int
+}
+
+type RefreshToken struct {
+	Id        string
+	CreatedAt *time.Time
+	UserId    string
+	FamilyId  string
+	// SessionId is empty for tokens issued before sessions were recorded
+	SessionId string
+	TokenHash string
+	ExpiresAt time.Time
+	RotatedAt *time.Time
+	RevokedAt *time.Time
+}
+
+type InsertRefreshTokenInput struct {
+	UserId string
+	// FamilyId groups every token rotated from the same log in,
+	// leave it empty to start a new family.
+	FamilyId  string
+	SessionId string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+type InsertRefreshTokenOutput struct {
+	Id       string
+	FamilyId string
+}
+
+type GetRefreshTokenInput struct {
+	TokenHash string
+}
+
+type RotateRefreshTokenInput struct {
+	CurrentId string
+	Next      InsertRefreshTokenInput
+}
+
+type UserSession struct {
+	Id          string
+	CreatedAt   time.Time
+	UserId      string
+	UserAgent   string
+	IpAddress   string
+	DeviceLabel string
+	LastUsedAt  time.Time
+	ExpiresAt   time.Time
+	RevokedAt   *time.Time
+}
+
+type InsertUserSessionInput struct {
+	UserId      string
+	UserAgent   string
+	IpAddress   string
+	DeviceLabel string
+	ExpiresAt   time.Time
+}
+
+type InsertUserSessionOutput struct {
+	Id string
+}
+
+type TouchUserSessionInput struct {
+	Id string
+	// ExpiresAt is the expiry of the refresh token just issued for the session
+	ExpiresAt time.Time
+}
+
+type RevokeUserSessionInput struct {
+	Id     string
+	UserId string
+}
+
+type RevokeOtherUserSessionsInput struct {
+	UserId string
+	// KeepSessionId stays active, every session is revoked when empty
+	KeepSessionId string
+}
+
+type OneTimeCode struct {
+	Id        string
+	CreatedAt time.Time
+	UserId    string
+	Purpose   string
+	CodeHash  string
+	// Attempts counts the verifications of the code, right or wrong
+	Attempts   int
+	ExpiresAt  time.Time
+	ConsumedAt *time.Time
+}
+
+type InsertOneTimeCodeInput struct {
+	UserId    string
+	Purpose   string
+	CodeHash  string
+	ExpiresAt time.Time
+}
+
+type InsertOneTimeCodeOutput struct {
+	Id string
+}
+
+type GetActiveOneTimeCodeInput struct {
+	UserId  string
+	Purpose string
+}
+
+type IncrementOneTimeCodeAttemptsInput struct {
+	Id          string
+	MaxAttempts int
+}
+
+type UserTOTP struct {
+	UserId    string
+	CreatedAt time.Time
+	// SecretCiphertext is the encrypted base32 secret, see encryption.Keyring
+	SecretCiphertext string
+	// ConfirmedAt is nil until a first code confirms the enrollment
+	ConfirmedAt *time.Time
+	// LastUsedStep is the time step of the last accepted code
+	LastUsedStep int64
+}
+
+type UpsertUserTOTPInput struct {
+	UserId           string
+	SecretCiphertext string
+}
+
+type UseUserTOTPStepInput struct {
+	UserId string
+	// Step is the time step of the accepted code, it must be after the last used one
+	Step int64
+	// Confirm accepts the first code of an enrollment, otherwise the enrollment must be confirmed
+	Confirm bool
+}
+
+type MFAChallenge struct {
+	Id          string
+	CreatedAt   time.Time
+	UserId      string
+	TokenHash   string
+	DeviceLabel string
+	// Attempts counts the codes tried for the challenge, right or wrong
+	Attempts   int
+	ExpiresAt  time.Time
+	ConsumedAt *time.Time
+}
+
+type InsertMFAChallengeInput struct {
+	UserId      string
+	TokenHash   string
+	DeviceLabel string
+	ExpiresAt   time.Time
+}
+
+type InsertMFAChallengeOutput struct {
+	Id string
+}
+
+type IncrementMFAChallengeAttemptsInput struct {
+	Id          string
+	MaxAttempts int
+}
+
+type RecordMFAFailureInput struct {
+	UserId          string
+	MaxFailures     int
+	LockoutDuration time.Duration
+}
+
+type RecordOTPFailureInput struct {
+	UserId          string
+	MaxFailures     int
+	LockoutDuration time.Duration
 }
 
+type ReplaceMFARecoveryCodesInput struct {
+	UserId     string
+	CodeHashes []string
+}
+
+type UseMFARecoveryCodeInput struct {
+	UserId   string
+	CodeHash string
+}
+
+type InsertAuditLogInput struct {
+	UserId    string
+	Event     string
+	IpAddress string
+	UserAgent string
+	Details   string
+}
+
+type WebAuthnChallenge struct {
+	Id        string
+	CreatedAt time.Time
+	// UserId is empty for a sign in challenge, the user is only known from the passkey
+	UserId        string
+	Ceremony      string
+	ChallengeHash string
+	ExpiresAt     time.Time
+	ConsumedAt    *time.Time
+	// SteppedUpAt is set when the user with MFA confirmed the registration with a code
+	SteppedUpAt *time.Time
+}
+
+type InsertWebAuthnChallengeInput struct {
+	UserId        string
+	Ceremony      string
+	ChallengeHash string
+	ExpiresAt     time.Time
+	SteppedUp     bool
+}
+
+type ConsumeWebAuthnChallengeInput struct {
+	Ceremony      string
+	ChallengeHash string
+}
+
+type Passkey struct {
+	Id        string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserId    string
+	// CredentialId is base64url encoded
+	CredentialId string
+	// PublicKey is the COSE_Key of the credential
+	PublicKey      []byte
+	SignCount      uint32
+	Transports     []string
+	Name           string
+	BackupEligible bool
+	BackedUp       bool
+	LastUsedAt     *time.Time
+}
+
+type InsertPasskeyInput struct {
+	UserId         string
+	CredentialId   string
+	PublicKey      []byte
+	SignCount      uint32
+	Transports     []string
+	Name           string
+	BackupEligible bool
+	BackedUp       bool
+}
+
+type InsertPasskeyOutput struct {
+	Id        string
+	CreatedAt time.Time
+}
+
+type UpdatePasskeyNameInput struct {
+	Id     string
+	UserId string
+	Name   string
+}
+
+type DeletePasskeyInput struct {
+	Id     string
+	UserId string
+}
+
+type UsePasskeyInput struct {
+	Id string
+	// PrevSignCount is the counter the assertion was verified against, a concurrent assertion changing it fails the update
+	PrevSignCount uint32
+	SignCount     uint32
+	BackedUp      bool
+}
+
+// UpdateByReq applies the full name of req, a new phone number is only set once confirmed, see ChangeUserPhoneNumber.
 func (u *User) UpdateByReq(req generated.UpdateUserJSONRequestBody) bool {
 	if u == nil {
 		return false
@@ -53,10 +397,5 @@ func (u *User) UpdateByReq(req generated.UpdateUserJSONRequestBody) bool {
 		updated = true
 	}
 
-	if reqPhoneNumber := string_helper.GetAndTrimPointerStringValue(req.PhoneNumber); reqPhoneNumber != "" && reqPhoneNumber != u.PhoneNumber {
-		u.PhoneNumber = reqPhoneNumber
-		updated = true
-	}
-
 	return updated
 }
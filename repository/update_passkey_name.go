@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// UpdatePasskeyName renames a passkey of the user, it returns sql.ErrNoRows when the user has no such passkey.
+func (r *Repository) UpdatePasskeyName(ctx context.Context, tx *sql.Tx, input UpdatePasskeyNameInput) (output Passkey, err error) {
+	if input.Id == "" || input.UserId == "" || input.Name == "" {
+		return output, ErrInvalidInputParam
+	}
+
+	query := `
+		UPDATE passkeys
+		SET 
+			name = $3,
+			updated_at = $4
+		WHERE id = $1
+			AND user_id = $2
+		RETURNING` + passkeyColumns
+	params := []interface{}{
+		input.Id,
+		input.UserId,
+		input.Name,
+		time.Now().UTC(),
+	}
+
+	if tx != nil {
+		return scanPasskey(tx.QueryRowContext(ctx, query, params...))
+	}
+
+	return scanPasskey(r.Db.QueryRowContext(ctx, query, params...))
+}
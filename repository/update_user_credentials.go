@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// UpdateUserCredentials replaces the password hash of the user. Unless it is a rehash,
+// the replaced hash goes to the password history, pruned to the input HistorySize.
+func (r *Repository) UpdateUserCredentials(ctx context.Context, tx *sql.Tx, input UpdateUserCredentialsInput) (err error) {
+
+	if input.Id == "" || input.PasswordHash == "" || input.HistorySize < 0 {
+		return ErrInvalidInputParam
+	}
+
+	if tx == nil {
+		tx, err = r.Db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			err = tx.Commit()
+		}()
+	}
+
+	updatedAt := time.Now().UTC()
+
+	if !input.Rehash && input.HistorySize > 0 {
+		query := `
+			INSERT INTO password_history (created_at, user_id, password_hash, salt)
+			SELECT $2, id, password_hash, salt
+			FROM users
+			WHERE id = $1
+		`
+		if _, err = tx.ExecContext(ctx, query, input.Id, updatedAt); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		UPDATE users
+		SET
+			updated_at = $2,
+			password_hash = $3,
+			salt = NULL
+		WHERE id = $1
+	`
+	params := []interface{}{
+		input.Id,
+		updatedAt,
+		input.PasswordHash,
+	}
+	if _, err = tx.ExecContext(ctx, query, params...); err != nil {
+		return err
+	}
+
+	if !input.Rehash {
+		// also prunes what a lowered history size no longer covers
+		query = `
+			DELETE FROM password_history
+			WHERE user_id = $1
+				AND id NOT IN (
+					SELECT id
+					FROM password_history
+					WHERE user_id = $1
+					ORDER BY created_at DESC
+					LIMIT $2
+				)
+		`
+		if _, err = tx.ExecContext(ctx, query, input.Id, input.HistorySize); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// UpsertUserTOTP stores a new TOTP secret of the user, replacing an enrollment that wasn't confirmed yet.
+// It returns sql.ErrNoRows when the user already has a confirmed TOTP authenticator.
+func (r *Repository) UpsertUserTOTP(ctx context.Context, tx *sql.Tx, input UpsertUserTOTPInput) (err error) {
+	if input.UserId == "" || input.SecretCiphertext == "" {
+		return ErrInvalidInputParam
+	}
+
+	createdAt := time.Now().UTC()
+	query := `
+		INSERT INTO user_totp (user_id, created_at, secret_ciphertext)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET
+			created_at = EXCLUDED.created_at,
+			secret_ciphertext = EXCLUDED.secret_ciphertext,
+			last_used_step = 0
+		WHERE user_totp.confirmed_at IS NULL
+		RETURNING user_id
+	`
+	params := []interface{}{
+		input.UserId,
+		createdAt,
+		input.SecretCiphertext,
+	}
+
+	var userId string
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, query, params...).Scan(&userId)
+	} else {
+		err = r.Db.QueryRowContext(ctx, query, params...).Scan(&userId)
+	}
+
+	return err
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// UseMFARecoveryCode marks the recovery code of the user as used and returns the number of unused codes left.
+// It returns sql.ErrNoRows when the user has no such unused code.
+func (r *Repository) UseMFARecoveryCode(ctx context.Context, tx *sql.Tx, input UseMFARecoveryCodeInput) (remaining int, err error) {
+	if input.UserId == "" || input.CodeHash == "" {
+		return 0, ErrInvalidInputParam
+	}
+
+	usedAt := time.Now().UTC()
+	query := `
+		WITH used AS (
+			UPDATE mfa_recovery_codes
+			SET 
+				used_at = $3
+			WHERE user_id = $1
+				AND code_hash = $2
+				AND used_at IS NULL
+			RETURNING id
+		)
+		SELECT COUNT(*)
+		FROM mfa_recovery_codes
+		WHERE user_id = $1
+			AND used_at IS NULL
+			AND id NOT IN (SELECT id FROM used)
+		HAVING EXISTS (SELECT 1 FROM used)
+	`
+	params := []interface{}{
+		input.UserId,
+		input.CodeHash,
+		usedAt,
+	}
+
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, query, params...).Scan(&remaining)
+	} else {
+		err = r.Db.QueryRowContext(ctx, query, params...).Scan(&remaining)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return remaining, nil
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// UsePasskey records a verified assertion of the passkey with its new signature counter.
+// It returns sql.ErrNoRows when the counter changed since the assertion was verified, e.g. by a concurrent sign in.
+func (r *Repository) UsePasskey(ctx context.Context, tx *sql.Tx, input UsePasskeyInput) (err error) {
+	if input.Id == "" {
+		return ErrInvalidInputParam
+	}
+
+	now := time.Now().UTC()
+	query := `
+		UPDATE passkeys
+		SET 
+			sign_count = $3,
+			backed_up = $4,
+			last_used_at = $5,
+			updated_at = $5
+		WHERE id = $1
+			AND sign_count = $2
+		RETURNING id
+	`
+	params := []interface{}{
+		input.Id,
+		int64(input.PrevSignCount),
+		int64(input.SignCount),
+		input.BackedUp,
+		now,
+	}
+
+	var usedId string
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, query, params...).Scan(&usedId)
+	} else {
+		err = r.Db.QueryRowContext(ctx, query, params...).Scan(&usedId)
+	}
+
+	return err
+}
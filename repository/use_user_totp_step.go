@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// UseUserTOTPStep records the time step of an accepted TOTP code, confirming the enrollment when Confirm is set.
+// It returns sql.ErrNoRows when a code of the step or a later one was already accepted, so a code can't be replayed,
+// or when the enrollment isn't in the expected state.
+func (r *Repository) UseUserTOTPStep(ctx context.Context, tx *sql.Tx, input UseUserTOTPStepInput) (err error) {
+	if input.UserId == "" || input.Step <= 0 {
+		return ErrInvalidInputParam
+	}
+
+	now := time.Now().UTC()
+	query := `
+		UPDATE user_totp
+		SET
+			last_used_step = $2,
+			confirmed_at = COALESCE(confirmed_at, $3)
+		WHERE user_id = $1
+			AND last_used_step < $2
+			AND (confirmed_at IS NULL) = $4
+		RETURNING user_id
+	`
+	params := []interface{}{
+		input.UserId,
+		input.Step,
+		now,
+		input.Confirm,
+	}
+
+	var userId string
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, query, params...).Scan(&userId)
+	} else {
+		err = r.Db.QueryRowContext(ctx, query, params...).Scan(&userId)
+	}
+
+	return err
+}
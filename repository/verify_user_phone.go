@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// VerifyUserPhone activates a pending user, it returns sql.ErrNoRows when the phone number already was verified.
+func (r *Repository) VerifyUserPhone(ctx context.Context, tx *sql.Tx, userId string) (err error) {
+	if userId == "" {
+		return ErrInvalidInputParam
+	}
+
+	verifiedAt := time.Now().UTC()
+	query := `
+		UPDATE users
+		SET
+			updated_at = $2,
+			phone_verified_at = $2
+		WHERE id = $1
+			AND phone_verified_at IS NULL
+		RETURNING id
+	`
+
+	var verifiedId string
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, query, userId, verifiedAt).Scan(&verifiedId)
+	} else {
+		err = r.Db.QueryRowContext(ctx, query, userId, verifiedAt).Scan(&verifiedId)
+	}
+
+	return err
+}
@@ -0,0 +1,44 @@
+package authentication
+
+import (
+	"time"
+
+	"user-service-sample/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// IDTokenClaims are the claims carried by OpenID Connect ID tokens.
+type IDTokenClaims struct {
+	StandardClaims
+	// AtHash binds the ID token to the access token issued with it
+	AtHash    string `json:"at_hash,omitempty"`
+	SessionId string `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func (ks *keySet) generateIDToken(user repository.User, sessionId, accessToken string) (token string, err error) {
+	cfg := ks.cfg
+	audience := jwt.ClaimStrings(cfg.Audiences)
+	if cfg.ClientId != "" {
+		audience = jwt.ClaimStrings{cfg.ClientId}
+	}
+
+	now := time.Now()
+	claims := &IDTokenClaims{
+		StandardClaims: NewStandardClaims(user),
+		SessionId:      sessionId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    cfg.Issuer,
+			Subject:   user.Id,
+			Audience:  audience,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(cfg.GetAccessTokenTTL())),
+		},
+	}
+	if accessToken != "" {
+		claims.AtHash = ks.active.tokenHash(accessToken)
+	}
+
+	return ks.active.Sign(claims)
+}
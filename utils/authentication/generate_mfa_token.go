@@ -0,0 +1,36 @@
+package authentication
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"user-service-sample/config"
+)
+
+const (
+	mfaTokenByteLength = 32
+)
+
+type MFAToken struct {
+	// Token is the opaque value handed to the client with the MFA challenge, it is never stored.
+	Token     string
+	Hash      string
+	ExpiresAt time.Time
+}
+
+// GenerateMFAToken creates a new random opaque MFA token along with the hash to be persisted,
+// it is hashed the same way as refresh tokens, see HashRefreshToken.
+func GenerateMFAToken(cfg config.MFAConfig) (MFAToken, error) {
+	b := make([]byte, mfaTokenByteLength)
+	if _, err := rand.Read(b); err != nil {
+		return MFAToken{}, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	return MFAToken{
+		Token:     token,
+		Hash:      HashRefreshToken(token),
+		ExpiresAt: time.Now().Add(cfg.GetChallengeTTL()),
+	}, nil
+}
@@ -0,0 +1,43 @@
+package authentication
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"user-service-sample/config"
+)
+
+const (
+	refreshTokenByteLength = 32
+)
+
+type RefreshToken struct {
+	// Token is the opaque value handed to the client, it is never stored.
+	Token     string
+	Hash      string
+	ExpiresAt time.Time
+}
+
+// GenerateRefreshToken creates a new random opaque refresh token along with the hash to be persisted.
+func GenerateRefreshToken(cfg config.SecretConfig) (RefreshToken, error) {
+	b := make([]byte, refreshTokenByteLength)
+	if _, err := rand.Read(b); err != nil {
+		return RefreshToken{}, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	return RefreshToken{
+		Token:     token,
+		Hash:      HashRefreshToken(token),
+		ExpiresAt: time.Now().Add(cfg.GetRefreshTokenTTL()),
+	}, nil
+}
+
+// HashRefreshToken returns the hex encoded SHA-256 hash used to look up a refresh token.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,44 +2,55 @@ package authentication
 
 import (
 	"crypto/rsa"
+	"strings"
 	"time"
 
-	"user-service-sample/config"
 	"user-service-sample/repository"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-var (
-	tokenTTL = 72 * time.Hour
-)
-
-type jwtCustomClaims struct {
+// Claims are the claims carried by access tokens, see ClaimsFromContext to read them in handlers.
+type Claims struct {
+	// Id is the user id, same as 'sub'. Kept for clients reading it before 'sub' was issued.
 	Id       string `json:"id"`
 	FullName string `json:"fullName"`
+	// PhoneNumberVerified is false for tokens issued before phone numbers were verified
+	PhoneNumberVerified bool `json:"phone_number_verified"`
+	// Scope & ClientId follow RFC 8693, they are reported as is by token introspection.
+	Scope    string `json:"scope,omitempty"`
+	ClientId string `json:"client_id,omitempty"`
+	// SessionId is the log in session the token was issued for, see RevokeSession
+	SessionId string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateSignedToken(cfg config.SecretConfig, user repository.User) (token string, err error) {
-	// Set custom claims
-	claims := &jwtCustomClaims{
-		Id:       user.Id,
-		FullName: user.FullName,
+func (ks *keySet) generateSignedToken(user repository.User, sessionId string) (token string, err error) {
+	cfg := ks.cfg
+	now := time.Now()
+
+	// Set custom claims, access tokens are short-lived and renewed with the refresh token
+	claims := &Claims{
+		Id:                  user.Id,
+		FullName:            user.FullName,
+		PhoneNumberVerified: user.PhoneVerifiedAt != nil,
+		Scope:               strings.Join(cfg.Scopes, " "),
+		ClientId:            cfg.ClientId,
+		SessionId:           sessionId,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenTTL)),
+			ID:        uuid.NewString(),
+			Issuer:    cfg.Issuer,
+			Subject:   user.Id,
+			Audience:  cfg.Audiences,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(cfg.GetAccessTokenTTL())),
 		},
 	}
 
-	// Create JWT with claims
-	jwtWithClaim := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-
-	privateKey, err := readRSAPrivateKey([]byte(cfg.RsaPrivatePem))
-	if err != nil {
-		return "", err
-	}
-
-	// Generate encoded token and send it as response.
-	return jwtWithClaim.SignedString(privateKey)
+	// Generate encoded token signed by the active key, with the algorithm of that key
+	return ks.active.Sign(claims)
 }
 
 func readRSAPrivateKey(privateKeyBytes []byte) (*rsa.PrivateKey, error) {
@@ -0,0 +1,42 @@
+package authentication
+
+import (
+	"errors"
+)
+
+// Introspection is the RFC 7662 view of an access token,
+// every other field is empty when the token is not active.
+type Introspection struct {
+	Active    bool
+	Subject   string
+	ExpiresAt int64
+	IssuedAt  int64
+	Scope     string
+	ClientId  string
+}
+
+func introspect(cc *Claims, err error) (Introspection, error) {
+	if err != nil {
+		if errors.Is(err, ErrVerificationUnavailable) {
+			return Introspection{}, err
+		}
+		return Introspection{Active: false}, nil
+	}
+
+	introspection := Introspection{
+		Active:    true,
+		Subject:   cc.Subject,
+		ExpiresAt: cc.ExpiresAt.Unix(),
+		Scope:     cc.Scope,
+		ClientId:  cc.ClientId,
+	}
+	// tokens issued before 'sub' was issued only carry the user id
+	if introspection.Subject == "" {
+		introspection.Subject = cc.Id
+	}
+	if cc.IssuedAt != nil {
+		introspection.IssuedAt = cc.IssuedAt.Unix()
+	}
+
+	return introspection, nil
+}
@@ -0,0 +1,25 @@
+package authentication
+
+// JSONWebKey is the public part of a signing key in RFC 7517 format,
+// N & E are set for RSA keys, Crv & X (and Y for EC) for EC and OKP keys.
+type JSONWebKey struct {
+	Kty string
+	Use string
+	Alg string
+	Kid string
+	N   string
+	E   string
+	Crv string
+	X   string
+	Y   string
+}
+
+func (ks *keySet) publicJWKS() []JSONWebKey {
+	keys := ks.publishedKeys()
+	jwks := make([]JSONWebKey, 0, len(keys))
+	for _, key := range keys {
+		jwks = append(jwks, key.JWK())
+	}
+
+	return jwks
+}
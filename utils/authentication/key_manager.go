@@ -0,0 +1,98 @@
+package authentication
+
+import (
+	"context"
+	"sync/atomic"
+
+	"user-service-sample/config"
+	"user-service-sample/repository"
+	"user-service-sample/utils/revocation"
+
+	"github.com/labstack/echo/v4"
+)
+
+// KeyManager holds the parsed keyset for the lifetime of the server,
+// so requests sign and verify tokens without parsing the PEMs again.
+// It is safe for concurrent use, Reload swaps the keyset atomically.
+type KeyManager struct {
+	// settings is the config the manager was created with, its token settings
+	// (issuer, audiences, TTLs, leeway, client id & scopes) are never reloaded
+	settings config.SecretConfig
+	keys     atomic.Pointer[keySet]
+}
+
+// NewKeyManager parses the keys of cfg, a malformed or incomplete keyset or a missing issuer is an error
+// so a misconfigured server fails at startup rather than on its first request.
+func NewKeyManager(cfg config.SecretConfig) (*KeyManager, error) {
+	m := &KeyManager{settings: cfg}
+	if err := m.Reload(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Issuer == "" {
+		return nil, ErrMissingIssuer
+	}
+
+	return m, nil
+}
+
+// Reload replaces the signing keys with the ones of cfg, every other setting of cfg is ignored:
+// handlers read them from the server config, which is only loaded at startup.
+// On error the current keyset is kept, requests in flight finish with the keyset they started with.
+func (m *KeyManager) Reload(cfg config.SecretConfig) error {
+	settings := m.settings
+	settings.Keys = cfg.Keys
+	settings.RsaPrivatePem = cfg.RsaPrivatePem
+	settings.RsaPublicPem = cfg.RsaPublicPem
+
+	ks, err := loadKeySet(settings)
+	if err != nil {
+		return err
+	}
+
+	m.keys.Store(ks)
+	return nil
+}
+
+// GenerateSignedToken signs an access token for the user, see Claims.
+func (m *KeyManager) GenerateSignedToken(user repository.User, sessionId string) (string, error) {
+	return m.keys.Load().generateSignedToken(user, sessionId)
+}
+
+// GenerateIDToken issues the ID token of the user for the session, alongside accessToken.
+// The audience is the client the tokens are issued to, defaulting to the configured audiences.
+func (m *KeyManager) GenerateIDToken(user repository.User, sessionId, accessToken string) (string, error) {
+	return m.keys.Load().generateIDToken(user, sessionId, accessToken)
+}
+
+// VerifyToken validates the bearer token of the request and returns its claims.
+// When store is not nil the token is also checked against the revocation denylist.
+func (m *KeyManager) VerifyToken(ctx echo.Context, store revocation.Store) (*Claims, error) {
+	receivedToken, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.ParseToken(ctx.Request().Context(), store, receivedToken)
+}
+
+// ParseToken validates a signed access token and returns its claims,
+// see VerifyToken to read the token from the Authorization header.
+func (m *KeyManager) ParseToken(ctx context.Context, store revocation.Store, receivedToken string) (*Claims, error) {
+	return m.keys.Load().parseToken(ctx, store, receivedToken)
+}
+
+// IntrospectToken reports whether the token is active. Invalid, expired and revoked tokens
+// are not an error, they are inactive. An error is only returned when the token can't be checked.
+func (m *KeyManager) IntrospectToken(ctx context.Context, store revocation.Store, token string) (Introspection, error) {
+	return introspect(m.ParseToken(ctx, store, token))
+}
+
+// PublicJWKS returns the public keys able to verify outstanding tokens.
+func (m *KeyManager) PublicJWKS() []JSONWebKey {
+	return m.keys.Load().publicJWKS()
+}
+
+// SigningAlgorithms returns the algorithms tokens may be signed with.
+func (m *KeyManager) SigningAlgorithms() []string {
+	return m.keys.Load().algorithms()
+}
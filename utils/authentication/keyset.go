@@ -0,0 +1,200 @@
+package authentication
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	"user-service-sample/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrNoActiveSigningKey       = errors.New("keyset must have exactly one active signing key")
+	ErrDuplicateSigningKeyId    = errors.New("duplicate signing key id")
+	ErrMissingSigningKeyId      = errors.New("signing key id is required")
+	ErrMissingSigningPrivateKey = errors.New("active signing key requires a private key")
+	ErrMissingSigningPublicKey  = errors.New("retired signing key requires a public key")
+	ErrUnknownSigningKey        = errors.New("unknown signing key")
+	ErrSigningKeyPairMismatch   = errors.New("signing public key does not match the private key")
+	ErrMissingIssuer            = errors.New("issuer is required")
+)
+
+type keySet struct {
+	// cfg is the config the keyset was loaded from, the token settings come with the keys
+	cfg    config.SecretConfig
+	active *signingKey
+	keys   []*signingKey
+	// tokenTTL is the longest lifetime of a token signed by any key, leeway included
+	tokenTTL time.Duration
+}
+
+func loadKeySet(cfg config.SecretConfig) (*keySet, error) {
+	keyConfigs := cfg.Keys
+	if len(keyConfigs) == 0 {
+		keyConfigs = []config.SigningKeyConfig{{
+			PrivatePem: cfg.RsaPrivatePem,
+			PublicPem:  cfg.RsaPublicPem,
+		}}
+	}
+
+	ks := &keySet{
+		cfg:      cfg,
+		tokenTTL: cfg.GetAccessTokenTTL() + cfg.Leeway,
+	}
+	seen := make(map[string]bool, len(keyConfigs))
+	for _, kc := range keyConfigs {
+		key, err := parseSigningKey(kc, len(cfg.Keys) == 0)
+		if err != nil {
+			return nil, err
+		}
+		if seen[key.kid] {
+			return nil, ErrDuplicateSigningKeyId
+		}
+		seen[key.kid] = true
+
+		if key.retiredAt == nil {
+			if ks.active != nil {
+				return nil, ErrNoActiveSigningKey
+			}
+			ks.active = key
+		}
+		ks.keys = append(ks.keys, key)
+	}
+	if ks.active == nil {
+		return nil, ErrNoActiveSigningKey
+	}
+
+	return ks, nil
+}
+
+func parseSigningKey(kc config.SigningKeyConfig, legacy bool) (key *signingKey, err error) {
+	algorithmName := kc.Algorithm
+	if algorithmName == "" {
+		algorithmName = AlgorithmRS256
+	}
+	algorithm, ok := signingAlgorithms[algorithmName]
+	if !ok {
+		return nil, ErrUnsupportedSigningAlgorithm
+	}
+
+	key = &signingKey{
+		kid:       kc.Kid,
+		algorithm: algorithm,
+		retiredAt: kc.RetiredAt,
+	}
+
+	if kc.PrivatePem != "" {
+		if key.privateKey, key.publicKey, err = algorithm.parsePrivateKey([]byte(kc.PrivatePem)); err != nil {
+			return nil, err
+		}
+	} else if kc.RetiredAt == nil {
+		return nil, ErrMissingSigningPrivateKey
+	}
+
+	if kc.PublicPem != "" {
+		publicKey, err := algorithm.parsePublicKey([]byte(kc.PublicPem))
+		if err != nil {
+			return nil, err
+		}
+		// a public key not matching the private key would publish tokens nobody can verify
+		if key.publicKey != nil && !publicKeyEqual(key.publicKey, publicKey) {
+			return nil, ErrSigningKeyPairMismatch
+		}
+		key.publicKey = publicKey
+	} else if key.publicKey == nil {
+		return nil, ErrMissingSigningPublicKey
+	}
+
+	// the legacy single key has no configured kid, derive a stable one from the key itself
+	if key.kid == "" {
+		if !legacy {
+			return nil, ErrMissingSigningKeyId
+		}
+		key.kid = rsaThumbprint(key.publicKey.(*rsa.PublicKey))
+	}
+
+	return key, nil
+}
+
+// verificationKey returns the public key for the kid in the token header.
+// The algorithm comes from the key, a token claiming any other algorithm is rejected.
+// Tokens issued before keysets existed have no kid and are verified with the active key.
+func (ks *keySet) verificationKey(token *jwt.Token) (interface{}, error) {
+	key, err := ks.verifier(token)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method == nil || token.Method.Alg() != key.Algorithm() {
+		return nil, ErrUnexpectedSigningMethod
+	}
+
+	return key.PublicKey(), nil
+}
+
+func (ks *keySet) verifier(token *jwt.Token) (Verifier, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return ks.active, nil
+	}
+
+	now := time.Now()
+	for _, key := range ks.keys {
+		if key.kid == kid && key.usable(now, ks.tokenTTL) {
+			return key, nil
+		}
+	}
+
+	return nil, ErrUnknownSigningKey
+}
+
+// algorithms lists the algorithms of every key, the only ones the parser may accept.
+func (ks *keySet) algorithms() []string {
+	algorithms := make([]string, 0, len(ks.keys))
+	seen := make(map[string]bool, len(ks.keys))
+	for _, key := range ks.keys {
+		if alg := key.Algorithm(); !seen[alg] {
+			seen[alg] = true
+			algorithms = append(algorithms, alg)
+		}
+	}
+
+	return algorithms
+}
+
+// publishedKeys returns every key that may still verify an outstanding token.
+func (ks *keySet) publishedKeys() []Verifier {
+	now := time.Now()
+	keys := make([]Verifier, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		if key.usable(now, ks.tokenTTL) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+func publicKeyEqual(a, b crypto.PublicKey) bool {
+	key, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && key.Equal(b)
+}
+
+// rsaThumbprint computes the RFC 7638 JWK thumbprint of an RSA public key.
+func rsaThumbprint(publicKey *rsa.PublicKey) string {
+	// members must be in lexicographic order without whitespace, json.Marshal sorts map keys
+	b, _ := json.Marshal(map[string]string{
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		"kty": "RSA",
+		"n":   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+	})
+	sum := sha256.Sum256(b)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
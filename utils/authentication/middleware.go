@@ -0,0 +1,121 @@
+package authentication
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"user-service-sample/utils/response"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// ClaimsContextKey is the echo context key the verified claims are stored under
+	ClaimsContextKey = "authentication.claims"
+
+	authRealm = "user-service"
+)
+
+var (
+	ErrMissingClaims = errors.New("no verified claims in context")
+)
+
+type MiddlewareOptions struct {
+	// Swagger is the API spec, only operations requiring SecurityScheme are authenticated.
+	Swagger        *openapi3.T
+	SecurityScheme string
+	// Verify verifies the credentials of the request and returns their claims.
+	Verify func(ctx echo.Context) (*Claims, error)
+}
+
+// Middleware verifies the bearer token once for every operation secured by the security scheme
+// in the API spec, and stores the claims in the context. Missing or invalid credentials
+// are rejected with 401 and a WWW-Authenticate challenge (RFC 6750).
+func Middleware(opts MiddlewareOptions) echo.MiddlewareFunc {
+	protected := securedRoutes(opts.Swagger, opts.SecurityScheme)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if !protected[routeKey(ctx.Request().Method, ctx.Path())] {
+				return next(ctx)
+			}
+
+			claims, err := opts.Verify(ctx)
+			if err != nil {
+				if errors.Is(err, ErrVerificationUnavailable) {
+					ctx.Logger().Errorf("authentication.Middleware, failed VerifyToken, err: %v", err)
+					return response.InternalErrorResponse(ctx)
+				}
+				ctx.Logger().Infof("authentication.Middleware, VerifyToken failed, err: %+v", err)
+				return response.Unauthorized(ctx, bearerChallenge(err))
+			}
+
+			ctx.Set(ClaimsContextKey, claims)
+			return next(ctx)
+		}
+	}
+}
+
+// ClaimsFromContext returns the claims verified by Middleware for the current request.
+func ClaimsFromContext(ctx echo.Context) (*Claims, error) {
+	claims, ok := ctx.Get(ClaimsContextKey).(*Claims)
+	if !ok || claims == nil {
+		return nil, ErrMissingClaims
+	}
+
+	return claims, nil
+}
+
+func bearerChallenge(err error) string {
+	// a request without credentials gets no error code, see RFC 6750 section 3.1
+	if err == ErrMissingAuthenticationHeader {
+		return fmt.Sprintf(`%s realm="%s"`, AuthHeaderScheme, authRealm)
+	}
+
+	description := "the access token is invalid"
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		description = "the access token expired"
+	} else if err == ErrTokenRevoked {
+		description = "the access token has been revoked"
+	}
+
+	return fmt.Sprintf(`%s realm="%s", error="invalid_token", error_description="%s"`, AuthHeaderScheme, authRealm, description)
+}
+
+// securedRoutes lists the echo routes of every operation requiring the security scheme.
+func securedRoutes(swagger *openapi3.T, scheme string) map[string]bool {
+	routes := make(map[string]bool)
+	if swagger == nil {
+		return routes
+	}
+
+	for path, item := range swagger.Paths {
+		for method, op := range item.Operations() {
+			requirements := swagger.Security
+			if op.Security != nil {
+				requirements = *op.Security
+			}
+
+			for _, requirement := range requirements {
+				if _, ok := requirement[scheme]; ok {
+					routes[routeKey(method, echoPath(path))] = true
+					break
+				}
+			}
+		}
+	}
+
+	return routes
+}
+
+// echoPath converts OpenAPI path params "{id}" into echo's ":id" format.
+func echoPath(path string) string {
+	return strings.NewReplacer("{", ":", "}", "").Replace(path)
+}
+
+func routeKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
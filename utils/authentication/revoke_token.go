@@ -0,0 +1,51 @@
+package authentication
+
+import (
+	"context"
+	"time"
+
+	"user-service-sample/config"
+	"user-service-sample/utils/revocation"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// RevokeToken denylists the token the claims were read from until it expires.
+func RevokeToken(ctx context.Context, store revocation.Store, cc *Claims) error {
+	if cc.ExpiresAt == nil {
+		return jwt.ErrTokenRequiredClaimMissing
+	}
+	expiresAt := cc.ExpiresAt.Time
+
+	// tokens issued before the jti claim existed can't be told apart,
+	// revoke them together with every other token of the user
+	if cc.ID == "" {
+		return store.RevokeUser(ctx, cc.Id, time.Now(), expiresAt)
+	}
+
+	return store.Revoke(ctx, cc.ID, expiresAt)
+}
+
+// RevokeAllUserTokens revokes every access token issued to the user so far.
+func RevokeAllUserTokens(ctx context.Context, store revocation.Store, cfg config.SecretConfig, userId string) error {
+	now := time.Now()
+	return store.RevokeUser(ctx, userId, now, now.Add(cfg.GetAccessTokenTTL()+cfg.Leeway))
+}
+
+// RevokeSession revokes every access token issued for the session so far.
+func RevokeSession(ctx context.Context, store revocation.Store, cfg config.SecretConfig, sessionId string) error {
+	return store.Revoke(ctx, sessionId, time.Now().Add(cfg.GetAccessTokenTTL()+cfg.Leeway))
+}
+
+func (cc *Claims) revocationToken() revocation.Token {
+	token := revocation.Token{
+		Id:        cc.ID,
+		SessionId: cc.SessionId,
+		UserId:    cc.Id,
+	}
+	if cc.IssuedAt != nil {
+		token.IssuedAt = cc.IssuedAt.Time
+	}
+
+	return token
+}
@@ -0,0 +1,197 @@
+package authentication
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+var (
+	ErrUnsupportedSigningAlgorithm = errors.New("unsupported signing algorithm")
+	ErrSigningKeyTypeMismatch      = errors.New("signing key type does not match its algorithm")
+	ErrUnexpectedSigningMethod     = errors.New("token signing method does not match its key")
+)
+
+// Signer signs tokens with a private key, the algorithm is bound to the key.
+type Signer interface {
+	Kid() string
+	Algorithm() string
+	Sign(claims jwt.Claims) (string, error)
+}
+
+// Verifier holds a public key and the only algorithm it accepts.
+type Verifier interface {
+	Kid() string
+	Algorithm() string
+	PublicKey() crypto.PublicKey
+	JWK() JSONWebKey
+}
+
+// signingAlgorithm parses and describes the key material of one JWS algorithm.
+type signingAlgorithm struct {
+	method jwt.SigningMethod
+	// hash is used for the OpenID Connect 'at_hash' of tokens signed with the algorithm
+	hash            crypto.Hash
+	parsePrivateKey func(pem []byte) (crypto.PrivateKey, crypto.PublicKey, error)
+	parsePublicKey  func(pem []byte) (crypto.PublicKey, error)
+	jwk             func(publicKey crypto.PublicKey) JSONWebKey
+}
+
+var signingAlgorithms = map[string]signingAlgorithm{
+	AlgorithmRS256: {
+		method: jwt.SigningMethodRS256,
+		hash:   crypto.SHA256,
+		parsePrivateKey: func(pem []byte) (crypto.PrivateKey, crypto.PublicKey, error) {
+			key, err := readRSAPrivateKey(pem)
+			if err != nil {
+				return nil, nil, err
+			}
+			return key, &key.PublicKey, nil
+		},
+		parsePublicKey: func(pem []byte) (crypto.PublicKey, error) {
+			return readRSAPublicKey(pem)
+		},
+		jwk: func(publicKey crypto.PublicKey) JSONWebKey {
+			key := publicKey.(*rsa.PublicKey)
+			return JSONWebKey{
+				Kty: "RSA",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}
+		},
+	},
+	AlgorithmES256: {
+		method: jwt.SigningMethodES256,
+		hash:   crypto.SHA256,
+		parsePrivateKey: func(pem []byte) (crypto.PrivateKey, crypto.PublicKey, error) {
+			key, err := jwt.ParseECPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, nil, err
+			}
+			if key.Curve != elliptic.P256() {
+				return nil, nil, ErrSigningKeyTypeMismatch
+			}
+			return key, &key.PublicKey, nil
+		},
+		parsePublicKey: func(pem []byte) (crypto.PublicKey, error) {
+			key, err := jwt.ParseECPublicKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			if key.Curve != elliptic.P256() {
+				return nil, ErrSigningKeyTypeMismatch
+			}
+			return key, nil
+		},
+		jwk: func(publicKey crypto.PublicKey) JSONWebKey {
+			key := publicKey.(*ecdsa.PublicKey)
+			// coordinates are fixed size for the curve, see RFC 7518 section 6.2.1.2
+			size := (key.Curve.Params().BitSize + 7) / 8
+			return JSONWebKey{
+				Kty: "EC",
+				Crv: key.Curve.Params().Name,
+				X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size))),
+				Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size))),
+			}
+		},
+	},
+	AlgorithmEdDSA: {
+		method: jwt.SigningMethodEdDSA,
+		hash:   crypto.SHA512,
+		parsePrivateKey: func(pem []byte) (crypto.PrivateKey, crypto.PublicKey, error) {
+			key, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, nil, err
+			}
+			edKey, ok := key.(ed25519.PrivateKey)
+			if !ok {
+				return nil, nil, ErrSigningKeyTypeMismatch
+			}
+			return edKey, edKey.Public(), nil
+		},
+		parsePublicKey: func(pem []byte) (crypto.PublicKey, error) {
+			key, err := jwt.ParseEdPublicKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := key.(ed25519.PublicKey); !ok {
+				return nil, ErrSigningKeyTypeMismatch
+			}
+			return key, nil
+		},
+		jwk: func(publicKey crypto.PublicKey) JSONWebKey {
+			return JSONWebKey{
+				Kty: "OKP",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(publicKey.(ed25519.PublicKey)),
+			}
+		},
+	},
+}
+
+type signingKey struct {
+	kid        string
+	algorithm  signingAlgorithm
+	privateKey crypto.PrivateKey
+	publicKey  crypto.PublicKey
+	retiredAt  *time.Time
+}
+
+func (k *signingKey) Kid() string {
+	return k.kid
+}
+
+func (k *signingKey) Algorithm() string {
+	return k.algorithm.method.Alg()
+}
+
+func (k *signingKey) PublicKey() crypto.PublicKey {
+	return k.publicKey
+}
+
+func (k *signingKey) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.algorithm.method, claims)
+	token.Header["kid"] = k.kid
+
+	return token.SignedString(k.privateKey)
+}
+
+// tokenHash is the left-most half of the hash of a token, as the OpenID Connect 'at_hash' claim.
+func (k *signingKey) tokenHash(token string) string {
+	h := k.algorithm.hash.New()
+	h.Write([]byte(token))
+	sum := h.Sum(nil)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
+
+func (k *signingKey) JWK() JSONWebKey {
+	jwk := k.algorithm.jwk(k.publicKey)
+	jwk.Use = "sig"
+	jwk.Alg = k.Algorithm()
+	jwk.Kid = k.kid
+
+	return jwk
+}
+
+// usable reports whether tokens signed by the key may still be valid,
+// a retired key ages out once every token it signed has expired.
+func (k *signingKey) usable(now time.Time, tokenTTL time.Duration) bool {
+	return k.retiredAt == nil || now.Before(k.retiredAt.Add(tokenTTL))
+}
@@ -0,0 +1,32 @@
+package authentication
+
+import (
+	"user-service-sample/repository"
+)
+
+// StandardClaims are the OpenID Connect standard claims of a user,
+// shared by ID tokens and the userinfo endpoint.
+type StandardClaims struct {
+	Name                string `json:"name"`
+	PhoneNumber         string `json:"phone_number"`
+	PhoneNumberVerified bool   `json:"phone_number_verified"`
+	// UpdatedAt is seconds since epoch, zero when unknown
+	UpdatedAt int64 `json:"updated_at,omitempty"`
+}
+
+func NewStandardClaims(user repository.User) StandardClaims {
+	claims := StandardClaims{
+		Name:                user.FullName,
+		PhoneNumber:         user.PhoneNumber,
+		PhoneNumberVerified: user.PhoneVerifiedAt != nil,
+	}
+
+	switch {
+	case user.UpdatedAt != nil:
+		claims.UpdatedAt = user.UpdatedAt.Unix()
+	case user.CreatedAt != nil:
+		claims.UpdatedAt = user.CreatedAt.Unix()
+	}
+
+	return claims
+}
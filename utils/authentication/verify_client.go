@@ -0,0 +1,44 @@
+package authentication
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+
+	"user-service-sample/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	ErrMissingClientCredentials = errors.New("missing client credentials")
+	ErrInvalidClientCredentials = errors.New("invalid client credentials")
+)
+
+// VerifyClient authenticates the OAuth client of the request with HTTP Basic auth
+// and returns its id.
+func VerifyClient(ctx echo.Context, clients []config.OAuthClientConfig) (clientId string, err error) {
+	id, secret, ok := ctx.Request().BasicAuth()
+	if !ok {
+		return "", ErrMissingClientCredentials
+	}
+
+	// compare digests so neither the secret nor its length leaks through timing,
+	// every client is compared so the time doesn't depend on which id matched
+	secretSum := sha256.Sum256([]byte(secret))
+	matched := 0
+	for _, client := range clients {
+		if client.Id == "" || client.Secret == "" {
+			continue
+		}
+		clientSum := sha256.Sum256([]byte(client.Secret))
+		idMatch := subtle.ConstantTimeCompare([]byte(id), []byte(client.Id))
+		secretMatch := subtle.ConstantTimeCompare(secretSum[:], clientSum[:])
+		matched |= idMatch & secretMatch
+	}
+	if matched != 1 {
+		return "", ErrInvalidClientCredentials
+	}
+
+	return id, nil
+}
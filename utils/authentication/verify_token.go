@@ -1,44 +1,62 @@
 package authentication
 
 import (
+	"context"
 	"crypto/rsa"
 	"errors"
+	"fmt"
 	"strings"
 
-	"user-service-sample/config"
+	"user-service-sample/utils/revocation"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
-	"github.com/mitchellh/mapstructure"
 )
 
 const (
-	AuthHeaderKey = "Authorization"
+	AuthHeaderKey    = "Authorization"
+	AuthHeaderScheme = "Bearer"
 )
 
 var (
-	ErrInvalidAutheticatonHeader = errors.New("invalid Authentication header")
-	ErrAccessingClaims           = errors.New("error accessing claims")
-	ErrDecodingClaims            = errors.New("error decoding claims")
+	ErrMissingAuthenticationHeader = errors.New("missing Authentication header")
+	ErrInvalidAutheticatonHeader   = errors.New("invalid Authentication header")
+	ErrTokenRevoked                = errors.New("token has been revoked")
+	// ErrVerificationUnavailable wraps failures on our side, as opposed to an invalid token
+	ErrVerificationUnavailable = errors.New("token verification unavailable")
 )
 
-func VerifyToken(ctx echo.Context, cfg config.SecretConfig) (cc *jwtCustomClaims, err error) {
+func bearerToken(ctx echo.Context) (string, error) {
 	authHeaderVal := strings.TrimSpace(ctx.Request().Header.Get(AuthHeaderKey))
+	if authHeaderVal == "" {
+		return "", ErrMissingAuthenticationHeader
+	}
 	authHeaderSplit := strings.Split(authHeaderVal, " ")
-	if len(authHeaderSplit) != 2 {
-		return nil, ErrInvalidAutheticatonHeader
+	if len(authHeaderSplit) != 2 || !strings.EqualFold(authHeaderSplit[0], AuthHeaderScheme) {
+		return "", ErrInvalidAutheticatonHeader
 	}
-	receivedToken := authHeaderSplit[1]
 
-	publicKey, err := readRSAPublicKey([]byte(cfg.RsaPublicPem))
-	if err != nil {
-		return nil, err
+	return authHeaderSplit[1], nil
+}
+
+func (ks *keySet) parseToken(ctx context.Context, store revocation.Store, receivedToken string) (cc *Claims, err error) {
+	cfg := ks.cfg
+
+	// Parse and validate the token, retired keys are still accepted until they age out.
+	// The algorithm is pinned by the key so a token can't pick its own verification method.
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods(ks.algorithms()),
+		jwt.WithLeeway(cfg.Leeway),
+		jwt.WithIssuedAt(),
+	}
+	if cfg.Issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(cfg.Issuer))
 	}
 
-	// Parse and validate the token
-	token, err := jwt.Parse(receivedToken, func(token *jwt.Token) (interface{}, error) {
-		return publicKey, nil
-	})
+	cc = new(Claims)
+	token, err := jwt.ParseWithClaims(receivedToken, cc, func(token *jwt.Token) (interface{}, error) {
+		return ks.verificationKey(token)
+	}, parserOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -46,21 +64,42 @@ func VerifyToken(ctx echo.Context, cfg config.SecretConfig) (cc *jwtCustomClaims
 		return nil, ErrInvalidAutheticatonHeader
 	}
 
-	// Access the claims
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, ErrAccessingClaims
+	if cc.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w: exp", jwt.ErrTokenRequiredClaimMissing)
+	}
+	// only access tokens carry the user id claim, an ID token signed by the same keys is not accepted
+	if cc.Id == "" {
+		return nil, fmt.Errorf("%w: id", jwt.ErrTokenRequiredClaimMissing)
+	}
+	if len(cfg.Audiences) > 0 && !hasAnyAudience(cc.Audience, cfg.Audiences) {
+		return nil, jwt.ErrTokenInvalidAudience
 	}
 
-	// Safely parse the claims into custom claims struct
-	cc = new(jwtCustomClaims)
-	if err := mapstructure.Decode(claims, cc); err != nil {
-		return nil, ErrDecodingClaims
+	if store != nil {
+		revoked, err := store.IsRevoked(ctx, cc.revocationToken())
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrVerificationUnavailable, err)
+		}
+		if revoked {
+			return nil, ErrTokenRevoked
+		}
 	}
 
 	return cc, nil
 }
 
+func hasAnyAudience(tokenAudiences jwt.ClaimStrings, expected []string) bool {
+	for _, aud := range tokenAudiences {
+		for _, exp := range expected {
+			if aud == exp {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func readRSAPublicKey(publicKeyBytes []byte) (*rsa.PublicKey, error) {
 	// publicKeyBytes, err := os.ReadFile(publicKeyPath)
 	// if err != nil {
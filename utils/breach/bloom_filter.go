@@ -0,0 +1,139 @@
+package breach
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+const (
+	bloomFilterMagic = "BRCHBF01"
+)
+
+var (
+	ErrInvalidBloomFilter       = errors.New("invalid bloom filter file")
+	ErrInvalidFalsePositiveRate = errors.New("false positive rate must be between 0 and 1")
+)
+
+// BloomFilter is a compact index of password hashes, it may answer true for a password
+// that was never added (at the false positive rate it was sized for) but never false for one that was.
+type BloomFilter struct {
+	bits []uint64
+	// m is the number of bits, k the number of bit positions per hash
+	m uint64
+	k uint32
+}
+
+// NewBloomFilter sizes a filter for n hashes with the given false positive rate, e.g. 0.001.
+func NewBloomFilter(n uint64, falsePositiveRate float64) (*BloomFilter, error) {
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return nil, ErrInvalidFalsePositiveRate
+	}
+	if n == 0 {
+		n = 1
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	k := uint32(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k == 0 {
+		k = 1
+	}
+
+	return &BloomFilter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}, nil
+}
+
+// Add adds the SHA-1 of a password, as read from a dump with ParseHashLine.
+func (b *BloomFilter) Add(hash [sha1.Size]byte) {
+	h1, h2 := splitHash(hash)
+	for i := uint64(0); i < uint64(b.k); i++ {
+		pos := (h1 + i*h2) % b.m
+		b.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+func (b *BloomFilter) Contains(password string) (bool, error) {
+	return b.containsHash(sum(password)), nil
+}
+
+func (b *BloomFilter) containsHash(hash [sha1.Size]byte) bool {
+	h1, h2 := splitHash(hash)
+	for i := uint64(0); i < uint64(b.k); i++ {
+		pos := (h1 + i*h2) % b.m
+		if b.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// splitHash derives the bit positions by double hashing, SHA-1 is already uniform
+// so its first 16 bytes are used as the two hashes. h2 is odd to never be 0.
+func splitHash(hash [sha1.Size]byte) (h1, h2 uint64) {
+	return binary.BigEndian.Uint64(hash[0:8]), binary.BigEndian.Uint64(hash[8:16]) | 1
+}
+
+// WriteTo writes the filter as the magic, m & k then the bits, all little endian.
+func (b *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, len(bloomFilterMagic)+8+4)
+	copy(header, bloomFilterMagic)
+	binary.LittleEndian.PutUint64(header[len(bloomFilterMagic):], b.m)
+	binary.LittleEndian.PutUint32(header[len(bloomFilterMagic)+8:], b.k)
+
+	n, err := w.Write(header)
+	written := int64(n)
+	if err != nil {
+		return written, err
+	}
+
+	buf := make([]byte, 8*1024)
+	for i := 0; i < len(b.bits); {
+		chunk := buf[:0]
+		for ; i < len(b.bits) && len(chunk) < len(buf); i++ {
+			chunk = binary.LittleEndian.AppendUint64(chunk, b.bits[i])
+		}
+		n, err := w.Write(chunk)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
+
+// ReadBloomFilter reads a filter written by WriteTo.
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	header := make([]byte, len(bloomFilterMagic)+8+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, ErrInvalidBloomFilter
+	}
+	if string(header[:len(bloomFilterMagic)]) != bloomFilterMagic {
+		return nil, ErrInvalidBloomFilter
+	}
+
+	b := &BloomFilter{
+		m: binary.LittleEndian.Uint64(header[len(bloomFilterMagic):]),
+		k: binary.LittleEndian.Uint32(header[len(bloomFilterMagic)+8:]),
+	}
+	if b.m == 0 || b.k == 0 {
+		return nil, ErrInvalidBloomFilter
+	}
+
+	b.bits = make([]uint64, (b.m+63)/64)
+	word := make([]byte, 8)
+	for i := range b.bits {
+		if _, err := io.ReadFull(r, word); err != nil {
+			return nil, ErrInvalidBloomFilter
+		}
+		b.bits[i] = binary.LittleEndian.Uint64(word)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,169 @@
+package breach
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+)
+
+func TestNewBloomFilter(t *testing.T) {
+	testCases := []struct {
+		title             string
+		n                 uint64
+		falsePositiveRate float64
+
+		expectedM   uint64
+		expectedK   uint32
+		expectedErr error
+	}{
+		{
+			title:             "sized for the false positive rate",
+			n:                 1000,
+			falsePositiveRate: 0.01,
+			// -n ln(p) / ln(2)^2 bits, m/n ln(2) positions
+			expectedM: 9586,
+			expectedK: 7,
+		},
+		{
+			title:             "no hash",
+			n:                 0,
+			falsePositiveRate: 0.5,
+			expectedM:         2,
+			expectedK:         1,
+		},
+		{
+			title:             "rate of 0",
+			n:                 1000,
+			falsePositiveRate: 0,
+			expectedErr:       ErrInvalidFalsePositiveRate,
+		},
+		{
+			title:             "rate of 1",
+			n:                 1000,
+			falsePositiveRate: 1,
+			expectedErr:       ErrInvalidFalsePositiveRate,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			filter, err := NewBloomFilter(tc.n, tc.falsePositiveRate)
+			assert.Equal(t, tc.expectedErr, err)
+			if tc.expectedErr != nil {
+				return
+			}
+			assert.Equal(t, tc.expectedM, filter.m)
+			assert.Equal(t, tc.expectedK, filter.k)
+			assert.Equal(t, int((tc.expectedM+63)/64), len(filter.bits))
+		})
+	}
+}
+
+func TestBloomFilterContains(t *testing.T) {
+	const n = 10000
+	const falsePositiveRate = 0.01
+
+	filter, err := NewBloomFilter(n, falsePositiveRate)
+	assert.NoError(t, err)
+	for i := 0; i < n; i++ {
+		filter.Add(sum(fmt.Sprintf("breached-%d", i)))
+	}
+
+	// never a false negative
+	for i := 0; i < n; i++ {
+		found, err := filter.Contains(fmt.Sprintf("breached-%d", i))
+		assert.NoError(t, err)
+		if !found {
+			t.Fatalf("breached-%d not found", i)
+		}
+	}
+
+	// false positives at about the rate the filter is sized for
+	falsePositives := 0
+	for i := 0; i < n; i++ {
+		if found, _ := filter.Contains(fmt.Sprintf("not-breached-%d", i)); found {
+			falsePositives++
+		}
+	}
+	assert.True(t, float64(falsePositives)/n < 2*falsePositiveRate, "%d false positives", falsePositives)
+}
+
+func TestBloomFilterWriteTo(t *testing.T) {
+	filter, err := NewBloomFilter(uint64(len(breachedPasswords)), 0.001)
+	assert.NoError(t, err)
+	for _, password := range breachedPasswords {
+		filter.Add(sum(password))
+	}
+
+	var buf bytes.Buffer
+	written, err := filter.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), written)
+
+	// the magic, m & k little endian, then the bits as little endian words
+	file := buf.Bytes()
+	assert.Equal(t, bloomFilterMagic, string(file[:8]))
+	assert.Equal(t, filter.m, binary.LittleEndian.Uint64(file[8:16]))
+	assert.Equal(t, filter.k, binary.LittleEndian.Uint32(file[16:20]))
+	assert.Equal(t, 20+8*len(filter.bits), len(file))
+	assert.Equal(t, filter.bits[0], binary.LittleEndian.Uint64(file[20:28]))
+
+	read, err := ReadBloomFilter(bytes.NewReader(file))
+	assert.NoError(t, err)
+	assert.Equal(t, filter, read)
+}
+
+func TestReadBloomFilter(t *testing.T) {
+	filter, err := NewBloomFilter(100, 0.01)
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	_, err = filter.WriteTo(&buf)
+	assert.NoError(t, err)
+	valid := buf.Bytes()
+
+	header := func(magic string, m uint64, k uint32) []byte {
+		h := append([]byte(magic), binary.LittleEndian.AppendUint64(nil, m)...)
+		return binary.LittleEndian.AppendUint32(h, k)
+	}
+
+	testCases := []struct {
+		title string
+		file  []byte
+	}{
+		{
+			title: "empty",
+			file:  []byte{},
+		},
+		{
+			title: "header truncated",
+			file:  valid[:15],
+		},
+		{
+			title: "another magic",
+			file:  append(header("BRCHBF02", filter.m, filter.k), valid[20:]...),
+		},
+		{
+			title: "no bit",
+			file:  header(bloomFilterMagic, 0, filter.k),
+		},
+		{
+			title: "no bit position",
+			file:  append(header(bloomFilterMagic, filter.m, 0), valid[20:]...),
+		},
+		{
+			title: "bits truncated",
+			file:  valid[:len(valid)-1],
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			read, err := ReadBloomFilter(bytes.NewReader(tc.file))
+			assert.Equal(t, ErrInvalidBloomFilter, err)
+			assert.Nil(t, read)
+		})
+	}
+}
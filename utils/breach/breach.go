@@ -0,0 +1,66 @@
+package breach
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+var (
+	ErrInvalidHashLine = errors.New("invalid breached password hash line")
+)
+
+// Index tells whether a password appears in a breached password corpus.
+type Index interface {
+	Contains(password string) (bool, error)
+}
+
+// Open opens an index file written by cmd/breachindex, either a bloom filter
+// or a SHA-1 hash dump sorted by hash in the HIBP format (HASH:COUNT per line).
+func Open(path string) (Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	magic := make([]byte, len(bloomFilterMagic))
+	_, err = io.ReadFull(f, magic)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		f.Close()
+		return nil, err
+	}
+	if err == nil && string(magic) == bloomFilterMagic {
+		// a bloom filter is small enough to be read in memory
+		defer f.Close()
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return ReadBloomFilter(bufio.NewReader(f))
+	}
+
+	return newSortedFile(f)
+}
+
+// sum is the SHA-1 of the password, the hash breached password corpora are published with
+func sum(password string) [sha1.Size]byte {
+	return sha1.Sum([]byte(password))
+}
+
+// ParseHashLine reads the hash of a dump line, e.g. 7C4A8D09CA3762AF61E59520943DC26494F8941B:24230577
+func ParseHashLine(line string) ([sha1.Size]byte, error) {
+	var hash [sha1.Size]byte
+
+	hexHash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+	if len(hexHash) != hex.EncodedLen(sha1.Size) {
+		return hash, ErrInvalidHashLine
+	}
+	if _, err := hex.Decode(hash[:], []byte(hexHash)); err != nil {
+		return hash, ErrInvalidHashLine
+	}
+
+	return hash, nil
+}
@@ -0,0 +1,175 @@
+package breach
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+)
+
+// breachedPasswords are in the dumps & filters of the tests, notBreachedPasswords never are
+var (
+	breachedPasswords    = []string{"123456", "password", "qwerty", "Password1!", "iloveyou", "dragon", "sunshine", "rahasia"}
+	notBreachedPasswords = []string{"correct horse battery staple", "x7#Kq9!vLz2@pW", ""}
+)
+
+// hashLine is the line of the password in a HIBP dump, the hash in uppercase hex
+func hashLine(password string, count int) string {
+	hash := sum(password)
+	return strings.ToUpper(hex.EncodeToString(hash[:])) + ":" + strconv.Itoa(count)
+}
+
+// writeDump writes the lines of the passwords sorted by hash, as in the ordered-by-hash dump
+func writeDump(t *testing.T, passwords []string, lineEnding string) string {
+	lines := make([]string, 0, len(passwords))
+	for _, password := range passwords {
+		lines = append(lines, hashLine(password, 1))
+	}
+	sort.Strings(lines)
+
+	path := filepath.Join(t.TempDir(), "dump.txt")
+	assert.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, lineEnding)+lineEnding), 0o600))
+
+	return path
+}
+
+func TestParseHashLine(t *testing.T) {
+	testCases := []struct {
+		title string
+		line  string
+
+		expected    string
+		expectedErr error
+	}{
+		{
+			title:    "hash & count",
+			line:     "7C4A8D09CA3762AF61E59520943DC26494F8941B:24230577",
+			expected: "7c4a8d09ca3762af61e59520943dc26494f8941b",
+		},
+		{
+			title:    "hash without count",
+			line:     "7C4A8D09CA3762AF61E59520943DC26494F8941B",
+			expected: "7c4a8d09ca3762af61e59520943dc26494f8941b",
+		},
+		{
+			title:    "lowercase hash with a CRLF",
+			line:     "7c4a8d09ca3762af61e59520943dc26494f8941b:3\r\n",
+			expected: "7c4a8d09ca3762af61e59520943dc26494f8941b",
+		},
+		{
+			title:       "hash too short",
+			line:        "7C4A8D09CA3762AF61E59520943DC26494F8941:3",
+			expectedErr: ErrInvalidHashLine,
+		},
+		{
+			title:       "not hex",
+			line:        "ZC4A8D09CA3762AF61E59520943DC26494F8941B:3",
+			expectedErr: ErrInvalidHashLine,
+		},
+		{
+			title:       "empty",
+			line:        "",
+			expectedErr: ErrInvalidHashLine,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			hash, err := ParseHashLine(tc.line)
+			assert.Equal(t, tc.expectedErr, err)
+			if tc.expectedErr == nil {
+				assert.Equal(t, tc.expected, hex.EncodeToString(hash[:]))
+			}
+		})
+	}
+
+	// the hash of the dump is the SHA-1 the index looks up
+	hash, err := ParseHashLine(hashLine("123456", 1))
+	assert.NoError(t, err)
+	assert.Equal(t, sha1.Sum([]byte("123456")), hash)
+}
+
+func TestOpen(t *testing.T) {
+	filter, err := NewBloomFilter(uint64(len(breachedPasswords)), 0.001)
+	assert.NoError(t, err)
+	for _, password := range breachedPasswords {
+		filter.Add(sum(password))
+	}
+	bloomPath := filepath.Join(t.TempDir(), "breached.bloom")
+	f, err := os.Create(bloomPath)
+	assert.NoError(t, err)
+	_, err = filter.WriteTo(f)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	emptyPath := filepath.Join(t.TempDir(), "empty.txt")
+	assert.NoError(t, os.WriteFile(emptyPath, nil, 0o600))
+
+	testCases := []struct {
+		title string
+		path  string
+
+		expectedType interface{}
+		expectedErr  bool
+	}{
+		{
+			title:        "bloom filter",
+			path:         bloomPath,
+			expectedType: &BloomFilter{},
+		},
+		{
+			title:        "sorted dump",
+			path:         writeDump(t, breachedPasswords, "\n"),
+			expectedType: &sortedFile{},
+		},
+		{
+			title:        "empty dump",
+			path:         emptyPath,
+			expectedType: &sortedFile{},
+		},
+		{
+			title:       "missing file",
+			path:        filepath.Join(t.TempDir(), "missing.bloom"),
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			index, err := Open(tc.path)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.IsType(t, tc.expectedType, index)
+			if s, ok := index.(*sortedFile); ok {
+				defer s.Close()
+			}
+			if tc.path == emptyPath {
+				found, err := index.Contains(breachedPasswords[0])
+				assert.NoError(t, err)
+				assert.False(t, found)
+				return
+			}
+
+			for _, password := range breachedPasswords {
+				found, err := index.Contains(password)
+				assert.NoError(t, err)
+				assert.True(t, found, password)
+			}
+			for _, password := range notBreachedPasswords {
+				found, err := index.Contains(password)
+				assert.NoError(t, err)
+				assert.False(t, found, password)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package breach
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io"
+	"os"
+	"strings"
+)
+
+// sortedFile searches a hash dump sorted by hash in place, full dumps are too large for memory.
+type sortedFile struct {
+	f    *os.File
+	size int64
+}
+
+func newSortedFile(f *os.File) (*sortedFile, error) {
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &sortedFile{f: f, size: info.Size()}, nil
+}
+
+// Contains is a binary search over byte offsets, the line starting at or after the middle
+// offset is compared each step.
+func (s *sortedFile) Contains(password string) (bool, error) {
+	hash := sum(password)
+	target := strings.ToUpper(hex.EncodeToString(hash[:]))
+
+	lo, hi := int64(0), s.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, line, err := s.lineAt(mid)
+		if err != nil {
+			return false, err
+		}
+		// no line starts in [mid, hi), the one we look for can only start before mid
+		if start >= hi {
+			hi = mid
+			continue
+		}
+
+		hexHash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+		switch strings.Compare(strings.ToUpper(hexHash), target) {
+		case 0:
+			return true, nil
+		case -1:
+			lo = start + int64(len(line))
+		default:
+			hi = mid
+		}
+	}
+
+	return false, nil
+}
+
+// lineAt returns the first line starting at offset or after, with its newline
+func (s *sortedFile) lineAt(offset int64) (int64, string, error) {
+	start := offset
+	r := bufio.NewReader(io.NewSectionReader(s.f, offset, s.size-offset))
+	if offset > 0 {
+		// offset-1 being a newline means a line starts right at offset
+		r = bufio.NewReader(io.NewSectionReader(s.f, offset-1, s.size-offset+1))
+		skipped, err := r.ReadString('\n')
+		if err == io.EOF {
+			return s.size, "", nil
+		}
+		if err != nil {
+			return 0, "", err
+		}
+		start = offset - 1 + int64(len(skipped))
+	}
+
+	line, err := r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return 0, "", err
+	}
+	if line == "" {
+		return s.size, "", nil
+	}
+
+	return start, line, nil
+}
+
+// Close releases the dump file.
+func (s *sortedFile) Close() error {
+	return s.f.Close()
+}
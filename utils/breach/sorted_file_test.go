@@ -0,0 +1,103 @@
+package breach
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+)
+
+func TestSortedFileContains(t *testing.T) {
+	// enough lines for the search to land in the middle of lines of different lengths
+	many := make([]string, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		many = append(many, fmt.Sprintf("breached-%d", i))
+	}
+
+	withoutTrailingNewline := func() string {
+		path := filepath.Join(t.TempDir(), "dump.txt")
+		assert.NoError(t, os.WriteFile(path, []byte(hashLine("123456", 24230577)), 0o600))
+		return path
+	}()
+
+	testCases := []struct {
+		title string
+		path  string
+
+		breached    []string
+		notBreached []string
+	}{
+		{
+			title:       "dump with LF",
+			path:        writeDump(t, breachedPasswords, "\n"),
+			breached:    breachedPasswords,
+			notBreached: notBreachedPasswords,
+		},
+		{
+			title:       "dump with CRLF",
+			path:        writeDump(t, breachedPasswords, "\r\n"),
+			breached:    breachedPasswords,
+			notBreached: notBreachedPasswords,
+		},
+		{
+			title:       "large dump",
+			path:        writeDump(t, many, "\n"),
+			breached:    many,
+			notBreached: append([]string{"breached-1000", "breached--1"}, notBreachedPasswords...),
+		},
+		{
+			title:       "single line without newline",
+			path:        withoutTrailingNewline,
+			breached:    []string{"123456"},
+			notBreached: notBreachedPasswords,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			f, err := os.Open(tc.path)
+			assert.NoError(t, err)
+			s, err := newSortedFile(f)
+			assert.NoError(t, err)
+			defer s.Close()
+
+			for _, password := range tc.breached {
+				found, err := s.Contains(password)
+				assert.NoError(t, err)
+				if !found {
+					t.Fatalf("%q not found", password)
+				}
+			}
+			for _, password := range tc.notBreached {
+				found, err := s.Contains(password)
+				assert.NoError(t, err)
+				assert.False(t, found, password)
+			}
+		})
+	}
+}
+
+func TestSortedFileContainsLowercaseDump(t *testing.T) {
+	// 5baa61e4... is the hash of password, 7c4a8d09... of 123456
+	lines := ""
+	for _, password := range []string{"password", "123456"} {
+		hash := sum(password)
+		lines += fmt.Sprintf("%x:1\n", hash)
+	}
+	path := filepath.Join(t.TempDir(), "dump.txt")
+	assert.NoError(t, os.WriteFile(path, []byte(lines), 0o600))
+
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	s, err := newSortedFile(f)
+	assert.NoError(t, err)
+	defer s.Close()
+
+	for _, password := range []string{"123456", "password"} {
+		found, err := s.Contains(password)
+		assert.NoError(t, err)
+		assert.True(t, found, password)
+	}
+}
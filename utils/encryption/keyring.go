@@ -0,0 +1,135 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+
+	"user-service-sample/config"
+)
+
+const (
+	minKeyLength = 32
+)
+
+var (
+	ErrInvalidKey        = errors.New("invalid encryption key")
+	ErrUnknownKeyVersion = errors.New("unknown encryption key version")
+	ErrNoCurrentKey      = errors.New("no current encryption key")
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+	keyVersionPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// Keyring encrypts with the current key & decrypts with the key of the version recorded in the ciphertext,
+// so keys can be rotated without re-encrypting everything at once.
+type Keyring struct {
+	current string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewKeyring fails on a malformed key or a current version without key, a keyring without
+// current version may only decrypt.
+func NewKeyring(cfg config.EncryptionConfig) (*Keyring, error) {
+	k := &Keyring{
+		current: cfg.CurrentVersion,
+		aeads:   map[string]cipher.AEAD{},
+	}
+
+	for _, key := range cfg.Keys {
+		if !keyVersionPattern.MatchString(key.Version) || k.aeads[key.Version] != nil {
+			return nil, ErrInvalidKey
+		}
+
+		secret := key.Secret
+		if key.SecretFile != "" {
+			if secret != "" {
+				return nil, ErrInvalidKey
+			}
+			content, err := os.ReadFile(key.SecretFile)
+			if err != nil {
+				return nil, err
+			}
+			secret = strings.TrimSpace(string(content))
+		}
+		if len(secret) < minKeyLength {
+			return nil, ErrInvalidKey
+		}
+
+		// the secret is any string of enough entropy, the AES-256 key is derived from it
+		aesKey := sha256.Sum256([]byte(secret))
+		block, err := aes.NewCipher(aesKey[:])
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		k.aeads[key.Version] = aead
+	}
+
+	if k.current != "" && k.aeads[k.current] == nil {
+		return nil, ErrUnknownKeyVersion
+	}
+
+	return k, nil
+}
+
+// CanEncrypt is false without a current key
+func (k *Keyring) CanEncrypt() bool {
+	return k != nil && k.current != ""
+}
+
+// Encrypt returns the ciphertext of plaintext as <version>$<base64 nonce & sealed data>.
+// The associated data, e.g. the id of the owner, must be given again to decrypt,
+// a ciphertext copied to another owner doesn't decrypt.
+func (k *Keyring) Encrypt(plaintext, associatedData []byte) (string, error) {
+	if !k.CanEncrypt() {
+		return "", ErrNoCurrentKey
+	}
+
+	aead := k.aeads[k.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, associatedData)
+
+	return k.current + "$" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a ciphertext of Encrypt with the same associated data
+func (k *Keyring) Decrypt(ciphertext string, associatedData []byte) ([]byte, error) {
+	if k == nil {
+		return nil, ErrUnknownKeyVersion
+	}
+
+	version, encoded, found := strings.Cut(ciphertext, "$")
+	if !found {
+		return nil, ErrInvalidCiphertext
+	}
+	aead := k.aeads[version]
+	if aead == nil {
+		return nil, ErrUnknownKeyVersion
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], associatedData)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,55 @@
+package otp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"math/big"
+)
+
+const (
+	// PurposePasswordReset codes let a user set a new password without the current one
+	PurposePasswordReset = "password_reset"
+	// PurposePhoneVerification codes prove the ownership of the phone number of a pending registration
+	PurposePhoneVerification = "phone_verification"
+	// PurposePhoneChange codes prove the ownership of the new phone number of a user
+	PurposePhoneChange = "phone_change"
+	// PurposeLogin codes log a user in without the password
+	PurposeLogin = "login"
+)
+
+var (
+	ErrInvalidCodeLength = errors.New("invalid one-time code length")
+)
+
+// Generate returns a random numeric code of length digits, leading zeros included.
+func Generate(length int) (string, error) {
+	if length <= 0 {
+		return "", ErrInvalidCodeLength
+	}
+
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code[i] = byte('0' + n.Int64())
+	}
+
+	return string(code), nil
+}
+
+// Hash returns the hash stored for a code. It is bound to the purpose, user and phone number the code
+// was sent for, a code can't be replayed for another purpose or user, nor confirm another phone number.
+func Hash(purpose, userId, phoneNumber, code string) string {
+	sum := sha256.Sum256([]byte(purpose + ":" + userId + ":" + phoneNumber + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify compares code with the stored hash in constant time.
+func Verify(codeHash, purpose, userId, phoneNumber, code string) bool {
+	return subtle.ConstantTimeCompare([]byte(codeHash), []byte(Hash(purpose, userId, phoneNumber, code))) == 1
+}
@@ -0,0 +1,120 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"user-service-sample/config"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2idPrefix = "$" + AlgorithmArgon2id + "$"
+
+	defaultArgon2idMemory      = 19 * 1024
+	defaultArgon2idIterations  = 2
+	defaultArgon2idParallelism = 1
+	defaultArgon2idSaltLength  = 16
+	defaultArgon2idKeyLength   = 32
+)
+
+type argon2idParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	saltLength  uint32
+	keyLength   uint32
+}
+
+func newArgon2idParams(cfg config.Argon2idConfig) argon2idParams {
+	p := argon2idParams{
+		memory:      cfg.Memory,
+		iterations:  cfg.Iterations,
+		parallelism: cfg.Parallelism,
+		saltLength:  cfg.SaltLength,
+		keyLength:   cfg.KeyLength,
+	}
+	if p.memory == 0 {
+		p.memory = defaultArgon2idMemory
+	}
+	if p.iterations == 0 {
+		p.iterations = defaultArgon2idIterations
+	}
+	if p.parallelism == 0 {
+		p.parallelism = defaultArgon2idParallelism
+	}
+	if p.saltLength == 0 {
+		p.saltLength = defaultArgon2idSaltLength
+	}
+	if p.keyLength == 0 {
+		p.keyLength = defaultArgon2idKeyLength
+	}
+
+	return p
+}
+
+// validate rejects parameters below the minimums of RFC 9106
+func (p argon2idParams) validate() error {
+	if p.memory < 8*uint32(p.parallelism) || p.saltLength < 8 || p.keyLength < 16 {
+		return ErrInvalidHashCost
+	}
+
+	return nil
+}
+
+func (p argon2idParams) weakerThan(current argon2idParams) bool {
+	return p.memory < current.memory ||
+		p.iterations < current.iterations ||
+		p.keyLength < current.keyLength
+}
+
+// hash encodes the key as a PHC string, e.g. $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+func (p argon2idParams) hash(password string) (string, error) {
+	salt := make([]byte, p.saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// verifyArgon2id returns the parameters the hash was created with
+func verifyArgon2id(password, passwordHash string) (p argon2idParams, match bool, err error) {
+	parts := strings.Split(passwordHash, "$")
+	if len(parts) != 6 {
+		return p, false, ErrUnknownHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, false, ErrUnknownHashFormat
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return p, false, ErrUnknownHashFormat
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, false, ErrUnknownHashFormat
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return p, false, ErrUnknownHashFormat
+	}
+	p.saltLength = uint32(len(salt))
+	p.keyLength = uint32(len(key))
+
+	otherKey := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
+
+	return p, subtle.ConstantTimeCompare(key, otherKey) == 1, nil
+}
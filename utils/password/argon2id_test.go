@@ -0,0 +1,217 @@
+package password
+
+import (
+	"encoding/base64"
+	"regexp"
+	"testing"
+
+	"user-service-sample/config"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+)
+
+// known answers of the argon2 package for "password" & "somesalt" (c29tZXNhbHQ), as PHC strings
+const (
+	knownArgon2idT1M64P1 = "$argon2id$v=19$m=64,t=1,p=1$c29tZXNhbHQ$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7"
+	knownArgon2idT2M64P1 = "$argon2id$v=19$m=64,t=2,p=1$c29tZXNhbHQ$Bo1ismRVk2qm6+YAYLCmWHDb+j3fjUH3"
+	knownArgon2idT2M64P2 = "$argon2id$v=19$m=64,t=2,p=2$c29tZXNhbHQ$NQrDciL0Nsy1wJcvHr079rlYvyBxhBNi"
+)
+
+func TestVerifyArgon2id(t *testing.T) {
+	testCases := []struct {
+		title        string
+		password     string
+		passwordHash string
+
+		expectedParams argon2idParams
+		expectedMatch  bool
+		expectedErr    error
+	}{
+		{
+			title:          "known answer, 1 iteration",
+			password:       "password",
+			passwordHash:   knownArgon2idT1M64P1,
+			expectedParams: argon2idParams{memory: 64, iterations: 1, parallelism: 1, saltLength: 8, keyLength: 24},
+			expectedMatch:  true,
+		},
+		{
+			title:          "known answer, 2 iterations",
+			password:       "password",
+			passwordHash:   knownArgon2idT2M64P1,
+			expectedParams: argon2idParams{memory: 64, iterations: 2, parallelism: 1, saltLength: 8, keyLength: 24},
+			expectedMatch:  true,
+		},
+		{
+			title:          "known answer, 2 threads",
+			password:       "password",
+			passwordHash:   knownArgon2idT2M64P2,
+			expectedParams: argon2idParams{memory: 64, iterations: 2, parallelism: 2, saltLength: 8, keyLength: 24},
+			expectedMatch:  true,
+		},
+		{
+			title:          "hash of the test user",
+			password:       test_helper.TestUserPassword,
+			passwordHash:   test_helper.TestUserArgon2idHash,
+			expectedParams: argon2idParams{memory: 64, iterations: 1, parallelism: 1, saltLength: 16, keyLength: 32},
+			expectedMatch:  true,
+		},
+		{
+			title:          "wrong password",
+			password:       "Password",
+			passwordHash:   knownArgon2idT1M64P1,
+			expectedParams: argon2idParams{memory: 64, iterations: 1, parallelism: 1, saltLength: 8, keyLength: 24},
+			expectedMatch:  false,
+		},
+		{
+			title:          "parameters of another hash",
+			password:       "password",
+			passwordHash:   "$argon2id$v=19$m=64,t=2,p=1$c29tZXNhbHQ$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7",
+			expectedParams: argon2idParams{memory: 64, iterations: 2, parallelism: 1, saltLength: 8, keyLength: 24},
+			expectedMatch:  false,
+		},
+		{
+			title:        "missing part",
+			password:     "password",
+			passwordHash: "$argon2id$v=19$m=64,t=1,p=1$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7",
+			expectedErr:  ErrUnknownHashFormat,
+		},
+		{
+			title:        "other version",
+			password:     "password",
+			passwordHash: "$argon2id$v=16$m=64,t=1,p=1$c29tZXNhbHQ$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7",
+			expectedErr:  ErrUnknownHashFormat,
+		},
+		{
+			title:        "version missing",
+			password:     "password",
+			passwordHash: "$argon2id$$m=64,t=1,p=1$c29tZXNhbHQ$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7",
+			expectedErr:  ErrUnknownHashFormat,
+		},
+		{
+			title:        "parameters missing",
+			password:     "password",
+			passwordHash: "$argon2id$v=19$m=64,t=1$c29tZXNhbHQ$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7",
+			expectedErr:  ErrUnknownHashFormat,
+		},
+		{
+			title:        "parameters not numbers",
+			password:     "password",
+			passwordHash: "$argon2id$v=19$m=x,t=1,p=1$c29tZXNhbHQ$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7",
+			expectedErr:  ErrUnknownHashFormat,
+		},
+		{
+			title:        "salt not base64",
+			password:     "password",
+			passwordHash: "$argon2id$v=19$m=64,t=1,p=1$c29tZX*hbHQ$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7",
+			expectedErr:  ErrUnknownHashFormat,
+		},
+		{
+			title:        "key padded",
+			password:     "password",
+			passwordHash: "$argon2id$v=19$m=64,t=1,p=1$c29tZXNhbHQ$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7=",
+			expectedErr:  ErrUnknownHashFormat,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			params, match, err := verifyArgon2id(tc.password, tc.passwordHash)
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedMatch, match)
+			if tc.expectedErr == nil {
+				assert.Equal(t, tc.expectedParams, params)
+			}
+		})
+	}
+}
+
+func TestArgon2idHash(t *testing.T) {
+	params := argon2idParams{memory: 64, iterations: 1, parallelism: 2, saltLength: 16, keyLength: 32}
+
+	hash, err := params.hash("password")
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^\$argon2id\$v=19\$m=64,t=1,p=2\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`), hash)
+
+	parsed, match, err := verifyArgon2id("password", hash)
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.Equal(t, params, parsed)
+
+	// every hash has its own salt
+	other, err := params.hash("password")
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, other)
+	_, err = base64.RawStdEncoding.DecodeString(other[len(other)-43:])
+	assert.NoError(t, err)
+}
+
+func TestNewArgon2idParams(t *testing.T) {
+	testCases := []struct {
+		title string
+		cfg   config.Argon2idConfig
+
+		expected    argon2idParams
+		expectedErr error
+	}{
+		{
+			title:    "defaults",
+			cfg:      config.Argon2idConfig{},
+			expected: argon2idParams{memory: 19 * 1024, iterations: 2, parallelism: 1, saltLength: 16, keyLength: 32},
+		},
+		{
+			title:    "configured",
+			cfg:      config.Argon2idConfig{Memory: 64 * 1024, Iterations: 3, Parallelism: 4, SaltLength: 32, KeyLength: 64},
+			expected: argon2idParams{memory: 64 * 1024, iterations: 3, parallelism: 4, saltLength: 32, keyLength: 64},
+		},
+		{
+			title:       "memory below 8 KiB per thread",
+			cfg:         config.Argon2idConfig{Memory: 31, Parallelism: 4},
+			expected:    argon2idParams{memory: 31, iterations: 2, parallelism: 4, saltLength: 16, keyLength: 32},
+			expectedErr: ErrInvalidHashCost,
+		},
+		{
+			title:       "salt too short",
+			cfg:         config.Argon2idConfig{SaltLength: 7},
+			expected:    argon2idParams{memory: 19 * 1024, iterations: 2, parallelism: 1, saltLength: 7, keyLength: 32},
+			expectedErr: ErrInvalidHashCost,
+		},
+		{
+			title:       "key too short",
+			cfg:         config.Argon2idConfig{KeyLength: 15},
+			expected:    argon2idParams{memory: 19 * 1024, iterations: 2, parallelism: 1, saltLength: 16, keyLength: 15},
+			expectedErr: ErrInvalidHashCost,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			params := newArgon2idParams(tc.cfg)
+			assert.Equal(t, tc.expected, params)
+			assert.Equal(t, tc.expectedErr, params.validate())
+		})
+	}
+}
+
+func TestArgon2idWeakerThan(t *testing.T) {
+	current := argon2idParams{memory: 64, iterations: 2, parallelism: 1, saltLength: 16, keyLength: 32}
+
+	testCases := []struct {
+		title    string
+		params   argon2idParams
+		expected bool
+	}{
+		{title: "same", params: current, expected: false},
+		{title: "less memory", params: argon2idParams{memory: 32, iterations: 2, parallelism: 1, saltLength: 16, keyLength: 32}, expected: true},
+		{title: "fewer iterations", params: argon2idParams{memory: 64, iterations: 1, parallelism: 1, saltLength: 16, keyLength: 32}, expected: true},
+		{title: "shorter key", params: argon2idParams{memory: 64, iterations: 2, parallelism: 1, saltLength: 16, keyLength: 24}, expected: true},
+		{title: "more threads", params: argon2idParams{memory: 64, iterations: 2, parallelism: 4, saltLength: 16, keyLength: 32}, expected: false},
+		{title: "stronger", params: argon2idParams{memory: 128, iterations: 3, parallelism: 1, saltLength: 16, keyLength: 32}, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.params.weakerThan(current))
+		})
+	}
+}
@@ -1,38 +1,158 @@
 package password
 
 import (
-	"crypto/rand"
-	"crypto/sha256"
-	"encoding/base64"
-	"encoding/hex"
+	"errors"
+	"strings"
+
+	"user-service-sample/config"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 const (
-	saltByteLength = 10
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+
+	defaultBcryptCost = 12
 )
 
-func SaltAndHashPassword(password string) (passwordHash, salt string) {
-	// generate salt
-	salt = generateRandomString(saltByteLength)
+var (
+	ErrUnsupportedHashAlgorithm = errors.New("unsupported password hash algorithm")
+	ErrInvalidHashCost          = errors.New("invalid password hash cost")
+	ErrUnknownHashFormat        = errors.New("unknown password hash format")
+)
+
+// Hasher creates password hashes as PHC strings with the configured algorithm & cost,
+// and verifies hashes created with any supported algorithm, legacy SHA-256 included.
+type Hasher struct {
+	algorithm  string
+	argon2id   argon2idParams
+	bcryptCost int
+	peppers    peppers
+}
 
-	// generate hash
-	passwordHash = createHash(password, salt)
+// NewHasher fails on an unknown algorithm, a cost out of the algorithm bounds,
+// or a pepper that can't be loaded.
+func NewHasher(cfg config.PasswordHashingConfig) (*Hasher, error) {
+	h := &Hasher{
+		algorithm:  cfg.Algorithm,
+		argon2id:   newArgon2idParams(cfg.Argon2id),
+		bcryptCost: cfg.Bcrypt.Cost,
+	}
+	if h.algorithm == "" {
+		h.algorithm = AlgorithmArgon2id
+	}
+	if h.bcryptCost == 0 {
+		h.bcryptCost = defaultBcryptCost
+	}
 
-	return passwordHash, salt
+	switch h.algorithm {
+	case AlgorithmArgon2id:
+		if err := h.argon2id.validate(); err != nil {
+			return nil, err
+		}
+	case AlgorithmBcrypt:
+		if h.bcryptCost < bcrypt.MinCost || h.bcryptCost > bcrypt.MaxCost {
+			return nil, ErrInvalidHashCost
+		}
+	default:
+		return nil, ErrUnsupportedHashAlgorithm
+	}
+
+	peppers, err := newPeppers(cfg.Pepper)
+	if err != nil {
+		return nil, err
+	}
+	h.peppers = peppers
+
+	return h, nil
 }
 
-func CheckPassword(password, passwordHash, salt string) bool {
-	return passwordHash == createHash(password, salt)
+// Hash returns the PHC string of the password, the salt is part of it.
+// With a current pepper the password is peppered first and the PHC string is prefixed with the pepper version.
+func (h *Hasher) Hash(password string) (string, error) {
+	version := h.peppers.current
+	if version == "" {
+		return h.hash(password)
+	}
+
+	peppered, err := h.peppers.apply(version, password)
+	if err != nil {
+		return "", err
+	}
+	hash, err := h.hash(peppered)
+	if err != nil {
+		return "", err
+	}
+
+	return wrapPeppered(version, hash), nil
 }
 
-func generateRandomString(length int) string {
-	b := make([]byte, length)
-	rand.Read(b)
-	return base64.StdEncoding.EncodeToString(b)
+func (h *Hasher) hash(password string) (string, error) {
+	if h.algorithm == AlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		return string(hash), err
+	}
+
+	return h.argon2id.hash(password)
 }
 
-func createHash(password, salt string) string {
-	hasher := sha256.New()
-	hasher.Write([]byte(password + salt))
-	return hex.EncodeToString(hasher.Sum(nil))
+// Verify checks the password against the stored hash, salt is only set for legacy SHA-256 hashes.
+// needsRehash reports a match on a hash with another algorithm, a lower cost or another pepper
+// than configured, it should be replaced with Hash of the password.
+func (h *Hasher) Verify(password, passwordHash, salt string) (match, needsRehash bool, err error) {
+	version, innerHash, peppered := unwrapPeppered(passwordHash)
+	if peppered {
+		if password, err = h.peppers.apply(version, password); err != nil {
+			return false, false, err
+		}
+	}
+
+	match, needsRehash, err = h.verifyHash(password, innerHash, salt)
+	if err != nil || !match {
+		return false, false, err
+	}
+
+	return true, needsRehash || version != h.peppers.current, nil
+}
+
+func (h *Hasher) verifyHash(password, passwordHash, salt string) (match, needsRehash bool, err error) {
+	switch {
+	case strings.HasPrefix(passwordHash, argon2idPrefix):
+		params, match, err := verifyArgon2id(password, passwordHash)
+		if err != nil || !match {
+			return false, false, err
+		}
+		return true, h.algorithm != AlgorithmArgon2id || params.weakerThan(h.argon2id), nil
+
+	case isBcryptHash(passwordHash):
+		err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+		cost, err := bcrypt.Cost([]byte(passwordHash))
+		if err != nil {
+			return false, false, err
+		}
+		return true, h.algorithm != AlgorithmBcrypt || cost < h.bcryptCost, nil
+
+	case salt != "":
+		// hashes created before PHC strings always need a rehash
+		return checkLegacyHash(password, passwordHash, salt), true, nil
+	}
+
+	return false, false, ErrUnknownHashFormat
+}
+
+func isBcryptHash(passwordHash string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(passwordHash, prefix) {
+			return true
+		}
+	}
+
+	return false
 }
@@ -0,0 +1,322 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"user-service-sample/config"
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// knownBcrypt is the OpenWall test vector of "U*U", at cost 5
+const knownBcrypt = "$2a$05$CCCCCCCCCCCCCCCCCCCCC.E5YPO9kmyuRGyh0XouQYb4YMJKvyOeW"
+
+// testPepperConfig peppers with current, the rotated out versions 1 & 2 stay configured
+func testPepperConfig(current string) config.PepperConfig {
+	return config.PepperConfig{
+		CurrentVersion: current,
+		Keys: []config.PepperKeyConfig{
+			{Version: "1", Secret: testPepperV1},
+			{Version: "2", Secret: testPepperV2},
+		},
+	}
+}
+
+func newTestHasher(t *testing.T, cfg config.PasswordHashingConfig) *Hasher {
+	if cfg.Argon2id == (config.Argon2idConfig{}) {
+		cfg.Argon2id = test_helper.TestArgon2idConfig
+	}
+	h, err := NewHasher(cfg)
+	assert.NoError(t, err)
+	return h
+}
+
+func TestNewHasher(t *testing.T) {
+	testCases := []struct {
+		title string
+		cfg   config.PasswordHashingConfig
+
+		expectedAlgorithm  string
+		expectedBcryptCost int
+		expectedErr        error
+	}{
+		{
+			title:              "defaults",
+			cfg:                config.PasswordHashingConfig{},
+			expectedAlgorithm:  AlgorithmArgon2id,
+			expectedBcryptCost: 12,
+		},
+		{
+			title:              "bcrypt",
+			cfg:                config.PasswordHashingConfig{Algorithm: AlgorithmBcrypt, Bcrypt: config.BcryptConfig{Cost: 10}},
+			expectedAlgorithm:  AlgorithmBcrypt,
+			expectedBcryptCost: 10,
+		},
+		{
+			title:       "unknown algorithm",
+			cfg:         config.PasswordHashingConfig{Algorithm: "scrypt"},
+			expectedErr: ErrUnsupportedHashAlgorithm,
+		},
+		{
+			title:       "bcrypt cost too low",
+			cfg:         config.PasswordHashingConfig{Algorithm: AlgorithmBcrypt, Bcrypt: config.BcryptConfig{Cost: bcrypt.MinCost - 1}},
+			expectedErr: ErrInvalidHashCost,
+		},
+		{
+			title:       "bcrypt cost too high",
+			cfg:         config.PasswordHashingConfig{Algorithm: AlgorithmBcrypt, Bcrypt: config.BcryptConfig{Cost: bcrypt.MaxCost + 1}},
+			expectedErr: ErrInvalidHashCost,
+		},
+		{
+			title:       "argon2id key too short",
+			cfg:         config.PasswordHashingConfig{Argon2id: config.Argon2idConfig{KeyLength: 8}},
+			expectedErr: ErrInvalidHashCost,
+		},
+		{
+			title:       "unknown current pepper",
+			cfg:         config.PasswordHashingConfig{Pepper: testPepperConfig("3")},
+			expectedErr: ErrUnknownPepperVersion,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			h, err := NewHasher(tc.cfg)
+			assert.Equal(t, tc.expectedErr, err)
+			if tc.expectedErr != nil {
+				assert.Nil(t, h)
+				return
+			}
+			assert.Equal(t, tc.expectedAlgorithm, h.algorithm)
+			assert.Equal(t, tc.expectedBcryptCost, h.bcryptCost)
+		})
+	}
+}
+
+func TestHasherHash(t *testing.T) {
+	testCases := []struct {
+		title string
+		cfg   config.PasswordHashingConfig
+
+		expectedPrefix string
+	}{
+		{
+			title:          "argon2id",
+			cfg:            config.PasswordHashingConfig{},
+			expectedPrefix: "$argon2id$v=19$m=64,t=1,p=1$",
+		},
+		{
+			title:          "bcrypt",
+			cfg:            config.PasswordHashingConfig{Algorithm: AlgorithmBcrypt, Bcrypt: config.BcryptConfig{Cost: bcrypt.MinCost}},
+			expectedPrefix: "$2a$04$",
+		},
+		{
+			title:          "peppered argon2id",
+			cfg:            config.PasswordHashingConfig{Pepper: testPepperConfig("2")},
+			expectedPrefix: "$pepper$v=2$argon2id$v=19$m=64,t=1,p=1$",
+		},
+		{
+			title:          "peppered bcrypt",
+			cfg:            config.PasswordHashingConfig{Algorithm: AlgorithmBcrypt, Bcrypt: config.BcryptConfig{Cost: bcrypt.MinCost}, Pepper: testPepperConfig("1")},
+			expectedPrefix: "$pepper$v=1$2a$04$",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			h := newTestHasher(t, tc.cfg)
+
+			hash, err := h.Hash(test_helper.TestUserPassword)
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(hash, tc.expectedPrefix), hash)
+
+			match, needsRehash, err := h.Verify(test_helper.TestUserPassword, hash, "")
+			assert.NoError(t, err)
+			assert.True(t, match)
+			assert.False(t, needsRehash)
+
+			match, _, err = h.Verify("wrong password", hash, "")
+			assert.NoError(t, err)
+			assert.False(t, match)
+		})
+	}
+}
+
+func TestHasherVerify(t *testing.T) {
+	// hashes peppered with the versions rotated out in testPepperConfig
+	pepperedV1, err := newTestHasher(t, config.PasswordHashingConfig{Pepper: testPepperConfig("1")}).Hash("password")
+	assert.NoError(t, err)
+	pepperedV2, err := newTestHasher(t, config.PasswordHashingConfig{Pepper: testPepperConfig("2")}).Hash("password")
+	assert.NoError(t, err)
+	pepperedV1Inner := strings.TrimPrefix(pepperedV1, "$pepper$v=1")
+
+	testCases := []struct {
+		title        string
+		cfg          config.PasswordHashingConfig
+		password     string
+		passwordHash string
+		salt         string
+
+		expectedMatch       bool
+		expectedNeedsRehash bool
+		expectedErr         error
+	}{
+		{
+			title:         "argon2id with the configured cost",
+			cfg:           config.PasswordHashingConfig{Argon2id: config.Argon2idConfig{Memory: 64, Iterations: 1, Parallelism: 1, KeyLength: 24}},
+			password:      "password",
+			passwordHash:  knownArgon2idT1M64P1,
+			expectedMatch: true,
+		},
+		{
+			title:               "argon2id with a lower cost",
+			cfg:                 config.PasswordHashingConfig{Argon2id: config.Argon2idConfig{Memory: 64, Iterations: 2, Parallelism: 1, KeyLength: 24}},
+			password:            "password",
+			passwordHash:        knownArgon2idT1M64P1,
+			expectedMatch:       true,
+			expectedNeedsRehash: true,
+		},
+		{
+			title:               "argon2id when bcrypt is configured",
+			cfg:                 config.PasswordHashingConfig{Algorithm: AlgorithmBcrypt},
+			password:            "password",
+			passwordHash:        knownArgon2idT2M64P2,
+			expectedMatch:       true,
+			expectedNeedsRehash: true,
+		},
+		{
+			title:         "argon2id wrong password",
+			password:      "Password",
+			passwordHash:  knownArgon2idT1M64P1,
+			expectedMatch: false,
+		},
+		{
+			title:         "bcrypt with the configured cost",
+			cfg:           config.PasswordHashingConfig{Algorithm: AlgorithmBcrypt, Bcrypt: config.BcryptConfig{Cost: 5}},
+			password:      "U*U",
+			passwordHash:  knownBcrypt,
+			expectedMatch: true,
+		},
+		{
+			title:               "bcrypt with a lower cost",
+			cfg:                 config.PasswordHashingConfig{Algorithm: AlgorithmBcrypt, Bcrypt: config.BcryptConfig{Cost: 6}},
+			password:            "U*U",
+			passwordHash:        knownBcrypt,
+			expectedMatch:       true,
+			expectedNeedsRehash: true,
+		},
+		{
+			title:               "bcrypt when argon2id is configured",
+			password:            test_helper.TestUserPassword,
+			passwordHash:        test_helper.TestUserBcryptHash,
+			expectedMatch:       true,
+			expectedNeedsRehash: true,
+		},
+		{
+			title:         "bcrypt wrong password",
+			password:      "U*V",
+			passwordHash:  knownBcrypt,
+			expectedMatch: false,
+		},
+		{
+			title:               "legacy SHA-256",
+			password:            test_helper.TestUserPassword,
+			passwordHash:        test_helper.TestUserPasswordHash,
+			salt:                test_helper.TestUserSalt,
+			expectedMatch:       true,
+			expectedNeedsRehash: true,
+		},
+		{
+			title:         "legacy SHA-256 wrong password",
+			password:      "password",
+			passwordHash:  test_helper.TestUserPasswordHash,
+			salt:          test_helper.TestUserSalt,
+			expectedMatch: false,
+		},
+		{
+			title:        "legacy SHA-256 without salt",
+			password:     test_helper.TestUserPassword,
+			passwordHash: test_helper.TestUserPasswordHash,
+			expectedErr:  ErrUnknownHashFormat,
+		},
+		{
+			title:         "peppered with the current version",
+			cfg:           config.PasswordHashingConfig{Pepper: testPepperConfig("2")},
+			password:      "password",
+			passwordHash:  pepperedV2,
+			expectedMatch: true,
+		},
+		{
+			title:               "peppered with a rotated out version",
+			cfg:                 config.PasswordHashingConfig{Pepper: testPepperConfig("2")},
+			password:            "password",
+			passwordHash:        pepperedV1,
+			expectedMatch:       true,
+			expectedNeedsRehash: true,
+		},
+		{
+			title:               "not peppered once a pepper is configured",
+			cfg:                 config.PasswordHashingConfig{Argon2id: config.Argon2idConfig{Memory: 64, Iterations: 1, Parallelism: 1, KeyLength: 24}, Pepper: testPepperConfig("2")},
+			password:            "password",
+			passwordHash:        knownArgon2idT1M64P1,
+			expectedMatch:       true,
+			expectedNeedsRehash: true,
+		},
+		{
+			title:               "peppered once the pepper is removed",
+			cfg:                 config.PasswordHashingConfig{Pepper: testPepperConfig("")},
+			password:            "password",
+			passwordHash:        pepperedV2,
+			expectedMatch:       true,
+			expectedNeedsRehash: true,
+		},
+		{
+			title:         "peppered wrong password",
+			cfg:           config.PasswordHashingConfig{Pepper: testPepperConfig("2")},
+			password:      "Password",
+			passwordHash:  pepperedV1,
+			expectedMatch: false,
+		},
+		{
+			title:         "peppered with another version than recorded",
+			cfg:           config.PasswordHashingConfig{Pepper: testPepperConfig("2")},
+			password:      "password",
+			passwordHash:  "$pepper$v=2" + pepperedV1Inner,
+			expectedMatch: false,
+		},
+		{
+			title:         "peppered hash stripped of its version",
+			cfg:           config.PasswordHashingConfig{Pepper: testPepperConfig("2")},
+			password:      "password",
+			passwordHash:  pepperedV1Inner,
+			expectedMatch: false,
+		},
+		{
+			title:        "pepper version no longer configured",
+			cfg:          config.PasswordHashingConfig{Pepper: testPepperConfig("2")},
+			password:     "password",
+			passwordHash: "$pepper$v=0" + pepperedV1Inner,
+			expectedErr:  ErrUnknownPepperVersion,
+		},
+		{
+			title:        "unknown format",
+			password:     "password",
+			passwordHash: "$scrypt$ln=16,r=8,p=1$c29tZXNhbHQ$aGFzaA",
+			expectedErr:  ErrUnknownHashFormat,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			h := newTestHasher(t, tc.cfg)
+
+			match, needsRehash, err := h.Verify(tc.password, tc.passwordHash, tc.salt)
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedMatch, match)
+			assert.Equal(t, tc.expectedNeedsRehash, needsRehash)
+		})
+	}
+}
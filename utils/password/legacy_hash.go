@@ -0,0 +1,16 @@
+package password
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+)
+
+// checkLegacyHash verifies hashes created before PHC strings, a single round of SHA-256
+// over password+salt with the salt stored apart. Those are replaced on the next log in.
+func checkLegacyHash(password, passwordHash, salt string) bool {
+	hasher := sha256.New()
+	hasher.Write([]byte(password + salt))
+
+	return subtle.ConstantTimeCompare([]byte(passwordHash), []byte(hex.EncodeToString(hasher.Sum(nil)))) == 1
+}
@@ -0,0 +1,72 @@
+package password
+
+import (
+	"testing"
+
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+)
+
+func TestCheckLegacyHash(t *testing.T) {
+	// SHA-256 of "abc", the FIPS 180-2 example
+	const sha256OfABC = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+
+	testCases := []struct {
+		title        string
+		password     string
+		passwordHash string
+		salt         string
+
+		expected bool
+	}{
+		{
+			title:        "known answer",
+			password:     "ab",
+			passwordHash: sha256OfABC,
+			salt:         "c",
+			expected:     true,
+		},
+		{
+			title:        "hash of the test user",
+			password:     test_helper.TestUserPassword,
+			passwordHash: test_helper.TestUserPasswordHash,
+			salt:         test_helper.TestUserSalt,
+			expected:     true,
+		},
+		{
+			title:        "wrong password",
+			password:     "abc",
+			passwordHash: sha256OfABC,
+			salt:         "c",
+			expected:     false,
+		},
+		{
+			title:        "wrong salt",
+			password:     test_helper.TestUserPassword,
+			passwordHash: test_helper.TestUserPasswordHash,
+			salt:         "5lob7JFgxk+sXw==",
+			expected:     false,
+		},
+		{
+			title:        "uppercase hex",
+			password:     "ab",
+			passwordHash: "BA7816BF8F01CFEA414140DE5DAE2223B00361A396177A9CB410FF61F20015AD",
+			salt:         "c",
+			expected:     false,
+		},
+		{
+			title:        "empty hash",
+			password:     "ab",
+			passwordHash: "",
+			salt:         "c",
+			expected:     false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			assert.Equal(t, tc.expected, checkLegacyHash(tc.password, tc.passwordHash, tc.salt))
+		})
+	}
+}
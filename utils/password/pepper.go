@@ -0,0 +1,103 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+
+	"user-service-sample/config"
+)
+
+const (
+	pepperPrefix = "$pepper$v="
+
+	minPepperLength = 16
+)
+
+var (
+	ErrInvalidPepper        = errors.New("invalid password pepper")
+	ErrUnknownPepperVersion = errors.New("unknown password pepper version")
+
+	pepperVersionPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// peppers are HMAC keys by version, kept out of the database so a leaked users table
+// alone is not enough to crack the hashes
+type peppers struct {
+	current string
+	keys    map[string][]byte
+}
+
+func newPeppers(cfg config.PepperConfig) (peppers, error) {
+	p := peppers{
+		current: cfg.CurrentVersion,
+		keys:    map[string][]byte{},
+	}
+
+	for _, key := range cfg.Keys {
+		if !pepperVersionPattern.MatchString(key.Version) || p.keys[key.Version] != nil {
+			return p, ErrInvalidPepper
+		}
+
+		secret := key.Secret
+		if key.SecretFile != "" {
+			if secret != "" {
+				return p, ErrInvalidPepper
+			}
+			content, err := os.ReadFile(key.SecretFile)
+			if err != nil {
+				return p, err
+			}
+			secret = strings.TrimSpace(string(content))
+		}
+		if len(secret) < minPepperLength {
+			return p, ErrInvalidPepper
+		}
+
+		p.keys[key.Version] = []byte(secret)
+	}
+
+	if p.current != "" && p.keys[p.current] == nil {
+		return p, ErrUnknownPepperVersion
+	}
+
+	return p, nil
+}
+
+// apply is the HMAC-SHA256 of the password with the pepper of the version,
+// base64 encoded to stay within the 72 bytes bcrypt takes
+func (p peppers) apply(version, password string) (string, error) {
+	key := p.keys[version]
+	if key == nil {
+		return "", ErrUnknownPepperVersion
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(password))
+
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// wrapPeppered records the pepper version in front of the hash, e.g. $pepper$v=2$argon2id$v=19$...
+func wrapPeppered(version, passwordHash string) string {
+	return pepperPrefix + version + passwordHash
+}
+
+// unwrapPeppered returns the pepper version & the hash of the peppered password,
+// ok is false for a hash created without pepper
+func unwrapPeppered(passwordHash string) (version, innerHash string, ok bool) {
+	if !strings.HasPrefix(passwordHash, pepperPrefix) {
+		return "", passwordHash, false
+	}
+
+	version, rest, found := strings.Cut(strings.TrimPrefix(passwordHash, pepperPrefix), "$")
+	if !found {
+		return "", passwordHash, false
+	}
+
+	return version, "$" + rest, true
+}
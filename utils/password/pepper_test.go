@@ -0,0 +1,185 @@
+package password
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"user-service-sample/config"
+
+	"github.com/c2fo/testify/assert"
+)
+
+const (
+	testPepperV1 = "pepper-v1-0123456789abcdef"
+	testPepperV2 = "pepper-v2-0123456789abcdef"
+)
+
+func TestNewPeppers(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "pepper")
+	assert.NoError(t, os.WriteFile(secretFile, []byte(testPepperV2+"\n"), 0o600))
+
+	testCases := []struct {
+		title string
+		cfg   config.PepperConfig
+
+		expected    peppers
+		expectedErr error
+	}{
+		{
+			title:    "no pepper",
+			cfg:      config.PepperConfig{},
+			expected: peppers{keys: map[string][]byte{}},
+		},
+		{
+			title: "secret & secret file",
+			cfg: config.PepperConfig{
+				CurrentVersion: "2",
+				Keys: []config.PepperKeyConfig{
+					{Version: "1", Secret: testPepperV1},
+					{Version: "2", SecretFile: secretFile},
+				},
+			},
+			expected: peppers{
+				current: "2",
+				keys:    map[string][]byte{"1": []byte(testPepperV1), "2": []byte(testPepperV2)},
+			},
+		},
+		{
+			title: "rotated out version kept without current version",
+			cfg: config.PepperConfig{
+				Keys: []config.PepperKeyConfig{{Version: "1", Secret: testPepperV1}},
+			},
+			expected: peppers{keys: map[string][]byte{"1": []byte(testPepperV1)}},
+		},
+		{
+			title: "current version not configured",
+			cfg: config.PepperConfig{
+				CurrentVersion: "3",
+				Keys:           []config.PepperKeyConfig{{Version: "1", Secret: testPepperV1}},
+			},
+			expectedErr: ErrUnknownPepperVersion,
+		},
+		{
+			title: "version with a $",
+			cfg: config.PepperConfig{
+				Keys: []config.PepperKeyConfig{{Version: "1$", Secret: testPepperV1}},
+			},
+			expectedErr: ErrInvalidPepper,
+		},
+		{
+			title: "version missing",
+			cfg: config.PepperConfig{
+				Keys: []config.PepperKeyConfig{{Secret: testPepperV1}},
+			},
+			expectedErr: ErrInvalidPepper,
+		},
+		{
+			title: "version configured twice",
+			cfg: config.PepperConfig{
+				Keys: []config.PepperKeyConfig{
+					{Version: "1", Secret: testPepperV1},
+					{Version: "1", Secret: testPepperV2},
+				},
+			},
+			expectedErr: ErrInvalidPepper,
+		},
+		{
+			title: "secret too short",
+			cfg: config.PepperConfig{
+				Keys: []config.PepperKeyConfig{{Version: "1", Secret: "0123456789abcde"}},
+			},
+			expectedErr: ErrInvalidPepper,
+		},
+		{
+			title: "both secret & secret file",
+			cfg: config.PepperConfig{
+				Keys: []config.PepperKeyConfig{{Version: "1", Secret: testPepperV1, SecretFile: secretFile}},
+			},
+			expectedErr: ErrInvalidPepper,
+		},
+		{
+			title: "secret file missing",
+			cfg: config.PepperConfig{
+				Keys: []config.PepperKeyConfig{{Version: "1", SecretFile: filepath.Join(t.TempDir(), "missing")}},
+			},
+			expectedErr: os.ErrNotExist,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			p, err := newPeppers(tc.cfg)
+			if tc.expectedErr != nil {
+				assert.True(t, errors.Is(err, tc.expectedErr), "expected %v, got %v", tc.expectedErr, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, p)
+		})
+	}
+}
+
+func TestPeppersApply(t *testing.T) {
+	// HMAC-SHA256 test case 2 of RFC 4231, base64 encoded
+	p := peppers{keys: map[string][]byte{"rfc4231": []byte("Jefe")}}
+
+	peppered, err := p.apply("rfc4231", "what do ya want for nothing?")
+	assert.NoError(t, err)
+	assert.Equal(t, "W9zBRr9gdU5qBCQmCJV1x1oAPwidJzmDnexYuWTsOEM", peppered)
+
+	peppered, err = p.apply("1", "what do ya want for nothing?")
+	assert.Equal(t, ErrUnknownPepperVersion, err)
+	assert.Equal(t, "", peppered)
+}
+
+func TestUnwrapPeppered(t *testing.T) {
+	testCases := []struct {
+		title        string
+		passwordHash string
+
+		expectedVersion   string
+		expectedInnerHash string
+		expectedOk        bool
+	}{
+		{
+			title:             "peppered",
+			passwordHash:      "$pepper$v=2" + knownArgon2idT1M64P1,
+			expectedVersion:   "2",
+			expectedInnerHash: knownArgon2idT1M64P1,
+			expectedOk:        true,
+		},
+		{
+			title:             "not peppered",
+			passwordHash:      knownArgon2idT1M64P1,
+			expectedInnerHash: knownArgon2idT1M64P1,
+		},
+		{
+			title:             "legacy hash",
+			passwordHash:      "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+			expectedInnerHash: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		},
+		{
+			title:             "pepper version without hash",
+			passwordHash:      "$pepper$v=2",
+			expectedInnerHash: "$pepper$v=2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			version, innerHash, ok := unwrapPeppered(tc.passwordHash)
+			assert.Equal(t, tc.expectedVersion, version)
+			assert.Equal(t, tc.expectedInnerHash, innerHash)
+			assert.Equal(t, tc.expectedOk, ok)
+		})
+	}
+
+	// wrapPeppered is the reverse
+	version, innerHash, ok := unwrapPeppered(wrapPeppered("v-2_b", knownArgon2idT1M64P1))
+	assert.Equal(t, "v-2_b", version)
+	assert.Equal(t, knownArgon2idT1M64P1, innerHash)
+	assert.True(t, ok)
+}
@@ -0,0 +1,176 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"user-service-sample/config"
+	"user-service-sample/utils/phone"
+	"user-service-sample/utils/strength"
+)
+
+const (
+	ClassUppercase = "uppercase"
+	ClassLowercase = "lowercase"
+	ClassNumber    = "number"
+	ClassSpecial   = "special"
+
+	defaultMinLength = 6
+	defaultMaxLength = 64
+
+	// minPersonalInfoLength keeps short name parts like "Al" from rejecting most passwords
+	minPersonalInfoLength = 3
+)
+
+var (
+	ErrUnknownCharacterClass = errors.New("unknown password character class")
+	ErrInvalidPolicyLength   = errors.New("invalid password policy length")
+	ErrInvalidMinScore       = errors.New("invalid password policy min score")
+
+	defaultRequiredClasses = []string{ClassUppercase, ClassNumber, ClassSpecial}
+
+	classRules = map[string]struct {
+		in      func(r rune) bool
+		message string
+	}{
+		ClassUppercase: {unicode.IsUpper, "%s must contain at least 1 uppercase letter"},
+		ClassLowercase: {unicode.IsLower, "%s must contain at least 1 lowercase letter"},
+		ClassNumber:    {unicode.IsDigit, "%s must contain at least 1 number"},
+		ClassSpecial:   {isSpecial, "%s must contain at least 1 special (non alpha-numeric) character"},
+	}
+)
+
+// Policy is the set of rules new passwords must follow.
+type Policy struct {
+	MinLength          int
+	MaxLength          int
+	RequiredClasses    []string
+	DeniedWords        []string
+	RejectPersonalInfo bool
+	// MinScore is the strength estimate a password must reach, 0 accepts any
+	MinScore int
+}
+
+// PersonalInfo of the user a password is set for, a password may not contain it.
+type PersonalInfo struct {
+	PhoneNumber string
+	FullName    string
+}
+
+// NewPolicy fails on an unknown character class, lengths that can't be satisfied or a min score out of range.
+func NewPolicy(cfg config.PasswordPolicyConfig) (*Policy, error) {
+	p := &Policy{
+		MinLength:          cfg.MinLength,
+		MaxLength:          cfg.MaxLength,
+		RequiredClasses:    cfg.RequiredClasses,
+		RejectPersonalInfo: !cfg.AllowPersonalInfo,
+		MinScore:           cfg.MinScore,
+	}
+	if p.MinLength == 0 {
+		p.MinLength = defaultMinLength
+	}
+	if p.MaxLength == 0 {
+		p.MaxLength = defaultMaxLength
+	}
+	if p.MinLength < 1 || p.MaxLength < p.MinLength {
+		return nil, ErrInvalidPolicyLength
+	}
+
+	if p.RequiredClasses == nil {
+		p.RequiredClasses = defaultRequiredClasses
+	}
+	for _, class := range p.RequiredClasses {
+		if _, ok := classRules[class]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCharacterClass, class)
+		}
+	}
+
+	if p.MinScore < 0 || p.MinScore > strength.MaxScore {
+		return nil, ErrInvalidMinScore
+	}
+
+	for _, word := range cfg.DeniedWords {
+		if word = strings.TrimSpace(word); word != "" {
+			p.DeniedWords = append(p.DeniedWords, strings.ToLower(word))
+		}
+	}
+
+	return p, nil
+}
+
+// Validate returns a message for every rule the password of the field fails, none when it follows the policy.
+func (p *Policy) Validate(field, password string, info PersonalInfo) []string {
+	var messages []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		messages = append(messages, fmt.Sprintf("%s must be at least %d characters in length", field, p.MinLength))
+	}
+	if length > p.MaxLength {
+		messages = append(messages, fmt.Sprintf("%s must be a maximum of %d characters in length", field, p.MaxLength))
+	}
+
+	for _, class := range p.RequiredClasses {
+		rule := classRules[class]
+		if strings.IndexFunc(password, rule.in) < 0 {
+			messages = append(messages, fmt.Sprintf(rule.message, field))
+		}
+	}
+
+	lower := strings.ToLower(password)
+	for _, word := range p.DeniedWords {
+		if strings.Contains(lower, word) {
+			messages = append(messages, fmt.Sprintf("%s must not contain %q", field, word))
+		}
+	}
+
+	if p.RejectPersonalInfo {
+		if containsPhoneNumber(lower, info.PhoneNumber) {
+			messages = append(messages, fmt.Sprintf("%s must not contain your phone number", field))
+		}
+		if containsName(lower, info.FullName) {
+			messages = append(messages, fmt.Sprintf("%s must not contain your name", field))
+		}
+	}
+
+	// the personal info is guessed first whether or not the policy rejects it
+	if p.MinScore > 0 && strength.Estimate(password, info.PhoneNumber, info.FullName).Score < p.MinScore {
+		messages = append(messages, fmt.Sprintf("%s is too easy to guess", field))
+	}
+
+	return messages
+}
+
+func isSpecial(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+}
+
+// containsPhoneNumber matches the national number, without the calling code,
+// so the local and the international format are both matched
+func containsPhoneNumber(password, phoneNumber string) bool {
+	national := strings.TrimPrefix(phoneNumber, "+")
+	if _, n, ok := phone.NationalNumber(phoneNumber); ok {
+		national = n
+	}
+	if len(national) < minPersonalInfoLength {
+		return false
+	}
+
+	return strings.Contains(password, national)
+}
+
+// containsName matches any part of the name, and the name without spaces
+func containsName(password, fullName string) bool {
+	parts := strings.Fields(strings.ToLower(fullName))
+	parts = append(parts, strings.Join(parts, ""))
+	for _, part := range parts {
+		if utf8.RuneCountInString(part) >= minPersonalInfoLength && strings.Contains(password, part) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,100 @@
+package phone
+
+// Country is the numbering plan of the mobile phone numbers of a country,
+// only mobile phone numbers can receive the codes sent by SMS.
+type Country struct {
+	// Code is the ISO 3166-1 alpha-2 code, e.g. ID
+	Code string
+	Name string
+	// CallingCode is the international calling code, without the +
+	CallingCode string
+	// TrunkPrefix starts the local format of a phone number, e.g. the 0 of 0812...,
+	// empty in countries dialing the national number as is
+	TrunkPrefix string
+	// MinLength & MaxLength bound the digits of the national number, without calling code nor trunk prefix
+	MinLength int
+	MaxLength int
+	// MobilePrefixes start the national number of mobile phones
+	MobilePrefixes []string
+}
+
+// countries are the numbering plans known, a country must be one of them to be allowed
+var countries = []Country{
+	{
+		Code:        "ID",
+		Name:        "Indonesia",
+		CallingCode: "62",
+		TrunkPrefix: "0",
+		// 0812-345-678 up to 0812-3456-78901
+		MinLength:      9,
+		MaxLength:      12,
+		MobilePrefixes: []string{"81", "82", "83", "85", "87", "88", "89"},
+	},
+	{
+		Code:        "MY",
+		Name:        "Malaysia",
+		CallingCode: "60",
+		TrunkPrefix: "0",
+		// 012-345 6789, 011 numbers have one more digit
+		MinLength:      9,
+		MaxLength:      10,
+		MobilePrefixes: []string{"10", "11", "12", "13", "14", "16", "17", "18", "19"},
+	},
+	{
+		Code:           "SG",
+		Name:           "Singapore",
+		CallingCode:    "65",
+		MinLength:      8,
+		MaxLength:      8,
+		MobilePrefixes: []string{"8", "9"},
+	},
+}
+
+// LookupCountry returns the numbering plan of the country with the ISO 3166-1 alpha-2 code
+func LookupCountry(code string) (Country, bool) {
+	for _, c := range countries {
+		if c.Code == code {
+			return c, true
+		}
+	}
+
+	return Country{}, false
+}
+
+// NationalNumber splits a phone number in E.164 format into its country & national number,
+// it returns false for a country without a known numbering plan.
+func NationalNumber(phoneNumber string) (Country, string, bool) {
+	if len(phoneNumber) == 0 || phoneNumber[0] != '+' {
+		return Country{}, "", false
+	}
+
+	for _, c := range countries {
+		if national, ok := cutPrefix(phoneNumber[1:], c.CallingCode); ok {
+			return c, national, true
+		}
+	}
+
+	return Country{}, "", false
+}
+
+func (c Country) isMobile(national string) bool {
+	if len(national) < c.MinLength || len(national) > c.MaxLength {
+		return false
+	}
+	for _, prefix := range c.MobilePrefixes {
+		if _, ok := cutPrefix(national, prefix); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cutPrefix is strings.CutPrefix, missing from the Go version of the module
+func cutPrefix(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || s[:len(prefix)] != prefix {
+		return s, false
+	}
+
+	return s[len(prefix):], true
+}
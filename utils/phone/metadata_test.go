@@ -0,0 +1,75 @@
+package phone
+
+import (
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+)
+
+func TestNationalNumber(t *testing.T) {
+	testCases := []struct {
+		title       string
+		phoneNumber string
+
+		expectedCountry  string
+		expectedNational string
+		expectedOk       bool
+	}{
+		{
+			title:            "ID",
+			phoneNumber:      "+6281234567890",
+			expectedCountry:  "ID",
+			expectedNational: "81234567890",
+			expectedOk:       true,
+		},
+		{
+			title:            "MY",
+			phoneNumber:      "+60123456789",
+			expectedCountry:  "MY",
+			expectedNational: "123456789",
+			expectedOk:       true,
+		},
+		{
+			title:            "SG",
+			phoneNumber:      "+6591234567",
+			expectedCountry:  "SG",
+			expectedNational: "91234567",
+			expectedOk:       true,
+		},
+		{
+			title:       "unknown calling code",
+			phoneNumber: "+14155550100",
+		},
+		{
+			title:       "local format",
+			phoneNumber: "081234567890",
+		},
+		{
+			title:       "empty",
+			phoneNumber: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			country, national, ok := NationalNumber(tc.phoneNumber)
+			assert.Equal(t, tc.expectedCountry, country.Code)
+			assert.Equal(t, tc.expectedNational, national)
+			assert.Equal(t, tc.expectedOk, ok)
+		})
+	}
+}
+
+func TestLookupCountry(t *testing.T) {
+	for _, code := range []string{"ID", "MY", "SG"} {
+		country, ok := LookupCountry(code)
+		assert.True(t, ok, code)
+		assert.Equal(t, code, country.Code)
+	}
+
+	// codes are looked up as configured, NewNormalizer uppercases them
+	_, ok := LookupCountry("id")
+	assert.False(t, ok)
+	_, ok = LookupCountry("US")
+	assert.False(t, ok)
+}
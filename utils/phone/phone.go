@@ -0,0 +1,115 @@
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidPhoneNumber    = errors.New("invalid phone number")
+	ErrCountryNotAllowed     = errors.New("phone number country not allowed")
+	ErrNotMobile             = errors.New("not a mobile phone number")
+	ErrUnknownCountry        = errors.New("unknown phone number country")
+	ErrInvalidDefaultCountry = errors.New("default phone number country not allowed")
+)
+
+// separators are left out of a phone number, e.g. in +62 812-3456-7890 or (0812) 3456 7890
+const separators = " -.()/"
+
+// Normalizer brings the phone numbers of the allowed countries to their canonical E.164 format,
+// the same phone number written in any format is stored & looked up the same way.
+type Normalizer struct {
+	allowed []Country
+	// defaultCountry is the country of phone numbers in a local format
+	defaultCountry Country
+}
+
+// NewNormalizer fails when a country has no known numbering plan or the default country isn't allowed.
+func NewNormalizer(allowedCountries []string, defaultCountry string) (*Normalizer, error) {
+	n := &Normalizer{}
+	for _, code := range allowedCountries {
+		country, ok := LookupCountry(strings.ToUpper(code))
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCountry, code)
+		}
+		n.allowed = append(n.allowed, country)
+	}
+
+	country, ok := n.allowedCountry(strings.ToUpper(defaultCountry))
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDefaultCountry, defaultCountry)
+	}
+	n.defaultCountry = country
+
+	return n, nil
+}
+
+// Countries are the allowed countries, in the configured order
+func (n *Normalizer) Countries() []Country {
+	return n.allowed
+}
+
+// Normalize returns the E.164 format of a mobile phone number of an allowed country. The number may be
+// in the international format, with + or 00, or in the local format of the default country,
+// with or without separators: +62 812-3456-7890, 006281234567890 and 0812 3456 7890 are all +6281234567890.
+func (n *Normalizer) Normalize(phoneNumber string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(separators, r) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phoneNumber))
+
+	international := false
+	if rest, ok := cutPrefix(digits, "+"); ok {
+		digits, international = rest, true
+	} else if rest, ok := cutPrefix(digits, "00"); ok {
+		digits, international = rest, true
+	}
+	if digits == "" || strings.TrimLeft(digits, "0123456789") != "" {
+		return "", ErrInvalidPhoneNumber
+	}
+
+	country, national := n.defaultCountry, digits
+	if international {
+		var ok bool
+		country, national, ok = NationalNumber("+" + digits)
+		if !ok {
+			return "", ErrCountryNotAllowed
+		}
+		if _, ok := n.allowedCountry(country.Code); !ok {
+			return "", ErrCountryNotAllowed
+		}
+		// the trunk prefix is often kept after the calling code, e.g. +62 0812...
+		if country.TrunkPrefix != "" {
+			if rest, ok := cutPrefix(national, country.TrunkPrefix); ok && country.isMobile(rest) {
+				national = rest
+			}
+		}
+	} else if country.TrunkPrefix != "" {
+		var ok bool
+		if national, ok = cutPrefix(digits, country.TrunkPrefix); !ok {
+			return "", ErrInvalidPhoneNumber
+		}
+	}
+
+	if len(national) < country.MinLength || len(national) > country.MaxLength {
+		return "", ErrInvalidPhoneNumber
+	}
+	if !country.isMobile(national) {
+		return "", ErrNotMobile
+	}
+
+	return "+" + country.CallingCode + national, nil
+}
+
+func (n *Normalizer) allowedCountry(code string) (Country, bool) {
+	for _, c := range n.allowed {
+		if c.Code == code {
+			return c, true
+		}
+	}
+
+	return Country{}, false
+}
@@ -0,0 +1,260 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+)
+
+func TestNewNormalizer(t *testing.T) {
+	testCases := []struct {
+		title            string
+		allowedCountries []string
+		defaultCountry   string
+
+		expectedCountries []string
+		expectedDefault   string
+		expectedErr       error
+	}{
+		{
+			title:             "allowed countries in order",
+			allowedCountries:  []string{"SG", "ID", "MY"},
+			defaultCountry:    "ID",
+			expectedCountries: []string{"SG", "ID", "MY"},
+			expectedDefault:   "ID",
+		},
+		{
+			title:             "lowercase codes",
+			allowedCountries:  []string{"id", "my"},
+			defaultCountry:    "my",
+			expectedCountries: []string{"ID", "MY"},
+			expectedDefault:   "MY",
+		},
+		{
+			title:            "unknown country",
+			allowedCountries: []string{"ID", "XX"},
+			defaultCountry:   "ID",
+			expectedErr:      ErrUnknownCountry,
+		},
+		{
+			title:            "default country not allowed",
+			allowedCountries: []string{"ID", "MY"},
+			defaultCountry:   "SG",
+			expectedErr:      ErrInvalidDefaultCountry,
+		},
+		{
+			title:            "no default country",
+			allowedCountries: []string{"ID"},
+			defaultCountry:   "",
+			expectedErr:      ErrInvalidDefaultCountry,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			n, err := NewNormalizer(tc.allowedCountries, tc.defaultCountry)
+			if tc.expectedErr != nil {
+				assert.True(t, errors.Is(err, tc.expectedErr), "expected %v, got %v", tc.expectedErr, err)
+				assert.Nil(t, n)
+				return
+			}
+
+			assert.NoError(t, err)
+			codes := []string{}
+			for _, c := range n.Countries() {
+				codes = append(codes, c.Code)
+			}
+			assert.Equal(t, tc.expectedCountries, codes)
+			assert.Equal(t, tc.expectedDefault, n.defaultCountry.Code)
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	testCases := []struct {
+		title          string
+		allowed        []string
+		defaultCountry string
+		phoneNumber    string
+
+		expected    string
+		expectedErr error
+	}{
+		// Indonesia
+		{
+			title:       "ID international",
+			phoneNumber: "+6281234567890",
+			expected:    "+6281234567890",
+		},
+		{
+			title:       "ID international with separators",
+			phoneNumber: " +62 812-3456-7890 ",
+			expected:    "+6281234567890",
+		},
+		{
+			title:       "ID international with 00",
+			phoneNumber: "006281234567890",
+			expected:    "+6281234567890",
+		},
+		{
+			title:       "ID international keeping the trunk prefix",
+			phoneNumber: "+62 0812 3456 7890",
+			expected:    "+6281234567890",
+		},
+		{
+			title:       "ID national",
+			phoneNumber: "081234567890",
+			expected:    "+6281234567890",
+		},
+		{
+			title:       "ID national with separators",
+			phoneNumber: "(0812) 3456.7890",
+			expected:    "+6281234567890",
+		},
+		{
+			title:       "ID shortest",
+			phoneNumber: "0812345678",
+			expected:    "+62812345678",
+		},
+		{
+			title:       "ID local without trunk prefix",
+			phoneNumber: "81234567890",
+			expectedErr: ErrInvalidPhoneNumber,
+		},
+		{
+			title:       "ID too short",
+			phoneNumber: "+6281234567",
+			expectedErr: ErrInvalidPhoneNumber,
+		},
+		{
+			title:       "ID too long",
+			phoneNumber: "+628123456789012",
+			expectedErr: ErrInvalidPhoneNumber,
+		},
+		{
+			title:       "ID landline",
+			phoneNumber: "+62 21 5551 2345",
+			expectedErr: ErrNotMobile,
+		},
+		{
+			title:       "ID national landline",
+			phoneNumber: "021-5551-2345",
+			expectedErr: ErrNotMobile,
+		},
+		// Malaysia
+		{
+			title:       "MY international",
+			phoneNumber: "+60 12-345 6789",
+			expected:    "+60123456789",
+		},
+		{
+			title:       "MY international 011 number",
+			phoneNumber: "+601123456789",
+			expected:    "+601123456789",
+		},
+		{
+			title:       "MY international keeping the trunk prefix",
+			phoneNumber: "+60 012-345 6789",
+			expected:    "+60123456789",
+		},
+		{
+			title:          "MY national",
+			allowed:        []string{"MY", "SG"},
+			defaultCountry: "MY",
+			phoneNumber:    "012-345 6789",
+			expected:       "+60123456789",
+		},
+		{
+			title:       "MY landline",
+			phoneNumber: "+60 3-1234 5678",
+			expectedErr: ErrNotMobile,
+		},
+		{
+			title:       "MY too long",
+			phoneNumber: "+6011234567890",
+			expectedErr: ErrInvalidPhoneNumber,
+		},
+		// Singapore
+		{
+			title:       "SG international",
+			phoneNumber: "+65 9123 4567",
+			expected:    "+6591234567",
+		},
+		{
+			title:       "SG international with 00",
+			phoneNumber: "0065 8123 4567",
+			expected:    "+6581234567",
+		},
+		{
+			title:          "SG national",
+			allowed:        []string{"SG"},
+			defaultCountry: "SG",
+			phoneNumber:    "9123 4567",
+			expected:       "+6591234567",
+		},
+		{
+			title:       "SG landline",
+			phoneNumber: "+65 6123 4567",
+			expectedErr: ErrNotMobile,
+		},
+		{
+			title:       "SG too long",
+			phoneNumber: "+65 9123 45678",
+			expectedErr: ErrInvalidPhoneNumber,
+		},
+		// invalid input
+		{
+			title:       "empty",
+			phoneNumber: "",
+			expectedErr: ErrInvalidPhoneNumber,
+		},
+		{
+			title:       "only separators",
+			phoneNumber: " - ",
+			expectedErr: ErrInvalidPhoneNumber,
+		},
+		{
+			title:       "only +",
+			phoneNumber: "+",
+			expectedErr: ErrInvalidPhoneNumber,
+		},
+		{
+			title:       "letters",
+			phoneNumber: "+62812abc7890",
+			expectedErr: ErrInvalidPhoneNumber,
+		},
+		{
+			title:       "+ twice",
+			phoneNumber: "++6281234567890",
+			expectedErr: ErrInvalidPhoneNumber,
+		},
+		{
+			title:       "unknown country",
+			phoneNumber: "+1 415 555 0100",
+			expectedErr: ErrCountryNotAllowed,
+		},
+		{
+			title:          "country not allowed",
+			allowed:        []string{"ID"},
+			defaultCountry: "ID",
+			phoneNumber:    "+65 9123 4567",
+			expectedErr:    ErrCountryNotAllowed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			allowed, defaultCountry := tc.allowed, tc.defaultCountry
+			if allowed == nil {
+				allowed, defaultCountry = []string{"ID", "MY", "SG"}, "ID"
+			}
+			n, err := NewNormalizer(allowed, defaultCountry)
+			assert.NoError(t, err)
+
+			normalized, err := n.Normalize(tc.phoneNumber)
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expected, normalized)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+// Package pruning deletes the expired rows of the repository in the background: one-time codes,
+// MFA challenges & passkey challenges. Anyone may request the latter, they would pile up otherwise.
+package pruning
+
+import (
+	"context"
+	"time"
+)
+
+// Repository deletes its expired rows, consumed or not, see repository.Repository.Prune.
+type Repository interface {
+	Prune(ctx context.Context) error
+}
+
+// Start prunes the repository every interval until ctx is done.
+// Errors are passed to onError and do not stop the pruning loop.
+func Start(ctx context.Context, repo Repository, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := repo.Prune(ctx); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,61 @@
+package pruning
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/c2fo/testify/assert"
+)
+
+// repositoryMock counts the prunes, failing every other one
+type repositoryMock struct {
+	prunes int32
+}
+
+func (r *repositoryMock) Prune(ctx context.Context) error {
+	if atomic.AddInt32(&r.prunes, 1)%2 == 0 {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	repo := &repositoryMock{}
+	errs := make(chan error, 100)
+
+	Start(ctx, repo, time.Millisecond, func(err error) {
+		errs <- err
+	})
+
+	// an error doesn't stop the loop
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, "connection refused")
+	case <-time.After(time.Second):
+		t.Fatal("no prune failed")
+	}
+	assert.True(t, waitFor(func() bool { return atomic.LoadInt32(&repo.prunes) >= 3 }))
+
+	cancel()
+	// a tick may still win over ctx.Done in the select for a few rounds, none does after that
+	time.Sleep(50 * time.Millisecond)
+	prunes := atomic.LoadInt32(&repo.prunes)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, prunes, atomic.LoadInt32(&repo.prunes))
+}
+
+func waitFor(condition func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	return false
+}
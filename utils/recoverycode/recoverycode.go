@@ -0,0 +1,55 @@
+package recoverycode
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
+)
+
+const (
+	// alphabet leaves out 0, 1, i, l, o & u, easily mistaken when typed from a printout
+	alphabet = "23456789abcdefghjkmnpqrstvwxyz"
+	// length characters of 30 possibilities, about 78 bits
+	length    = 16
+	groupSize = 4
+)
+
+// Generate returns n random codes formatted in groups, e.g. 7kq2-mx9d-4hpt-bw3z.
+func Generate(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		code := make([]byte, 0, length+length/groupSize)
+		for j := 0; j < length; j++ {
+			if j > 0 && j%groupSize == 0 {
+				code = append(code, '-')
+			}
+			k, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+			if err != nil {
+				return nil, err
+			}
+			code = append(code, alphabet[k.Int64()])
+		}
+		codes[i] = string(code)
+	}
+
+	return codes, nil
+}
+
+// Normalize drops the separators & case of a typed code, any code compares by its normalized form.
+func Normalize(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+}
+
+// Hash returns the hash stored for a code, bound to the user it was given to.
+// The codes are random enough for a fast hash, like refresh tokens.
+func Hash(userId, code string) string {
+	sum := sha256.Sum256([]byte("recovery:" + userId + ":" + Normalize(code)))
+	return hex.EncodeToString(sum[:])
+}
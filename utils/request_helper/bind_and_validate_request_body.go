@@ -10,7 +10,27 @@ import (
 type requestBody interface {
 	generated.LoginJSONRequestBody |
 		generated.RegisterJSONRequestBody |
-		generated.UpdateUserJSONRequestBody
+		generated.UpdateUserJSONRequestBody |
+		generated.RefreshTokenJSONRequestBody |
+		generated.LogoutJSONRequestBody |
+		generated.IntrospectTokenFormdataRequestBody |
+		generated.ChangePasswordJSONRequestBody |
+		generated.ForgotPasswordJSONRequestBody |
+		generated.ResetPasswordJSONRequestBody |
+		generated.EstimatePasswordStrengthJSONRequestBody |
+		generated.VerifyRegistrationJSONRequestBody |
+		generated.ResendRegistrationCodeJSONRequestBody |
+		generated.VerifyPhoneNumberChangeJSONRequestBody |
+		generated.EnrollTotpJSONRequestBody |
+		generated.VerifyTotpEnrollmentJSONRequestBody |
+		generated.LoginMfaJSONRequestBody |
+		generated.RegenerateRecoveryCodesJSONRequestBody |
+		generated.PasskeyRegistrationOptionsJSONRequestBody |
+		generated.RegisterPasskeyJSONRequestBody |
+		generated.RenamePasskeyJSONRequestBody |
+		generated.LoginPasskeyJSONRequestBody |
+		generated.StartOtpLoginJSONRequestBody |
+		generated.VerifyOtpLoginJSONRequestBody
 }
 
 // BindAndValidateReqBody binds the request body into 'reqPtr'(pointer to a req body struct)
@@ -0,0 +1,31 @@
+package request_helper
+
+import (
+	"net"
+
+	"github.com/labstack/echo/v4"
+)
+
+// NewIPExtractor reads the client IP address from the X-Forwarded-For header set by the trusted proxies,
+// any other address in the header is ignored so clients can't pick their own address.
+// Without trusted proxies the address of the connection is the client's, forwarding headers are ignored.
+func NewIPExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, cidr := range trustedProxies {
+		_, ipRange, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
@@ -0,0 +1,16 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	CurrentPasswordRequiredErrorMsg = "the current password is required"
+)
+
+// CurrentPasswordRequired refuses a sensitive action of a user without MFA confirmed by the access token only
+func CurrentPasswordRequired(ctx echo.Context) error {
+	return SingleErrorResponse(ctx, http.StatusForbidden, CurrentPasswordRequiredErrorMsg)
+}
@@ -0,0 +1,15 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	IncorrectPasswordErrorMsg = "current password is incorrect"
+)
+
+func IncorrectPassword(ctx echo.Context) error {
+	return SingleErrorResponse(ctx, http.StatusBadRequest, IncorrectPasswordErrorMsg)
+}
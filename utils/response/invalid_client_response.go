@@ -0,0 +1,18 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	InvalidClientErrorMsg  = "invalid client credentials"
+	invalidClientChallenge = `Basic realm="user-service"`
+)
+
+// InvalidClient responds 401 asking the OAuth client to authenticate with HTTP Basic auth.
+func InvalidClient(ctx echo.Context) error {
+	ctx.Response().Header().Set(echo.HeaderWWWAuthenticate, invalidClientChallenge)
+	return SingleErrorResponse(ctx, http.StatusUnauthorized, InvalidClientErrorMsg)
+}
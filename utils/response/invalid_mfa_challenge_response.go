@@ -0,0 +1,16 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	InvalidMFAChallengeErrorMsg = "mfa token is invalid or expired, log in again"
+)
+
+// InvalidMFAChallenge doesn't tell an expired, used or exhausted MFA token from an unknown one
+func InvalidMFAChallenge(ctx echo.Context) error {
+	return SingleErrorResponse(ctx, http.StatusUnauthorized, InvalidMFAChallengeErrorMsg)
+}
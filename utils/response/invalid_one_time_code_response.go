@@ -0,0 +1,16 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	InvalidOneTimeCodeErrorMsg = "code is invalid or expired"
+)
+
+// InvalidOneTimeCode doesn't tell a wrong code from an expired, used or unknown one
+func InvalidOneTimeCode(ctx echo.Context) error {
+	return SingleErrorResponse(ctx, http.StatusBadRequest, InvalidOneTimeCodeErrorMsg)
+}
@@ -0,0 +1,16 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	InvalidPasskeyErrorMsg = "passkey verification failed, request new options and try again"
+)
+
+// InvalidPasskey tells the passkey ceremony failed, it doesn't tell why so the credentials can't be probed.
+func InvalidPasskey(ctx echo.Context) error {
+	return SingleErrorResponse(ctx, http.StatusBadRequest, InvalidPasskeyErrorMsg)
+}
@@ -0,0 +1,15 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	InvalidRefreshTokenErrorMsg = "refresh token is invalid or expired"
+)
+
+func InvalidRefreshToken(ctx echo.Context) error {
+	return SingleErrorResponse(ctx, http.StatusUnauthorized, InvalidRefreshTokenErrorMsg)
+}
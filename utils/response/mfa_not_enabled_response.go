@@ -0,0 +1,15 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	MFANotEnabledErrorMsg = "mfa is not enabled"
+)
+
+func MFANotEnabled(ctx echo.Context) error {
+	return SingleErrorResponse(ctx, http.StatusConflict, MFANotEnabledErrorMsg)
+}
@@ -0,0 +1,16 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	MFAStepUpRequiredErrorMsg = "a current code of the authenticator app or a recovery code is required"
+)
+
+// MFAStepUpRequired refuses a sensitive action of a user with MFA confirmed by the access token only
+func MFAStepUpRequired(ctx echo.Context) error {
+	return SingleErrorResponse(ctx, http.StatusForbidden, MFAStepUpRequiredErrorMsg)
+}
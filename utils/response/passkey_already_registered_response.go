@@ -0,0 +1,15 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	PasskeyAlreadyRegisteredErrorMsg = "passkey already registered"
+)
+
+func PasskeyAlreadyRegistered(ctx echo.Context) error {
+	return SingleErrorResponse(ctx, http.StatusConflict, PasskeyAlreadyRegisteredErrorMsg)
+}
@@ -0,0 +1,15 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	PasskeyNotFoundErrorMsg = "passkey not found"
+)
+
+func PasskeyNotFound(ctx echo.Context) error {
+	return SingleErrorResponse(ctx, http.StatusNotFound, PasskeyNotFoundErrorMsg)
+}
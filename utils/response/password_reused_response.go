@@ -0,0 +1,16 @@
+package response
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	PasswordReusedErrorMsg = "new password must not be one of your last %d passwords"
+)
+
+func PasswordReused(ctx echo.Context, remembered int) error {
+	return SingleErrorResponse(ctx, http.StatusBadRequest, fmt.Sprintf(PasswordReusedErrorMsg, remembered))
+}
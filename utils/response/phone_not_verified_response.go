@@ -0,0 +1,17 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	PhoneNotVerifiedErrorMsg = "phone number is not verified, verify it with the code sent at registration"
+)
+
+// PhoneNotVerified is only responded once the password is known to be right,
+// it must not tell pending registrations apart to anyone else
+func PhoneNotVerified(ctx echo.Context) error {
+	return SingleErrorResponse(ctx, http.StatusForbidden, PhoneNotVerifiedErrorMsg)
+}
@@ -0,0 +1,15 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	RegistrationPasswordMismatchErrorMsg = "password is not the one of the pending registration, register again to replace it"
+)
+
+func RegistrationPasswordMismatch(ctx echo.Context) error {
+	return SingleErrorResponse(ctx, http.StatusBadRequest, RegistrationPasswordMismatchErrorMsg)
+}
@@ -0,0 +1,15 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	SessionNotFoundErrorMsg = "session not found"
+)
+
+func SessionNotFound(ctx echo.Context) error {
+	return SingleErrorResponse(ctx, http.StatusNotFound, SessionNotFoundErrorMsg)
+}
@@ -0,0 +1,20 @@
+package response
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	TooManyRequestsErrorMsg = "too many requests, please retry later"
+)
+
+// TooManyRequests responds 429, telling the client to wait retryAfter, in whole seconds, before retrying.
+func TooManyRequests(ctx echo.Context, retryAfter time.Duration) error {
+	ctx.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	return SingleErrorResponse(ctx, http.StatusTooManyRequests, TooManyRequestsErrorMsg)
+}
@@ -0,0 +1,15 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	TOTPAlreadyEnabledErrorMsg = "totp is already enabled"
+)
+
+func TOTPAlreadyEnabled(ctx echo.Context) error {
+	return SingleErrorResponse(ctx, http.StatusConflict, TOTPAlreadyEnabledErrorMsg)
+}
@@ -0,0 +1,17 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	UnauthorizedErrorMsg = "missing or invalid access token"
+)
+
+// Unauthorized responds 401 with the given WWW-Authenticate challenge.
+func Unauthorized(ctx echo.Context, challenge string) error {
+	ctx.Response().Header().Set(echo.HeaderWWWAuthenticate, challenge)
+	return SingleErrorResponse(ctx, http.StatusUnauthorized, UnauthorizedErrorMsg)
+}
@@ -0,0 +1,15 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	UserNotFoundErrorMsg = "user not found"
+)
+
+func UserNotFound(ctx echo.Context) error {
+	return SingleErrorResponse(ctx, http.StatusNotFound, UserNotFoundErrorMsg)
+}
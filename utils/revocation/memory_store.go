@@ -0,0 +1,102 @@
+package revocation
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type userRevocation struct {
+	issuedBefore time.Time
+	expiresAt    time.Time
+}
+
+// MemoryStore is an in-process Store, suitable for tests and single instance deployments.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	tokens map[string]time.Time
+	users  map[string]userRevocation
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tokens: make(map[string]time.Time),
+		users:  make(map[string]userRevocation),
+	}
+}
+
+func (m *MemoryStore) Revoke(ctx context.Context, tokenId string, expiresAt time.Time) error {
+	if tokenId == "" {
+		return ErrInvalidTokenId
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if current, ok := m.tokens[tokenId]; !ok || expiresAt.After(current) {
+		m.tokens[tokenId] = expiresAt
+	}
+
+	return nil
+}
+
+func (m *MemoryStore) RevokeUser(ctx context.Context, userId string, issuedBefore, expiresAt time.Time) error {
+	if userId == "" {
+		return ErrInvalidUserId
+	}
+
+	issuedBefore = issuedBeforeCutoff(issuedBefore)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := m.users[userId]
+	if issuedBefore.After(current.issuedBefore) {
+		current.issuedBefore = issuedBefore
+	}
+	if expiresAt.After(current.expiresAt) {
+		current.expiresAt = expiresAt
+	}
+	m.users[userId] = current
+
+	return nil
+}
+
+func (m *MemoryStore) IsRevoked(ctx context.Context, token Token) (bool, error) {
+	now := time.Now()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, id := range []string{token.Id, token.SessionId} {
+		if expiresAt, ok := m.tokens[id]; ok && id != "" && now.Before(expiresAt) {
+			return true, nil
+		}
+	}
+
+	if ur, ok := m.users[token.UserId]; ok && now.Before(ur.expiresAt) && token.IssuedAt.Before(ur.issuedBefore) {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (m *MemoryStore) Prune(ctx context.Context) error {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, expiresAt := range m.tokens {
+		if !now.Before(expiresAt) {
+			delete(m.tokens, id)
+		}
+	}
+	for id, ur := range m.users {
+		if !now.Before(ur.expiresAt) {
+			delete(m.users, id)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package revocation
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// PostgresStore is a Store backed by the 'revoked_tokens' and 'revoked_user_tokens' tables,
+// shared by every instance of the service.
+type PostgresStore struct {
+	Db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{
+		Db: db,
+	}
+}
+
+func (p *PostgresStore) Revoke(ctx context.Context, tokenId string, expiresAt time.Time) error {
+	if tokenId == "" {
+		return ErrInvalidTokenId
+	}
+
+	query := `
+		INSERT INTO revoked_tokens (token_id, expires_at)
+		VALUES ( $1, $2)
+		ON CONFLICT (token_id) DO UPDATE
+		SET expires_at = GREATEST(revoked_tokens.expires_at, EXCLUDED.expires_at)
+	`
+
+	_, err := p.Db.ExecContext(ctx, query, tokenId, expiresAt.UTC())
+	return err
+}
+
+func (p *PostgresStore) RevokeUser(ctx context.Context, userId string, issuedBefore, expiresAt time.Time) error {
+	if userId == "" {
+		return ErrInvalidUserId
+	}
+
+	query := `
+		INSERT INTO revoked_user_tokens (user_id, issued_before, expires_at)
+		VALUES ( $1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET 
+			issued_before = GREATEST(revoked_user_tokens.issued_before, EXCLUDED.issued_before),
+			expires_at = GREATEST(revoked_user_tokens.expires_at, EXCLUDED.expires_at)
+	`
+
+	_, err := p.Db.ExecContext(ctx, query, userId, issuedBeforeCutoff(issuedBefore).UTC(), expiresAt.UTC())
+	return err
+}
+
+func (p *PostgresStore) IsRevoked(ctx context.Context, token Token) (revoked bool, err error) {
+	query := `
+	SELECT
+		EXISTS (
+			SELECT 1 FROM revoked_tokens
+			WHERE token_id IN ($1, $5) AND expires_at > $4
+		) OR EXISTS (
+			SELECT 1 FROM revoked_user_tokens
+			WHERE user_id = NULLIF($2, '')::uuid AND issued_before > $3 AND expires_at > $4
+		)
+	`
+
+	err = p.Db.QueryRowContext(ctx, query,
+		token.Id,
+		token.UserId,
+		token.IssuedAt.UTC(),
+		time.Now().UTC(),
+		token.SessionId,
+	).Scan(&revoked)
+
+	return revoked, err
+}
+
+func (p *PostgresStore) Prune(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	if _, err := p.Db.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at <= $1`, now); err != nil {
+		return err
+	}
+
+	_, err := p.Db.ExecContext(ctx, `DELETE FROM revoked_user_tokens WHERE expires_at <= $1`, now)
+	return err
+}
@@ -0,0 +1,26 @@
+package revocation
+
+import (
+	"context"
+	"time"
+)
+
+// StartPruning periodically removes expired entries from the store until ctx is done.
+// Errors are passed to onError and do not stop the pruning loop.
+func StartPruning(ctx context.Context, store Store, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := store.Prune(ctx); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+}
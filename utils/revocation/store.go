@@ -0,0 +1,42 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidTokenId = errors.New("invalid token id")
+	ErrInvalidUserId  = errors.New("invalid user id")
+)
+
+// Store keeps track of revoked access tokens until they would have expired on their own.
+type Store interface {
+	// Revoke denylists a single token id until expiresAt,
+	// a session id revokes every token issued for that session.
+	Revoke(ctx context.Context, tokenId string, expiresAt time.Time) error
+	// RevokeUser invalidates every token of the user issued before issuedBefore. The iat claim is in
+	// seconds, so issuedBefore is rounded up to the next second: a token issued within the same second
+	// is revoked as well, it can't be told whether it was issued before or after the revocation.
+	// expiresAt should be the latest expiry of those tokens.
+	RevokeUser(ctx context.Context, userId string, issuedBefore, expiresAt time.Time) error
+	// IsRevoked reports whether the token was revoked individually, through its session or its user.
+	IsRevoked(ctx context.Context, token Token) (bool, error)
+	// Prune removes denylist entries that already expired.
+	Prune(ctx context.Context) error
+}
+
+// issuedBeforeCutoff rounds issuedBefore up to the next second, the iat claim of the tokens it is compared to
+// has no fraction of a second.
+func issuedBeforeCutoff(issuedBefore time.Time) time.Time {
+	return issuedBefore.Truncate(time.Second).Add(time.Second)
+}
+
+// Token holds the claims needed to check a token against the denylist.
+type Token struct {
+	Id        string
+	SessionId string
+	UserId    string
+	IssuedAt  time.Time
+}
@@ -0,0 +1,56 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileSender appends every message to a file as a JSON line, so local tools and
+// end-to-end tests can read the codes sent to a phone number.
+type FileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+// Message is a line of the file written by FileSender.
+type Message struct {
+	PhoneNumber string    `json:"phoneNumber"`
+	Message     string    `json:"message"`
+	SentAt      time.Time `json:"sentAt"`
+}
+
+func NewFileSender(path string) *FileSender {
+	return &FileSender{path: path}
+}
+
+func (s *FileSender) Send(ctx context.Context, phoneNumber, message string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(Message{
+		PhoneNumber: phoneNumber,
+		Message:     message,
+		SentAt:      time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
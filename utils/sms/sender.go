@@ -0,0 +1,40 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"user-service-sample/config"
+)
+
+const (
+	SenderLog  = "log"
+	SenderFile = "file"
+)
+
+var (
+	ErrUnsupportedSender = errors.New("unsupported sms sender")
+	ErrMissingFilePath   = errors.New("file sms sender requires a file path")
+)
+
+// Sender delivers text messages to phone numbers in E.164 format.
+type Sender interface {
+	Send(ctx context.Context, phoneNumber, message string) error
+}
+
+// NewSender returns the sender of the config. Only the log and file senders exist for now,
+// both are meant for local development and tests, messages never leave the host.
+func NewSender(cfg config.SMSConfig) (Sender, error) {
+	switch cfg.Sender {
+	case "", SenderLog:
+		return NewLogSender(os.Stderr), nil
+	case SenderFile:
+		if cfg.FilePath == "" {
+			return nil, ErrMissingFilePath
+		}
+		return NewFileSender(cfg.FilePath), nil
+	}
+
+	return nil, ErrUnsupportedSender
+}
@@ -0,0 +1,80 @@
+package strength
+
+import (
+	"embed"
+	"strings"
+	"unicode"
+
+	"user-service-sample/utils/phone"
+)
+
+const (
+	dictionaryPasswords  = "passwords"
+	dictionaryEnglish    = "english"
+	dictionaryIndonesian = "indonesian"
+	dictionaryNames      = "names"
+	dictionaryUserInputs = "user_inputs"
+)
+
+// the word lists are ordered by how common a word is, the rank of a word is its line number
+//
+//go:embed dictionaries/*.txt
+var dictionaryFiles embed.FS
+
+// rankedDictionary maps the words of a dictionary to their rank
+type rankedDictionary struct {
+	name  string
+	ranks map[string]int
+}
+
+// rankedDictionaries are matched in order, on a tie the first dictionary gives the feedback
+var rankedDictionaries = loadDictionaries(dictionaryPasswords, dictionaryEnglish, dictionaryIndonesian, dictionaryNames)
+
+func loadDictionaries(names ...string) []rankedDictionary {
+	dictionaries := make([]rankedDictionary, 0, len(names))
+	for _, name := range names {
+		content, err := dictionaryFiles.ReadFile("dictionaries/" + name + ".txt")
+		if err != nil {
+			panic(err)
+		}
+		dictionaries = append(dictionaries, rankedDictionary{name: name, ranks: rankWords(strings.Fields(string(content)))})
+	}
+
+	return dictionaries
+}
+
+func rankWords(words []string) map[string]int {
+	ranked := make(map[string]int, len(words))
+	for i, word := range words {
+		if _, ok := ranked[word]; !ok {
+			ranked[word] = i + 1
+		}
+	}
+
+	return ranked
+}
+
+// userInputWords are the inputs and their words, e.g. the name & phone number of the user,
+// a password made of them is as guessable as a common password
+func userInputWords(userInputs []string) []string {
+	var words []string
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		if input == "" {
+			continue
+		}
+
+		parts := strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		words = append(words, strings.Join(parts, ""))
+		words = append(words, parts...)
+
+		// a phone number also appears in its local format, without the calling code
+		if country, national, ok := phone.NationalNumber(input); ok {
+			words = append(words, national, country.TrunkPrefix+national)
+		}
+	}
+
+	return words
+}
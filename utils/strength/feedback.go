@@ -0,0 +1,137 @@
+package strength
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Feedback explains a weak password & how to make it stronger, it is empty for a strong one.
+type Feedback struct {
+	Warning     string
+	Suggestions []string
+}
+
+const (
+	suggestionAddWord = "Add another word or two. Uncommon words are better."
+)
+
+var (
+	defaultFeedback = Feedback{
+		Suggestions: []string{
+			"Use a few words, avoid common phrases",
+			"No need for symbols, digits, or uppercase letters",
+		},
+	}
+)
+
+func feedbackFor(score int, sequence guessSequence) Feedback {
+	if len(sequence.matches) == 0 {
+		return defaultFeedback
+	}
+	if score > 2 {
+		return Feedback{Suggestions: []string{}}
+	}
+
+	// the longest match is what makes the password weak
+	longest := sequence.matches[0]
+	for _, m := range sequence.matches[1:] {
+		if len(m.token) > len(longest.token) {
+			longest = m
+		}
+	}
+
+	feedback := matchFeedback(longest, len(sequence.matches) == 1)
+	feedback.Suggestions = append([]string{suggestionAddWord}, feedback.Suggestions...)
+
+	return feedback
+}
+
+func matchFeedback(m match, soleMatch bool) Feedback {
+	switch m.pattern {
+	case patternDictionary:
+		return dictionaryFeedback(m, soleMatch)
+	case patternSpatial:
+		warning := "Short keyboard patterns are easy to guess"
+		if m.turns == 1 {
+			warning = "Straight rows of keys are easy to guess"
+		}
+		return Feedback{
+			Warning:     warning,
+			Suggestions: []string{"Use a longer keyboard pattern with more turns"},
+		}
+	case patternRepeat:
+		warning := `Repeats like "abcabcabc" are only slightly harder to guess than "abc"`
+		if len(m.baseToken) == 1 {
+			warning = `Repeats like "aaa" are easy to guess`
+		}
+		return Feedback{
+			Warning:     warning,
+			Suggestions: []string{"Avoid repeated words and characters"},
+		}
+	case patternSequence:
+		return Feedback{
+			Warning:     "Sequences like abc or 6543 are easy to guess",
+			Suggestions: []string{"Avoid sequences"},
+		}
+	case patternYear:
+		return Feedback{
+			Warning:     "Recent years are easy to guess",
+			Suggestions: []string{"Avoid recent years", "Avoid years that are associated with you"},
+		}
+	case patternDate:
+		return Feedback{
+			Warning:     "Dates are often easy to guess",
+			Suggestions: []string{"Avoid dates and years that are associated with you"},
+		}
+	}
+
+	return Feedback{Suggestions: []string{}}
+}
+
+func dictionaryFeedback(m match, soleMatch bool) Feedback {
+	var warning string
+	switch m.dictionary {
+	case dictionaryPasswords:
+		switch {
+		case soleMatch && !m.reversed && len(m.l33tSubs) == 0 && m.rank <= 10:
+			warning = "This is a top-10 common password"
+		case soleMatch && !m.reversed && len(m.l33tSubs) == 0 && m.rank <= 100:
+			warning = "This is a top-100 common password"
+		case soleMatch && !m.reversed && len(m.l33tSubs) == 0:
+			warning = "This is a very common password"
+		default:
+			warning = "This is similar to a commonly used password"
+		}
+	case dictionaryEnglish, dictionaryIndonesian:
+		if soleMatch {
+			warning = "A word by itself is easy to guess"
+		} else {
+			warning = "Common words are easy to guess"
+		}
+	case dictionaryNames:
+		if soleMatch {
+			warning = "Names and surnames by themselves are easy to guess"
+		} else {
+			warning = "Common names and surnames are easy to guess"
+		}
+	case dictionaryUserInputs:
+		warning = "Your name or phone number is easy to guess"
+	}
+
+	suggestions := []string{}
+	word, rest := string(m.token), string(m.token[1:])
+	switch {
+	case unicode.IsUpper(m.token[0]) && strings.ToLower(rest) == rest:
+		suggestions = append(suggestions, "Capitalization doesn't help very much")
+	case strings.ToUpper(word) == word && strings.ToLower(word) != word:
+		suggestions = append(suggestions, "All-uppercase is almost as easy to guess as all-lowercase")
+	}
+	if m.reversed && len(m.token) >= 4 {
+		suggestions = append(suggestions, "Reversed words aren't much harder to guess")
+	}
+	if len(m.l33tSubs) > 0 {
+		suggestions = append(suggestions, "Predictable substitutions like '@' instead of 'a' don't help very much")
+	}
+
+	return Feedback{Warning: warning, Suggestions: suggestions}
+}
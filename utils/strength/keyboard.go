@@ -0,0 +1,85 @@
+package strength
+
+// qwertyRows are the unshifted & shifted characters of each row of a qwerty keyboard
+var qwertyRows = [][2]string{
+	{"`1234567890-=", "~!@#$%^&*()_+"},
+	{"qwertyuiop[]\\", "QWERTYUIOP{}|"},
+	{"asdfghjkl;'", "ASDFGHJKL:\""},
+	{"zxcvbnm,./", "ZXCVBNM<>?"},
+}
+
+// qwertyRowOffsets place each row in half key widths, q sits between 1 & 2, a below q & w, z below a & s
+var qwertyRowOffsets = []int{0, 3, 4, 5}
+
+type keyPosition struct {
+	row, x  int
+	shifted bool
+}
+
+var (
+	qwertyKeys = qwertyPositions()
+
+	// keyboardStartingPositions & keyboardAverageDegree size the space of keyboard walks
+	keyboardStartingPositions, keyboardAverageDegree = qwertyGraphSize()
+)
+
+func qwertyPositions() map[rune]keyPosition {
+	keys := map[rune]keyPosition{}
+	for row, chars := range qwertyRows {
+		for shifted, line := range chars {
+			for col, r := range []rune(line) {
+				keys[r] = keyPosition{row: row, x: qwertyRowOffsets[row] + 2*col, shifted: shifted == 1}
+			}
+		}
+	}
+
+	return keys
+}
+
+// keyDirection tells in which of the 6 directions b is next to a on the keyboard, 0 when it isn't
+func keyDirection(a, b rune) int {
+	pa, ok := qwertyKeys[a]
+	if !ok {
+		return 0
+	}
+	pb, ok := qwertyKeys[b]
+	if !ok {
+		return 0
+	}
+
+	dx, dy := pb.x-pa.x, pb.row-pa.row
+	switch {
+	case dy == 0 && dx == -2:
+		return 1
+	case dy == 0 && dx == 2:
+		return 2
+	case dy == -1 && dx == -1:
+		return 3
+	case dy == -1 && dx == 1:
+		return 4
+	case dy == 1 && dx == -1:
+		return 5
+	case dy == 1 && dx == 1:
+		return 6
+	}
+
+	return 0
+}
+
+// qwertyGraphSize counts the keys once, the shifted character shares the key of the unshifted one
+func qwertyGraphSize() (float64, float64) {
+	keys, neighbors := 0, 0
+	for a, pa := range qwertyKeys {
+		if pa.shifted {
+			continue
+		}
+		keys++
+		for b, pb := range qwertyKeys {
+			if !pb.shifted && keyDirection(a, b) != 0 {
+				neighbors++
+			}
+		}
+	}
+
+	return float64(keys), float64(neighbors) / float64(keys)
+}
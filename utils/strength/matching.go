@@ -0,0 +1,506 @@
+package strength
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	patternDictionary = "dictionary"
+	patternSpatial    = "spatial"
+	patternSequence   = "sequence"
+	patternRepeat     = "repeat"
+	patternYear       = "year"
+	patternDate       = "date"
+	patternBruteforce = "bruteforce"
+
+	minPatternLength = 3
+	maxSequenceDelta = 5
+
+	minDateYear = 1000
+	maxDateYear = 2050
+)
+
+// match is a part of the password, from rune i to rune j included, guessable as a known pattern
+type match struct {
+	pattern string
+	i, j    int
+	token   []rune
+
+	// dictionary
+	dictionary  string
+	matchedWord string
+	rank        int
+	reversed    bool
+	l33tSubs    map[rune]rune
+
+	// spatial
+	turns        int
+	shiftedCount int
+
+	// sequence
+	ascending bool
+
+	// repeat
+	baseToken        []rune
+	baseGuessesLog10 float64
+	repeatCount      int
+
+	// year & date
+	year      int
+	separator string
+}
+
+var (
+	// l33tTable lists the characters commonly substituted for a letter
+	l33tTable = map[rune][]rune{
+		'a': {'4', '@'},
+		'b': {'8'},
+		'c': {'(', '{', '[', '<'},
+		'e': {'3'},
+		'g': {'6', '9'},
+		'i': {'1', '!', '|'},
+		'l': {'1', '|', '7'},
+		'o': {'0'},
+		's': {'$', '5'},
+		't': {'+', '7'},
+		'x': {'%'},
+		'z': {'2'},
+	}
+
+	// dateSplits are the positions a date without separators is split at, by the length of the date
+	dateSplits = map[int][][2]int{
+		4: {{1, 2}, {2, 3}},
+		5: {{1, 3}, {2, 3}},
+		6: {{1, 2}, {2, 4}, {4, 5}},
+		7: {{1, 3}, {2, 3}, {4, 5}, {4, 6}},
+		8: {{2, 4}, {4, 6}},
+	}
+
+	dateWithSeparatorPattern = regexp.MustCompile(`^(\d{1,4})([\s/\\_.-])(\d{1,2})([\s/\\_.-])(\d{1,4})$`)
+)
+
+// omnimatch returns every pattern found in the password, sorted by position
+func omnimatch(password []rune, dictionaries []rankedDictionary, referenceYear int) []match {
+	var matches []match
+	matches = append(matches, dictionaryMatches(password, dictionaries)...)
+	matches = append(matches, reversedDictionaryMatches(password, dictionaries)...)
+	matches = append(matches, l33tMatches(password, dictionaries)...)
+	matches = append(matches, spatialMatches(password)...)
+	matches = append(matches, sequenceMatches(password)...)
+	matches = append(matches, repeatMatches(password, dictionaries, referenceYear)...)
+	matches = append(matches, yearMatches(password)...)
+	matches = append(matches, dateMatches(password, referenceYear)...)
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].i != matches[b].i {
+			return matches[a].i < matches[b].i
+		}
+		return matches[a].j < matches[b].j
+	})
+
+	return matches
+}
+
+func dictionaryMatches(password []rune, dictionaries []rankedDictionary) []match {
+	lower := toLower(password)
+
+	var matches []match
+	for i := range lower {
+		for j := i; j < len(lower); j++ {
+			word := string(lower[i : j+1])
+			for _, dictionary := range dictionaries {
+				rank, ok := dictionary.ranks[word]
+				if !ok {
+					continue
+				}
+				matches = append(matches, match{
+					pattern:     patternDictionary,
+					i:           i,
+					j:           j,
+					token:       password[i : j+1],
+					dictionary:  dictionary.name,
+					matchedWord: word,
+					rank:        rank,
+				})
+			}
+		}
+	}
+
+	return matches
+}
+
+func reversedDictionaryMatches(password []rune, dictionaries []rankedDictionary) []match {
+	reversed := reverse(password)
+
+	matches := dictionaryMatches(reversed, dictionaries)
+	for k := range matches {
+		m := &matches[k]
+		m.i, m.j = len(password)-1-m.j, len(password)-1-m.i
+		m.token = password[m.i : m.j+1]
+		m.reversed = true
+	}
+
+	return matches
+}
+
+// l33tMatches finds dictionary words with letters substituted, e.g. p@ssw0rd
+func l33tMatches(password []rune, dictionaries []rankedDictionary) []match {
+	var matches []match
+	for _, subs := range l33tSubstitutions(password) {
+		translated := make([]rune, len(password))
+		for k, r := range password {
+			if letter, ok := subs[r]; ok {
+				r = letter
+			}
+			translated[k] = r
+		}
+
+		for _, m := range dictionaryMatches(translated, dictionaries) {
+			// a single substituted character is as likely a plain symbol or digit
+			if m.i == m.j {
+				continue
+			}
+			used := map[rune]rune{}
+			for _, r := range password[m.i : m.j+1] {
+				if letter, ok := subs[r]; ok {
+					used[r] = letter
+				}
+			}
+			if len(used) == 0 {
+				continue
+			}
+			m.token = password[m.i : m.j+1]
+			m.l33tSubs = used
+			matches = append(matches, m)
+		}
+	}
+
+	return matches
+}
+
+// l33tSubstitutions returns every way to read the substituted characters of the password as letters,
+// a character like 1 may stand for an i or an l
+func l33tSubstitutions(password []rune) []map[rune]rune {
+	candidates := map[rune][]rune{}
+	for letter, subs := range l33tTable {
+		for _, sub := range subs {
+			if containsRune(password, sub) {
+				candidates[sub] = append(candidates[sub], letter)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	chars := make([]rune, 0, len(candidates))
+	for sub, letters := range candidates {
+		chars = append(chars, sub)
+		sort.Slice(letters, func(a, b int) bool { return letters[a] < letters[b] })
+	}
+	sort.Slice(chars, func(a, b int) bool { return chars[a] < chars[b] })
+
+	substitutions := []map[rune]rune{{}}
+	for _, sub := range chars {
+		var next []map[rune]rune
+		for _, subs := range substitutions {
+			for _, letter := range candidates[sub] {
+				extended := make(map[rune]rune, len(subs)+1)
+				for k, v := range subs {
+					extended[k] = v
+				}
+				extended[sub] = letter
+				next = append(next, extended)
+			}
+		}
+		substitutions = next
+	}
+
+	return substitutions
+}
+
+// spatialMatches finds runs of neighboring keys on the keyboard, e.g. qwerty or zxcvfr
+func spatialMatches(password []rune) []match {
+	var matches []match
+	for i := 0; i < len(password)-1; {
+		j, turns, lastDirection := i+1, 0, 0
+		for ; j < len(password); j++ {
+			direction := keyDirection(password[j-1], password[j])
+			if direction == 0 {
+				break
+			}
+			if direction != lastDirection {
+				turns++
+				lastDirection = direction
+			}
+		}
+
+		if j-i >= minPatternLength {
+			shifted := 0
+			for _, r := range password[i:j] {
+				if qwertyKeys[r].shifted {
+					shifted++
+				}
+			}
+			matches = append(matches, match{
+				pattern:      patternSpatial,
+				i:            i,
+				j:            j - 1,
+				token:        password[i:j],
+				turns:        turns,
+				shiftedCount: shifted,
+			})
+		}
+		i = j
+	}
+
+	return matches
+}
+
+// sequenceMatches finds characters of the same class going up or down by a constant step, e.g. abc or 9753
+func sequenceMatches(password []rune) []match {
+	var matches []match
+	for i := 0; i < len(password)-1; {
+		delta := password[i+1] - password[i]
+		j := i + 1
+		for j+1 < len(password) && password[j+1]-password[j] == delta {
+			j++
+		}
+
+		token := password[i : j+1]
+		if len(token) >= minPatternLength && delta != 0 && abs(int(delta)) <= maxSequenceDelta && sameClass(token) {
+			matches = append(matches, match{
+				pattern:   patternSequence,
+				i:         i,
+				j:         j,
+				token:     token,
+				ascending: delta > 0,
+			})
+		}
+		i = j
+	}
+
+	return matches
+}
+
+// repeatMatches finds a base repeated at least twice, e.g. aaa or abcabc, the base is estimated on its own
+func repeatMatches(password []rune, dictionaries []rankedDictionary, referenceYear int) []match {
+	var matches []match
+	for i := 0; i < len(password)-1; {
+		bestLength, bestCount := 0, 0
+		for length := 1; i+2*length <= len(password); length++ {
+			base := password[i : i+length]
+			count := 1
+			for i+(count+1)*length <= len(password) && string(password[i+count*length:i+(count+1)*length]) == string(base) {
+				count++
+			}
+			if count >= 2 && length*count > bestLength*bestCount {
+				bestLength, bestCount = length, count
+			}
+		}
+
+		if bestCount == 0 {
+			i++
+			continue
+		}
+
+		j := i + bestLength*bestCount - 1
+		base := password[i : i+bestLength]
+		baseSequence := mostGuessableMatchSequence(base, omnimatch(base, dictionaries, referenceYear), referenceYear)
+		matches = append(matches, match{
+			pattern:          patternRepeat,
+			i:                i,
+			j:                j,
+			token:            password[i : j+1],
+			baseToken:        base,
+			baseGuessesLog10: baseSequence.guessesLog10,
+			repeatCount:      bestCount,
+		})
+		i = j + 1
+	}
+
+	return matches
+}
+
+// yearMatches finds years from 1900 to 2099
+func yearMatches(password []rune) []match {
+	var matches []match
+	for i := 0; i+4 <= len(password); i++ {
+		token := password[i : i+4]
+		if !allDigits(token) || !(strings.HasPrefix(string(token), "19") || strings.HasPrefix(string(token), "20")) {
+			continue
+		}
+		year, _ := strconv.Atoi(string(token))
+		matches = append(matches, match{
+			pattern: patternYear,
+			i:       i,
+			j:       i + 3,
+			token:   token,
+			year:    year,
+		})
+	}
+
+	return matches
+}
+
+// dateMatches finds day, month & year in any order, with or without separators, e.g. 17081945 or 1945-8-17
+func dateMatches(password []rune, referenceYear int) []match {
+	var matches []match
+
+	for i := range password {
+		for j := i + 3; j < i+8 && j < len(password); j++ {
+			token := password[i : j+1]
+			if !allDigits(token) {
+				break
+			}
+
+			best, found := 0, false
+			for _, split := range dateSplits[len(token)] {
+				a, _ := strconv.Atoi(string(token[:split[0]]))
+				b, _ := strconv.Atoi(string(token[split[0]:split[1]]))
+				c, _ := strconv.Atoi(string(token[split[1]:]))
+				year, ok := dateYear(a, b, c)
+				if ok && (!found || abs(year-referenceYear) < abs(best-referenceYear)) {
+					best, found = year, true
+				}
+			}
+			if found {
+				matches = append(matches, match{pattern: patternDate, i: i, j: j, token: token, year: best})
+			}
+		}
+	}
+
+	for i := range password {
+		for j := i + 5; j < i+10 && j < len(password); j++ {
+			token := password[i : j+1]
+			parts := dateWithSeparatorPattern.FindStringSubmatch(string(token))
+			if parts == nil || parts[2] != parts[4] {
+				continue
+			}
+			a, _ := strconv.Atoi(parts[1])
+			b, _ := strconv.Atoi(parts[3])
+			c, _ := strconv.Atoi(parts[5])
+			if year, ok := dateYear(a, b, c); ok {
+				matches = append(matches, match{pattern: patternDate, i: i, j: j, token: token, year: year, separator: parts[2]})
+			}
+		}
+	}
+
+	// a date within another date, like 1708 in 17081945, adds nothing
+	var outer []match
+	for _, m := range matches {
+		contained := false
+		for _, other := range matches {
+			if (other.i != m.i || other.j != m.j) && other.i <= m.i && other.j >= m.j {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			outer = append(outer, m)
+		}
+	}
+
+	return outer
+}
+
+// dateYear reads the year of a date from 3 numbers, with the year first or last and the day & month in either order
+func dateYear(a, b, c int) (int, bool) {
+	if b < 1 || b > 31 {
+		return 0, false
+	}
+
+	for _, candidate := range [][3]int{{c, a, b}, {a, b, c}} {
+		year, dayOrMonth, monthOrDay := candidate[0], candidate[1], candidate[2]
+		switch {
+		case year < 50:
+			year += 2000
+		case year < 100:
+			year += 1900
+		}
+		if year < minDateYear || year > maxDateYear {
+			continue
+		}
+		if validDayMonth(dayOrMonth, monthOrDay) || validDayMonth(monthOrDay, dayOrMonth) {
+			return year, true
+		}
+	}
+
+	return 0, false
+}
+
+func validDayMonth(day, month int) bool {
+	return day >= 1 && day <= 31 && month >= 1 && month <= 12
+}
+
+func toLower(runes []rune) []rune {
+	lower := make([]rune, len(runes))
+	for k, r := range runes {
+		lower[k] = unicode.ToLower(r)
+	}
+
+	return lower
+}
+
+func reverse(runes []rune) []rune {
+	reversed := make([]rune, len(runes))
+	for k, r := range runes {
+		reversed[len(runes)-1-k] = r
+	}
+
+	return reversed
+}
+
+func containsRune(runes []rune, r rune) bool {
+	for _, c := range runes {
+		if c == r {
+			return true
+		}
+	}
+
+	return false
+}
+
+func allDigits(runes []rune) bool {
+	for _, r := range runes {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sameClass tells whether the runes are all lowercase, all uppercase or all digits
+func sameClass(runes []rune) bool {
+	for _, in := range []func(r rune) bool{
+		func(r rune) bool { return r >= 'a' && r <= 'z' },
+		func(r rune) bool { return r >= 'A' && r <= 'Z' },
+		func(r rune) bool { return r >= '0' && r <= '9' },
+	} {
+		all := true
+		for _, r := range runes {
+			if !in(r) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+
+	return false
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
@@ -0,0 +1,323 @@
+package strength
+
+import (
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+)
+
+// matchSummary are the fields of a match a matcher sets, tokens as strings to read the test cases
+type matchSummary struct {
+	pattern string
+	i, j    int
+	token   string
+
+	dictionary string
+	rank       int
+	reversed   bool
+	l33tSubs   map[rune]rune
+
+	turns        int
+	shiftedCount int
+
+	ascending bool
+
+	baseToken   string
+	repeatCount int
+
+	year      int
+	separator string
+}
+
+func summarize(matches []match) []matchSummary {
+	summaries := []matchSummary{}
+	for _, m := range matches {
+		summaries = append(summaries, matchSummary{
+			pattern:      m.pattern,
+			i:            m.i,
+			j:            m.j,
+			token:        string(m.token),
+			dictionary:   m.dictionary,
+			rank:         m.rank,
+			reversed:     m.reversed,
+			l33tSubs:     m.l33tSubs,
+			turns:        m.turns,
+			shiftedCount: m.shiftedCount,
+			ascending:    m.ascending,
+			baseToken:    string(m.baseToken),
+			repeatCount:  m.repeatCount,
+			year:         m.year,
+			separator:    m.separator,
+		})
+	}
+
+	return summaries
+}
+
+func TestMatchers(t *testing.T) {
+	const referenceYear = 2026
+	dictionaries := []rankedDictionary{{name: "test", ranks: rankWords([]string{"pass", "word", "password"})}}
+
+	testCases := []struct {
+		title    string
+		password string
+		matcher  func(password []rune) []match
+
+		expected []matchSummary
+	}{
+		{
+			title:    "dictionary words in any case",
+			password: "PassWord",
+			matcher: func(password []rune) []match {
+				return dictionaryMatches(password, dictionaries)
+			},
+			expected: []matchSummary{
+				{pattern: patternDictionary, i: 0, j: 3, token: "Pass", dictionary: "test", rank: 1},
+				{pattern: patternDictionary, i: 0, j: 7, token: "PassWord", dictionary: "test", rank: 3},
+				{pattern: patternDictionary, i: 4, j: 7, token: "Word", dictionary: "test", rank: 2},
+			},
+		},
+		{
+			title:    "no dictionary word",
+			password: "zebra",
+			matcher: func(password []rune) []match {
+				return dictionaryMatches(password, dictionaries)
+			},
+			expected: []matchSummary{},
+		},
+		{
+			title:    "reversed dictionary words",
+			password: "drowssap",
+			matcher: func(password []rune) []match {
+				return reversedDictionaryMatches(password, dictionaries)
+			},
+			expected: []matchSummary{
+				{pattern: patternDictionary, i: 4, j: 7, token: "ssap", dictionary: "test", rank: 1, reversed: true},
+				{pattern: patternDictionary, i: 0, j: 7, token: "drowssap", dictionary: "test", rank: 3, reversed: true},
+				{pattern: patternDictionary, i: 0, j: 3, token: "drow", dictionary: "test", rank: 2, reversed: true},
+			},
+		},
+		{
+			title:    "l33t dictionary words",
+			password: "p@ssw0rd",
+			matcher: func(password []rune) []match {
+				return l33tMatches(password, dictionaries)
+			},
+			expected: []matchSummary{
+				{pattern: patternDictionary, i: 0, j: 3, token: "p@ss", dictionary: "test", rank: 1, l33tSubs: map[rune]rune{'@': 'a'}},
+				{pattern: patternDictionary, i: 0, j: 7, token: "p@ssw0rd", dictionary: "test", rank: 3, l33tSubs: map[rune]rune{'@': 'a', '0': 'o'}},
+				{pattern: patternDictionary, i: 4, j: 7, token: "w0rd", dictionary: "test", rank: 2, l33tSubs: map[rune]rune{'0': 'o'}},
+			},
+		},
+		{
+			title:    "l33t without substitution",
+			password: "password",
+			matcher: func(password []rune) []match {
+				return l33tMatches(password, dictionaries)
+			},
+			expected: []matchSummary{},
+		},
+		{
+			title:    "straight keyboard row",
+			password: "qwerty",
+			matcher:  spatialMatches,
+			expected: []matchSummary{
+				{pattern: patternSpatial, i: 0, j: 5, token: "qwerty", turns: 1},
+			},
+		},
+		{
+			title:    "keyboard walk with a turn",
+			password: "zxcvfr",
+			matcher:  spatialMatches,
+			expected: []matchSummary{
+				{pattern: patternSpatial, i: 0, j: 5, token: "zxcvfr", turns: 2},
+			},
+		},
+		{
+			title:    "shifted keyboard walk",
+			password: "qwERty",
+			matcher:  spatialMatches,
+			expected: []matchSummary{
+				{pattern: patternSpatial, i: 0, j: 5, token: "qwERty", turns: 1, shiftedCount: 2},
+			},
+		},
+		{
+			title:    "keyboard walks broken by a jump",
+			password: "asdf;lkj",
+			matcher:  spatialMatches,
+			expected: []matchSummary{
+				{pattern: patternSpatial, i: 0, j: 3, token: "asdf", turns: 1},
+				{pattern: patternSpatial, i: 4, j: 7, token: ";lkj", turns: 1},
+			},
+		},
+		{
+			title:    "keyboard walk too short",
+			password: "qw",
+			matcher:  spatialMatches,
+			expected: []matchSummary{},
+		},
+		{
+			title:    "ascending sequence",
+			password: "abcdef",
+			matcher:  sequenceMatches,
+			expected: []matchSummary{
+				{pattern: patternSequence, i: 0, j: 5, token: "abcdef", ascending: true},
+			},
+		},
+		{
+			title:    "descending sequence by 2",
+			password: "9753",
+			matcher:  sequenceMatches,
+			expected: []matchSummary{
+				{pattern: patternSequence, i: 0, j: 3, token: "9753"},
+			},
+		},
+		{
+			title:    "sequence of characters of different classes",
+			password: "a1b2",
+			matcher:  sequenceMatches,
+			expected: []matchSummary{},
+		},
+		{
+			title:    "repeated character",
+			password: "aaaa",
+			matcher: func(password []rune) []match {
+				return repeatMatches(password, dictionaries, referenceYear)
+			},
+			expected: []matchSummary{
+				{pattern: patternRepeat, i: 0, j: 3, token: "aaaa", baseToken: "a", repeatCount: 4},
+			},
+		},
+		{
+			title:    "repeated word",
+			password: "abcabcabc",
+			matcher: func(password []rune) []match {
+				return repeatMatches(password, dictionaries, referenceYear)
+			},
+			expected: []matchSummary{
+				{pattern: patternRepeat, i: 0, j: 8, token: "abcabcabc", baseToken: "abc", repeatCount: 3},
+			},
+		},
+		{
+			title:    "year",
+			password: "born1998",
+			matcher:  yearMatches,
+			expected: []matchSummary{
+				{pattern: patternYear, i: 4, j: 7, token: "1998", year: 1998},
+			},
+		},
+		{
+			title:    "number out of the years",
+			password: "1850",
+			matcher:  yearMatches,
+			expected: []matchSummary{},
+		},
+		{
+			title:    "date with separators",
+			password: "13/05/1998",
+			matcher: func(password []rune) []match {
+				return dateMatches(password, referenceYear)
+			},
+			expected: []matchSummary{
+				{pattern: patternDate, i: 0, j: 9, token: "13/05/1998", year: 1998, separator: "/"},
+			},
+		},
+		{
+			title:    "date with the year first",
+			password: "1998-5-13",
+			matcher: func(password []rune) []match {
+				return dateMatches(password, referenceYear)
+			},
+			expected: []matchSummary{
+				{pattern: patternDate, i: 0, j: 8, token: "1998-5-13", year: 1998, separator: "-"},
+			},
+		},
+		{
+			title:    "date without separators",
+			password: "19980513",
+			matcher: func(password []rune) []match {
+				return dateMatches(password, referenceYear)
+			},
+			expected: []matchSummary{
+				{pattern: patternDate, i: 0, j: 7, token: "19980513", year: 1998},
+			},
+		},
+		{
+			title:    "date with a 2 digit year",
+			password: "130598",
+			matcher: func(password []rune) []match {
+				return dateMatches(password, referenceYear)
+			},
+			expected: []matchSummary{
+				{pattern: patternDate, i: 0, j: 5, token: "130598", year: 1998},
+			},
+		},
+		{
+			title:    "no valid day & month",
+			password: "45/45/45",
+			matcher: func(password []rune) []match {
+				return dateMatches(password, referenceYear)
+			},
+			expected: []matchSummary{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			assert.Equal(t, tc.expected, summarize(tc.matcher([]rune(tc.password))))
+		})
+	}
+}
+
+func TestMostGuessableMatchSequence(t *testing.T) {
+	const referenceYear = 2026
+
+	// rank looks up a word in the embedded dictionaries, the word lists get updated
+	rank := func(dictionary, word string) int {
+		for _, d := range rankedDictionaries {
+			if d.name == dictionary {
+				return d.ranks[word]
+			}
+		}
+		return 0
+	}
+
+	testCases := []struct {
+		title    string
+		password string
+
+		expected []matchSummary
+	}{
+		{
+			title:    "word & year",
+			password: "dragon2023",
+			expected: []matchSummary{
+				{pattern: patternDictionary, i: 0, j: 5, token: "dragon", dictionary: dictionaryPasswords, rank: rank(dictionaryPasswords, "dragon")},
+				{pattern: patternYear, i: 6, j: 9, token: "2023", year: 2023},
+			},
+		},
+		{
+			title:    "bruteforce between words",
+			password: "kucinglucu",
+			expected: []matchSummary{
+				{pattern: patternDictionary, i: 0, j: 5, token: "kucing", dictionary: dictionaryIndonesian, rank: rank(dictionaryIndonesian, "kucing")},
+				{pattern: patternBruteforce, i: 6, j: 9, token: "lucu"},
+			},
+		},
+		{
+			title:    "no pattern",
+			password: "x7#Kq9!vLz2@pW",
+			expected: []matchSummary{
+				{pattern: patternBruteforce, i: 0, j: 13, token: "x7#Kq9!vLz2@pW"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			password := []rune(tc.password)
+			sequence := mostGuessableMatchSequence(password, omnimatch(password, rankedDictionaries, referenceYear), referenceYear)
+			assert.Equal(t, tc.expected, summarize(sequence.matches))
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package strength
+
+import (
+	"math"
+	"sort"
+	"unicode"
+)
+
+const (
+	// bruteforceCardinality is the guesses per character of a part matching no pattern
+	bruteforceCardinality = 10
+
+	minSubmatchGuessesSingleChar = 10
+	minSubmatchGuessesMultiChar  = 50
+
+	// minYearSpace keeps a year close to the reference year from being guessed in a handful of tries
+	minYearSpace = 20
+
+	// sequencePenalty is the guesses a password made of one more match costs,
+	// an attacker has to try every number of matches
+	sequencePenalty = 10000
+)
+
+// guessSequence is the sequence of matches covering the password an attacker would guess first
+type guessSequence struct {
+	guessesLog10 float64
+	matches      []match
+}
+
+// candidate is the best last match of a sequence of l matches ending at a position,
+// with the product of the guesses of the l matches & the total guesses of the sequence
+type candidate struct {
+	m       match
+	piLog10 float64
+	gLog10  float64
+}
+
+// mostGuessableMatchSequence finds the sequence of non-overlapping matches, with bruteforce filling the gaps,
+// that takes the fewest guesses. Guesses are kept in log10, a long password overflows a float64.
+func mostGuessableMatchSequence(password []rune, matches []match, referenceYear int) guessSequence {
+	n := len(password)
+	if n == 0 {
+		return guessSequence{}
+	}
+
+	matchesByJ := make([][]match, n)
+	for _, m := range matches {
+		matchesByJ[m.j] = append(matchesByJ[m.j], m)
+	}
+
+	// optimal keeps, for each end position k, the best candidate by number of matches
+	optimal := make([]map[int]candidate, n)
+	for k := range optimal {
+		optimal[k] = map[int]candidate{}
+	}
+
+	update := func(m match, l int) {
+		k := m.j
+		piLog10 := estimateGuessesLog10(m, n, referenceYear)
+		if l > 1 {
+			piLog10 += optimal[m.i-1][l-1].piLog10
+		}
+		gLog10 := addLog10(factorialLog10(l)+piLog10, float64(l-1)*math.Log10(sequencePenalty))
+
+		for competingL, competing := range optimal[k] {
+			if competingL <= l && competing.gLog10 <= gLog10 {
+				return
+			}
+		}
+		optimal[k][l] = candidate{m: m, piLog10: piLog10, gLog10: gLog10}
+	}
+
+	bruteforceUpdate := func(k int) {
+		update(bruteforceMatch(password, 0, k), 1)
+		for i := 1; i <= k; i++ {
+			m := bruteforceMatch(password, i, k)
+			for _, l := range sortedLengths(optimal[i-1]) {
+				// consecutive bruteforce matches are one bruteforce match
+				if optimal[i-1][l].m.pattern == patternBruteforce {
+					continue
+				}
+				update(m, l+1)
+			}
+		}
+	}
+
+	for k := 0; k < n; k++ {
+		for _, m := range matchesByJ[k] {
+			if m.i == 0 {
+				update(m, 1)
+				continue
+			}
+			for _, l := range sortedLengths(optimal[m.i-1]) {
+				update(m, l+1)
+			}
+		}
+		bruteforceUpdate(k)
+	}
+
+	best := 0
+	for _, l := range sortedLengths(optimal[n-1]) {
+		if best == 0 || optimal[n-1][l].gLog10 < optimal[n-1][best].gLog10 {
+			best = l
+		}
+	}
+
+	sequence := guessSequence{guessesLog10: optimal[n-1][best].gLog10}
+	for k, l := n-1, best; k >= 0; k, l = sequence.matches[0].i-1, l-1 {
+		sequence.matches = append([]match{optimal[k][l].m}, sequence.matches...)
+	}
+
+	return sequence
+}
+
+func bruteforceMatch(password []rune, i, j int) match {
+	return match{pattern: patternBruteforce, i: i, j: j, token: password[i : j+1]}
+}
+
+// estimateGuessesLog10 is the log10 of the guesses to find the match, a part of a password of length n
+func estimateGuessesLog10(m match, n, referenceYear int) float64 {
+	length := m.j - m.i + 1
+
+	var guessesLog10 float64
+	switch m.pattern {
+	case patternDictionary:
+		guessesLog10 = math.Log10(float64(m.rank)) + uppercaseVariationsLog10(m.token) + l33tVariationsLog10(m)
+		if m.reversed {
+			guessesLog10 += math.Log10(2)
+		}
+	case patternSpatial:
+		guessesLog10 = spatialGuessesLog10(m)
+	case patternSequence:
+		guessesLog10 = sequenceGuessesLog10(m)
+	case patternRepeat:
+		guessesLog10 = m.baseGuessesLog10 + math.Log10(float64(m.repeatCount))
+	case patternYear:
+		guessesLog10 = math.Log10(yearSpace(m.year, referenceYear))
+	case patternDate:
+		guessesLog10 = math.Log10(yearSpace(m.year, referenceYear) * 365)
+		if m.separator != "" {
+			guessesLog10 += math.Log10(4)
+		}
+	case patternBruteforce:
+		guessesLog10 = float64(length) * math.Log10(bruteforceCardinality)
+		minGuesses := minSubmatchGuessesMultiChar + 1
+		if length == 1 {
+			minGuesses = minSubmatchGuessesSingleChar + 1
+		}
+		guessesLog10 = math.Max(guessesLog10, math.Log10(float64(minGuesses)))
+	}
+
+	// a match of a part of the password takes at least a few guesses, a match of the whole password at least one
+	if length < n {
+		minGuesses := minSubmatchGuessesMultiChar
+		if length == 1 {
+			minGuesses = minSubmatchGuessesSingleChar
+		}
+		guessesLog10 = math.Max(guessesLog10, math.Log10(float64(minGuesses)))
+	}
+
+	return guessesLog10
+}
+
+func yearSpace(year, referenceYear int) float64 {
+	return math.Max(float64(abs(year-referenceYear)), minYearSpace)
+}
+
+// uppercaseVariationsLog10 counts the ways to capitalize the word, a capitalized first or last letter is tried first
+func uppercaseVariationsLog10(token []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range token {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+
+	switch {
+	case upper == 0:
+		return 0
+	case lower == 0,
+		upper == 1 && unicode.IsUpper(token[0]),
+		upper == 1 && unicode.IsUpper(token[len(token)-1]):
+		return math.Log10(2)
+	}
+
+	return math.Log10(combinationsUpTo(upper+lower, min(upper, lower)))
+}
+
+// l33tVariationsLog10 counts the ways to substitute the letters, per substituted character
+func l33tVariationsLog10(m match) float64 {
+	if len(m.l33tSubs) == 0 {
+		return 0
+	}
+
+	var variationsLog10 float64
+	for sub, letter := range m.l33tSubs {
+		subbed, unsubbed := 0, 0
+		for _, r := range m.token {
+			switch unicode.ToLower(r) {
+			case sub:
+				subbed++
+			case letter:
+				unsubbed++
+			}
+		}
+		if unsubbed == 0 {
+			variationsLog10 += math.Log10(2)
+			continue
+		}
+		variationsLog10 += math.Log10(combinationsUpTo(subbed+unsubbed, min(subbed, unsubbed)))
+	}
+
+	return variationsLog10
+}
+
+// spatialGuessesLog10 counts the keyboard walks up to the length of the match with up to as many turns
+func spatialGuessesLog10(m match) float64 {
+	length := m.j - m.i + 1
+
+	var guesses float64
+	for i := 2; i <= length; i++ {
+		for j := 1; j <= min(m.turns, i-1); j++ {
+			guesses += binomial(i-1, j-1) * keyboardStartingPositions * math.Pow(keyboardAverageDegree, float64(j))
+		}
+	}
+
+	if m.shiftedCount > 0 {
+		unshifted := length - m.shiftedCount
+		if unshifted == 0 {
+			guesses *= 2
+		} else {
+			guesses *= combinationsUpTo(length, min(m.shiftedCount, unshifted))
+		}
+	}
+
+	return math.Log10(guesses)
+}
+
+// sequenceGuessesLog10 tries the obvious starts first, like a or 1, and ascending sequences before descending ones
+func sequenceGuessesLog10(m match) float64 {
+	var base float64
+	switch first := m.token[0]; {
+	case first == 'a' || first == 'z' || first == 'A' || first == 'Z' || first == '0' || first == '1' || first == '9':
+		base = 4
+	case first >= '0' && first <= '9':
+		base = 10
+	default:
+		base = 26
+	}
+	if !m.ascending {
+		base *= 2
+	}
+
+	return math.Log10(base * float64(len(m.token)))
+}
+
+// combinationsUpTo sums n choose i for i from 1 to k
+func combinationsUpTo(n, k int) float64 {
+	var sum float64
+	for i := 1; i <= k; i++ {
+		sum += binomial(n, i)
+	}
+
+	return sum
+}
+
+func binomial(n, k int) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+
+	return result
+}
+
+func factorialLog10(n int) float64 {
+	lgamma, _ := math.Lgamma(float64(n) + 1)
+	return lgamma / math.Ln10
+}
+
+// addLog10 is log10(10^a + 10^b) without leaving log space
+func addLog10(a, b float64) float64 {
+	if a < b {
+		a, b = b, a
+	}
+
+	return a + math.Log10(1+math.Pow(10, b-a))
+}
+
+func sortedLengths(candidates map[int]candidate) []int {
+	lengths := make([]int, 0, len(candidates))
+	for l := range candidates {
+		lengths = append(lengths, l)
+	}
+	sort.Ints(lengths)
+
+	return lengths
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,144 @@
+package strength
+
+import (
+	"math"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+)
+
+func TestEstimateGuessesLog10(t *testing.T) {
+	const referenceYear = 2026
+
+	testCases := []struct {
+		title string
+		m     match
+		// n is the length of the password the match is part of
+		n int
+
+		expected float64
+	}{
+		{
+			title:    "dictionary word",
+			m:        match{pattern: patternDictionary, i: 0, j: 3, token: []rune("pass"), rank: 100},
+			n:        4,
+			expected: 2,
+		},
+		{
+			title:    "reversed dictionary word",
+			m:        match{pattern: patternDictionary, i: 0, j: 3, token: []rune("ssap"), rank: 100, reversed: true},
+			n:        4,
+			expected: 2 + math.Log10(2),
+		},
+		{
+			title:    "capitalized dictionary word",
+			m:        match{pattern: patternDictionary, i: 0, j: 3, token: []rune("Pass"), rank: 1},
+			n:        4,
+			expected: math.Log10(2),
+		},
+		{
+			title: "dictionary word of mixed case",
+			m:     match{pattern: patternDictionary, i: 0, j: 3, token: []rune("PaSs"), rank: 1},
+			n:     4,
+			// 4 choose 1 + 4 choose 2
+			expected: 1,
+		},
+		{
+			title:    "l33t dictionary word",
+			m:        match{pattern: patternDictionary, i: 0, j: 3, token: []rune("p@ss"), rank: 1, l33tSubs: map[rune]rune{'@': 'a'}},
+			n:        4,
+			expected: math.Log10(2),
+		},
+		{
+			title:    "dictionary word part of the password",
+			m:        match{pattern: patternDictionary, i: 0, j: 3, token: []rune("pass"), rank: 1},
+			n:        8,
+			expected: math.Log10(minSubmatchGuessesMultiChar),
+		},
+		{
+			title:    "keyboard row",
+			m:        match{pattern: patternSpatial, i: 0, j: 5, token: []rune("qwerty"), turns: 1},
+			n:        6,
+			expected: math.Log10(5 * keyboardStartingPositions * keyboardAverageDegree),
+		},
+		{
+			title:    "shifted keyboard row",
+			m:        match{pattern: patternSpatial, i: 0, j: 5, token: []rune("QWERTY"), turns: 1, shiftedCount: 6},
+			n:        6,
+			expected: math.Log10(2 * 5 * keyboardStartingPositions * keyboardAverageDegree),
+		},
+		{
+			title:    "sequence from an obvious start",
+			m:        match{pattern: patternSequence, i: 0, j: 5, token: []rune("abcdef"), ascending: true},
+			n:        6,
+			expected: math.Log10(4 * 6),
+		},
+		{
+			title:    "descending sequence",
+			m:        match{pattern: patternSequence, i: 0, j: 3, token: []rune("8642")},
+			n:        4,
+			expected: math.Log10(10 * 2 * 4),
+		},
+		{
+			title:    "sequence of letters",
+			m:        match{pattern: patternSequence, i: 0, j: 3, token: []rune("mnop"), ascending: true},
+			n:        4,
+			expected: math.Log10(26 * 4),
+		},
+		{
+			title:    "repeat",
+			m:        match{pattern: patternRepeat, i: 0, j: 8, token: []rune("abcabcabc"), baseGuessesLog10: 1.5, repeatCount: 3},
+			n:        9,
+			expected: 1.5 + math.Log10(3),
+		},
+		{
+			title:    "year",
+			m:        match{pattern: patternYear, i: 0, j: 3, token: []rune("1998"), year: 1998},
+			n:        4,
+			expected: math.Log10(28),
+		},
+		{
+			title:    "recent year",
+			m:        match{pattern: patternYear, i: 0, j: 3, token: []rune("2025"), year: 2025},
+			n:        4,
+			expected: math.Log10(minYearSpace),
+		},
+		{
+			title:    "date",
+			m:        match{pattern: patternDate, i: 0, j: 7, token: []rune("19980513"), year: 1998},
+			n:        8,
+			expected: math.Log10(28 * 365),
+		},
+		{
+			title:    "date with separators",
+			m:        match{pattern: patternDate, i: 0, j: 9, token: []rune("13/05/1998"), year: 1998, separator: "/"},
+			n:        10,
+			expected: math.Log10(28 * 365 * 4),
+		},
+		{
+			title:    "bruteforce",
+			m:        match{pattern: patternBruteforce, i: 0, j: 4, token: []rune("x7#Kq")},
+			n:        5,
+			expected: 5,
+		},
+		{
+			title:    "bruteforce of one character",
+			m:        match{pattern: patternBruteforce, i: 0, j: 0, token: []rune("x")},
+			n:        5,
+			expected: math.Log10(minSubmatchGuessesSingleChar + 1),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			assert.InDelta(t, tc.expected, estimateGuessesLog10(tc.m, tc.n, referenceYear), 1e-9)
+		})
+	}
+}
+
+func TestAddLog10(t *testing.T) {
+	assert.InDelta(t, math.Log10(300), addLog10(2, math.Log10(200)), 1e-9)
+	assert.InDelta(t, math.Log10(300), addLog10(math.Log10(200), 2), 1e-9)
+	// far apart, the smaller one vanishes without overflowing
+	assert.InDelta(t, 400, addLog10(400, 1), 1e-9)
+}
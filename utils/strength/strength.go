@@ -0,0 +1,113 @@
+package strength
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// MaxScore is the score of a password hard enough to guess for any use
+	MaxScore = 4
+
+	// maxPasswordLength bounds the matching, which grows with the square of the length,
+	// the rest of a longer password only makes it stronger
+	maxPasswordLength = 100
+
+	// guessesPerSecond is an offline attack on a slow hash like bcrypt or argon2id
+	guessesPerSecond = 1e4
+)
+
+// scoreThresholdsLog10 are the guesses a password needs for each score above 0,
+// the 5 keeps a password right at the threshold in the lower score
+var scoreThresholdsLog10 = []float64{
+	math.Log10(1e3 + 5),
+	math.Log10(1e6 + 5),
+	math.Log10(1e8 + 5),
+	math.Log10(1e10 + 5),
+}
+
+// Result is how hard a password is to guess.
+type Result struct {
+	// Score goes from 0, too guessable, to 4, very unguessable
+	Score            int
+	GuessesLog10     float64
+	CrackTimeSeconds float64
+	CrackTimeDisplay string
+	Feedback         Feedback
+}
+
+// Estimate the guesses an attacker needs to find the password, trying the common patterns first:
+// dictionary words, keyboard walks, sequences, repeats, years & dates.
+// User inputs like the name & phone number of the user are guessed as common words.
+func Estimate(password string, userInputs ...string) Result {
+	runes := []rune(password)
+	if len(runes) > maxPasswordLength {
+		runes = runes[:maxPasswordLength]
+	}
+
+	dictionaries := rankedDictionaries
+	if words := userInputWords(userInputs); len(words) > 0 {
+		dictionaries = append([]rankedDictionary{{name: dictionaryUserInputs, ranks: rankWords(words)}}, dictionaries...)
+	}
+
+	referenceYear := time.Now().UTC().Year()
+	sequence := mostGuessableMatchSequence(runes, omnimatch(runes, dictionaries, referenceYear), referenceYear)
+
+	score := 0
+	for score < MaxScore && sequence.guessesLog10 >= scoreThresholdsLog10[score] {
+		score++
+	}
+
+	seconds := math.Pow(10, sequence.guessesLog10) / guessesPerSecond
+
+	return Result{
+		Score:            score,
+		GuessesLog10:     sequence.guessesLog10,
+		CrackTimeSeconds: seconds,
+		CrackTimeDisplay: displayTime(seconds),
+		Feedback:         feedbackFor(score, sequence),
+	}
+}
+
+func displayTime(seconds float64) string {
+	const (
+		minute  = 60
+		hour    = minute * 60
+		day     = hour * 24
+		month   = day * 31
+		year    = month * 12
+		century = year * 100
+	)
+
+	units := []struct {
+		name    string
+		seconds float64
+	}{
+		{"year", year},
+		{"month", month},
+		{"day", day},
+		{"hour", hour},
+		{"minute", minute},
+		{"second", 1},
+	}
+
+	switch {
+	case seconds < 1:
+		return "less than a second"
+	case seconds >= century:
+		return "centuries"
+	}
+
+	for _, unit := range units {
+		if seconds >= unit.seconds {
+			n := int(math.Round(seconds / unit.seconds))
+			if n == 1 {
+				return fmt.Sprintf("1 %s", unit.name)
+			}
+			return fmt.Sprintf("%d %ss", n, unit.name)
+		}
+	}
+
+	return "less than a second"
+}
@@ -0,0 +1,209 @@
+package strength
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+)
+
+func TestEstimate(t *testing.T) {
+	addWord := suggestionAddWord
+
+	testCases := []struct {
+		title      string
+		password   string
+		userInputs []string
+
+		expectedScore       int
+		expectedWarning     string
+		expectedSuggestions []string
+	}{
+		{
+			title:               "empty",
+			password:            "",
+			expectedScore:       0,
+			expectedSuggestions: defaultFeedback.Suggestions,
+		},
+		{
+			title:               "top-10 common password",
+			password:            "password",
+			expectedScore:       0,
+			expectedWarning:     "This is a top-10 common password",
+			expectedSuggestions: []string{addWord},
+		},
+		{
+			title:               "top-100 common password",
+			password:            "qwertyuiop",
+			expectedScore:       0,
+			expectedWarning:     "This is a top-100 common password",
+			expectedSuggestions: []string{addWord},
+		},
+		{
+			title:               "capitalized common password",
+			password:            "Password",
+			expectedScore:       0,
+			expectedWarning:     "This is a top-10 common password",
+			expectedSuggestions: []string{addWord, "Capitalization doesn't help very much"},
+		},
+		{
+			title:               "uppercase common password",
+			password:            "PASSWORD",
+			expectedScore:       0,
+			expectedWarning:     "This is a top-10 common password",
+			expectedSuggestions: []string{addWord, "All-uppercase is almost as easy to guess as all-lowercase"},
+		},
+		{
+			title:           "l33t common password",
+			password:        "P@ssw0rd",
+			expectedScore:   0,
+			expectedWarning: "This is similar to a commonly used password",
+			expectedSuggestions: []string{
+				addWord,
+				"Capitalization doesn't help very much",
+				"Predictable substitutions like '@' instead of 'a' don't help very much",
+			},
+		},
+		{
+			title:               "reversed common password",
+			password:            "drowssap",
+			expectedScore:       0,
+			expectedWarning:     "This is similar to a commonly used password",
+			expectedSuggestions: []string{addWord, "Reversed words aren't much harder to guess"},
+		},
+		{
+			title:               "indonesian word",
+			password:            "kucinglucu",
+			expectedScore:       2,
+			expectedWarning:     "Common words are easy to guess",
+			expectedSuggestions: []string{addWord},
+		},
+		{
+			title:               "name & year",
+			password:            "budi1998",
+			expectedScore:       1,
+			expectedWarning:     "Common names and surnames are easy to guess",
+			expectedSuggestions: []string{addWord},
+		},
+		{
+			title:               "keyboard walk",
+			password:            "zxcvfr",
+			expectedScore:       1,
+			expectedWarning:     "Short keyboard patterns are easy to guess",
+			expectedSuggestions: []string{addWord, "Use a longer keyboard pattern with more turns"},
+		},
+		{
+			title:               "sequence",
+			password:            "9876543",
+			expectedScore:       0,
+			expectedWarning:     "Sequences like abc or 6543 are easy to guess",
+			expectedSuggestions: []string{addWord, "Avoid sequences"},
+		},
+		{
+			title:               "repeated character",
+			password:            "aaaaaaa",
+			expectedScore:       0,
+			expectedWarning:     `Repeats like "aaa" are easy to guess`,
+			expectedSuggestions: []string{addWord, "Avoid repeated words and characters"},
+		},
+		{
+			title:               "repeated word",
+			password:            "abcabcabc",
+			expectedScore:       0,
+			expectedWarning:     `Repeats like "abcabcabc" are only slightly harder to guess than "abc"`,
+			expectedSuggestions: []string{addWord, "Avoid repeated words and characters"},
+		},
+		{
+			title:               "year",
+			password:            "1998",
+			expectedScore:       0,
+			expectedWarning:     "Recent years are easy to guess",
+			expectedSuggestions: []string{addWord, "Avoid recent years", "Avoid years that are associated with you"},
+		},
+		{
+			title:               "date",
+			password:            "13/05/1998",
+			expectedScore:       1,
+			expectedWarning:     "Dates are often easy to guess",
+			expectedSuggestions: []string{addWord, "Avoid dates and years that are associated with you"},
+		},
+		{
+			title:               "passphrase",
+			password:            "correct horse battery staple",
+			expectedScore:       4,
+			expectedSuggestions: []string{},
+		},
+		{
+			title:               "random characters",
+			password:            "x7#Kq9!vLz2@pW",
+			expectedScore:       4,
+			expectedSuggestions: []string{},
+		},
+		{
+			title:               "name of the user",
+			password:            "budisantoso",
+			userInputs:          []string{"Budi Santoso", "+6281234567890"},
+			expectedScore:       0,
+			expectedWarning:     "Your name or phone number is easy to guess",
+			expectedSuggestions: []string{addWord},
+		},
+		{
+			title:               "phone number of the user in the local format",
+			password:            "081234567890",
+			userInputs:          []string{"Budi Santoso", "+6281234567890"},
+			expectedScore:       0,
+			expectedWarning:     "Your name or phone number is easy to guess",
+			expectedSuggestions: []string{addWord},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			result := Estimate(tc.password, tc.userInputs...)
+			assert.Equal(t, tc.expectedScore, result.Score)
+			assert.Equal(t, tc.expectedWarning, result.Feedback.Warning)
+			assert.Equal(t, tc.expectedSuggestions, result.Feedback.Suggestions)
+		})
+	}
+}
+
+func TestEstimateUserInputsOnlyWeakenTheirPasswords(t *testing.T) {
+	withoutInputs := Estimate("budisantoso")
+	withInputs := Estimate("budisantoso", "Budi Santoso")
+
+	assert.True(t, withInputs.GuessesLog10 < withoutInputs.GuessesLog10)
+	assert.Equal(t, Estimate("x7#Kq9!vLz2@pW"), Estimate("x7#Kq9!vLz2@pW", "Budi Santoso"))
+}
+
+func TestEstimateLongPassword(t *testing.T) {
+	// the part above the max length isn't matched, it can't make the password weaker
+	password := strings.Repeat("x7#Kq9!vLz", maxPasswordLength)
+	result := Estimate(password)
+
+	assert.Equal(t, MaxScore, result.Score)
+	assert.Equal(t, Estimate(password[:maxPasswordLength]).GuessesLog10, result.GuessesLog10)
+}
+
+func TestDisplayTime(t *testing.T) {
+	testCases := []struct {
+		seconds  float64
+		expected string
+	}{
+		{seconds: 0, expected: "less than a second"},
+		{seconds: 0.5, expected: "less than a second"},
+		{seconds: 1, expected: "1 second"},
+		{seconds: 59, expected: "59 seconds"},
+		{seconds: 60, expected: "1 minute"},
+		{seconds: 90 * 60, expected: "2 hours"},
+		{seconds: 2 * 24 * 3600, expected: "2 days"},
+		{seconds: 3 * 31 * 24 * 3600, expected: "3 months"},
+		{seconds: 5 * 12 * 31 * 24 * 3600, expected: "5 years"},
+		{seconds: 100 * 12 * 31 * 24 * 3600, expected: "centuries"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			assert.Equal(t, tc.expected, displayTime(tc.seconds))
+		})
+	}
+}
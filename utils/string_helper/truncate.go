@@ -0,0 +1,11 @@
+package string_helper
+
+// Truncate cuts s to at most maxLen characters, without splitting a multi-byte character.
+func Truncate(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+
+	return string(runes[:maxLen])
+}
@@ -0,0 +1,34 @@
+package structvalidator
+
+import (
+	"user-service-sample/utils/breach"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	notBreachedTag = "_notbreached"
+
+	notBreachedValidationMessage = "{0} has appeared in a data breach, please choose a different password"
+)
+
+// WithBreachedPasswordValidationTag rejects passwords found in the index.
+// Without an index every password passes, and so does one the index fails to look up:
+// the corpus is a screening on top of the password rules, not a reason to refuse sign ups.
+func WithBreachedPasswordValidationTag(index breach.Index) func(*StructValidator) {
+
+	return func(sv *StructValidator) {
+		sv.validator.RegisterValidation(notBreachedTag, func(fl validator.FieldLevel) bool {
+			if index == nil {
+				return true
+			}
+			breached, err := index.Contains(fl.Field().String())
+			return err != nil || !breached
+		})
+		ct := customTranslation{
+			Tag:         notBreachedTag,
+			Translation: notBreachedValidationMessage,
+		}
+		ct.RegisterCustomTranslation(sv.validator, sv.translator)
+	}
+}
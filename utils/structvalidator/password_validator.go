@@ -1,29 +1,44 @@
 package structvalidator
 
 import (
+	"fmt"
+	"unicode/utf8"
+
 	"user-service-sample/utils/password"
 
 	"github.com/go-playground/validator/v10"
 )
 
 const (
-	// custom validation tags (prefix with "_")
-	passwordTag = "_password"
+	passwordTag          = "_password"
+	passwordMaxLengthTag = "_passwordmax"
 
-	passwordValidationMessage = "{0} must contain at least 1 capital characters, 1 number, and 1 special (non alpha-numeric) character"
+	passwordMaxLengthValidationMessage = "{0} must be a maximum of %d characters in length"
 )
 
-func validatePassword(fl validator.FieldLevel) bool {
-	return password.Validate(fl.Field().String())
-}
-
-func WithPasswordValidationTag() func(*StructValidator) {
+// WithPasswordValidationTags validates passwords against the policy. "_password" is for a new password, it
+// reports every rule of the policy the password fails, the rules on the personal info of the user are left to
+// the handler. "_passwordmax" is for a password the user already has, e.g. to log in, only its length is bounded
+// so a password set under an older policy still matches.
+func WithPasswordValidationTags(policy *password.Policy) func(*StructValidator) {
+	if policy == nil {
+		return nil
+	}
 
 	return func(sv *StructValidator) {
-		sv.validator.RegisterValidation(passwordTag, validatePassword)
+		sv.validator.RegisterValidation(passwordTag, func(fl validator.FieldLevel) bool {
+			return len(policy.Validate(fl.FieldName(), fl.Field().String(), password.PersonalInfo{})) == 0
+		})
+		sv.messages[passwordTag] = func(fe validator.FieldError) []string {
+			return policy.Validate(fe.Field(), fmt.Sprint(fe.Value()), password.PersonalInfo{})
+		}
+
+		sv.validator.RegisterValidation(passwordMaxLengthTag, func(fl validator.FieldLevel) bool {
+			return utf8.RuneCountInString(fl.Field().String()) <= policy.MaxLength
+		})
 		ct := customTranslation{
-			Tag:         passwordTag,
-			Translation: passwordValidationMessage,
+			Tag:         passwordMaxLengthTag,
+			Translation: fmt.Sprintf(passwordMaxLengthValidationMessage, policy.MaxLength),
 		}
 		ct.RegisterCustomTranslation(sv.validator, sv.translator)
 	}
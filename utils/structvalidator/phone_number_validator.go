@@ -0,0 +1,49 @@
+package structvalidator
+
+import (
+	"fmt"
+	"strings"
+
+	"user-service-sample/utils/phone"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	phoneNumberTag = "_phone"
+
+	phoneNumberValidationMessage = "{0} must be a mobile phone number from %s"
+)
+
+// WithPhoneNumberValidationTag accepts mobile phone numbers the normalizer brings to E.164,
+// the validated field is left as is, handlers store its normalized value.
+func WithPhoneNumberValidationTag(normalizer *phone.Normalizer) func(*StructValidator) {
+	if normalizer == nil {
+		return nil
+	}
+
+	return func(sv *StructValidator) {
+		sv.validator.RegisterValidation(phoneNumberTag, func(fl validator.FieldLevel) bool {
+			_, err := normalizer.Normalize(fl.Field().String())
+			return err == nil
+		})
+		ct := customTranslation{
+			Tag:         phoneNumberTag,
+			Translation: fmt.Sprintf(phoneNumberValidationMessage, countryNames(normalizer.Countries())),
+		}
+		ct.RegisterCustomTranslation(sv.validator, sv.translator)
+	}
+}
+
+// countryNames lists the countries for the validation message, e.g. Indonesia, Malaysia or Singapore
+func countryNames(countries []phone.Country) string {
+	names := make([]string, len(countries))
+	for i, c := range countries {
+		names[i] = c.Name
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
@@ -10,15 +10,12 @@ import (
 	enTranslations "github.com/go-playground/validator/v10/translations/en"
 )
 
-// custom validation tags (prefix with "_")
-const (
-	PasswordTag = "_password"
-)
-
 // StructValidator wraps https://github.com/go-playground/validator utility to create human-readable validation messages.
 type StructValidator struct {
 	validator  *validator.Validate
 	translator ut.Translator
+	// messages of tags reporting one message per rule the field fails, instead of a single translation
+	messages map[string]func(fe validator.FieldError) []string
 }
 
 // New creates new struct validator. Golang's field names will be used for validation messages.
@@ -33,6 +30,7 @@ func New() *StructValidator {
 	return &StructValidator{
 		validator:  validate,
 		translator: translator,
+		messages:   make(map[string]func(fe validator.FieldError) []string),
 	}
 }
 
@@ -90,6 +88,10 @@ func (j *StructValidator) ValidateStruct(s interface{}) ([]string, error) {
 func (j *StructValidator) translateValidationErrors(validationErrors validator.ValidationErrors) []string {
 	var messages []string
 	for _, validationError := range validationErrors {
+		if fieldMessages, ok := j.messages[validationError.Tag()]; ok {
+			messages = append(messages, fieldMessages(validationError)...)
+			continue
+		}
 		messages = append(messages, validationError.Translate(j.translator))
 	}
 	return messages
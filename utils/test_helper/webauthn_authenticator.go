@@ -0,0 +1,286 @@
+package test_helper
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"sort"
+)
+
+const (
+	// TestWebAuthnRPID & TestWebAuthnOrigin are the relying party of a server without WebAuthn config
+	TestWebAuthnRPID   = "localhost"
+	TestWebAuthnOrigin = "https://localhost"
+)
+
+// COSE algorithms of the passkeys an authenticator can hold
+const (
+	TestWebAuthnAlgES256 int64 = -7
+	TestWebAuthnAlgEdDSA int64 = -8
+	TestWebAuthnAlgRS256 int64 = -257
+)
+
+const (
+	authenticatorFlagUserPresent        = 0x01
+	authenticatorFlagUserVerified       = 0x04
+	authenticatorFlagAttestedCredential = 0x40
+)
+
+// WebAuthnAuthenticator is a software authenticator holding one passkey, to run the ceremonies in tests.
+// Binary values are base64url encoded, the same as in requests.
+type WebAuthnAuthenticator struct {
+	RPID   string
+	Origin string
+	// CrossOrigin is sent in the client data, as by a page embedded in another origin
+	CrossOrigin bool
+	// CredentialId is set by Register
+	CredentialId string
+	// UserHandle is the user id given to Register, returned by assertions
+	UserHandle string
+	// SignCount is incremented by every assertion
+	SignCount uint32
+	// Flags of the authenticator data, the user is present and verified by default
+	Flags byte
+	// Algorithm is the COSE algorithm of the passkey
+	Algorithm int64
+	key       crypto.Signer
+}
+
+type WebAuthnRegistration struct {
+	Id                string
+	ClientDataJSON    string
+	AttestationObject string
+}
+
+type WebAuthnAssertion struct {
+	Id                string
+	ClientDataJSON    string
+	AuthenticatorData string
+	Signature         string
+	UserHandle        string
+}
+
+// NewWebAuthnAuthenticator returns an authenticator holding an ES256 passkey.
+func NewWebAuthnAuthenticator() *WebAuthnAuthenticator {
+	return NewWebAuthnAuthenticatorWithAlgorithm(TestWebAuthnAlgES256)
+}
+
+// NewWebAuthnAuthenticatorWithAlgorithm returns an authenticator holding a passkey of the COSE algorithm alg.
+func NewWebAuthnAuthenticatorWithAlgorithm(alg int64) *WebAuthnAuthenticator {
+	var key crypto.Signer
+	var err error
+	switch alg {
+	case TestWebAuthnAlgES256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case TestWebAuthnAlgEdDSA:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	case TestWebAuthnAlgRS256:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		panic("NewWebAuthnAuthenticatorWithAlgorithm: unsupported algorithm")
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return &WebAuthnAuthenticator{
+		RPID:      TestWebAuthnRPID,
+		Origin:    TestWebAuthnOrigin,
+		Flags:     authenticatorFlagUserPresent | authenticatorFlagUserVerified,
+		Algorithm: alg,
+		key:       key,
+	}
+}
+
+// PublicKey returns the COSE_Key of the passkey, as stored on registration.
+func (a *WebAuthnAuthenticator) PublicKey() []byte {
+	switch key := a.key.Public().(type) {
+	case *ecdsa.PublicKey:
+		x := make([]byte, 32)
+		y := make([]byte, 32)
+		key.X.FillBytes(x)
+		key.Y.FillBytes(y)
+		return EncodeCBOR(map[int64]interface{}{
+			1:  int64(2), // kty: EC2
+			3:  a.Algorithm,
+			-1: int64(1), // crv: P-256
+			-2: x,
+			-3: y,
+		})
+	case ed25519.PublicKey:
+		return EncodeCBOR(map[int64]interface{}{
+			1:  int64(1), // kty: OKP
+			3:  a.Algorithm,
+			-1: int64(6), // crv: Ed25519
+			-2: []byte(key),
+		})
+	case *rsa.PublicKey:
+		return EncodeCBOR(map[int64]interface{}{
+			1:  int64(3), // kty: RSA
+			3:  a.Algorithm,
+			-1: key.N.Bytes(),
+			-2: big.NewInt(int64(key.E)).Bytes(),
+		})
+	}
+
+	panic("PublicKey: unsupported key")
+}
+
+// Register creates the passkey for the challenge of the registration options, with a "none" attestation.
+func (a *WebAuthnAuthenticator) Register(challenge, userId string) WebAuthnRegistration {
+	credentialId := make([]byte, 16)
+	if _, err := rand.Read(credentialId); err != nil {
+		panic(err)
+	}
+	a.CredentialId = base64.RawURLEncoding.EncodeToString(credentialId)
+	a.UserHandle = base64.RawURLEncoding.EncodeToString([]byte(userId))
+
+	authData := a.authenticatorData(a.Flags | authenticatorFlagAttestedCredential)
+	// AAGUID, all zero for a "none" attestation
+	authData = append(authData, make([]byte, 16)...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(credentialId)))
+	authData = append(authData, credentialId...)
+	authData = append(authData, a.PublicKey()...)
+
+	attestationObject := EncodeCBOR(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+
+	return WebAuthnRegistration{
+		Id:                a.CredentialId,
+		ClientDataJSON:    base64.RawURLEncoding.EncodeToString(a.clientData("webauthn.create", challenge)),
+		AttestationObject: base64.RawURLEncoding.EncodeToString(attestationObject),
+	}
+}
+
+// Assert signs the challenge of the log in options with the passkey, incrementing the signature counter.
+func (a *WebAuthnAuthenticator) Assert(challenge string) WebAuthnAssertion {
+	a.SignCount++
+	authData := a.authenticatorData(a.Flags)
+	clientDataJSON := a.clientData("webauthn.get", challenge)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signature := a.sign(append(append([]byte{}, authData...), clientDataHash[:]...))
+
+	return WebAuthnAssertion{
+		Id:                a.CredentialId,
+		ClientDataJSON:    base64.RawURLEncoding.EncodeToString(clientDataJSON),
+		AuthenticatorData: base64.RawURLEncoding.EncodeToString(authData),
+		Signature:         base64.RawURLEncoding.EncodeToString(signature),
+		UserHandle:        a.UserHandle,
+	}
+}
+
+// sign signs data as WebAuthn expects it of the algorithm: ES256 signatures are ASN.1 DER encoded,
+// EdDSA signs the data itself
+func (a *WebAuthnAuthenticator) sign(data []byte) []byte {
+	var signature []byte
+	var err error
+	switch a.Algorithm {
+	case TestWebAuthnAlgEdDSA:
+		signature, err = a.key.Sign(rand.Reader, data, crypto.Hash(0))
+	default:
+		digest := sha256.Sum256(data)
+		// ecdsa keys sign ASN.1 DER, rsa keys PKCS #1 v1.5
+		signature, err = a.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return signature
+}
+
+func (a *WebAuthnAuthenticator) authenticatorData(flags byte) []byte {
+	rpIdHash := sha256.Sum256([]byte(a.RPID))
+	authData := append([]byte{}, rpIdHash[:]...)
+	authData = append(authData, flags)
+
+	return binary.BigEndian.AppendUint32(authData, a.SignCount)
+}
+
+func (a *WebAuthnAuthenticator) clientData(ceremony, challenge string) []byte {
+	clientDataJSON, err := json.Marshal(map[string]interface{}{
+		"type":        ceremony,
+		"challenge":   challenge,
+		"origin":      a.Origin,
+		"crossOrigin": a.CrossOrigin,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return clientDataJSON
+}
+
+// EncodeCBOR encodes the few CBOR types of attestation objects & COSE keys, map keys are sorted
+// by their encoding as canonical CBOR requires.
+func EncodeCBOR(item interface{}) []byte {
+	switch v := item.(type) {
+	case int64:
+		if v < 0 {
+			return cborHead(1, uint64(-1-v))
+		}
+		return cborHead(0, uint64(v))
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case map[int64]interface{}:
+		entries := make([][2][]byte, 0, len(v))
+		for key, value := range v {
+			entries = append(entries, [2][]byte{EncodeCBOR(key), EncodeCBOR(value)})
+		}
+		return cborMap(entries)
+	case map[string]interface{}:
+		entries := make([][2][]byte, 0, len(v))
+		for key, value := range v {
+			entries = append(entries, [2][]byte{EncodeCBOR(key), EncodeCBOR(value)})
+		}
+		return cborMap(entries)
+	default:
+		panic("EncodeCBOR: unsupported type")
+	}
+}
+
+func cborMap(entries [][2][]byte) []byte {
+	sort.Slice(entries, func(i, j int) bool {
+		ki, kj := entries[i][0], entries[j][0]
+		if len(ki) != len(kj) {
+			return len(ki) < len(kj)
+		}
+		return bytes.Compare(ki, kj) < 0
+	})
+
+	out := cborHead(5, uint64(len(entries)))
+	for _, entry := range entries {
+		out = append(out, entry[0]...)
+		out = append(out, entry[1]...)
+	}
+
+	return out
+}
+
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n <= 0xff:
+		return []byte{major<<5 | 24, byte(n)}
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+	}
+}
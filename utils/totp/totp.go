@@ -0,0 +1,111 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Period, Digits & SHA-1 are the defaults of RFC 6238, the only parameters every authenticator app supports
+	Period = 30 * time.Second
+	Digits = 6
+
+	// secretLength is the 160 bits recommended by RFC 4226 for HMAC-SHA1
+	secretLength = 20
+
+	// skew is the number of periods accepted before & after the current one, for clock drift & typing time
+	skew = 1
+)
+
+var (
+	ErrInvalidSecret = errors.New("invalid TOTP secret")
+
+	encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+)
+
+// GenerateSecret returns a new random secret, base32 encoded without padding like authenticator apps expect
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(secret), nil
+}
+
+// URI is the otpauth:// URI of the key, shown as a QR code for authenticator apps to add the account,
+// see https://github.com/google/google-authenticator/wiki/Key-Uri-Format
+func URI(issuer, accountName, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(Digits))
+	query.Set("period", fmt.Sprint(int(Period/time.Second)))
+
+	label := escape(issuer) + ":" + escape(accountName)
+
+	return "otpauth://totp/" + label + "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+// escape encodes spaces as %20, some apps show a + as is, and the + of a phone number as %2B
+func escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// Step is the number of periods since the Unix epoch at t
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Code is the code of the secret at the time step
+func Code(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(key) == 0 {
+		return "", ErrInvalidSecret
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation of RFC 4226
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < Digits; i++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", Digits, value%modulo), nil
+}
+
+// Validate returns the time step the code is valid for around t, ok is false for a wrong code.
+// Callers must reject a step already used, a code stays valid for up to 3 periods.
+func Validate(secret, code string, t time.Time) (step int64, ok bool, err error) {
+	current := Step(t)
+	for s := current - skew; s <= current+skew; s++ {
+		expected, err := Code(secret, s)
+		if err != nil {
+			return 0, false, err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return s, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
@@ -0,0 +1,148 @@
+package webauthn
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	testCases := []struct {
+		title string
+		data  []byte
+
+		expectedItem interface{}
+		expectedRest []byte
+		expectedErr  error
+	}{
+		{
+			title:        "small unsigned integer",
+			data:         []byte{0x05},
+			expectedItem: int64(5),
+			expectedRest: []byte{},
+		},
+		{
+			title:        "unsigned integer of one byte",
+			data:         []byte{0x18, 0x64},
+			expectedItem: int64(100),
+			expectedRest: []byte{},
+		},
+		{
+			title:        "negative integer",
+			data:         []byte{0x39, 0x01, 0x00},
+			expectedItem: int64(-257),
+			expectedRest: []byte{},
+		},
+		{
+			title:       "unsigned integer above int64",
+			data:        []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+			expectedErr: errCBORUnsupported,
+		},
+		{
+			title:        "byte string",
+			data:         []byte{0x43, 0x01, 0x02, 0x03},
+			expectedItem: []byte{0x01, 0x02, 0x03},
+			expectedRest: []byte{},
+		},
+		{
+			title:        "text string",
+			data:         append([]byte{0x64}, "none"...),
+			expectedItem: "none",
+			expectedRest: []byte{},
+		},
+		{
+			title:        "array of simple values",
+			data:         []byte{0x83, 0xf5, 0xf4, 0xf6},
+			expectedItem: []interface{}{true, false, nil},
+			expectedRest: []byte{},
+		},
+		{
+			title:        "map of integer & text keys",
+			data:         append([]byte{0xa2, 0x03, 0x26, 0x63}, append([]byte("fmt"), 0x64, 'n', 'o', 'n', 'e')...),
+			expectedItem: map[interface{}]interface{}{int64(3): int64(-7), "fmt": "none"},
+			expectedRest: []byte{},
+		},
+		{
+			title:        "bytes following the item",
+			data:         []byte{0x01, 0xa0},
+			expectedItem: int64(1),
+			expectedRest: []byte{0xa0},
+		},
+		{
+			title:       "empty",
+			data:        []byte{},
+			expectedErr: errCBORTruncated,
+		},
+		{
+			title:       "argument truncated",
+			data:        []byte{0x19, 0x01},
+			expectedErr: errCBORTruncated,
+		},
+		{
+			title:       "byte string truncated",
+			data:        []byte{0x45, 0x01, 0x02},
+			expectedErr: errCBORTruncated,
+		},
+		{
+			title:       "array truncated",
+			data:        []byte{0x82, 0x01},
+			expectedErr: errCBORTruncated,
+		},
+		{
+			title:       "array longer than the data",
+			data:        []byte{0x99, 0xff, 0xff, 0x01},
+			expectedErr: errCBORTruncated,
+		},
+		{
+			title:       "map value missing",
+			data:        []byte{0xa1, 0x01},
+			expectedErr: errCBORTruncated,
+		},
+		{
+			title:       "map longer than the data",
+			data:        []byte{0xb9, 0xff, 0xff, 0x01, 0x02},
+			expectedErr: errCBORTruncated,
+		},
+		{
+			title:       "indefinite length",
+			data:        []byte{0x5f, 0x41, 0x00, 0xff},
+			expectedErr: errCBORUnsupported,
+		},
+		{
+			title:       "tag",
+			data:        []byte{0xc0, 0x01},
+			expectedErr: errCBORUnsupported,
+		},
+		{
+			title:       "float",
+			data:        []byte{0xf9, 0x3c, 0x00},
+			expectedErr: errCBORUnsupported,
+		},
+		{
+			title:       "map keyed by a byte string",
+			data:        []byte{0xa1, 0x41, 0x00, 0x01},
+			expectedErr: errCBORUnsupported,
+		},
+		{
+			title:       "nesting too deep",
+			data:        append(bytes.Repeat([]byte{0x81}, cborMaxDepth+1), 0x01),
+			expectedErr: errCBORTooDeep,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			item, rest, err := decodeCBOR(tc.data)
+			if tc.expectedErr != nil {
+				assert.True(t, errors.Is(err, tc.expectedErr), "expected %v, got %v", tc.expectedErr, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedItem, item)
+			assert.Equal(t, tc.expectedRest, rest)
+		})
+	}
+}
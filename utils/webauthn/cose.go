@@ -0,0 +1,124 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithms (RFC 9053) of the credentials accepted, in order of preference
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+// SupportedAlgorithms are offered to authenticators on registration
+var SupportedAlgorithms = []int64{AlgES256, AlgEdDSA, AlgRS256}
+
+const (
+	coseKeyKty = 1
+	coseKeyAlg = 3
+	// EC2 & OKP keys
+	coseKeyCrv = -1
+	coseKeyX   = -2
+	coseKeyY   = -3
+	// RSA keys
+	coseKeyN = -1
+	coseKeyE = -2
+
+	coseKtyOKP = 1
+	coseKtyEC2 = 2
+	coseKtyRSA = 3
+
+	coseCrvP256    = 1
+	coseCrvEd25519 = 6
+
+	// rsaMinBits refuses weak RSA credentials
+	rsaMinBits = 2048
+)
+
+// publicKey is a credential public key with the algorithm it signs with
+type publicKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+// parsePublicKey reads a COSE_Key of a supported algorithm, it returns the bytes following the key.
+func parsePublicKey(coseKey []byte) (publicKey, []byte, error) {
+	item, rest, err := decodeCBOR(coseKey)
+	if err != nil {
+		return publicKey{}, nil, fmt.Errorf("%w: %v", ErrUnsupportedAlgorithm, err)
+	}
+	m, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return publicKey{}, nil, ErrUnsupportedAlgorithm
+	}
+
+	kty, _ := m[int64(coseKeyKty)].(int64)
+	alg, _ := m[int64(coseKeyAlg)].(int64)
+	switch {
+	case alg == AlgES256 && kty == coseKtyEC2:
+		crv, _ := m[int64(coseKeyCrv)].(int64)
+		x, _ := m[int64(coseKeyX)].([]byte)
+		y, _ := m[int64(coseKeyY)].([]byte)
+		if crv != coseCrvP256 || len(x) != 32 || len(y) != 32 {
+			return publicKey{}, nil, ErrUnsupportedAlgorithm
+		}
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return publicKey{}, nil, ErrUnsupportedAlgorithm
+		}
+		return publicKey{alg: alg, key: key}, rest, nil
+
+	case alg == AlgEdDSA && kty == coseKtyOKP:
+		crv, _ := m[int64(coseKeyCrv)].(int64)
+		x, _ := m[int64(coseKeyX)].([]byte)
+		if crv != coseCrvEd25519 || len(x) != ed25519.PublicKeySize {
+			return publicKey{}, nil, ErrUnsupportedAlgorithm
+		}
+		return publicKey{alg: alg, key: ed25519.PublicKey(x)}, rest, nil
+
+	case alg == AlgRS256 && kty == coseKtyRSA:
+		n, _ := m[int64(coseKeyN)].([]byte)
+		e, _ := m[int64(coseKeyE)].([]byte)
+		if len(e) == 0 || len(e) > 4 {
+			return publicKey{}, nil, ErrUnsupportedAlgorithm
+		}
+		key := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		if key.N.BitLen() < rsaMinBits || key.E < 3 {
+			return publicKey{}, nil, ErrUnsupportedAlgorithm
+		}
+		return publicKey{alg: alg, key: key}, rest, nil
+	}
+
+	return publicKey{}, nil, ErrUnsupportedAlgorithm
+}
+
+// verify checks the signature of data, ES256 signatures are ASN.1 DER encoded as WebAuthn sends them
+func (k publicKey) verify(data, signature []byte) bool {
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, data, signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	}
+
+	return false
+}
@@ -0,0 +1,156 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+)
+
+func TestParsePublicKey(t *testing.T) {
+	es256 := test_helper.NewWebAuthnAuthenticatorWithAlgorithm(AlgES256).PublicKey()
+	eddsa := test_helper.NewWebAuthnAuthenticatorWithAlgorithm(AlgEdDSA).PublicKey()
+	rs256 := test_helper.NewWebAuthnAuthenticatorWithAlgorithm(AlgRS256).PublicKey()
+
+	weakRSAKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		title   string
+		coseKey []byte
+
+		expectedAlg  int64
+		expectedRest []byte
+		expectedErr  error
+	}{
+		{
+			title:        "ES256",
+			coseKey:      es256,
+			expectedAlg:  AlgES256,
+			expectedRest: []byte{},
+		},
+		{
+			title:        "EdDSA",
+			coseKey:      eddsa,
+			expectedAlg:  AlgEdDSA,
+			expectedRest: []byte{},
+		},
+		{
+			title:        "RS256",
+			coseKey:      rs256,
+			expectedAlg:  AlgRS256,
+			expectedRest: []byte{},
+		},
+		{
+			title:        "extensions following the key",
+			coseKey:      append(append([]byte{}, es256...), 0xa0),
+			expectedAlg:  AlgES256,
+			expectedRest: []byte{0xa0},
+		},
+		{
+			title:       "malformed cbor",
+			coseKey:     es256[:len(es256)-1],
+			expectedErr: ErrUnsupportedAlgorithm,
+		},
+		{
+			title:       "not a map",
+			coseKey:     test_helper.EncodeCBOR(int64(-7)),
+			expectedErr: ErrUnsupportedAlgorithm,
+		},
+		{
+			title: "ES256 on another curve",
+			coseKey: test_helper.EncodeCBOR(map[int64]interface{}{
+				coseKeyKty: int64(coseKtyEC2),
+				coseKeyAlg: AlgES256,
+				coseKeyCrv: int64(2), // P-384
+				coseKeyX:   bytes.Repeat([]byte{0x01}, 32),
+				coseKeyY:   bytes.Repeat([]byte{0x01}, 32),
+			}),
+			expectedErr: ErrUnsupportedAlgorithm,
+		},
+		{
+			title: "ES256 point not on the curve",
+			coseKey: test_helper.EncodeCBOR(map[int64]interface{}{
+				coseKeyKty: int64(coseKtyEC2),
+				coseKeyAlg: AlgES256,
+				coseKeyCrv: int64(coseCrvP256),
+				coseKeyX:   bytes.Repeat([]byte{0x01}, 32),
+				coseKeyY:   bytes.Repeat([]byte{0x01}, 32),
+			}),
+			expectedErr: ErrUnsupportedAlgorithm,
+		},
+		{
+			title: "EdDSA key too short",
+			coseKey: test_helper.EncodeCBOR(map[int64]interface{}{
+				coseKeyKty: int64(coseKtyOKP),
+				coseKeyAlg: AlgEdDSA,
+				coseKeyCrv: int64(coseCrvEd25519),
+				coseKeyX:   bytes.Repeat([]byte{0x01}, 31),
+			}),
+			expectedErr: ErrUnsupportedAlgorithm,
+		},
+		{
+			title: "algorithm of another key type",
+			coseKey: test_helper.EncodeCBOR(map[int64]interface{}{
+				coseKeyKty: int64(coseKtyOKP),
+				coseKeyAlg: AlgES256,
+				coseKeyCrv: int64(coseCrvEd25519),
+				coseKeyX:   bytes.Repeat([]byte{0x01}, 32),
+			}),
+			expectedErr: ErrUnsupportedAlgorithm,
+		},
+		{
+			title: "RSA key too weak",
+			coseKey: test_helper.EncodeCBOR(map[int64]interface{}{
+				coseKeyKty: int64(coseKtyRSA),
+				coseKeyAlg: AlgRS256,
+				coseKeyN:   weakRSAKey.N.Bytes(),
+				coseKeyE:   big.NewInt(int64(weakRSAKey.E)).Bytes(),
+			}),
+			expectedErr: ErrUnsupportedAlgorithm,
+		},
+		{
+			title: "RSA exponent of 1",
+			coseKey: test_helper.EncodeCBOR(map[int64]interface{}{
+				coseKeyKty: int64(coseKtyRSA),
+				coseKeyAlg: AlgRS256,
+				coseKeyN:   rsaKey.N.Bytes(),
+				coseKeyE:   []byte{0x01},
+			}),
+			expectedErr: ErrUnsupportedAlgorithm,
+		},
+		{
+			title: "unsupported algorithm",
+			coseKey: test_helper.EncodeCBOR(map[int64]interface{}{
+				coseKeyKty: int64(coseKtyEC2),
+				coseKeyAlg: int64(-35), // ES384
+				coseKeyCrv: int64(2),
+				coseKeyX:   bytes.Repeat([]byte{0x01}, 48),
+				coseKeyY:   bytes.Repeat([]byte{0x01}, 48),
+			}),
+			expectedErr: ErrUnsupportedAlgorithm,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			key, rest, err := parsePublicKey(tc.coseKey)
+			if tc.expectedErr != nil {
+				assert.True(t, errors.Is(err, tc.expectedErr), "expected %v, got %v", tc.expectedErr, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedAlg, key.alg)
+			assert.Equal(t, tc.expectedRest, rest)
+		})
+	}
+}
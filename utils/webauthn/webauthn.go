@@ -0,0 +1,315 @@
+// Package webauthn verifies the WebAuthn (https://www.w3.org/TR/webauthn-2/) ceremonies of passkeys:
+// registration, where an authenticator creates a credential, and assertion, where it signs a challenge.
+// Attestation statements aren't verified, relying parties ask for "none" and trust the logged in user.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"user-service-sample/config"
+)
+
+const (
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+
+	challengeByteLength = 32
+
+	flagUserPresent        = 0x01
+	flagUserVerified       = 0x04
+	flagBackupEligible     = 0x08
+	flagBackedUp           = 0x10
+	flagAttestedCredential = 0x40
+
+	// authenticatorDataMinLength is the RP ID hash, the flags & the signature counter
+	authenticatorDataMinLength = 37
+	// credentialIdMaxLength is the limit of the WebAuthn spec
+	credentialIdMaxLength = 1023
+)
+
+var (
+	ErrInvalidClientData    = errors.New("invalid client data")
+	ErrChallengeMismatch    = errors.New("challenge mismatch")
+	ErrOriginNotAllowed     = errors.New("origin not allowed")
+	ErrInvalidAuthData      = errors.New("invalid authenticator data")
+	ErrRPIDMismatch         = errors.New("rp id mismatch")
+	ErrUserNotVerified      = errors.New("user not present or not verified")
+	ErrInvalidAttestation   = errors.New("invalid attestation object")
+	ErrUnsupportedAlgorithm = errors.New("unsupported credential public key")
+	ErrInvalidSignature     = errors.New("invalid signature")
+	// ErrSignCountRegressed tells the credential may have been cloned, the counter didn't grow
+	ErrSignCountRegressed = errors.New("signature counter regressed")
+)
+
+// RelyingParty verifies the ceremonies of passkeys bound to ID, coming from one of Origins.
+// User verification, e.g. a fingerprint or PIN, is always required so a passkey is a complete sign in.
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+}
+
+func NewRelyingParty(cfg config.WebAuthnConfig) *RelyingParty {
+	return &RelyingParty{
+		ID:      cfg.GetRPID(),
+		Name:    cfg.GetRPName(),
+		Origins: cfg.GetOrigins(),
+	}
+}
+
+// Challenge is sent to the client in the ceremony options, only its hash is stored.
+type Challenge struct {
+	// Value is base64url encoded, the same as the client data returns it
+	Value string
+	Hash  string
+}
+
+// Credential is a credential created on registration, to keep for assertions.
+type Credential struct {
+	ID []byte
+	// PublicKey is the COSE_Key of the credential
+	PublicKey      []byte
+	Algorithm      int64
+	SignCount      uint32
+	BackupEligible bool
+	BackedUp       bool
+}
+
+// Assertion is the outcome of a verified assertion, to update the stored credential with.
+type Assertion struct {
+	SignCount uint32
+	BackedUp  bool
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+type authenticatorData struct {
+	rpIdHash  []byte
+	flags     byte
+	signCount uint32
+	// attestedCredentialData is only set on registration
+	credentialId []byte
+	publicKey    []byte
+	alg          int64
+}
+
+type attestationObject struct {
+	Fmt      string
+	AuthData []byte
+}
+
+// NewChallenge returns a new random challenge.
+func NewChallenge() (Challenge, error) {
+	b := make([]byte, challengeByteLength)
+	if _, err := rand.Read(b); err != nil {
+		return Challenge{}, err
+	}
+	value := base64.RawURLEncoding.EncodeToString(b)
+
+	return Challenge{
+		Value: value,
+		Hash:  HashChallenge(value),
+	}, nil
+}
+
+// HashChallenge returns the hex encoded SHA-256 hash used to look up a challenge.
+func HashChallenge(challenge string) string {
+	sum := sha256.Sum256([]byte(challenge))
+	return hex.EncodeToString(sum[:])
+}
+
+// ChallengeOf returns the challenge the client data was signed for, to look up the stored challenge
+// before the ceremony is verified.
+func ChallengeOf(clientDataJSON []byte) (string, error) {
+	var cd clientData
+	if err := json.Unmarshal(clientDataJSON, &cd); err != nil || cd.Challenge == "" {
+		return "", ErrInvalidClientData
+	}
+
+	return cd.Challenge, nil
+}
+
+// VerifyRegistration verifies the response of navigator.credentials.create() to the challenge,
+// see https://www.w3.org/TR/webauthn-2/#sctn-registering-a-new-credential
+func (rp *RelyingParty) VerifyRegistration(challenge string, clientDataJSON, attestationObjectCBOR []byte) (Credential, error) {
+	if err := rp.verifyClientData(clientDataJSON, ceremonyCreate, challenge); err != nil {
+		return Credential{}, err
+	}
+
+	attestation, err := parseAttestationObject(attestationObjectCBOR)
+	if err != nil {
+		return Credential{}, err
+	}
+	authData, err := parseAuthenticatorData(attestation.AuthData, true)
+	if err != nil {
+		return Credential{}, err
+	}
+	if err := rp.verifyAuthenticatorData(authData); err != nil {
+		return Credential{}, err
+	}
+
+	return Credential{
+		ID:             authData.credentialId,
+		PublicKey:      authData.publicKey,
+		Algorithm:      authData.alg,
+		SignCount:      authData.signCount,
+		BackupEligible: authData.flags&flagBackupEligible != 0,
+		BackedUp:       authData.flags&flagBackedUp != 0,
+	}, nil
+}
+
+// VerifyAssertion verifies the response of navigator.credentials.get() to the challenge, signed
+// by the credential with the stored public key & signature counter,
+// see https://www.w3.org/TR/webauthn-2/#sctn-verifying-assertion
+func (rp *RelyingParty) VerifyAssertion(challenge string, clientDataJSON, authenticatorDataBytes, signature, credentialPublicKey []byte, storedSignCount uint32) (Assertion, error) {
+	if err := rp.verifyClientData(clientDataJSON, ceremonyGet, challenge); err != nil {
+		return Assertion{}, err
+	}
+
+	authData, err := parseAuthenticatorData(authenticatorDataBytes, false)
+	if err != nil {
+		return Assertion{}, err
+	}
+	if err := rp.verifyAuthenticatorData(authData); err != nil {
+		return Assertion{}, err
+	}
+
+	key, _, err := parsePublicKey(credentialPublicKey)
+	if err != nil {
+		return Assertion{}, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, authenticatorDataBytes...), clientDataHash[:]...)
+	if !key.verify(signed, signature) {
+		return Assertion{}, ErrInvalidSignature
+	}
+
+	// authenticators without a counter always send 0
+	if (authData.signCount != 0 || storedSignCount != 0) && authData.signCount <= storedSignCount {
+		return Assertion{}, ErrSignCountRegressed
+	}
+
+	return Assertion{
+		SignCount: authData.signCount,
+		BackedUp:  authData.flags&flagBackedUp != 0,
+	}, nil
+}
+
+func (rp *RelyingParty) verifyClientData(clientDataJSON []byte, ceremony, challenge string) error {
+	var cd clientData
+	if err := json.Unmarshal(clientDataJSON, &cd); err != nil {
+		return ErrInvalidClientData
+	}
+	if cd.Type != ceremony {
+		return fmt.Errorf("%w: type %q", ErrInvalidClientData, cd.Type)
+	}
+	if subtle.ConstantTimeCompare([]byte(cd.Challenge), []byte(challenge)) != 1 {
+		return ErrChallengeMismatch
+	}
+	// a page of another origin embedding ours may not sign in the user
+	if cd.CrossOrigin || !rp.isAllowedOrigin(cd.Origin) {
+		return fmt.Errorf("%w: %q", ErrOriginNotAllowed, cd.Origin)
+	}
+
+	return nil
+}
+
+func (rp *RelyingParty) isAllowedOrigin(origin string) bool {
+	for _, o := range rp.Origins {
+		if o == origin {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (rp *RelyingParty) verifyAuthenticatorData(authData authenticatorData) error {
+	rpIdHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(authData.rpIdHash, rpIdHash[:]) {
+		return ErrRPIDMismatch
+	}
+	if authData.flags&flagUserPresent == 0 || authData.flags&flagUserVerified == 0 {
+		return ErrUserNotVerified
+	}
+
+	return nil
+}
+
+func parseAttestationObject(data []byte) (attestationObject, error) {
+	item, rest, err := decodeCBOR(data)
+	if err != nil || len(rest) != 0 {
+		return attestationObject{}, ErrInvalidAttestation
+	}
+	m, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return attestationObject{}, ErrInvalidAttestation
+	}
+
+	fmtName, _ := m["fmt"].(string)
+	authData, _ := m["authData"].([]byte)
+	if fmtName == "" || len(authData) == 0 {
+		return attestationObject{}, ErrInvalidAttestation
+	}
+
+	return attestationObject{
+		Fmt:      fmtName,
+		AuthData: authData,
+	}, nil
+}
+
+// parseAuthenticatorData reads the authenticator data, see https://www.w3.org/TR/webauthn-2/#sctn-authenticator-data.
+// withCredential requires the attested credential data of a registration.
+func parseAuthenticatorData(data []byte, withCredential bool) (authenticatorData, error) {
+	if len(data) < authenticatorDataMinLength {
+		return authenticatorData{}, ErrInvalidAuthData
+	}
+
+	authData := authenticatorData{
+		rpIdHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	if !withCredential {
+		return authData, nil
+	}
+	if authData.flags&flagAttestedCredential == 0 {
+		return authenticatorData{}, ErrInvalidAuthData
+	}
+
+	// AAGUID (16 bytes), then the credential id length (2 bytes)
+	rest := data[authenticatorDataMinLength:]
+	if len(rest) < 18 {
+		return authenticatorData{}, ErrInvalidAuthData
+	}
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if idLength == 0 || idLength > credentialIdMaxLength || len(rest) < idLength {
+		return authenticatorData{}, ErrInvalidAuthData
+	}
+	authData.credentialId = append([]byte{}, rest[:idLength]...)
+	rest = rest[idLength:]
+
+	key, afterKey, err := parsePublicKey(rest)
+	if err != nil {
+		return authenticatorData{}, err
+	}
+	authData.publicKey = append([]byte{}, rest[:len(rest)-len(afterKey)]...)
+	authData.alg = key.alg
+
+	return authData, nil
+}
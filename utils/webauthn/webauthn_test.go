@@ -0,0 +1,474 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"math"
+	"testing"
+
+	"user-service-sample/utils/test_helper"
+
+	"github.com/c2fo/testify/assert"
+)
+
+// testAlgorithms are the algorithms of the authenticators every ceremony is verified with
+var testAlgorithms = []struct {
+	name string
+	alg  int64
+}{
+	{name: "ES256", alg: AlgES256},
+	{name: "EdDSA", alg: AlgEdDSA},
+	{name: "RS256", alg: AlgRS256},
+}
+
+func testRelyingParty() *RelyingParty {
+	return &RelyingParty{
+		ID:      test_helper.TestWebAuthnRPID,
+		Name:    "Unit Test",
+		Origins: []string{test_helper.TestWebAuthnOrigin},
+	}
+}
+
+func mustDecodeBase64URL(t *testing.T, s string) []byte {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	assert.NoError(t, err)
+	return b
+}
+
+// noneAttestation is an attestation object of the authenticator data, as an authenticator returns it
+func noneAttestation(authData []byte) []byte {
+	return test_helper.EncodeCBOR(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	challenge, err := NewChallenge()
+	assert.NoError(t, err)
+
+	for _, algorithm := range testAlgorithms {
+		// the key is generated once per algorithm, every case runs on a copy of the authenticator
+		base := test_helper.NewWebAuthnAuthenticatorWithAlgorithm(algorithm.alg)
+
+		testCases := []struct {
+			title     string
+			challenge string
+			setup     func(a *test_helper.WebAuthnAuthenticator)
+			// clientData, attestationObject & authData tamper with the response of the authenticator
+			clientData        func(clientDataJSON []byte) []byte
+			attestationObject func(attestationObject []byte) []byte
+			authData          func(authData []byte) []byte
+
+			expectedErr error
+		}{
+			{
+				title: "valid registration",
+			},
+			{
+				title:       "challenge mismatch",
+				challenge:   "another-challenge",
+				expectedErr: ErrChallengeMismatch,
+			},
+			{
+				title: "client data not json",
+				clientData: func(clientDataJSON []byte) []byte {
+					return clientDataJSON[:len(clientDataJSON)-1]
+				},
+				expectedErr: ErrInvalidClientData,
+			},
+			{
+				title: "client data of an assertion",
+				clientData: func(clientDataJSON []byte) []byte {
+					return bytes.Replace(clientDataJSON, []byte(ceremonyCreate), []byte(ceremonyGet), 1)
+				},
+				expectedErr: ErrInvalidClientData,
+			},
+			{
+				title: "origin not allowed",
+				setup: func(a *test_helper.WebAuthnAuthenticator) {
+					a.Origin = "https://evil.example"
+				},
+				expectedErr: ErrOriginNotAllowed,
+			},
+			{
+				title: "cross origin",
+				setup: func(a *test_helper.WebAuthnAuthenticator) {
+					a.CrossOrigin = true
+				},
+				expectedErr: ErrOriginNotAllowed,
+			},
+			{
+				title: "rp id hash of another relying party",
+				setup: func(a *test_helper.WebAuthnAuthenticator) {
+					a.RPID = "evil.example"
+				},
+				expectedErr: ErrRPIDMismatch,
+			},
+			{
+				title: "user not verified",
+				setup: func(a *test_helper.WebAuthnAuthenticator) {
+					a.Flags = flagUserPresent
+				},
+				expectedErr: ErrUserNotVerified,
+			},
+			{
+				title: "user not present",
+				setup: func(a *test_helper.WebAuthnAuthenticator) {
+					a.Flags = flagUserVerified
+				},
+				expectedErr: ErrUserNotVerified,
+			},
+			{
+				title: "attestation object truncated",
+				attestationObject: func(attestationObject []byte) []byte {
+					return attestationObject[:len(attestationObject)-1]
+				},
+				expectedErr: ErrInvalidAttestation,
+			},
+			{
+				title: "attestation object followed by other data",
+				attestationObject: func(attestationObject []byte) []byte {
+					return append(attestationObject, 0x00)
+				},
+				expectedErr: ErrInvalidAttestation,
+			},
+			{
+				title: "attestation object of indefinite length",
+				attestationObject: func(attestationObject []byte) []byte {
+					return []byte{0xbf, 0xff}
+				},
+				expectedErr: ErrInvalidAttestation,
+			},
+			{
+				title: "attestation object not a map",
+				attestationObject: func(attestationObject []byte) []byte {
+					return test_helper.EncodeCBOR("none")
+				},
+				expectedErr: ErrInvalidAttestation,
+			},
+			{
+				title: "attestation object without authenticator data",
+				attestationObject: func(attestationObject []byte) []byte {
+					return test_helper.EncodeCBOR(map[string]interface{}{"fmt": "none"})
+				},
+				expectedErr: ErrInvalidAttestation,
+			},
+			{
+				title: "authenticator data truncated before the signature counter",
+				authData: func(authData []byte) []byte {
+					return authData[:authenticatorDataMinLength-1]
+				},
+				expectedErr: ErrInvalidAuthData,
+			},
+			{
+				title: "authenticator data without attested credential data",
+				authData: func(authData []byte) []byte {
+					authData[32] &^= flagAttestedCredential
+					return authData
+				},
+				expectedErr: ErrInvalidAuthData,
+			},
+			{
+				title: "attested credential data truncated",
+				authData: func(authData []byte) []byte {
+					return authData[:authenticatorDataMinLength+17]
+				},
+				expectedErr: ErrInvalidAuthData,
+			},
+			{
+				title: "credential id longer than the authenticator data",
+				authData: func(authData []byte) []byte {
+					authData[authenticatorDataMinLength+16] = 0x03
+					authData[authenticatorDataMinLength+17] = 0xff
+					return authData
+				},
+				expectedErr: ErrInvalidAuthData,
+			},
+			{
+				title: "credential public key truncated",
+				authData: func(authData []byte) []byte {
+					return authData[:len(authData)-1]
+				},
+				expectedErr: ErrUnsupportedAlgorithm,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(algorithm.name+" "+tc.title, func(t *testing.T) {
+				a := *base
+				if tc.setup != nil {
+					tc.setup(&a)
+				}
+				registration := a.Register(challenge.Value, test_helper.TestUserId)
+
+				clientDataJSON := mustDecodeBase64URL(t, registration.ClientDataJSON)
+				if tc.clientData != nil {
+					clientDataJSON = tc.clientData(clientDataJSON)
+				}
+				attestationObject := mustDecodeBase64URL(t, registration.AttestationObject)
+				if tc.authData != nil {
+					attestation, err := parseAttestationObject(attestationObject)
+					assert.NoError(t, err)
+					attestationObject = noneAttestation(tc.authData(attestation.AuthData))
+				}
+				if tc.attestationObject != nil {
+					attestationObject = tc.attestationObject(attestationObject)
+				}
+				verifiedChallenge := challenge.Value
+				if tc.challenge != "" {
+					verifiedChallenge = tc.challenge
+				}
+
+				credential, err := testRelyingParty().VerifyRegistration(verifiedChallenge, clientDataJSON, attestationObject)
+				if tc.expectedErr != nil {
+					assert.True(t, errors.Is(err, tc.expectedErr), "expected %v, got %v", tc.expectedErr, err)
+					return
+				}
+
+				assert.NoError(t, err)
+				assert.Equal(t, mustDecodeBase64URL(t, a.CredentialId), credential.ID)
+				assert.Equal(t, a.PublicKey(), credential.PublicKey)
+				assert.Equal(t, algorithm.alg, credential.Algorithm)
+				assert.Equal(t, uint32(0), credential.SignCount)
+				assert.False(t, credential.BackupEligible)
+				assert.False(t, credential.BackedUp)
+			})
+		}
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	registrationChallenge, err := NewChallenge()
+	assert.NoError(t, err)
+	challenge, err := NewChallenge()
+	assert.NoError(t, err)
+
+	for _, algorithm := range testAlgorithms {
+		base := test_helper.NewWebAuthnAuthenticatorWithAlgorithm(algorithm.alg)
+		base.Register(registrationChallenge.Value, test_helper.TestUserId)
+		forger := test_helper.NewWebAuthnAuthenticatorWithAlgorithm(algorithm.alg)
+
+		testCases := []struct {
+			title           string
+			challenge       string
+			setup           func(a *test_helper.WebAuthnAuthenticator)
+			storedSignCount uint32
+			// publicKey replaces the stored public key of the passkey
+			publicKey func(a *test_helper.WebAuthnAuthenticator) []byte
+			// clientData, authData & signature tamper with the response of the authenticator
+			clientData func(clientDataJSON []byte) []byte
+			authData   func(authData []byte) []byte
+			signature  func(signature []byte) []byte
+
+			expectedErr       error
+			expectedSignCount uint32
+		}{
+			{
+				title:             "valid assertion",
+				expectedSignCount: 1,
+			},
+			{
+				title: "valid assertion after others",
+				setup: func(a *test_helper.WebAuthnAuthenticator) {
+					a.SignCount = 41
+				},
+				storedSignCount:   41,
+				expectedSignCount: 42,
+			},
+			{
+				title: "authenticator without signature counter",
+				setup: func(a *test_helper.WebAuthnAuthenticator) {
+					// the next assertion sends 0
+					a.SignCount = math.MaxUint32
+				},
+				expectedSignCount: 0,
+			},
+			{
+				title: "signature counter regressed",
+				setup: func(a *test_helper.WebAuthnAuthenticator) {
+					a.SignCount = 4
+				},
+				storedSignCount: 7,
+				expectedErr:     ErrSignCountRegressed,
+			},
+			{
+				title: "signature counter not grown",
+				setup: func(a *test_helper.WebAuthnAuthenticator) {
+					a.SignCount = 6
+				},
+				storedSignCount: 7,
+				expectedErr:     ErrSignCountRegressed,
+			},
+			{
+				title: "signature counter reset to zero",
+				setup: func(a *test_helper.WebAuthnAuthenticator) {
+					a.SignCount = math.MaxUint32
+				},
+				storedSignCount: 3,
+				expectedErr:     ErrSignCountRegressed,
+			},
+			{
+				title:       "challenge mismatch",
+				challenge:   registrationChallenge.Value,
+				expectedErr: ErrChallengeMismatch,
+			},
+			{
+				title: "client data of a registration",
+				clientData: func(clientDataJSON []byte) []byte {
+					return bytes.Replace(clientDataJSON, []byte(ceremonyGet), []byte(ceremonyCreate), 1)
+				},
+				expectedErr: ErrInvalidClientData,
+			},
+			{
+				title: "origin not allowed",
+				setup: func(a *test_helper.WebAuthnAuthenticator) {
+					a.Origin = "https://evil.example"
+				},
+				expectedErr: ErrOriginNotAllowed,
+			},
+			{
+				title: "cross origin",
+				setup: func(a *test_helper.WebAuthnAuthenticator) {
+					a.CrossOrigin = true
+				},
+				expectedErr: ErrOriginNotAllowed,
+			},
+			{
+				title: "rp id hash of another relying party",
+				setup: func(a *test_helper.WebAuthnAuthenticator) {
+					a.RPID = "evil.example"
+				},
+				expectedErr: ErrRPIDMismatch,
+			},
+			{
+				title: "user not verified",
+				setup: func(a *test_helper.WebAuthnAuthenticator) {
+					a.Flags = flagUserPresent
+				},
+				expectedErr: ErrUserNotVerified,
+			},
+			{
+				title: "user not present",
+				setup: func(a *test_helper.WebAuthnAuthenticator) {
+					a.Flags = flagUserVerified
+				},
+				expectedErr: ErrUserNotVerified,
+			},
+			{
+				title: "authenticator data truncated",
+				authData: func(authData []byte) []byte {
+					return authData[:authenticatorDataMinLength-1]
+				},
+				expectedErr: ErrInvalidAuthData,
+			},
+			{
+				title: "authenticator data tampered",
+				authData: func(authData []byte) []byte {
+					authData[32] |= flagBackedUp
+					return authData
+				},
+				expectedErr: ErrInvalidSignature,
+			},
+			{
+				title: "signature truncated",
+				signature: func(signature []byte) []byte {
+					return signature[:len(signature)-1]
+				},
+				expectedErr: ErrInvalidSignature,
+			},
+			{
+				title: "signed by another passkey",
+				publicKey: func(a *test_helper.WebAuthnAuthenticator) []byte {
+					return forger.PublicKey()
+				},
+				expectedErr: ErrInvalidSignature,
+			},
+			{
+				title: "stored public key truncated",
+				publicKey: func(a *test_helper.WebAuthnAuthenticator) []byte {
+					publicKey := a.PublicKey()
+					return publicKey[:len(publicKey)-1]
+				},
+				expectedErr: ErrUnsupportedAlgorithm,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(algorithm.name+" "+tc.title, func(t *testing.T) {
+				a := *base
+				if tc.setup != nil {
+					tc.setup(&a)
+				}
+				assertion := a.Assert(challenge.Value)
+
+				clientDataJSON := mustDecodeBase64URL(t, assertion.ClientDataJSON)
+				if tc.clientData != nil {
+					clientDataJSON = tc.clientData(clientDataJSON)
+				}
+				authData := mustDecodeBase64URL(t, assertion.AuthenticatorData)
+				if tc.authData != nil {
+					authData = tc.authData(authData)
+				}
+				signature := mustDecodeBase64URL(t, assertion.Signature)
+				if tc.signature != nil {
+					signature = tc.signature(signature)
+				}
+				publicKey := a.PublicKey()
+				if tc.publicKey != nil {
+					publicKey = tc.publicKey(&a)
+				}
+				verifiedChallenge := challenge.Value
+				if tc.challenge != "" {
+					verifiedChallenge = tc.challenge
+				}
+
+				result, err := testRelyingParty().VerifyAssertion(verifiedChallenge, clientDataJSON, authData, signature, publicKey, tc.storedSignCount)
+				if tc.expectedErr != nil {
+					assert.True(t, errors.Is(err, tc.expectedErr), "expected %v, got %v", tc.expectedErr, err)
+					return
+				}
+
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedSignCount, result.SignCount)
+				assert.False(t, result.BackedUp)
+			})
+		}
+	}
+}
+
+func TestChallengeOf(t *testing.T) {
+	testCases := []struct {
+		title          string
+		clientDataJSON string
+
+		expectedChallenge string
+		expectedErr       error
+	}{
+		{
+			title:             "challenge of the client data",
+			clientDataJSON:    `{"type":"webauthn.get","challenge":"abc","origin":"https://localhost"}`,
+			expectedChallenge: "abc",
+		},
+		{
+			title:          "without challenge",
+			clientDataJSON: `{"type":"webauthn.get","origin":"https://localhost"}`,
+			expectedErr:    ErrInvalidClientData,
+		},
+		{
+			title:          "not json",
+			clientDataJSON: `{"type":`,
+			expectedErr:    ErrInvalidClientData,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			challenge, err := ChallengeOf([]byte(tc.clientDataJSON))
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedChallenge, challenge)
+		})
+	}
+}